@@ -28,6 +28,8 @@ type Config interface {
 	SqliteFolder() string
 	//files storage location.
 	MatterPath() string
+	//ips or cidrs of the reverse proxies whose forwarded headers are trusted.
+	TrustedProxies() []string
 	//table name strategy
 	NamingStrategy() schema.NamingStrategy
 	//when installed by user. Write configs to tank.json
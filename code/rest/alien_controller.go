@@ -198,7 +198,7 @@ func (this *AlienController) Confirm(writer http.ResponseWriter, request *http.R
 		panic(result.BadRequest("matter not belong to you"))
 	}
 
-	return this.Success(matter)
+	return this.Success(matter.ShowHash(user))
 }
 
 // a guest upload a file with a upload token.
@@ -250,7 +250,7 @@ func (this *AlienController) Upload(writer http.ResponseWriter, request *http.Re
 	uploadToken.ExpireTime = time.Now()
 	this.uploadTokenDao.Save(uploadToken)
 
-	return this.Success(matter)
+	return this.Success(matter.ShowHash(user))
 }
 
 // crawl a url with uploadToken. guest can visit this method.
@@ -287,7 +287,7 @@ func (this *AlienController) CrawlToken(writer http.ResponseWriter, request *htt
 	uploadToken.ExpireTime = time.Now()
 	this.uploadTokenDao.Save(uploadToken)
 
-	return this.Success(matter)
+	return this.Success(matter.ShowHash(user))
 }
 
 // crawl a url directly. only user can visit this method.
@@ -311,7 +311,7 @@ func (this *AlienController) CrawlDirect(writer http.ResponseWriter, request *ht
 
 	matter := this.matterService.AtomicCrawl(request, url, filename, user, space, dirMatter, privacy)
 
-	return this.Success(matter)
+	return this.Success(matter.ShowHash(user))
 }
 
 // fetch a download token for guest. Guest can download file with this token.
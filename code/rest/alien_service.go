@@ -20,6 +20,8 @@ type AlienService struct {
 	imageCacheDao     *ImageCacheDao
 	imageCacheService *ImageCacheService
 	spaceService      *SpaceService
+	blindService      *BlindService
+	storageService    *StorageService
 }
 
 func (this *AlienService) Init() {
@@ -68,6 +70,16 @@ func (this *AlienService) Init() {
 	if c, ok := b.(*SpaceService); ok {
 		this.spaceService = c
 	}
+
+	b = core.CONTEXT.GetBean(this.blindService)
+	if c, ok := b.(*BlindService); ok {
+		this.blindService = c
+	}
+
+	b = core.CONTEXT.GetBean(this.storageService)
+	if c, ok := b.(*StorageService); ok {
+		this.storageService = c
+	}
 }
 
 // check whether the request params ok.
@@ -80,7 +92,11 @@ func (this *AlienService) ValidMatter(
 	matter := this.matterDao.CheckByUuid(uuid)
 
 	if matter.Name != filename {
-		panic(result.BadRequest("filename in url incorrect"))
+		//judges of a blind round get the links by the blind names.
+		if filename == "" || this.blindService.BlindName(matter) != filename {
+			panic(result.BadRequest("filename in url incorrect"))
+		}
+		matter.Name = filename
 	}
 
 	//only private file need auth.
@@ -136,7 +152,7 @@ func (this *AlienService) PreviewOrDownload(
 	//download directory
 	if matter.Dir {
 
-		this.matterService.DownloadZip(writer, request, []*Matter{matter})
+		this.matterService.DownloadZipAs(writer, request, []*Matter{matter}, this.blindService.ZipNameFunc(this.findUser(request)))
 
 	} else {
 
@@ -152,10 +168,10 @@ func (this *AlienService) PreviewOrDownload(
 			}
 
 			//download the cache image file.
-			this.matterService.DownloadFile(writer, request, GetSpaceCacheRootDir(imageCache.Username)+imageCache.Path, imageCache.Name, withContentDisposition)
+			this.storageService.Download(writer, request, imageCache.StorageSpaceName(), imageCache.StorageKey(), imageCache.Name, withContentDisposition)
 
 		} else {
-			this.matterService.DownloadFile(writer, request, matter.AbsolutePath(), matter.Name, withContentDisposition)
+			this.matterService.DownloadFile(writer, request, matter, withContentDisposition)
 		}
 
 	}
@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+type AnomalyController struct {
+	BaseController
+	anomalyService *AnomalyService
+}
+
+func (this *AnomalyController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.anomalyService)
+	if b, ok := b.(*AnomalyService); ok {
+		this.anomalyService = b
+	}
+}
+
+func (this *AnomalyController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/anomaly/report"] = this.Wrap(this.Report, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+func (this *AnomalyController) formInt64(request *http.Request, key string) int64 {
+	str := request.FormValue(key)
+	if str == "" {
+		return 0
+	}
+	value, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		panic(result.BadRequest("%s格式错误", key))
+	}
+	return value
+}
+
+// suspicious ratings of the round. thresholds are set in the anomaly config of the preference.
+func (this *AnomalyController) Report(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	return this.Success(this.anomalyService.Report(this.formInt64(request, "roundId"), this.formInt64(request, "trackId")))
+}
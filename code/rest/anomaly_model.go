@@ -0,0 +1,31 @@
+package rest
+
+const (
+	//the judge gave the same score to many submissions in a row.
+	ANOMALY_TYPE_IDENTICAL_RUN = "IDENTICAL_RUN"
+	//the score is far from the other judges of the submission.
+	ANOMALY_TYPE_OUTLIER = "OUTLIER"
+	//the judge rated right after the previous rating.
+	ANOMALY_TYPE_FAST = "FAST"
+	//the judge is of the same college as the submission or one of its authors.
+	ANOMALY_TYPE_SAME_COLLEGE = "SAME_COLLEGE"
+)
+
+// RatingAnomaly is a suspicious rating, or a suspicious run of ratings of a judge.
+type RatingAnomaly struct {
+	Type          string  `json:"type"`
+	JudgeUuid     string  `json:"judgeUuid"`
+	JudgeName     string  `json:"judgeName"`
+	SubmissionIds []int64 `json:"submissionIds"`
+	RatingIds     []int64 `json:"ratingIds"`
+	Score         float64 `json:"score"`
+	Detail        string  `json:"detail"`
+}
+
+// AnomalyReport lists the anomalies of the ratings in a round.
+type AnomalyReport struct {
+	RoundId   int64            `json:"roundId"`
+	Config    *AnomalyConfig   `json:"config"`
+	Ratings   int              `json:"ratings"`
+	Anomalies []*RatingAnomaly `json:"anomalies"`
+}
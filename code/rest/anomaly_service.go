@@ -0,0 +1,249 @@
+package rest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+// @Service
+type AnomalyService struct {
+	BaseBean
+	ratingDao         *RatingDao
+	submissionDao     *SubmissionDao
+	userDao           *UserDao
+	assignmentService *AssignmentService
+	preferenceService *PreferenceService
+}
+
+func (this *AnomalyService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.ratingDao)
+	if b, ok := b.(*RatingDao); ok {
+		this.ratingDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userDao)
+	if b, ok := b.(*UserDao); ok {
+		this.userDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.assignmentService)
+	if b, ok := b.(*AssignmentService); ok {
+		this.assignmentService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.preferenceService)
+	if b, ok := b.(*PreferenceService); ok {
+		this.preferenceService = b
+	}
+}
+
+// check the ratings of the round, filtered by track if trackId is not 0.
+func (this *AnomalyService) Report(roundId int64, trackId int64) *AnomalyReport {
+	config := this.preferenceService.Fetch().FetchAnomalyConfig()
+
+	submissionMap := make(map[int64]*Submission)
+	var ratings []*Rating
+	for _, rating := range this.ratingDao.FindByRoundId(roundId) {
+		submission, ok := submissionMap[rating.SubmissionId]
+		if !ok {
+			submission = this.submissionDao.FindById(rating.SubmissionId)
+			submissionMap[rating.SubmissionId] = submission
+		}
+		if submission == nil || (trackId != 0 && submission.TrackId != trackId) {
+			continue
+		}
+		ratings = append(ratings, rating)
+	}
+
+	//ratings of every judge in the order they were given.
+	judgeRatings := make(map[string][]*Rating)
+	var judgeUuids []string
+	for _, rating := range ratings {
+		if _, ok := judgeRatings[rating.JudgeUuid]; !ok {
+			judgeUuids = append(judgeUuids, rating.JudgeUuid)
+		}
+		judgeRatings[rating.JudgeUuid] = append(judgeRatings[rating.JudgeUuid], rating)
+	}
+	for _, judgeUuid := range judgeUuids {
+		list := judgeRatings[judgeUuid]
+		sort.SliceStable(list, func(i, j int) bool {
+			if list[i].CreateTime.Equal(list[j].CreateTime) {
+				return list[i].Id < list[j].Id
+			}
+			return list[i].CreateTime.Before(list[j].CreateTime)
+		})
+	}
+
+	var anomalies []*RatingAnomaly
+	for _, judgeUuid := range judgeUuids {
+		anomalies = append(anomalies, this.identicalRuns(judgeRatings[judgeUuid], config)...)
+		anomalies = append(anomalies, this.fastRatings(judgeRatings[judgeUuid], config)...)
+	}
+	anomalies = append(anomalies, this.outliers(ratings, config)...)
+	anomalies = append(anomalies, this.sameColleges(ratings, submissionMap)...)
+
+	judgeNames := make(map[string]string)
+	for _, anomaly := range anomalies {
+		name, ok := judgeNames[anomaly.JudgeUuid]
+		if !ok {
+			if judge := this.userDao.FindByUuid(anomaly.JudgeUuid); judge != nil {
+				name = judge.Username
+			}
+			judgeNames[anomaly.JudgeUuid] = name
+		}
+		anomaly.JudgeName = name
+	}
+
+	return &AnomalyReport{
+		RoundId:   roundId,
+		Config:    config,
+		Ratings:   len(ratings),
+		Anomalies: anomalies,
+	}
+}
+
+// runs of the same score given by the judge, in the order of rating.
+func (this *AnomalyService) identicalRuns(ratings []*Rating, config *AnomalyConfig) []*RatingAnomaly {
+	scores := make([]float64, len(ratings))
+	for i, rating := range ratings {
+		scores[i] = rating.TotalScore()
+	}
+
+	var anomalies []*RatingAnomaly
+	for _, run := range util.EqualRuns(scores, config.IdenticalRun) {
+		anomaly := &RatingAnomaly{
+			Type:      ANOMALY_TYPE_IDENTICAL_RUN,
+			JudgeUuid: ratings[run[0]].JudgeUuid,
+			Score:     scores[run[0]],
+			Detail:    fmt.Sprintf("连续 %d 个作品评分均为 %v", run[1]-run[0], scores[run[0]]),
+		}
+		for _, rating := range ratings[run[0]:run[1]] {
+			anomaly.SubmissionIds = append(anomaly.SubmissionIds, rating.SubmissionId)
+			anomaly.RatingIds = append(anomaly.RatingIds, rating.Id)
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+	return anomalies
+}
+
+// ratings given within FastSeconds after the previous rating of the judge.
+func (this *AnomalyService) fastRatings(ratings []*Rating, config *AnomalyConfig) []*RatingAnomaly {
+	var anomalies []*RatingAnomaly
+	if config.FastSeconds <= 0 {
+		return anomalies
+	}
+	for i := 1; i < len(ratings); i++ {
+		previous, rating := ratings[i-1], ratings[i]
+		seconds := rating.CreateTime.Sub(previous.CreateTime).Seconds()
+		if seconds < float64(config.FastSeconds) {
+			anomalies = append(anomalies, &RatingAnomaly{
+				Type:          ANOMALY_TYPE_FAST,
+				JudgeUuid:     rating.JudgeUuid,
+				SubmissionIds: []int64{previous.SubmissionId, rating.SubmissionId},
+				RatingIds:     []int64{previous.Id, rating.Id},
+				Score:         rating.TotalScore(),
+				Detail:        fmt.Sprintf("距上一次评分仅 %.0f 秒", seconds),
+			})
+		}
+	}
+	return anomalies
+}
+
+// ratings more than OutlierSd standard deviations away from the mean of the other judges. it takes two other judges at least.
+func (this *AnomalyService) outliers(ratings []*Rating, config *AnomalyConfig) []*RatingAnomaly {
+	submissionRatings := make(map[int64][]*Rating)
+	var submissionIds []int64
+	for _, rating := range ratings {
+		if _, ok := submissionRatings[rating.SubmissionId]; !ok {
+			submissionIds = append(submissionIds, rating.SubmissionId)
+		}
+		submissionRatings[rating.SubmissionId] = append(submissionRatings[rating.SubmissionId], rating)
+	}
+
+	var anomalies []*RatingAnomaly
+	for _, submissionId := range submissionIds {
+		list := submissionRatings[submissionId]
+		if len(list) < 3 {
+			continue
+		}
+		for _, rating := range list {
+			var others []float64
+			for _, other := range list {
+				if other.Id != rating.Id {
+					others = append(others, other.TotalScore())
+				}
+			}
+			mean := util.Mean(others)
+			sd := math.Max(util.StdDev(others), config.MinSd)
+			var detail string
+			if sd == 0 {
+				//the others of an unanimous panel have no deviation, so any other score is an outlier.
+				if rating.TotalScore() == mean {
+					continue
+				}
+				detail = fmt.Sprintf("其他 %d 位评委均为 %.2f", len(others), mean)
+			} else {
+				deviation := math.Abs(util.ZScore(rating.TotalScore(), mean, sd))
+				if deviation <= config.OutlierSd {
+					continue
+				}
+				detail = fmt.Sprintf("其他 %d 位评委平均 %.2f，偏离 %.1f 个标准差", len(others), mean, deviation)
+			}
+			anomalies = append(anomalies, &RatingAnomaly{
+				Type:          ANOMALY_TYPE_OUTLIER,
+				JudgeUuid:     rating.JudgeUuid,
+				SubmissionIds: []int64{rating.SubmissionId},
+				RatingIds:     []int64{rating.Id},
+				Score:         rating.TotalScore(),
+				Detail:        detail,
+			})
+		}
+	}
+	return anomalies
+}
+
+// ratings of judges of the same college as the submission or one of its authors.
+func (this *AnomalyService) sameColleges(ratings []*Rating, submissionMap map[int64]*Submission) []*RatingAnomaly {
+	judgeColleges := make(map[string]string)
+	submissionColleges := make(map[int64]map[string]bool)
+
+	var anomalies []*RatingAnomaly
+	for _, rating := range ratings {
+		judgeCollege, ok := judgeColleges[rating.JudgeUuid]
+		if !ok {
+			judgeCollege = this.assignmentService.judgeCollege(rating.JudgeUuid)
+			judgeColleges[rating.JudgeUuid] = judgeCollege
+		}
+		if judgeCollege == "" {
+			continue
+		}
+
+		colleges, ok := submissionColleges[rating.SubmissionId]
+		if !ok {
+			colleges = this.assignmentService.submissionColleges(submissionMap[rating.SubmissionId])
+			submissionColleges[rating.SubmissionId] = colleges
+		}
+		if colleges[judgeCollege] {
+			anomalies = append(anomalies, &RatingAnomaly{
+				Type:          ANOMALY_TYPE_SAME_COLLEGE,
+				JudgeUuid:     rating.JudgeUuid,
+				SubmissionIds: []int64{rating.SubmissionId},
+				RatingIds:     []int64{rating.Id},
+				Score:         rating.TotalScore(),
+				Detail:        fmt.Sprintf("评委与作品同属 %s", judgeCollege),
+			})
+		}
+	}
+	return anomalies
+}
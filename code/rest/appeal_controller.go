@@ -0,0 +1,217 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+type AppealController struct {
+	BaseController
+	appealDao         *AppealDao
+	appealService     *AppealService
+	submissionDao     *SubmissionDao
+	submissionService *SubmissionService
+	matterService     *MatterService
+	auditService      *AuditService
+}
+
+func (this *AppealController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.appealDao)
+	if b, ok := b.(*AppealDao); ok {
+		this.appealDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.appealService)
+	if b, ok := b.(*AppealService); ok {
+		this.appealService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionService)
+	if b, ok := b.(*SubmissionService); ok {
+		this.submissionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterService)
+	if b, ok := b.(*MatterService); ok {
+		this.matterService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.auditService)
+	if b, ok := b.(*AuditService); ok {
+		this.auditService = b
+	}
+}
+
+func (this *AppealController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/appeal/create"] = this.Wrap(this.Create, USER_ROLE_USER)
+	routeMap["/api/appeal/detail"] = this.Wrap(this.Detail, USER_ROLE_USER)
+	routeMap["/api/appeal/list"] = this.Wrap(this.List, USER_ROLE_USER)
+	routeMap["/api/appeal/accept"] = this.Wrap(this.Accept, USER_ROLE_USER)
+	routeMap["/api/appeal/reject"] = this.Wrap(this.Reject, USER_ROLE_USER)
+	routeMap["/api/appeal/withdraw"] = this.Wrap(this.Withdraw, USER_ROLE_USER)
+	routeMap["/api/appeal/attachment/download"] = this.Wrap(this.DownloadAttachment, USER_ROLE_USER)
+
+	return routeMap
+}
+
+func (this *AppealController) formInt64(request *http.Request, key string) int64 {
+	str := request.FormValue(key)
+	if str == "" {
+		return 0
+	}
+	value, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		panic(result.BadRequest("%s格式错误", key))
+	}
+	return value
+}
+
+// find the appeal and check whether the user can view it.
+func (this *AppealController) checkAppeal(request *http.Request, user *User) (*Appeal, *Submission) {
+	appeal := this.appealDao.Find(this.formInt64(request, "id"))
+	if appeal == nil {
+		panic(result.BadRequest("申诉不存在"))
+	}
+	submission := this.submissionDao.FindById(appeal.SubmissionId)
+	if submission == nil {
+		panic(result.BadRequest("提交作品不存在"))
+	}
+	if !this.appealService.CanView(user, submission) {
+		panic(result.UNAUTHORIZED)
+	}
+	return appeal, submission
+}
+
+// file an appeal. attachments are comma separated uuids of files.
+func (this *AppealController) Create(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	submission := this.submissionDao.FindById(this.formInt64(request, "submissionId"))
+	if submission == nil {
+		return result.BadRequest("提交作品不存在")
+	}
+
+	var attachments []string
+	for _, uuid := range strings.Split(request.FormValue("attachments"), ",") {
+		if uuid = strings.TrimSpace(uuid); uuid != "" {
+			attachments = append(attachments, uuid)
+		}
+	}
+
+	appeal, webResult := this.appealService.File(user, submission, request.FormValue("type"), request.FormValue("reason"), attachments)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(this.appealService.Detail(appeal))
+}
+
+func (this *AppealController) Detail(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	appeal, submission := this.checkAppeal(request, user)
+
+	return this.Success(map[string]any{
+		"appeal":      this.appealService.Detail(appeal),
+		"submission":  submission,
+		"attachments": this.appealService.Attachments(appeal),
+		"canHandle":   appeal.Status == APPEAL_STATUS_PENDING && this.appealService.CanHandle(user, appeal, submission),
+	})
+}
+
+// administrators list all appeals, college admins those of their college, students their own.
+func (this *AppealController) List(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	filter := &AppealFilter{
+		SubmissionId: this.formInt64(request, "submissionId"),
+		CollegeId:    this.formInt64(request, "collegeId"),
+		Type:         request.FormValue("type"),
+		Handler:      request.FormValue("handler"),
+		Status:       request.FormValue("status"),
+		Overdue:      request.FormValue("overdue"),
+	}
+
+	switch user.Role {
+	case USER_ROLE_ADMINISTRATOR:
+	case USER_ROLE_COLLEGE_ADMIN:
+		college := this.submissionService.ManagedCollege(user)
+		if college == nil {
+			return result.BadRequest("未找到您所在的学院")
+		}
+		filter.CollegeId = college.Id
+	case USER_ROLE_JUDGE:
+		panic(result.UNAUTHORIZED)
+	default:
+		//team members see the appeals of their submission, filed by any of them.
+		submission := this.submissionDao.FindById(filter.SubmissionId)
+		if submission == nil || !this.submissionService.IsAuthor(user, submission) {
+			filter.UserUuid = user.Uuid
+		}
+	}
+
+	return this.Success(this.appealDao.FindByFilter(filter))
+}
+
+// accept the appeal. reviews is the number of judges of the re-review, the previous number if absent.
+func (this *AppealController) Accept(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	appeal, _ := this.checkAppeal(request, user)
+	before := map[string]any{"status": appeal.Status, "handler": appeal.Handler}
+
+	appeal, webResult := this.appealService.Accept(user, appeal, request.FormValue("reply"), int(this.formInt64(request, "reviews")))
+	if webResult != nil {
+		return webResult
+	}
+	this.auditService.Log(request, user, AUDIT_ACTION_APPEAL_ACCEPT, AUDIT_TARGET_APPEAL, appeal.Id, before, map[string]any{"status": appeal.Status, "handler": appeal.Handler, "reply": appeal.Reply})
+	return this.Success(this.appealService.Detail(appeal))
+}
+
+func (this *AppealController) Reject(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	appeal, _ := this.checkAppeal(request, user)
+	before := map[string]any{"status": appeal.Status, "handler": appeal.Handler}
+
+	appeal, webResult := this.appealService.Reject(user, appeal, request.FormValue("reply"))
+	if webResult != nil {
+		return webResult
+	}
+	this.auditService.Log(request, user, AUDIT_ACTION_APPEAL_REJECT, AUDIT_TARGET_APPEAL, appeal.Id, before, map[string]any{"status": appeal.Status, "handler": appeal.Handler, "reply": appeal.Reply})
+	return this.Success(this.appealService.Detail(appeal))
+}
+
+func (this *AppealController) Withdraw(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	appeal, _ := this.checkAppeal(request, user)
+
+	appeal, webResult := this.appealService.Withdraw(user, appeal)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(this.appealService.Detail(appeal))
+}
+
+// download an attachment of the appeal. whoever can view the appeal can download its attachments.
+func (this *AppealController) DownloadAttachment(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	appeal, _ := this.checkAppeal(request, user)
+
+	uuid := request.FormValue("uuid")
+	for _, matter := range this.appealService.Attachments(appeal) {
+		if matter.Uuid == uuid {
+			this.matterService.DownloadFile(writer, request, matter, true)
+			return nil
+		}
+	}
+	return result.BadRequest("附件不存在")
+}
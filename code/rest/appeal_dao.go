@@ -0,0 +1,109 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+type AppealDao struct {
+	BaseDao
+}
+
+func (this *AppealDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *AppealDao) Create(appeal *Appeal) *Appeal {
+	appeal.CreateTime = time.Now()
+	appeal.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(appeal)
+	this.PanicError(db.Error)
+	return appeal
+}
+
+func (this *AppealDao) Save(appeal *Appeal) *Appeal {
+	appeal.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Save(appeal)
+	this.PanicError(db.Error)
+	return appeal
+}
+
+func (this *AppealDao) Find(id int64) *Appeal {
+	var entity = &Appeal{}
+	db := core.CONTEXT.GetDB().Where("id = ?", id).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+func (this *AppealDao) FindBySubmissionId(submissionId int64) []*Appeal {
+	var entities []*Appeal
+	db := core.CONTEXT.GetDB().Where("submission_id = ?", submissionId).Order("id DESC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+// the accepted appeal whose re-review of the submission in the round is going on, nil if none.
+func (this *AppealDao) FindReviewing(submissionId int64, roundId int64) *Appeal {
+	var entity = &Appeal{}
+	db := core.CONTEXT.GetDB().Where("submission_id = ? AND round_id = ? AND status = ?", submissionId, roundId, APPEAL_STATUS_ACCEPTED).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+func (this *AppealDao) FindByStatus(status string) []*Appeal {
+	var entities []*Appeal
+	db := core.CONTEXT.GetDB().Where("status = ?", status).Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *AppealDao) FindByFilter(filter *AppealFilter) []*Appeal {
+	db := core.CONTEXT.GetDB()
+	if filter.SubmissionId != 0 {
+		db = db.Where("submission_id = ?", filter.SubmissionId)
+	}
+	if filter.CollegeId != 0 {
+		db = db.Where("college_id = ?", filter.CollegeId)
+	}
+	if filter.UserUuid != "" {
+		db = db.Where("user_uuid = ?", filter.UserUuid)
+	}
+	if filter.Type != "" {
+		db = db.Where("type = ?", filter.Type)
+	}
+	if filter.Handler != "" {
+		db = db.Where("handler = ?", filter.Handler)
+	}
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+	if filter.Overdue == TRUE {
+		now := time.Now()
+		db = db.Where("((status = ? AND handle_deadline < ?) OR (status = ? AND review_deadline < ?))", APPEAL_STATUS_PENDING, now, APPEAL_STATUS_ACCEPTED, now)
+	}
+
+	var entities []*Appeal
+	db = db.Order("id DESC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *AppealDao) CreateHistory(history *AppealHistory) *AppealHistory {
+	history.CreateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(history)
+	this.PanicError(db.Error)
+	return history
+}
+
+// the complete history of the appeal, oldest first.
+func (this *AppealDao) FindHistories(appealId int64) []*AppealHistory {
+	var entities []*AppealHistory
+	db := core.CONTEXT.GetDB().Where("appeal_id = ?", appealId).Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	//against the decision of the college not to recommend the submission.
+	APPEAL_TYPE_RECOMMEND = "RECOMMEND"
+	//against the scores given by the judges in a round.
+	APPEAL_TYPE_SCORE = "SCORE"
+)
+
+const (
+	//the college admin of the submission handles the appeal.
+	APPEAL_HANDLER_COLLEGE = "COLLEGE"
+	//the administrator of the competition handles the appeal.
+	APPEAL_HANDLER_COMPETITION = "COMPETITION"
+)
+
+const (
+	//waiting for the handler.
+	APPEAL_STATUS_PENDING = "PENDING"
+	//accepted, the submission is being reviewed again.
+	APPEAL_STATUS_ACCEPTED = "ACCEPTED"
+	//rejected with a reply.
+	APPEAL_STATUS_REJECTED = "REJECTED"
+	//accepted and the re-review is over, or the submission has been recommended.
+	APPEAL_STATUS_RESOLVED = "RESOLVED"
+	//withdrawn by the student.
+	APPEAL_STATUS_WITHDRAWN = "WITHDRAWN"
+)
+
+const (
+	APPEAL_ACTION_FILE     = "FILE"
+	APPEAL_ACTION_ESCALATE = "ESCALATE"
+	APPEAL_ACTION_ACCEPT   = "ACCEPT"
+	APPEAL_ACTION_REJECT   = "REJECT"
+	APPEAL_ACTION_RESOLVE  = "RESOLVE"
+	APPEAL_ACTION_WITHDRAW = "WITHDRAW"
+)
+
+// Appeal is a student contesting the college's decision or the judges' scores of a submission.
+type Appeal struct {
+	Id           int64  `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	SubmissionId int64  `json:"submissionId" gorm:"type:bigint(20) not null;index:idx_appeal_si"`
+	UserUuid     string `json:"userUuid" gorm:"type:char(36) not null;index:idx_appeal_uu"`
+	Type         string `json:"type" gorm:"type:varchar(20) not null"`
+	//the round whose scores are contested. 0 for recommend appeals.
+	RoundId   int64  `json:"roundId" gorm:"type:bigint(20) not null;default:0"`
+	CollegeId int64  `json:"collegeId" gorm:"type:bigint(20) not null;default:0"`
+	Handler   string `json:"handler" gorm:"type:varchar(20) not null"`
+	Status    string `json:"status" gorm:"type:varchar(20) not null;index:idx_appeal_s"`
+	Reason    string `json:"reason" gorm:"type:text"`
+	//comma separated uuids of the attached files.
+	Attachments string `json:"attachments" gorm:"type:varchar(1024)"`
+	Reply       string `json:"reply" gorm:"type:text"`
+	HandlerUuid string `json:"handlerUuid" gorm:"type:char(36)"`
+	//the handler must accept or reject before it. college appeals not handled in time are escalated to the competition.
+	HandleDeadline time.Time `json:"handleDeadline" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"`
+	//the judges of the re-review must rate before it.
+	ReviewDeadline time.Time `json:"reviewDeadline" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"`
+	//average score of the round when the appeal is accepted, and after the re-review.
+	OriginalScore float64          `json:"originalScore" gorm:"type:double not null;default:0"`
+	ReviewScore   float64          `json:"reviewScore" gorm:"type:double not null;default:0"`
+	CreateTime    time.Time        `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime    time.Time        `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	Histories     []*AppealHistory `json:"histories" gorm:"-"`
+}
+
+// uuids of the attached files.
+func (this *Appeal) AttachmentList() []string {
+	var uuids []string
+	for _, uuid := range strings.Split(this.Attachments, ",") {
+		if uuid = strings.TrimSpace(uuid); uuid != "" {
+			uuids = append(uuids, uuid)
+		}
+	}
+	return uuids
+}
+
+// whether the appeal is still open, ie. pending or being re-reviewed.
+func (this *Appeal) IsOpen() bool {
+	return this.Status == APPEAL_STATUS_PENDING || this.Status == APPEAL_STATUS_ACCEPTED
+}
+
+// AppealHistory records every step of an appeal.
+type AppealHistory struct {
+	Id           int64     `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	AppealId     int64     `json:"appealId" gorm:"type:bigint(20) not null;index:idx_appeal_history_ai"`
+	Action       string    `json:"action" gorm:"type:varchar(20) not null"`
+	Status       string    `json:"status" gorm:"type:varchar(20) not null"`
+	Handler      string    `json:"handler" gorm:"type:varchar(20)"`
+	OperatorUuid string    `json:"operatorUuid" gorm:"type:char(36)"`
+	Note         string    `json:"note" gorm:"type:text"`
+	CreateTime   time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
+
+// AppealFilter filters appeals. zero values mean no restriction.
+type AppealFilter struct {
+	SubmissionId int64
+	CollegeId    int64
+	UserUuid     string
+	Type         string
+	Handler      string
+	Status       string
+	//"true" for the open appeals past their deadlines.
+	Overdue string
+}
@@ -0,0 +1,445 @@
+package rest
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+const (
+	APPEAL_REASON_MAX_LENGTH = 2000
+	APPEAL_MAX_ATTACHMENTS   = 10
+)
+
+// @Service
+type AppealService struct {
+	BaseBean
+	appealDao               *AppealDao
+	submissionDao           *SubmissionDao
+	submissionService       *SubmissionService
+	submissionWindowService *SubmissionWindowService
+	roundDao                *RoundDao
+	roundHistoryDao         *RoundHistoryDao
+	roundService            *RoundService
+	assignmentDao           *AssignmentDao
+	assignmentService       *AssignmentService
+	ratingDao               *RatingDao
+	competitionService      *CompetitionService
+	matterDao               *MatterDao
+	preferenceService       *PreferenceService
+}
+
+func (this *AppealService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.appealDao)
+	if b, ok := b.(*AppealDao); ok {
+		this.appealDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionService)
+	if b, ok := b.(*SubmissionService); ok {
+		this.submissionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionWindowService)
+	if b, ok := b.(*SubmissionWindowService); ok {
+		this.submissionWindowService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.roundDao)
+	if b, ok := b.(*RoundDao); ok {
+		this.roundDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.roundHistoryDao)
+	if b, ok := b.(*RoundHistoryDao); ok {
+		this.roundHistoryDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.roundService)
+	if b, ok := b.(*RoundService); ok {
+		this.roundService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.assignmentDao)
+	if b, ok := b.(*AssignmentDao); ok {
+		this.assignmentDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.assignmentService)
+	if b, ok := b.(*AssignmentService); ok {
+		this.assignmentService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.ratingDao)
+	if b, ok := b.(*RatingDao); ok {
+		this.ratingDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.competitionService)
+	if b, ok := b.(*CompetitionService); ok {
+		this.competitionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.preferenceService)
+	if b, ok := b.(*PreferenceService); ok {
+		this.preferenceService = b
+	}
+}
+
+func (this *AppealService) history(appeal *Appeal, action string, operator *User, note string) {
+	operatorUuid := ""
+	if operator != nil {
+		operatorUuid = operator.Uuid
+	}
+	this.appealDao.CreateHistory(&AppealHistory{
+		AppealId:     appeal.Id,
+		Action:       action,
+		Status:       appeal.Status,
+		Handler:      appeal.Handler,
+		OperatorUuid: operatorUuid,
+		Note:         note,
+	})
+}
+
+func (this *AppealService) days(days int) time.Time {
+	return time.Now().AddDate(0, 0, days)
+}
+
+// when the contested decision was made. nil if it is unknown, then the filing is not limited in time.
+func (this *AppealService) decisionTime(submission *Submission, appealType string) (*time.Time, *result.WebResult) {
+	switch appealType {
+	case APPEAL_TYPE_RECOMMEND:
+		if submission.IsRecommended {
+			return nil, result.BadRequest("作品已被推荐")
+		}
+		closeTime := this.submissionWindowService.CloseTime(submission)
+		if closeTime != nil && time.Now().Before(*closeTime) {
+			return nil, result.BadRequest("提交截止前学院仍可推荐，暂不能申诉")
+		}
+		return closeTime, nil
+
+	case APPEAL_TYPE_SCORE:
+		if submission.RoundId == 0 {
+			return nil, result.BadRequest("作品未进入评审轮次")
+		}
+		round := this.roundDao.Find(submission.RoundId)
+		if round == nil {
+			return nil, result.BadRequest("作品所在轮次不存在")
+		}
+		switch submission.RoundStatus {
+		case ROUND_STATUS_ELIMINATED:
+		case ROUND_STATUS_ACTIVE:
+			if !round.IsClosed(time.Now()) {
+				return nil, result.BadRequest("%s 尚未结束评审", round.Name)
+			}
+		default:
+			return nil, result.BadRequest("作品已晋级，不能对本轮评分申诉")
+		}
+
+		decisionTime := round.CloseTime
+		for _, roundHistory := range this.roundHistoryDao.FindBySubmissionId(submission.Id) {
+			if roundHistory.RoundId == round.Id && roundHistory.Status == ROUND_STATUS_ELIMINATED {
+				decisionTime = roundHistory.CreateTime
+			}
+		}
+		return &decisionTime, nil
+
+	default:
+		return nil, result.BadRequest("申诉类型错误")
+	}
+}
+
+// attachments must be files of the student's own space, or of the submission folder.
+func (this *AppealService) checkAttachments(user *User, submission *Submission, uuids []string) *result.WebResult {
+	if len(uuids) > APPEAL_MAX_ATTACHMENTS {
+		return result.BadRequest("附件不能超过 %d 个", APPEAL_MAX_ATTACHMENTS)
+	}
+	folder := this.matterDao.FindByUuid(submission.MatterUuid)
+	for _, uuid := range uuids {
+		matter := this.matterDao.FindByUuid(uuid)
+		if matter == nil || matter.Deleted || matter.Dir {
+			return result.BadRequest("附件 %s 不存在", uuid)
+		}
+		inFolder := folder != nil && matter.SpaceUuid == folder.SpaceUuid && strings.HasPrefix(matter.Path, folder.Path+"/")
+		if matter.SpaceUuid != user.SpaceUuid && !inFolder {
+			return result.BadRequest("附件 %s 不属于您", matter.Name)
+		}
+	}
+	return nil
+}
+
+// a student files an appeal against the submission.
+func (this *AppealService) File(user *User, submission *Submission, appealType string, reason string, attachments []string) (*Appeal, *result.WebResult) {
+	if !this.submissionService.IsAuthor(user, submission) {
+		return nil, result.BadRequest("只有作品作者可以申诉")
+	}
+	if webResult := this.competitionService.CheckEditable(submission.CompetitionId); webResult != nil {
+		return nil, webResult
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, result.BadRequest("申诉理由不能为空")
+	}
+	if utf8.RuneCountInString(reason) > APPEAL_REASON_MAX_LENGTH {
+		return nil, result.BadRequest("申诉理由不能超过 %d 个字", APPEAL_REASON_MAX_LENGTH)
+	}
+
+	decisionTime, webResult := this.decisionTime(submission, appealType)
+	if webResult != nil {
+		return nil, webResult
+	}
+
+	config := this.preferenceService.Fetch().FetchAppealConfig()
+	if config.FileDays > 0 && decisionTime != nil && time.Now().After(decisionTime.AddDate(0, 0, config.FileDays)) {
+		return nil, result.BadRequest("已超过申诉期限（结果公布后 %d 天内）", config.FileDays)
+	}
+
+	appeals := this.appealDao.FindBySubmissionId(submission.Id)
+	for _, appeal := range appeals {
+		if appeal.IsOpen() {
+			return nil, result.BadRequest("作品已有正在处理的申诉")
+		}
+	}
+	if config.MaxPerSubmission > 0 && len(appeals) >= config.MaxPerSubmission {
+		return nil, result.BadRequest("每个作品最多申诉 %d 次", config.MaxPerSubmission)
+	}
+
+	if webResult := this.checkAttachments(user, submission, attachments); webResult != nil {
+		return nil, webResult
+	}
+
+	appeal := &Appeal{
+		SubmissionId:   submission.Id,
+		UserUuid:       user.Uuid,
+		Type:           appealType,
+		CollegeId:      submission.CollegeId,
+		Handler:        APPEAL_HANDLER_COMPETITION,
+		Status:         APPEAL_STATUS_PENDING,
+		Reason:         reason,
+		Attachments:    strings.Join(attachments, ","),
+		HandleDeadline: this.days(config.HandleDays),
+	}
+	//the college reconsiders its own decision first.
+	if appealType == APPEAL_TYPE_RECOMMEND && submission.CollegeId != 0 {
+		appeal.Handler = APPEAL_HANDLER_COLLEGE
+	}
+	if appealType == APPEAL_TYPE_SCORE {
+		appeal.RoundId = submission.RoundId
+		appeal.OriginalScore, _ = this.roundService.Score(submission.Id, submission.RoundId)
+	}
+
+	appeal = this.appealDao.Create(appeal)
+	this.history(appeal, APPEAL_ACTION_FILE, user, reason)
+	return appeal, nil
+}
+
+// authors, administrators and the college admins of the submission can view the appeal.
+func (this *AppealService) CanView(user *User, submission *Submission) bool {
+	switch user.Role {
+	case USER_ROLE_ADMINISTRATOR:
+		return true
+	case USER_ROLE_COLLEGE_ADMIN:
+		return this.submissionService.IsCollegeAdminOf(user, submission)
+	case USER_ROLE_JUDGE:
+		return false
+	default:
+		return this.submissionService.IsAuthor(user, submission)
+	}
+}
+
+// administrators handle any appeal, college admins those routed to their college.
+func (this *AppealService) CanHandle(user *User, appeal *Appeal, submission *Submission) bool {
+	switch user.Role {
+	case USER_ROLE_ADMINISTRATOR:
+		return true
+	case USER_ROLE_COLLEGE_ADMIN:
+		return appeal.Handler == APPEAL_HANDLER_COLLEGE && this.submissionService.IsCollegeAdminOf(user, submission)
+	default:
+		return false
+	}
+}
+
+func (this *AppealService) checkHandle(user *User, appeal *Appeal) (*Submission, *result.WebResult) {
+	if appeal.Status != APPEAL_STATUS_PENDING {
+		return nil, result.BadRequest("申诉已处理")
+	}
+	submission := this.submissionDao.FindById(appeal.SubmissionId)
+	if submission == nil {
+		return nil, result.BadRequest("提交作品不存在")
+	}
+	if !this.CanHandle(user, appeal, submission) {
+		return nil, result.BadRequest("您无权处理该申诉")
+	}
+	return submission, nil
+}
+
+// accept the appeal. a recommend appeal gets the submission recommended,
+// a score appeal gets it reviewed again in the round by `reviews` judges other than the previous ones. 0 keeps the number of judges.
+func (this *AppealService) Accept(user *User, appeal *Appeal, reply string, reviews int) (*Appeal, *result.WebResult) {
+	submission, webResult := this.checkHandle(user, appeal)
+	if webResult != nil {
+		return nil, webResult
+	}
+	//checked before anything is changed, so that a refused accept leaves the appeal as it was.
+	if webResult := this.competitionService.CheckEditable(submission.CompetitionId); webResult != nil {
+		return nil, webResult
+	}
+	reply = strings.TrimSpace(reply)
+
+	if appeal.Type == APPEAL_TYPE_RECOMMEND {
+		if !submission.IsRecommended {
+			if webResult := this.submissionService.Recommend(submission, user, false); webResult != nil {
+				return nil, webResult
+			}
+		}
+		appeal.Status = APPEAL_STATUS_RESOLVED
+		appeal.Reply = reply
+		appeal.HandlerUuid = user.Uuid
+		appeal = this.appealDao.Save(appeal)
+		this.history(appeal, APPEAL_ACTION_ACCEPT, user, reply)
+		return appeal, nil
+	}
+
+	if submission.RoundId != appeal.RoundId || submission.RoundStatus == ROUND_STATUS_PROMOTED {
+		return nil, result.BadRequest("作品已不在申诉的轮次中")
+	}
+	if reviews == 0 {
+		judges := make(map[string]bool)
+		for _, assignment := range this.assignmentDao.FindBySubmissionAndRound(submission.Id, appeal.RoundId) {
+			judges[assignment.JudgeUuid] = true
+		}
+		reviews = len(judges)
+		if reviews == 0 {
+			reviews = 1
+		}
+	}
+
+	original, _ := this.roundService.Score(submission.Id, appeal.RoundId)
+	if _, webResult := this.assignmentService.Reassign(submission, appeal.RoundId, reviews, user); webResult != nil {
+		return nil, webResult
+	}
+	this.ratingDao.SupersedeBySubmissionAndRound(submission.Id, appeal.RoundId)
+	if webResult := this.roundService.Reopen(submission, appeal.RoundId, user, "申诉复评"); webResult != nil {
+		return nil, webResult
+	}
+
+	appeal.Status = APPEAL_STATUS_ACCEPTED
+	appeal.Reply = reply
+	appeal.HandlerUuid = user.Uuid
+	appeal.OriginalScore = original
+	appeal.ReviewDeadline = this.days(this.preferenceService.Fetch().FetchAppealConfig().ReviewDays)
+	appeal = this.appealDao.Save(appeal)
+	this.history(appeal, APPEAL_ACTION_ACCEPT, user, reply)
+	return appeal, nil
+}
+
+// reject the appeal with a written reply.
+func (this *AppealService) Reject(user *User, appeal *Appeal, reply string) (*Appeal, *result.WebResult) {
+	if _, webResult := this.checkHandle(user, appeal); webResult != nil {
+		return nil, webResult
+	}
+	reply = strings.TrimSpace(reply)
+	if reply == "" {
+		return nil, result.BadRequest("驳回申诉必须填写答复")
+	}
+
+	appeal.Status = APPEAL_STATUS_REJECTED
+	appeal.Reply = reply
+	appeal.HandlerUuid = user.Uuid
+	appeal = this.appealDao.Save(appeal)
+	this.history(appeal, APPEAL_ACTION_REJECT, user, reply)
+	return appeal, nil
+}
+
+// the student withdraws a pending appeal.
+func (this *AppealService) Withdraw(user *User, appeal *Appeal) (*Appeal, *result.WebResult) {
+	if appeal.UserUuid != user.Uuid {
+		return nil, result.BadRequest("只能撤回自己提交的申诉")
+	}
+	if appeal.Status != APPEAL_STATUS_PENDING {
+		return nil, result.BadRequest("申诉已处理，不能撤回")
+	}
+
+	appeal.Status = APPEAL_STATUS_WITHDRAWN
+	appeal = this.appealDao.Save(appeal)
+	this.history(appeal, APPEAL_ACTION_WITHDRAW, user, "")
+	return appeal, nil
+}
+
+// close the re-review with the score of the new judges.
+func (this *AppealService) resolve(appeal *Appeal, note string) {
+	appeal.ReviewScore, _ = this.roundService.Score(appeal.SubmissionId, appeal.RoundId)
+	appeal.Status = APPEAL_STATUS_RESOLVED
+	appeal = this.appealDao.Save(appeal)
+	this.history(appeal, APPEAL_ACTION_RESOLVE, nil, note)
+	this.logger.Info("appeal %d resolved, score %.2f -> %.2f", appeal.Id, appeal.OriginalScore, appeal.ReviewScore)
+}
+
+// close the re-review of the submission once every new judge has rated.
+func (this *AppealService) CheckReview(submission *Submission) {
+	appeal := this.appealDao.FindReviewing(submission.Id, submission.RoundId)
+	if appeal == nil {
+		return
+	}
+	for _, assignment := range this.assignmentDao.FindBySubmissionAndRound(submission.Id, appeal.RoundId) {
+		rating := this.ratingDao.FindBySubmissionAndJudgeAndRound(submission.Id, assignment.JudgeUuid, appeal.RoundId)
+		if rating == nil || rating.Superseded {
+			return
+		}
+	}
+	this.resolve(appeal, "复评完成")
+}
+
+// escalate the college appeals not handled in time to the competition, and close the re-reviews past their deadlines.
+func (this *AppealService) CheckDeadlines() {
+	now := time.Now()
+	config := this.preferenceService.Fetch().FetchAppealConfig()
+
+	for _, appeal := range this.appealDao.FindByStatus(APPEAL_STATUS_PENDING) {
+		if appeal.Handler == APPEAL_HANDLER_COLLEGE && now.After(appeal.HandleDeadline) {
+			appeal.Handler = APPEAL_HANDLER_COMPETITION
+			appeal.HandleDeadline = this.days(config.HandleDays)
+			appeal = this.appealDao.Save(appeal)
+			this.history(appeal, APPEAL_ACTION_ESCALATE, nil, "学院未在期限内处理，转交竞赛管理员")
+		}
+	}
+
+	for _, appeal := range this.appealDao.FindByStatus(APPEAL_STATUS_ACCEPTED) {
+		if now.After(appeal.ReviewDeadline) {
+			this.resolve(appeal, "复评已截止")
+		}
+	}
+}
+
+// the appeal with its complete history.
+func (this *AppealService) Detail(appeal *Appeal) *Appeal {
+	appeal.Histories = this.appealDao.FindHistories(appeal.Id)
+	return appeal
+}
+
+// the attached files still existing.
+func (this *AppealService) Attachments(appeal *Appeal) []*Matter {
+	matters := []*Matter{}
+	for _, uuid := range appeal.AttachmentList() {
+		if matter := this.matterDao.FindByUuid(uuid); matter != nil && !matter.Deleted {
+			matters = append(matters, matter)
+		}
+	}
+	return matters
+}
@@ -0,0 +1,229 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+type AssignmentController struct {
+	BaseController
+	assignmentDao     *AssignmentDao
+	assignmentService *AssignmentService
+	submissionDao     *SubmissionDao
+}
+
+func (this *AssignmentController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.assignmentDao)
+	if b, ok := b.(*AssignmentDao); ok {
+		this.assignmentDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.assignmentService)
+	if b, ok := b.(*AssignmentService); ok {
+		this.assignmentService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+}
+
+func (this *AssignmentController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/assignment/run"] = this.Wrap(this.Run, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/assignment/create"] = this.Wrap(this.Create, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/assignment/delete"] = this.Wrap(this.Delete, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/assignment/list"] = this.Wrap(this.List, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/assignment/load"] = this.Wrap(this.Load, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/assignment/my"] = this.Wrap(this.My, USER_ROLE_JUDGE)
+	routeMap["/api/assignment/conflict/declare"] = this.Wrap(this.DeclareConflict, USER_ROLE_JUDGE)
+	routeMap["/api/assignment/conflict/list"] = this.Wrap(this.ListConflicts, USER_ROLE_JUDGE)
+	routeMap["/api/assignment/conflict/delete"] = this.Wrap(this.DeleteConflict, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+// parse an optional int64 form value, empty means 0.
+func (this *AssignmentController) formInt64(request *http.Request, key string) (int64, *result.WebResult) {
+	str := request.FormValue(key)
+	if str == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return 0, result.BadRequest("%s格式错误", key)
+	}
+	return value, nil
+}
+
+func (this *AssignmentController) checkJudge(request *http.Request) *User {
+	user := this.checkUser(request)
+	if user.Role != USER_ROLE_JUDGE {
+		panic(result.UNAUTHORIZED)
+	}
+	return user
+}
+
+// distribute the submissions of a round (or of the recommended submissions when roundId is empty) to judges.
+func (this *AssignmentController) Run(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	roundId, webResult := this.formInt64(request, "roundId")
+	if webResult != nil {
+		return webResult
+	}
+	trackId, webResult := this.formInt64(request, "trackId")
+	if webResult != nil {
+		return webResult
+	}
+	reviews, err := strconv.Atoi(request.FormValue("reviews"))
+	if err != nil {
+		return result.BadRequest("reviews格式错误")
+	}
+
+	report, webResult := this.assignmentService.Assign(roundId, trackId, reviews, user)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(report)
+}
+
+func (this *AssignmentController) Create(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	submissionId, err := strconv.ParseInt(request.FormValue("submissionId"), 10, 64)
+	if err != nil {
+		return result.BadRequest("submissionId格式错误")
+	}
+	judgeUuid := request.FormValue("judgeUuid")
+	if judgeUuid == "" {
+		return result.BadRequest("judgeUuid不能为空")
+	}
+
+	assignment, webResult := this.assignmentService.AssignOne(submissionId, judgeUuid, user)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(assignment)
+}
+
+func (this *AssignmentController) Delete(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	id, err := strconv.ParseInt(request.FormValue("id"), 10, 64)
+	if err != nil {
+		return result.BadRequest("id格式错误")
+	}
+	assignment := this.assignmentDao.Find(id)
+	if assignment == nil {
+		return result.BadRequest("分配记录不存在")
+	}
+
+	this.assignmentDao.Delete(assignment)
+	return this.Success("OK")
+}
+
+// assignments of a round, optionally of one submission or one judge.
+func (this *AssignmentController) List(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	roundId, webResult := this.formInt64(request, "roundId")
+	if webResult != nil {
+		return webResult
+	}
+	submissionId, webResult := this.formInt64(request, "submissionId")
+	if webResult != nil {
+		return webResult
+	}
+	judgeUuid := request.FormValue("judgeUuid")
+
+	var assignments []*Assignment
+	if submissionId != 0 {
+		assignments = this.assignmentDao.FindBySubmissionAndRound(submissionId, roundId)
+	} else if judgeUuid != "" {
+		assignments = this.assignmentDao.FindByJudgeAndRound(judgeUuid, roundId)
+	} else {
+		assignments = this.assignmentDao.FindByRoundId(roundId)
+	}
+	for _, assignment := range assignments {
+		assignment.Submission = this.submissionDao.FindById(assignment.SubmissionId)
+	}
+	return this.Success(assignments)
+}
+
+func (this *AssignmentController) Load(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	roundId, webResult := this.formInt64(request, "roundId")
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(this.assignmentService.Load(roundId))
+}
+
+// submissions assigned to the current judge.
+func (this *AssignmentController) My(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkJudge(request)
+	return this.Success(this.assignmentService.MyAssignments(user))
+}
+
+// a judge declares a conflict of interest on a submission or on an author. existing assignments covered are removed.
+func (this *AssignmentController) DeclareConflict(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkJudge(request)
+
+	submissionId, webResult := this.formInt64(request, "submissionId")
+	if webResult != nil {
+		return webResult
+	}
+	authorId := strings.TrimSpace(request.FormValue("authorId"))
+	if submissionId == 0 && authorId == "" {
+		return result.BadRequest("submissionId和authorId不能同时为空")
+	}
+	if submissionId != 0 && this.submissionDao.FindById(submissionId) == nil {
+		return result.BadRequest("提交作品不存在")
+	}
+
+	conflict := this.assignmentDao.CreateConflict(&JudgeConflict{
+		JudgeUuid:    user.Uuid,
+		SubmissionId: submissionId,
+		AuthorId:     authorId,
+		Reason:       request.FormValue("reason"),
+	})
+
+	for _, assignment := range this.assignmentDao.FindByJudge(user.Uuid) {
+		submission := this.submissionDao.FindById(assignment.SubmissionId)
+		if submission != nil && this.assignmentService.HasConflict(user.Uuid, submission) {
+			this.assignmentDao.Delete(assignment)
+		}
+	}
+
+	return this.Success(conflict)
+}
+
+// judges see their own conflicts, administrators see all of them.
+func (this *AssignmentController) ListConflicts(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	if user.Role == USER_ROLE_ADMINISTRATOR {
+		return this.Success(this.assignmentDao.FindAllConflicts())
+	}
+	if user.Role != USER_ROLE_JUDGE {
+		return result.ConstWebResult(result.UNAUTHORIZED)
+	}
+	return this.Success(this.assignmentDao.FindConflictsByJudge(user.Uuid))
+}
+
+func (this *AssignmentController) DeleteConflict(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	id, err := strconv.ParseInt(request.FormValue("id"), 10, 64)
+	if err != nil {
+		return result.BadRequest("id格式错误")
+	}
+	conflict := this.assignmentDao.FindConflict(id)
+	if conflict == nil {
+		return result.BadRequest("利益冲突声明不存在")
+	}
+
+	this.assignmentDao.DeleteConflict(conflict)
+	return this.Success("OK")
+}
@@ -0,0 +1,108 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+type AssignmentDao struct {
+	BaseDao
+}
+
+func (this *AssignmentDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *AssignmentDao) Create(assignment *Assignment) *Assignment {
+	assignment.CreateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(assignment)
+	this.PanicError(db.Error)
+	return assignment
+}
+
+func (this *AssignmentDao) Find(id int64) *Assignment {
+	var entity = &Assignment{}
+	db := core.CONTEXT.GetDB().Where("id = ?", id).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+func (this *AssignmentDao) FindBySubmissionAndJudgeAndRound(submissionId int64, judgeUuid string, roundId int64) *Assignment {
+	var entity = &Assignment{}
+	db := core.CONTEXT.GetDB().Where("submission_id = ? AND judge_uuid = ? AND round_id = ?", submissionId, judgeUuid, roundId).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+func (this *AssignmentDao) FindByRoundId(roundId int64) []*Assignment {
+	var entities []*Assignment
+	db := core.CONTEXT.GetDB().Where("round_id = ?", roundId).Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *AssignmentDao) FindBySubmissionAndRound(submissionId int64, roundId int64) []*Assignment {
+	var entities []*Assignment
+	db := core.CONTEXT.GetDB().Where("submission_id = ? AND round_id = ?", submissionId, roundId).Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *AssignmentDao) FindByJudgeAndRound(judgeUuid string, roundId int64) []*Assignment {
+	var entities []*Assignment
+	db := core.CONTEXT.GetDB().Where("judge_uuid = ? AND round_id = ?", judgeUuid, roundId).Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *AssignmentDao) FindByJudge(judgeUuid string) []*Assignment {
+	var entities []*Assignment
+	db := core.CONTEXT.GetDB().Where("judge_uuid = ?", judgeUuid).Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *AssignmentDao) Delete(assignment *Assignment) {
+	db := core.CONTEXT.GetDB().Delete(assignment)
+	this.PanicError(db.Error)
+}
+
+func (this *AssignmentDao) CreateConflict(conflict *JudgeConflict) *JudgeConflict {
+	conflict.CreateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(conflict)
+	this.PanicError(db.Error)
+	return conflict
+}
+
+func (this *AssignmentDao) FindConflict(id int64) *JudgeConflict {
+	var entity = &JudgeConflict{}
+	db := core.CONTEXT.GetDB().Where("id = ?", id).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+func (this *AssignmentDao) FindConflictsByJudge(judgeUuid string) []*JudgeConflict {
+	var entities []*JudgeConflict
+	db := core.CONTEXT.GetDB().Where("judge_uuid = ?", judgeUuid).Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *AssignmentDao) FindAllConflicts() []*JudgeConflict {
+	var entities []*JudgeConflict
+	db := core.CONTEXT.GetDB().Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *AssignmentDao) DeleteConflict(conflict *JudgeConflict) {
+	db := core.CONTEXT.GetDB().Delete(conflict)
+	this.PanicError(db.Error)
+}
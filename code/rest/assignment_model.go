@@ -0,0 +1,32 @@
+package rest
+
+import (
+	"time"
+)
+
+// Assignment means the judge should review the submission in the round. Judges can only rate assigned submissions.
+type Assignment struct {
+	Id           int64       `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	SubmissionId int64       `json:"submissionId" gorm:"type:bigint(20) not null;index:idx_assignment_si"`
+	JudgeUuid    string      `json:"judgeUuid" gorm:"type:char(36) not null;index:idx_assignment_ju"`
+	RoundId      int64       `json:"roundId" gorm:"type:bigint(20) not null;default:0"`
+	OperatorUuid string      `json:"operatorUuid" gorm:"type:char(36)"`
+	CreateTime   time.Time   `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	Submission   *Submission `json:"submission" gorm:"-"`
+}
+
+// JudgeConflict is a conflict of interest declared by a judge, on a submission or on all submissions of an author.
+type JudgeConflict struct {
+	Id           int64     `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	JudgeUuid    string    `json:"judgeUuid" gorm:"type:char(36) not null;index:idx_judge_conflict_ju"`
+	SubmissionId int64     `json:"submissionId" gorm:"type:bigint(20) not null;default:0"`
+	AuthorId     string    `json:"authorId" gorm:"type:varchar(50)"`
+	Reason       string    `json:"reason" gorm:"type:varchar(1024)"`
+	CreateTime   time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
+
+// whether the conflict covers the submission.
+func (this *JudgeConflict) Covers(submission *Submission) bool {
+	return (this.SubmissionId != 0 && this.SubmissionId == submission.Id) ||
+		(this.AuthorId != "" && this.AuthorId == submission.AuthorId)
+}
@@ -0,0 +1,417 @@
+package rest
+
+import (
+	"sort"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+// a submission which cannot get enough judges.
+type AssignmentShortage struct {
+	SubmissionId int64  `json:"submissionId"`
+	Title        string `json:"title"`
+	Assigned     int    `json:"assigned"`
+	Required     int    `json:"required"`
+}
+
+type AssignmentReport struct {
+	Created   []*Assignment         `json:"created"`
+	Shortages []*AssignmentShortage `json:"shortages"`
+}
+
+// workload of a judge in a round.
+type JudgeLoad struct {
+	Judge    *User `json:"judge"`
+	Assigned int   `json:"assigned"`
+	Rated    int   `json:"rated"`
+}
+
+// @Service
+type AssignmentService struct {
+	BaseBean
+	assignmentDao  *AssignmentDao
+	submissionDao  *SubmissionDao
+	ratingDao      *RatingDao
+	roundJudgeDao  *RoundJudgeDao
+	userDao        *UserDao
+	userProfileDao *UserProfileDao
+	collegeDao     *CollegeDao
+	teamService    *TeamService
+	blindService   *BlindService
+}
+
+func (this *AssignmentService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.assignmentDao)
+	if b, ok := b.(*AssignmentDao); ok {
+		this.assignmentDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.ratingDao)
+	if b, ok := b.(*RatingDao); ok {
+		this.ratingDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.roundJudgeDao)
+	if b, ok := b.(*RoundJudgeDao); ok {
+		this.roundJudgeDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userDao)
+	if b, ok := b.(*UserDao); ok {
+		this.userDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userProfileDao)
+	if b, ok := b.(*UserProfileDao); ok {
+		this.userProfileDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.collegeDao)
+	if b, ok := b.(*CollegeDao); ok {
+		this.collegeDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.teamService)
+	if b, ok := b.(*TeamService); ok {
+		this.teamService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.blindService)
+	if b, ok := b.(*BlindService); ok {
+		this.blindService = b
+	}
+}
+
+// names of the colleges the submission belongs to: the submission's college and the colleges of the authors, every team member included.
+func (this *AssignmentService) submissionColleges(submission *Submission) map[string]bool {
+	colleges := make(map[string]bool)
+	if college := this.collegeDao.Find(submission.CollegeId); college != nil {
+		colleges[college.Name] = true
+	}
+	for _, userProfile := range this.teamService.Profiles(submission) {
+		if userProfile.College != "" {
+			colleges[userProfile.College] = true
+		}
+	}
+	return colleges
+}
+
+func (this *AssignmentService) judgeCollege(judgeUuid string) string {
+	userProfile := this.userProfileDao.FindByUserUuid(judgeUuid)
+	if userProfile == nil {
+		return ""
+	}
+	return userProfile.College
+}
+
+// whether the judge has a conflict of interest with the submission.
+func (this *AssignmentService) HasConflict(judgeUuid string, submission *Submission) bool {
+	judgeCollege := this.judgeCollege(judgeUuid)
+	if judgeCollege != "" && this.submissionColleges(submission)[judgeCollege] {
+		return true
+	}
+	conflicts := this.assignmentDao.FindConflictsByJudge(judgeUuid)
+	for _, conflict := range conflicts {
+		if conflict.Covers(submission) {
+			return true
+		}
+	}
+	//a conflict with a student covers the team submissions of the student.
+	if submission.TeamId != 0 && len(conflicts) > 0 {
+		for _, userProfile := range this.teamService.Profiles(submission) {
+			for _, conflict := range conflicts {
+				if conflict.AuthorId != "" && conflict.AuthorId == userProfile.StudentId {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// judges who can be assigned in the round: the round's judge pool, or all judges if the pool is empty.
+func (this *AssignmentService) candidateJudges(roundId int64) []*User {
+	if roundId > 0 {
+		roundJudges := this.roundJudgeDao.FindByRoundId(roundId)
+		if len(roundJudges) > 0 {
+			var judges []*User
+			for _, roundJudge := range roundJudges {
+				judge := this.userDao.FindByUuid(roundJudge.JudgeUuid)
+				if judge != nil && judge.Status == USER_STATUS_OK {
+					judges = append(judges, judge)
+				}
+			}
+			return judges
+		}
+	}
+	return this.userDao.FindByRole(USER_ROLE_JUDGE)
+}
+
+// submissions to be reviewed in the round. roundId 0 means the recommended submissions out of any round.
+func (this *AssignmentService) roundSubmissions(roundId int64, trackId int64) []*Submission {
+	var submissions []*Submission
+	if roundId > 0 {
+		submissions = this.submissionDao.FindByRoundIdAndStatus(roundId, ROUND_STATUS_ACTIVE)
+	} else {
+		for _, submission := range this.submissionDao.FindRecommended() {
+			if submission.RoundId == 0 {
+				submissions = append(submissions, submission)
+			}
+		}
+	}
+
+	if trackId == 0 {
+		return submissions
+	}
+	var filtered []*Submission
+	for _, submission := range submissions {
+		if submission.TrackId == trackId {
+			filtered = append(filtered, submission)
+		}
+	}
+	return filtered
+}
+
+// distribute the submissions of the round so that each gets `reviews` judges.
+// Existing assignments are kept. The most constrained submissions pick first, and the least loaded judge is picked.
+func (this *AssignmentService) Assign(roundId int64, trackId int64, reviews int, operator *User) (*AssignmentReport, *result.WebResult) {
+	if reviews < 1 {
+		return nil, result.BadRequest("每个作品的评审数必须大于0")
+	}
+
+	judges := this.candidateJudges(roundId)
+	if len(judges) == 0 {
+		return nil, result.BadRequest("没有可分配的评委")
+	}
+
+	load := make(map[string]int)
+	for _, assignment := range this.assignmentDao.FindByRoundId(roundId) {
+		load[assignment.JudgeUuid]++
+	}
+
+	type task struct {
+		submission *Submission
+		eligible   []*User
+		assigned   int
+	}
+	var tasks []*task
+	for _, submission := range this.roundSubmissions(roundId, trackId) {
+		assigned := make(map[string]bool)
+		for _, assignment := range this.assignmentDao.FindBySubmissionAndRound(submission.Id, roundId) {
+			assigned[assignment.JudgeUuid] = true
+		}
+		if len(assigned) >= reviews {
+			continue
+		}
+
+		t := &task{submission: submission, assigned: len(assigned)}
+		for _, judge := range judges {
+			if !assigned[judge.Uuid] && !this.HasConflict(judge.Uuid, submission) {
+				t.eligible = append(t.eligible, judge)
+			}
+		}
+		tasks = append(tasks, t)
+	}
+
+	//the most constrained submissions first.
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return len(tasks[i].eligible)-(reviews-tasks[i].assigned) < len(tasks[j].eligible)-(reviews-tasks[j].assigned)
+	})
+
+	report := &AssignmentReport{Created: []*Assignment{}, Shortages: []*AssignmentShortage{}}
+
+	//one judge per submission in each pass, so the load spreads evenly.
+	for pass := 0; pass < reviews; pass++ {
+		for _, t := range tasks {
+			if t.assigned >= reviews || len(t.eligible) == 0 {
+				continue
+			}
+
+			sort.SliceStable(t.eligible, func(i, j int) bool {
+				if load[t.eligible[i].Uuid] != load[t.eligible[j].Uuid] {
+					return load[t.eligible[i].Uuid] < load[t.eligible[j].Uuid]
+				}
+				return t.eligible[i].Uuid < t.eligible[j].Uuid
+			})
+			judge := t.eligible[0]
+			t.eligible = t.eligible[1:]
+
+			assignment := this.assignmentDao.Create(&Assignment{
+				SubmissionId: t.submission.Id,
+				JudgeUuid:    judge.Uuid,
+				RoundId:      roundId,
+				OperatorUuid: operator.Uuid,
+			})
+			report.Created = append(report.Created, assignment)
+			load[judge.Uuid]++
+			t.assigned++
+		}
+	}
+
+	for _, t := range tasks {
+		if t.assigned < reviews {
+			report.Shortages = append(report.Shortages, &AssignmentShortage{
+				SubmissionId: t.submission.Id,
+				Title:        t.submission.Title,
+				Assigned:     t.assigned,
+				Required:     reviews,
+			})
+		}
+	}
+
+	this.logger.Info("assign round %d track %d: %d created, %d short", roundId, trackId, len(report.Created), len(report.Shortages))
+
+	return report, nil
+}
+
+// assign a judge to a submission manually. conflicts of interest are still not allowed.
+func (this *AssignmentService) AssignOne(submissionId int64, judgeUuid string, operator *User) (*Assignment, *result.WebResult) {
+	submission := this.submissionDao.FindById(submissionId)
+	if submission == nil {
+		return nil, result.BadRequest("提交作品不存在")
+	}
+	//the same submissions and judges as Assign picks from.
+	if submission.RoundId > 0 {
+		if submission.RoundStatus != ROUND_STATUS_ACTIVE {
+			return nil, result.BadRequest("该作品不在本轮评审中")
+		}
+	} else if !submission.IsRecommended {
+		return nil, result.BadRequest("该作品未被推荐，也未进入评审轮次")
+	}
+	judge := this.userDao.FindByUuid(judgeUuid)
+	if judge == nil || judge.Role != USER_ROLE_JUDGE {
+		return nil, result.BadRequest("该用户不是评委")
+	}
+	inPool := false
+	for _, candidate := range this.candidateJudges(submission.RoundId) {
+		if candidate.Uuid == judge.Uuid {
+			inPool = true
+			break
+		}
+	}
+	if !inPool {
+		return nil, result.BadRequest("评委 %s 不在本轮评委名单中", judge.Username)
+	}
+	if this.HasConflict(judge.Uuid, submission) {
+		return nil, result.BadRequest("评委 %s 与该作品存在利益冲突", judge.Username)
+	}
+	if this.assignmentDao.FindBySubmissionAndJudgeAndRound(submission.Id, judge.Uuid, submission.RoundId) != nil {
+		return nil, result.BadRequest("该评委已分配到该作品")
+	}
+
+	assignment := this.assignmentDao.Create(&Assignment{
+		SubmissionId: submission.Id,
+		JudgeUuid:    judge.Uuid,
+		RoundId:      submission.RoundId,
+		OperatorUuid: operator.Uuid,
+	})
+	return assignment, nil
+}
+
+// replace the judges of the submission in the round with `reviews` other judges, for the re-review of an accepted appeal.
+// judges who were assigned or have rated the submission in the round are not picked again.
+func (this *AssignmentService) Reassign(submission *Submission, roundId int64, reviews int, operator *User) ([]*Assignment, *result.WebResult) {
+	if reviews < 1 {
+		return nil, result.BadRequest("每个作品的评审数必须大于0")
+	}
+
+	previous := this.assignmentDao.FindBySubmissionAndRound(submission.Id, roundId)
+	excluded := make(map[string]bool)
+	for _, assignment := range previous {
+		excluded[assignment.JudgeUuid] = true
+	}
+	for _, rating := range this.ratingDao.FindBySubmissionId(submission.Id) {
+		if rating.RoundId == roundId {
+			excluded[rating.JudgeUuid] = true
+		}
+	}
+
+	load := make(map[string]int)
+	for _, assignment := range this.assignmentDao.FindByRoundId(roundId) {
+		load[assignment.JudgeUuid]++
+	}
+
+	var eligible []*User
+	for _, judge := range this.candidateJudges(roundId) {
+		if !excluded[judge.Uuid] && !this.HasConflict(judge.Uuid, submission) {
+			eligible = append(eligible, judge)
+		}
+	}
+	if len(eligible) < reviews {
+		return nil, result.BadRequest("可用于复评的评委不足，需要 %d 位，仅有 %d 位", reviews, len(eligible))
+	}
+	sort.SliceStable(eligible, func(i, j int) bool {
+		if load[eligible[i].Uuid] != load[eligible[j].Uuid] {
+			return load[eligible[i].Uuid] < load[eligible[j].Uuid]
+		}
+		return eligible[i].Uuid < eligible[j].Uuid
+	})
+
+	for _, assignment := range previous {
+		this.assignmentDao.Delete(assignment)
+	}
+
+	var assignments []*Assignment
+	for _, judge := range eligible[:reviews] {
+		assignments = append(assignments, this.assignmentDao.Create(&Assignment{
+			SubmissionId: submission.Id,
+			JudgeUuid:    judge.Uuid,
+			RoundId:      roundId,
+			OperatorUuid: operator.Uuid,
+		}))
+	}
+	return assignments, nil
+}
+
+// judges can only rate the submissions assigned to them in the current round.
+func (this *AssignmentService) CheckAssigned(submission *Submission, judge *User) *result.WebResult {
+	if this.assignmentDao.FindBySubmissionAndJudgeAndRound(submission.Id, judge.Uuid, submission.RoundId) == nil {
+		return result.BadRequest("该作品未分配给您评审")
+	}
+	return nil
+}
+
+// assignments of the judge in the current rounds of the submissions, with submission info.
+func (this *AssignmentService) MyAssignments(judge *User) []*Assignment {
+	var assignments []*Assignment
+	for _, assignment := range this.assignmentDao.FindByJudge(judge.Uuid) {
+		submission := this.submissionDao.FindById(assignment.SubmissionId)
+		if submission == nil || submission.RoundId != assignment.RoundId {
+			continue
+		}
+		assignment.Submission = this.blindService.ForUser(judge, submission)
+		assignments = append(assignments, assignment)
+	}
+	return assignments
+}
+
+// workload of every candidate judge in the round.
+func (this *AssignmentService) Load(roundId int64) []*JudgeLoad {
+	loadMap := make(map[string]*JudgeLoad)
+	var loads []*JudgeLoad
+	for _, judge := range this.candidateJudges(roundId) {
+		load := &JudgeLoad{Judge: judge}
+		loadMap[judge.Uuid] = load
+		loads = append(loads, load)
+	}
+
+	for _, assignment := range this.assignmentDao.FindByRoundId(roundId) {
+		if load, ok := loadMap[assignment.JudgeUuid]; ok {
+			load.Assigned++
+		}
+	}
+	for _, rating := range this.ratingDao.FindByRoundId(roundId) {
+		if load, ok := loadMap[rating.JudgeUuid]; ok {
+			load.Rated++
+		}
+	}
+	return loads
+}
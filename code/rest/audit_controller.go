@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+type AuditController struct {
+	BaseController
+	auditDao     *AuditDao
+	auditService *AuditService
+}
+
+func (this *AuditController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.auditDao)
+	if b, ok := b.(*AuditDao); ok {
+		this.auditDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.auditService)
+	if b, ok := b.(*AuditService); ok {
+		this.auditService = b
+	}
+}
+
+func (this *AuditController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/audit/page"] = this.Wrap(this.Page, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/audit/verify"] = this.Wrap(this.Verify, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+// parse an optional time form value.
+func (this *AuditController) formTime(request *http.Request, key string) *time.Time {
+	str := request.FormValue(key)
+	if str == "" {
+		return nil
+	}
+	local, _ := time.LoadLocation("Local")
+	value, err := time.ParseInLocation("2006-01-02 15:04:05", str, local)
+	if err != nil {
+		panic(result.BadRequest("%s格式错误", key))
+	}
+	return &value
+}
+
+// audit logs, newest first.
+func (this *AuditController) Page(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	page := util.ExtractRequestOptionalInt(request, "page", 0)
+	pageSize := util.ExtractRequestOptionalInt(request, "pageSize", 50)
+
+	filter := &AuditFilter{
+		Action:     request.FormValue("action"),
+		ActorUuid:  request.FormValue("actorUuid"),
+		TargetType: request.FormValue("targetType"),
+		TargetId:   request.FormValue("targetId"),
+		Ip:         request.FormValue("ip"),
+		StartTime:  this.formTime(request, "startTime"),
+		EndTime:    this.formTime(request, "endTime"),
+	}
+
+	return this.Success(this.auditDao.Page(page, pageSize, filter))
+}
+
+// check that no log has been edited, inserted or deleted.
+func (this *AuditController) Verify(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	return this.Success(this.auditService.Verify())
+}
@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/builder"
+	"gorm.io/gorm"
+)
+
+type AuditDao struct {
+	BaseDao
+}
+
+func (this *AuditDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *AuditDao) Create(auditLog *AuditLog) *AuditLog {
+	db := core.CONTEXT.GetDB().Create(auditLog)
+	this.PanicError(db.Error)
+	return auditLog
+}
+
+// the last log of the chain, nil if there is none.
+func (this *AuditDao) FindLast() *AuditLog {
+	var entity = &AuditLog{}
+	db := core.CONTEXT.GetDB().Order("id DESC").First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+// logs after the id in the chain order.
+func (this *AuditDao) FindAfterId(id int64, limit int) []*AuditLog {
+	var entities []*AuditLog
+	db := core.CONTEXT.GetDB().Where("id > ?", id).Order("id ASC").Limit(limit).Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *AuditDao) Page(page int, pageSize int, filter *AuditFilter) *Pager {
+
+	var wp = &builder.WherePair{}
+
+	if filter.Action != "" {
+		wp = wp.And(&builder.WherePair{Query: "action = ?", Args: []any{filter.Action}})
+	}
+	if filter.ActorUuid != "" {
+		wp = wp.And(&builder.WherePair{Query: "actor_uuid = ?", Args: []any{filter.ActorUuid}})
+	}
+	if filter.TargetType != "" {
+		wp = wp.And(&builder.WherePair{Query: "target_type = ?", Args: []any{filter.TargetType}})
+	}
+	if filter.TargetId != "" {
+		wp = wp.And(&builder.WherePair{Query: "target_id = ?", Args: []any{filter.TargetId}})
+	}
+	if filter.Ip != "" {
+		wp = wp.And(&builder.WherePair{Query: "ip = ?", Args: []any{filter.Ip}})
+	}
+	if filter.StartTime != nil {
+		wp = wp.And(&builder.WherePair{Query: "create_time >= ?", Args: []any{*filter.StartTime}})
+	}
+	if filter.EndTime != nil {
+		wp = wp.And(&builder.WherePair{Query: "create_time <= ?", Args: []any{*filter.EndTime}})
+	}
+
+	var conditionDB *gorm.DB
+	conditionDB = core.CONTEXT.GetDB().Model(&AuditLog{}).Where(wp.Query, wp.Args...)
+
+	var count int64 = 0
+	db := conditionDB.Count(&count)
+	this.PanicError(db.Error)
+
+	var auditLogs []*AuditLog
+	db = conditionDB.Order("id DESC").Offset(page * pageSize).Limit(pageSize).Find(&auditLogs)
+	this.PanicError(db.Error)
+
+	return NewPager(page, pageSize, int(count), auditLogs)
+}
@@ -0,0 +1,103 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AUDIT_ACTION_LABEL_ADD           = "LABEL_ADD"
+	AUDIT_ACTION_LABEL_DELETE        = "LABEL_DELETE"
+	AUDIT_ACTION_RECOMMEND           = "RECOMMEND"
+	AUDIT_ACTION_RATING_CREATE       = "RATING_CREATE"
+	AUDIT_ACTION_RATING_EDIT         = "RATING_EDIT"
+	AUDIT_ACTION_USER_ROLE           = "USER_ROLE"
+	AUDIT_ACTION_LATE_GRANT_CREATE   = "LATE_GRANT_CREATE"
+	AUDIT_ACTION_LATE_GRANT_DELETE   = "LATE_GRANT_DELETE"
+	AUDIT_ACTION_WINDOW_CONFIG_EDIT  = "WINDOW_CONFIG_EDIT"
+	AUDIT_ACTION_ROUND_DEADLINE_EDIT = "ROUND_DEADLINE_EDIT"
+	AUDIT_ACTION_APPEAL_ACCEPT       = "APPEAL_ACCEPT"
+	AUDIT_ACTION_APPEAL_REJECT       = "APPEAL_REJECT"
+	AUDIT_ACTION_COMPETITION_ARCHIVE = "COMPETITION_ARCHIVE"
+	AUDIT_ACTION_QUOTA_CONFIG_EDIT   = "QUOTA_CONFIG_EDIT"
+	AUDIT_ACTION_QUOTA_OVERRIDE      = "QUOTA_OVERRIDE"
+)
+
+const (
+	AUDIT_TARGET_MATTER      = "MATTER"
+	AUDIT_TARGET_SUBMISSION  = "SUBMISSION"
+	AUDIT_TARGET_RATING      = "RATING"
+	AUDIT_TARGET_USER        = "USER"
+	AUDIT_TARGET_LATE_GRANT  = "LATE_GRANT"
+	AUDIT_TARGET_PREFERENCE  = "PREFERENCE"
+	AUDIT_TARGET_ROUND       = "ROUND"
+	AUDIT_TARGET_APPEAL      = "APPEAL"
+	AUDIT_TARGET_COMPETITION = "COMPETITION"
+)
+
+// fields are joined by the unit separator before hashing.
+const AUDIT_HASH_SEPARATOR = "\x1f"
+
+// AuditLog is a domain event of the review, eg. a score is changed.
+// Every log carries the hash of the previous one, so editing or deleting a log breaks the chain after it.
+type AuditLog struct {
+	Id         int64  `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	Action     string `json:"action" gorm:"type:varchar(45) not null;index:idx_audit_log_a"`
+	ActorUuid  string `json:"actorUuid" gorm:"type:char(36);index:idx_audit_log_au"`
+	ActorName  string `json:"actorName" gorm:"type:varchar(45)"`
+	ActorRole  string `json:"actorRole" gorm:"type:varchar(45)"`
+	TargetType string `json:"targetType" gorm:"type:varchar(45) not null"`
+	TargetId   string `json:"targetId" gorm:"type:varchar(64) not null;index:idx_audit_log_ti"`
+	//json of the target before and after the change. empty if the target did not exist.
+	Before     string    `json:"before" gorm:"type:text"`
+	After      string    `json:"after" gorm:"type:text"`
+	Ip         string    `json:"ip" gorm:"type:varchar(128)"`
+	CreateTime time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	PrevHash   string    `json:"prevHash" gorm:"type:char(64)"`
+	Hash       string    `json:"hash" gorm:"type:char(64) not null"`
+}
+
+// sha256 of the previous hash and every field of the log except the id.
+// the time is hashed in seconds, which is what the database keeps.
+func (this *AuditLog) ComputeHash() string {
+	content := strings.Join([]string{
+		this.PrevHash,
+		this.Action,
+		this.ActorUuid,
+		this.ActorName,
+		this.ActorRole,
+		this.TargetType,
+		this.TargetId,
+		this.Before,
+		this.After,
+		this.Ip,
+		strconv.FormatInt(this.CreateTime.Unix(), 10),
+	}, AUDIT_HASH_SEPARATOR)
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditFilter filters audit logs. zero values mean no restriction.
+type AuditFilter struct {
+	Action     string
+	ActorUuid  string
+	TargetType string
+	TargetId   string
+	Ip         string
+	StartTime  *time.Time
+	EndTime    *time.Time
+}
+
+// AuditVerification is the result of walking the hash chain.
+type AuditVerification struct {
+	Valid bool  `json:"valid"`
+	Count int64 `json:"count"`
+	//the first log breaking the chain, 0 if valid.
+	BrokenId int64  `json:"brokenId"`
+	Reason   string `json:"reason"`
+	//hash of the last log. keep it elsewhere to detect the chain being rebuilt.
+	HeadHash string `json:"headHash"`
+}
@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/util"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// @Service
+type AuditService struct {
+	BaseBean
+	auditDao *AuditDao
+
+	//logs are chained one after another.
+	mutex sync.Mutex
+}
+
+func (this *AuditService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.auditDao)
+	if b, ok := b.(*AuditDao); ok {
+		this.auditDao = b
+	}
+}
+
+func (this *AuditService) toJson(value any) string {
+	if value == nil {
+		return ""
+	}
+	bytes, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(value)
+	this.PanicError(err)
+	//a nil pointer means the target did not exist either.
+	if string(bytes) == "null" {
+		return ""
+	}
+	return string(bytes)
+}
+
+// record a change of the target made by the actor. before is nil for a creation, after is nil for a deletion.
+func (this *AuditService) Log(request *http.Request, actor *User, action string, targetType string, targetId any, before any, after any) *AuditLog {
+	auditLog := &AuditLog{
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   fmt.Sprintf("%v", targetId),
+		Before:     this.toJson(before),
+		After:      this.toJson(after),
+		//the database keeps seconds only.
+		CreateTime: time.Now().Truncate(time.Second),
+	}
+	if actor != nil {
+		auditLog.ActorUuid = actor.Uuid
+		auditLog.ActorName = actor.Username
+		auditLog.ActorRole = actor.Role
+	}
+	if request != nil {
+		auditLog.Ip = util.GetIpAddress(request)
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if last := this.auditDao.FindLast(); last != nil {
+		auditLog.PrevHash = last.Hash
+	}
+	auditLog.Hash = auditLog.ComputeHash()
+	return this.auditDao.Create(auditLog)
+}
+
+// walk the chain from the first log and find the first one edited, inserted or following a deleted one.
+func (this *AuditService) Verify() *AuditVerification {
+	verification := &AuditVerification{Valid: true}
+
+	var lastId int64 = 0
+	prevHash := ""
+	for {
+		auditLogs := this.auditDao.FindAfterId(lastId, 1000)
+		if len(auditLogs) == 0 {
+			break
+		}
+		for _, auditLog := range auditLogs {
+			verification.Count++
+			if auditLog.PrevHash != prevHash {
+				verification.Valid = false
+				verification.BrokenId = auditLog.Id
+				verification.Reason = "上一条日志的哈希不匹配，日志可能被删除或插入"
+				return verification
+			}
+			if auditLog.ComputeHash() != auditLog.Hash {
+				verification.Valid = false
+				verification.BrokenId = auditLog.Id
+				verification.Reason = "日志内容与哈希不匹配，日志可能被修改"
+				return verification
+			}
+			prevHash = auditLog.Hash
+			lastId = auditLog.Id
+		}
+	}
+
+	verification.HeadHash = prevHash
+	return verification
+}
@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+// only administrators know whose submission a blind code stands for.
+type BlindController struct {
+	BaseController
+	blindService  *BlindService
+	submissionDao *SubmissionDao
+	roundDao      *RoundDao
+}
+
+func (this *BlindController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.blindService)
+	if b, ok := b.(*BlindService); ok {
+		this.blindService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.roundDao)
+	if b, ok := b.(*RoundDao); ok {
+		this.roundDao = b
+	}
+}
+
+func (this *BlindController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/blind/mapping"] = this.Wrap(this.Mapping, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/blind/lookup"] = this.Wrap(this.Lookup, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+// blind codes of every submission in the round.
+func (this *BlindController) Mapping(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	roundId, err := strconv.ParseInt(request.FormValue("roundId"), 10, 64)
+	if err != nil {
+		return result.BadRequest("roundId格式错误")
+	}
+	if this.roundDao.Find(roundId) == nil {
+		return result.BadRequest("轮次不存在")
+	}
+
+	mappings := []*BlindMapping{}
+	for _, submission := range this.submissionDao.FindByFilter(&SubmissionFilter{RoundId: roundId}) {
+		mappings = append(mappings, this.blindService.Mapping(submission))
+	}
+	return this.Success(mappings)
+}
+
+// the submission of a blind code.
+func (this *BlindController) Lookup(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	code := strings.ToUpper(strings.TrimSpace(request.FormValue("code")))
+	if code == "" {
+		return result.BadRequest("code不能为空")
+	}
+	submission := this.submissionDao.FindByBlindCode(code)
+	if submission == nil {
+		return result.BadRequest("作品编号不存在")
+	}
+	return this.Success(this.blindService.Mapping(submission))
+}
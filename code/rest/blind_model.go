@@ -0,0 +1,15 @@
+package rest
+
+// BlindMapping tells administrators whose submission a blind code stands for.
+type BlindMapping struct {
+	BlindCode    string `json:"blindCode"`
+	SubmissionId int64  `json:"submissionId"`
+	RoundId      int64  `json:"roundId"`
+	TrackId      int64  `json:"trackId"`
+	CollegeId    int64  `json:"collegeId"`
+	TeamId       int64  `json:"teamId"`
+	Title        string `json:"title"`
+	AuthorName   string `json:"authorName"`
+	AuthorId     string `json:"authorId"`
+	MatterUuid   string `json:"matterUuid"`
+}
@@ -0,0 +1,265 @@
+package rest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"path"
+	"strings"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+// @Service
+type BlindService struct {
+	BaseBean
+	submissionDao *SubmissionDao
+	roundDao      *RoundDao
+	matterDao     *MatterDao
+}
+
+func (this *BlindService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.roundDao)
+	if b, ok := b.(*RoundDao); ok {
+		this.roundDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+}
+
+// the blind round the submission is in. nil if the submission is reviewed openly.
+func (this *BlindService) blindRound(submission *Submission) *Round {
+	if submission == nil || submission.RoundId == 0 {
+		return nil
+	}
+	round := this.roundDao.Find(submission.RoundId)
+	if round == nil || !round.Blind {
+		return nil
+	}
+	return round
+}
+
+// whether the user looks at the submission blindly. only judges do.
+func (this *BlindService) Applies(user *User, submission *Submission) bool {
+	return user != nil && user.Role == USER_ROLE_JUDGE && this.blindRound(submission) != nil
+}
+
+// whether any round is blind. spaces are not bound to a round, so their owners are hidden from judges whenever blind review is in use.
+func (this *BlindService) AnyBlind() bool {
+	for _, round := range this.roundDao.FindAll() {
+		if round.Blind {
+			return true
+		}
+	}
+	return false
+}
+
+// the blind code of the submission, generated at the first use.
+func (this *BlindService) Code(submission *Submission) string {
+	if submission.BlindCode != "" {
+		return submission.BlindCode
+	}
+	for {
+		bytes := make([]byte, 4)
+		_, err := rand.Read(bytes)
+		this.PanicError(err)
+		code := "S" + strings.ToUpper(hex.EncodeToString(bytes))
+		if this.submissionDao.FindByBlindCode(code) == nil {
+			submission.BlindCode = code
+			this.submissionDao.Save(submission)
+			return code
+		}
+	}
+}
+
+// a copy of the submission without the identity of the authors.
+func (this *BlindService) MaskSubmission(submission *Submission) *Submission {
+	masked := *submission
+	masked.AuthorName = this.Code(submission)
+	masked.BlindCode = masked.AuthorName
+	masked.AuthorId = ""
+	masked.CollegeId = 0
+	masked.TeamId = 0
+	masked.RecommendedBy = ""
+	return &masked
+}
+
+// the submission as the user should see it.
+func (this *BlindService) ForUser(user *User, submission *Submission) *Submission {
+	if submission == nil || !this.Applies(user, submission) {
+		return submission
+	}
+	return this.MaskSubmission(submission)
+}
+
+// the outermost submission folder containing the matter, nil if the matter is not in a submission.
+func (this *BlindService) submissionOf(matter *Matter, cache map[string]*Submission) *Submission {
+	var chain []string
+	var found *Submission
+	for uuid := matter.Uuid; uuid != "" && uuid != MATTER_ROOT; {
+		if submission, ok := cache[uuid]; ok {
+			if submission != nil {
+				found = submission
+			}
+			break
+		}
+		chain = append(chain, uuid)
+
+		current := matter
+		if uuid != matter.Uuid {
+			current = this.matterDao.FindByUuid(uuid)
+		}
+		if current == nil {
+			break
+		}
+		if current.Dir {
+			if submission := this.submissionDao.FindByMatterUuid(current.Uuid); submission != nil {
+				found = submission
+			}
+		}
+		uuid = current.Puuid
+	}
+	for _, uuid := range chain {
+		cache[uuid] = found
+	}
+	return found
+}
+
+// name of the file shown to judges when the round renames files. empty if the file keeps its name.
+func (this *BlindService) blindFileName(round *Round, submission *Submission, matter *Matter) string {
+	if matter.Dir || !round.BlindRename {
+		return ""
+	}
+	return this.Code(submission) + "-" + strings.ReplaceAll(matter.Uuid, "-", "")[0:8] + path.Ext(matter.Name)
+}
+
+// the name judges see the matter by, empty if they see its own name.
+func (this *BlindService) BlindName(matter *Matter) string {
+	submission := this.submissionOf(matter, make(map[string]*Submission))
+	round := this.blindRound(submission)
+	if round == nil {
+		return ""
+	}
+	if matter.Uuid == submission.MatterUuid {
+		return this.Code(submission)
+	}
+	return this.blindFileName(round, submission, matter)
+}
+
+// a copy of the matter without its owner, the submission folder renamed after the blind code.
+func (this *BlindService) maskMatter(matter *Matter, cache map[string]*Submission) *Matter {
+	submission := this.submissionOf(matter, cache)
+	round := this.blindRound(submission)
+	if round == nil {
+		return matter
+	}
+	folder := this.matterDao.FindByUuid(submission.MatterUuid)
+	code := this.Code(submission)
+
+	masked := *matter
+	masked.UserUuid = ""
+	masked.User = nil
+	masked.SpaceName = ""
+	if masked.Uuid == submission.MatterUuid {
+		masked.Name = code
+	} else if name := this.blindFileName(round, submission, matter); name != "" {
+		masked.Name = name
+	}
+	if folder != nil && strings.HasPrefix(matter.Path, folder.Path) {
+		masked.Path = "/" + code + strings.TrimPrefix(matter.Path, folder.Path)
+		masked.Path = path.Join(path.Dir(masked.Path), masked.Name)
+	}
+	if matter.Parent != nil {
+		masked.Parent = this.maskMatter(matter.Parent, cache)
+	}
+	return &masked
+}
+
+// the matter as the user should see it.
+func (this *BlindService) MaskMatter(user *User, matter *Matter) *Matter {
+	if matter == nil || user == nil || user.Role != USER_ROLE_JUDGE {
+		return matter
+	}
+	return this.maskMatter(matter, make(map[string]*Submission))
+}
+
+// the matters as the user should see them.
+func (this *BlindService) MaskMatters(user *User, matters []*Matter) []*Matter {
+	if user == nil || user.Role != USER_ROLE_JUDGE {
+		return matters
+	}
+	cache := make(map[string]*Submission)
+	masked := make([]*Matter, len(matters))
+	for i, matter := range matters {
+		masked[i] = this.maskMatter(matter, cache)
+	}
+	return masked
+}
+
+// a copy of the space without its owner for judges.
+func (this *BlindService) MaskSpace(user *User, space *Space) *Space {
+	if space == nil || user == nil || user.Role != USER_ROLE_JUDGE || space.Uuid == user.SpaceUuid || !this.AnyBlind() {
+		return space
+	}
+	masked := *space
+	masked.Name = ""
+	masked.UserUuid = ""
+	masked.User = nil
+	return &masked
+}
+
+// names of matters in a zip as the user should see them.
+func (this *BlindService) ZipNameFunc(user *User) func(matter *Matter) string {
+	if user == nil || user.Role != USER_ROLE_JUDGE {
+		return func(matter *Matter) string {
+			return matter.Name
+		}
+	}
+	cache := make(map[string]*Submission)
+	return func(matter *Matter) string {
+		return this.maskMatter(matter, cache).Name
+	}
+}
+
+// entry names of the snapshot as the user should see them.
+func (this *BlindService) MaskEntries(user *User, submission *Submission, entries []*SnapshotEntry) []*SnapshotEntry {
+	round := this.blindRound(submission)
+	if !this.Applies(user, submission) || !round.BlindRename {
+		return entries
+	}
+	masked := make([]*SnapshotEntry, len(entries))
+	for i, entry := range entries {
+		copied := *entry
+		if !entry.Dir {
+			copied.Name = this.blindFileName(round, submission, &Matter{Uuid: entry.MatterUuid, Name: entry.Name})
+			copied.Path = path.Join(path.Dir(entry.Path), copied.Name)
+		}
+		masked[i] = &copied
+	}
+	return masked
+}
+
+// whose submission the blind code stands for.
+func (this *BlindService) Mapping(submission *Submission) *BlindMapping {
+	return &BlindMapping{
+		BlindCode:    this.Code(submission),
+		SubmissionId: submission.Id,
+		RoundId:      submission.RoundId,
+		TrackId:      submission.TrackId,
+		CollegeId:    submission.CollegeId,
+		TeamId:       submission.TeamId,
+		Title:        submission.Title,
+		AuthorName:   submission.AuthorName,
+		AuthorId:     submission.AuthorId,
+		MatterUuid:   submission.MatterUuid,
+	}
+}
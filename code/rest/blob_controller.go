@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+type BlobController struct {
+	BaseController
+	blobService *BlobService
+}
+
+func (this *BlobController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.blobService)
+	if b, ok := b.(*BlobService); ok {
+		this.blobService = b
+	}
+}
+
+func (this *BlobController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/blob/stat"] = this.Wrap(this.Stat, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/blob/migrate"] = this.Wrap(this.Migrate, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/blob/collect"] = this.Wrap(this.Collect, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+func (this *BlobController) Stat(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	return this.Success(this.blobService.Stat())
+}
+
+// put the files uploaded before the blob store into it. it can be run again, files already stored are skipped.
+func (this *BlobController) Migrate(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	return this.Success(this.blobService.Migrate())
+}
+
+// count the references of the blobs again and remove those no file references.
+func (this *BlobController) Collect(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	return this.Success(this.blobService.Collect())
+}
@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"gorm.io/gorm"
+)
+
+type BlobDao struct {
+	BaseDao
+}
+
+// find by sha256. if not found return nil.
+func (this *BlobDao) FindBySha256(sha256 string) *Blob {
+	var entity = &Blob{}
+	db := core.CONTEXT.GetDB().Where("sha256 = ?", sha256).First(entity)
+	if db.Error != nil {
+		if db.Error.Error() == result.DB_ERROR_NOT_FOUND {
+			return nil
+		} else {
+			panic(db.Error)
+		}
+	}
+	return entity
+}
+
+// blobs no matter references.
+func (this *BlobDao) FindUnreferenced() []*Blob {
+	var entities []*Blob
+	db := core.CONTEXT.GetDB().Where("ref_count <= 0").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *BlobDao) Create(blob *Blob) *Blob {
+
+	blob.CreateTime = time.Now()
+	blob.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(blob)
+	this.PanicError(db.Error)
+
+	return blob
+}
+
+// one more matter references the blob.
+func (this *BlobDao) Ref(sha256 string) {
+	db := core.CONTEXT.GetDB().Model(&Blob{}).Where("sha256 = ?", sha256).Updates(map[string]any{"ref_count": gorm.Expr("ref_count + 1"), "update_time": time.Now()})
+	this.PanicError(db.Error)
+}
+
+// one matter less references the blob.
+func (this *BlobDao) Unref(sha256 string) {
+	db := core.CONTEXT.GetDB().Model(&Blob{}).Where("sha256 = ?", sha256).Updates(map[string]any{"ref_count": gorm.Expr("ref_count - 1"), "update_time": time.Now()})
+	this.PanicError(db.Error)
+}
+
+// count the references again from the matters, their versions and the snapshots, eg. after the matters of a user are deleted at once.
+func (this *BlobDao) Recount() {
+	sql := fmt.Sprintf("UPDATE `%[1]sblob` SET ref_count = "+
+		"(SELECT COUNT(*) FROM `%[1]smatter` WHERE `%[1]smatter`.sha256 = `%[1]sblob`.sha256) + "+
+		"(SELECT COUNT(*) FROM `%[1]smatter_version` WHERE `%[1]smatter_version`.sha256 = `%[1]sblob`.sha256) + "+
+		"(SELECT COUNT(*) FROM `%[1]ssnapshot_entry` WHERE `%[1]ssnapshot_entry`.sha256 = `%[1]sblob`.sha256)",
+		core.TABLE_PREFIX)
+	db := core.CONTEXT.GetDB().Exec(sql)
+	this.PanicError(db.Error)
+}
+
+// how many matters, versions and snapshot entries reference the blob now, whatever its count says.
+func (this *BlobDao) CountReferences(sha256 string) int64 {
+	var count int64 = 0
+	for _, model := range []any{&Matter{}, &MatterVersion{}, &SnapshotEntry{}} {
+		var n int64
+		db := core.CONTEXT.GetDB().Model(model).Where("sha256 = ?", sha256).Count(&n)
+		this.PanicError(db.Error)
+		count += n
+	}
+	return count
+}
+
+// set the reference count of the blob.
+func (this *BlobDao) UpdateRefCount(sha256 string, refCount int64) {
+	db := core.CONTEXT.GetDB().Model(&Blob{}).Where("sha256 = ?", sha256).Updates(map[string]any{"ref_count": refCount, "update_time": time.Now()})
+	this.PanicError(db.Error)
+}
+
+func (this *BlobDao) Stat() *BlobStat {
+	stat := &BlobStat{}
+	db := core.CONTEXT.GetDB().Model(&Blob{}).
+		Select("COUNT(*) AS blob_count, COALESCE(SUM(size), 0) AS blob_size, COALESCE(SUM(size * ref_count), 0) AS referenced_size").
+		Scan(stat)
+	this.PanicError(db.Error)
+	stat.SavedSize = stat.ReferencedSize - stat.BlobSize
+	return stat
+}
+
+func (this *BlobDao) Delete(blob *Blob) {
+
+	db := core.CONTEXT.GetDB().Delete(blob)
+	this.PanicError(db.Error)
+
+}
@@ -0,0 +1,92 @@
+package rest
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+const (
+	//blob store of the matters. space name cannot start with dot, so it never conflicts with spaces.
+	MATTER_BLOB = ".blob"
+)
+
+// Blob is the content of files stored once, addressed by its sha256.
+// Every file of the same content is a hard link to the blob, so the bytes are on disk only once while the files keep their paths.
+// RefCount is the number of matters linked to the blob, including those in the recycle bin, and of the versions and snapshot
+// entries keeping it. A snapshot of a file out of the default storage keeps a copy of the bytes as its blob.
+// A blob with no reference is collected, which only removes its own link, never the bytes of a file.
+type Blob struct {
+	Sha256     string    `json:"sha256" gorm:"type:char(64);primary_key;unique"`
+	Md5        string    `json:"md5" gorm:"type:varchar(45) not null"`
+	Size       int64     `json:"size" gorm:"type:bigint(20) not null;default:0"`
+	RefCount   int64     `json:"refCount" gorm:"type:bigint(20) not null;default:0"`
+	UpdateTime time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	CreateTime time.Time `json:"createTime" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"`
+}
+
+// the result of moving the existing files into the blob store.
+type BlobMigration struct {
+	//files which were not in the blob store.
+	Scanned int64 `json:"scanned"`
+	//files now in the blob store.
+	Stored int64 `json:"stored"`
+	//files whose content was already stored, so their bytes were freed.
+	Shared    int64 `json:"shared"`
+	SavedSize int64 `json:"savedSize"`
+	//files missing on disk or failed to link.
+	Failed int64 `json:"failed"`
+	//blobs no file references any more.
+	Collected int64 `json:"collected"`
+}
+
+// usage of the blob store.
+type BlobStat struct {
+	BlobCount int64 `json:"blobCount"`
+	//bytes on disk.
+	BlobSize int64 `json:"blobSize"`
+	//bytes of the files referencing the blobs.
+	ReferencedSize int64 `json:"referencedSize"`
+	SavedSize      int64 `json:"savedSize"`
+}
+
+// BlobHash computes the sha256 and md5 of a content in one pass.
+type BlobHash struct {
+	sha256 hash.Hash
+	md5    hash.Hash
+}
+
+func NewBlobHash() *BlobHash {
+	return &BlobHash{sha256: sha256.New(), md5: md5.New()}
+}
+
+func (this *BlobHash) Write(p []byte) (int, error) {
+	this.sha256.Write(p)
+	return this.md5.Write(p)
+}
+
+func (this *BlobHash) Sha256() string {
+	return hex.EncodeToString(this.sha256.Sum(nil))
+}
+
+func (this *BlobHash) Md5() string {
+	return hex.EncodeToString(this.md5.Sum(nil))
+}
+
+// get the blob store absolute path.
+func GetBlobRootDir() (rootDirPath string) {
+
+	rootDirPath = fmt.Sprintf("%s/%s", core.CONFIG.MatterPath(), MATTER_BLOB)
+
+	return rootDirPath
+}
+
+// absolute path of the blob. blobs are spread into sub directories by the first 4 hex chars.
+func GetBlobPath(sha256 string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", GetBlobRootDir(), sha256[0:2], sha256[2:4], sha256)
+}
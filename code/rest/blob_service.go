@@ -0,0 +1,366 @@
+package rest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+// content-addressed store of the files, see Blob.
+// @Service
+type BlobService struct {
+	BaseBean
+	blobDao        *BlobDao
+	matterDao      *MatterDao
+	storageService *StorageService
+	//blobs are linked and collected one at a time, so that a blob is never collected while it gets a new file.
+	mutex sync.Mutex
+}
+
+func (this *BlobService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.blobDao)
+	if b, ok := b.(*BlobDao); ok {
+		this.blobDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.storageService)
+	if b, ok := b.(*StorageService); ok {
+		this.storageService = b
+	}
+}
+
+// the sha256 and md5 of the file of the matter.
+func (this *BlobService) hashFile(matter *Matter) (string, string, error) {
+	file, err := this.storageService.Open(matter.SpaceName, matter.StorageKey())
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	blobHash := NewBlobHash()
+	if _, err := io.Copy(blobHash, file); err != nil {
+		return "", "", err
+	}
+	return blobHash.Sha256(), blobHash.Md5(), nil
+}
+
+// put the file of the matter into the blob store. if the content is stored already, the file becomes a link to the blob
+// and its own bytes are freed. the hashes are computed if empty. the file is kept as it is if it cannot be linked,
+// eg. the file system has no hard link or the space is not in the default storage, and only its md5 is recorded.
+func (this *BlobService) Store(matter *Matter, sha256 string, md5 string) *Matter {
+	this.store(matter, sha256, md5)
+	return matter
+}
+
+// whether the bytes of the file are freed for a blob stored before, and whether the file is in the blob store now.
+func (this *BlobService) store(matter *Matter, sha256 string, md5 string) (bool, bool) {
+
+	if sha256 == "" || md5 == "" {
+		var err error
+		sha256, md5, err = this.hashFile(matter)
+		if err != nil {
+			this.logger.Error("cannot hash %s %s", matter.StorageKey(), err.Error())
+			return false, false
+		}
+	}
+
+	if !this.storageService.IsDefault(matter.SpaceName) {
+		matter.Md5 = md5
+		matter.Sha256 = ""
+		this.matterDao.UpdateHash(matter)
+		return false, false
+	}
+	fileAbsolutePath := matter.AbsolutePath()
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	matter.Md5 = md5
+	matter.Sha256 = ""
+
+	blobPath := GetBlobPath(sha256)
+	shared := util.PathExists(blobPath)
+	if shared {
+		//link aside and rename over, so that the file is never missing.
+		tempPath := fmt.Sprintf("%s.%d.blob", fileAbsolutePath, time.Now().UnixNano())
+		err := os.Link(blobPath, tempPath)
+		if err == nil {
+			err = os.Rename(tempPath, fileAbsolutePath)
+		}
+		if err != nil {
+			_ = os.Remove(tempPath)
+			this.logger.Error("cannot link %s to blob %s %s", fileAbsolutePath, sha256, err.Error())
+			this.matterDao.UpdateHash(matter)
+			return false, false
+		}
+	} else {
+		util.MakeDirAll(util.GetDirOfPath(blobPath))
+		err := os.Link(fileAbsolutePath, blobPath)
+		if err != nil {
+			this.logger.Error("cannot store %s as blob %s %s", fileAbsolutePath, sha256, err.Error())
+			this.matterDao.UpdateHash(matter)
+			return false, false
+		}
+	}
+
+	if this.blobDao.FindBySha256(sha256) == nil {
+		this.blobDao.Create(&Blob{Sha256: sha256, Md5: md5, Size: matter.Size})
+	}
+	this.blobDao.Ref(sha256)
+
+	matter.Sha256 = sha256
+	this.matterDao.UpdateHash(matter)
+
+	return shared, true
+}
+
+// link the blob to the key of the space for a new file, if the blob has the md5 and the size. the reference is counted
+// at once, the new matter should take the sha256 and md5 of the blob. nil if there is no such blob, or the space is not
+// in the default storage.
+func (this *BlobService) Link(spaceName string, key string, sha256 string, md5 string, size int64) *Blob {
+	if sha256 == "" || !this.storageService.IsDefault(spaceName) {
+		return nil
+	}
+	filePath := this.storageService.DefaultPath(key)
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	blob := this.blobDao.FindBySha256(sha256)
+	if blob == nil || blob.Md5 != md5 || blob.Size != size {
+		return nil
+	}
+	util.MakeDirAll(util.GetDirOfPath(filePath))
+	err := os.Link(GetBlobPath(sha256), filePath)
+	if err != nil {
+		this.logger.Error("cannot link blob %s to %s %s", sha256, filePath, err.Error())
+		return nil
+	}
+	this.blobDao.Ref(sha256)
+	return blob
+}
+
+// keep the content of the matter for a snapshot, the reference is counted. the file of the matter is put into the blob
+// store if it can be, otherwise its bytes are copied into a blob.
+func (this *BlobService) Keep(matter *Matter) *Blob {
+	if matter.Sha256 == "" && this.storageService.IsDefault(matter.SpaceName) {
+		this.store(matter, "", "")
+	}
+	if matter.Sha256 != "" {
+		if blob := this.refStored(matter.Sha256); blob != nil {
+			return blob
+		}
+	}
+
+	file, err := this.storageService.Open(matter.SpaceName, matter.StorageKey())
+	this.PanicError(err)
+	defer func() {
+		_ = file.Close()
+	}()
+
+	//staged under a unique name, so that snapshots taken at the same time never meet.
+	tempFile, err := os.CreateTemp(util.MakeDirAll(GetBlobRootDir()+"/tmp"), "keep-*")
+	this.PanicError(err)
+	blobHash := NewBlobHash()
+	size, err := io.Copy(io.MultiWriter(tempFile, blobHash), file)
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempFile.Name())
+		this.PanicError(err)
+	}
+
+	blob, err := this.Adopt(tempFile.Name(), blobHash.Sha256(), blobHash.Md5(), size)
+	this.PanicError(err)
+	return blob
+}
+
+// reference the blob once more if it is stored. nil if not.
+func (this *BlobService) refStored(sha256 string) *Blob {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	blob := this.blobDao.FindBySha256(sha256)
+	if blob == nil || !util.PathExists(GetBlobPath(sha256)) {
+		return nil
+	}
+	this.blobDao.Ref(sha256)
+	return blob
+}
+
+// move the file at the path into the blob store as the blob of its content, or remove it if the content is stored
+// already. the blob is referenced once more.
+func (this *BlobService) Adopt(path string, sha256 string, md5 string, size int64) (*Blob, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	blobPath := GetBlobPath(sha256)
+	if util.PathExists(blobPath) {
+		_ = os.Remove(path)
+	} else {
+		util.MakeDirAll(util.GetDirOfPath(blobPath))
+		if err := os.Rename(path, blobPath); err != nil {
+			_ = os.Remove(path)
+			return nil, err
+		}
+	}
+
+	blob := this.blobDao.FindBySha256(sha256)
+	if blob == nil {
+		blob = this.blobDao.Create(&Blob{Sha256: sha256, Md5: md5, Size: size})
+	}
+	this.blobDao.Ref(sha256)
+	return blob, nil
+}
+
+// the file of the matter is about to be replaced, so it no longer references its blob.
+func (this *BlobService) Unref(matter *Matter) {
+	this.Release(matter.Sha256)
+	matter.Sha256 = ""
+}
+
+// the version no longer references its blob.
+func (this *BlobService) UnrefVersion(version *MatterVersion) {
+	this.Release(version.Sha256)
+	version.Sha256 = ""
+}
+
+// one reference less to the blob. the blob is removed once nothing references it, the other blobs are left to Gc.
+func (this *BlobService) Release(sha256 string) {
+	if sha256 == "" {
+		return
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.blobDao.Unref(sha256)
+	blob := this.blobDao.FindBySha256(sha256)
+	if blob != nil && blob.RefCount <= 0 {
+		this.remove(blob)
+	}
+}
+
+// remove the file and the record of the blob. the caller holds the mutex. a blob still referenced by a matter, a
+// version or a snapshot entry is kept and its count is corrected, eg. a snapshot counted its blobs before it had entries.
+func (this *BlobService) remove(blob *Blob) bool {
+	if refCount := this.blobDao.CountReferences(blob.Sha256); refCount > 0 {
+		this.logger.Info("blob %s is still referenced %d times, keep it", blob.Sha256, refCount)
+		this.blobDao.UpdateRefCount(blob.Sha256, refCount)
+		return false
+	}
+	err := os.Remove(GetBlobPath(blob.Sha256))
+	if err != nil && !os.IsNotExist(err) {
+		this.logger.Error("cannot remove blob %s %s", blob.Sha256, err.Error())
+		return false
+	}
+	this.blobDao.Delete(blob)
+	return true
+}
+
+// remove the blobs no matter, version or snapshot references.
+func (this *BlobService) Gc() int64 {
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return this.gc()
+}
+
+// the caller holds the mutex.
+func (this *BlobService) gc() int64 {
+	var count int64 = 0
+	for _, blob := range this.blobDao.FindUnreferenced() {
+		if this.remove(blob) {
+			count++
+		}
+	}
+	if count > 0 {
+		this.logger.Info("collect %d blobs", count)
+	}
+	return count
+}
+
+// count the references again from the matters, their versions and the snapshots.
+func (this *BlobService) Recount() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.blobDao.Recount()
+}
+
+// count the references again and collect what is left, eg. after the matters of a user are deleted at once.
+func (this *BlobService) Collect() int64 {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.blobDao.Recount()
+	return this.gc()
+}
+
+// put the files uploaded before the blob store into it. files of the same content then share their bytes.
+func (this *BlobService) Migrate() *BlobMigration {
+
+	migration := &BlobMigration{}
+
+	//files of the spaces in other storages are left out.
+	defaults := make(map[string]bool)
+
+	afterUuid := ""
+	for {
+		matters := this.matterDao.FindFilesWithoutBlob(afterUuid, 1000)
+		if len(matters) == 0 {
+			break
+		}
+		for _, matter := range matters {
+			afterUuid = matter.Uuid
+			isDefault, ok := defaults[matter.SpaceName]
+			if !ok {
+				isDefault = this.storageService.IsDefault(matter.SpaceName)
+				defaults[matter.SpaceName] = isDefault
+			}
+			if !isDefault {
+				continue
+			}
+			migration.Scanned++
+
+			shared, stored := this.store(matter, "", "")
+			if !stored {
+				migration.Failed++
+				continue
+			}
+			migration.Stored++
+			if shared {
+				migration.Shared++
+				migration.SavedSize += matter.Size
+			}
+		}
+	}
+
+	migration.Collected = this.Collect()
+
+	this.logger.Info("migrate %d files into blob store. %d stored, %d shared, %d failed, %s saved",
+		migration.Scanned, migration.Stored, migration.Shared, migration.Failed, util.HumanFileSize(migration.SavedSize))
+
+	return migration
+}
+
+func (this *BlobService) Stat() *BlobStat {
+	return this.blobDao.Stat()
+}
@@ -0,0 +1,155 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+type CertificateController struct {
+	BaseController
+	certificateDao     *CertificateDao
+	certificateService *CertificateService
+}
+
+func (this *CertificateController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.certificateDao)
+	if b, ok := b.(*CertificateDao); ok {
+		this.certificateDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.certificateService)
+	if b, ok := b.(*CertificateService); ok {
+		this.certificateService = b
+	}
+}
+
+func (this *CertificateController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/certificate/template/create"] = this.Wrap(this.CreateTemplate, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/certificate/template/edit"] = this.Wrap(this.EditTemplate, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/certificate/template/delete"] = this.Wrap(this.DeleteTemplate, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/certificate/template/list"] = this.Wrap(this.ListTemplates, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/certificate/template/preview"] = this.Wrap(this.PreviewTemplate, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/certificate/generate"] = this.Wrap(this.Generate, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/certificate/list"] = this.Wrap(this.List, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/certificate/download/zip"] = this.Wrap(this.DownloadZip, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/certificate/my"] = this.Wrap(this.My, USER_ROLE_USER)
+
+	return routeMap
+}
+
+func (this *CertificateController) formInt64(request *http.Request, key string) int64 {
+	str := request.FormValue(key)
+	if str == "" {
+		return 0
+	}
+	value, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		panic(result.BadRequest("%s格式错误", key))
+	}
+	return value
+}
+
+func (this *CertificateController) checkTemplate(request *http.Request) *CertificateTemplate {
+	template := this.certificateDao.FindTemplate(this.formInt64(request, "id"))
+	if template == nil {
+		panic(result.BadRequest("证书模板不存在"))
+	}
+	return template
+}
+
+// fill the template with the form values.
+func (this *CertificateController) readTemplate(request *http.Request, template *CertificateTemplate) {
+	template.Name = request.FormValue("name")
+	template.Type = request.FormValue("type")
+	template.BackgroundUuid = request.FormValue("backgroundUuid")
+	template.FontUuid = request.FormValue("fontUuid")
+	template.Fields = request.FormValue("fields")
+	template.Dpi = 150
+	if str := request.FormValue("dpi"); str != "" {
+		dpi, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			panic(result.BadRequest("dpi格式错误"))
+		}
+		template.Dpi = dpi
+	}
+}
+
+func (this *CertificateController) CreateTemplate(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	template := &CertificateTemplate{}
+	this.readTemplate(request, template)
+	if webResult := this.certificateService.ValidTemplate(template); webResult != nil {
+		return webResult
+	}
+	return this.Success(this.certificateDao.CreateTemplate(template))
+}
+
+func (this *CertificateController) EditTemplate(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	template := this.checkTemplate(request)
+	this.readTemplate(request, template)
+	if webResult := this.certificateService.ValidTemplate(template); webResult != nil {
+		return webResult
+	}
+	return this.Success(this.certificateDao.SaveTemplate(template))
+}
+
+// certificates generated from the template keep their files.
+func (this *CertificateController) DeleteTemplate(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	this.certificateDao.DeleteTemplate(this.checkTemplate(request))
+	return this.Success("OK")
+}
+
+func (this *CertificateController) ListTemplates(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	return this.Success(this.certificateDao.FindAllTemplates())
+}
+
+// the template filled with example values, as png.
+func (this *CertificateController) PreviewTemplate(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	data := this.certificateService.Preview(this.checkTemplate(request))
+	writer.Header().Set("Content-Type", "image/png")
+	_, err := writer.Write(data)
+	this.PanicError(err)
+	return nil
+}
+
+// generate the certificates of the track. award certificates follow the leaderboard of the round.
+func (this *CertificateController) Generate(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	template := this.certificateDao.FindTemplate(this.formInt64(request, "templateId"))
+	if template == nil {
+		return result.BadRequest("证书模板不存在")
+	}
+	report, webResult := this.certificateService.Generate(request, template, this.formInt64(request, "trackId"), this.formInt64(request, "roundId"))
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(report)
+}
+
+func (this *CertificateController) List(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	return this.Success(this.certificateDao.FindByTrackId(this.formInt64(request, "trackId"), request.FormValue("type")))
+}
+
+// certificates of the track in one zip. format is pdf or png.
+func (this *CertificateController) DownloadZip(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	format := request.FormValue("format")
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "png" {
+		return result.BadRequest("format只能为pdf或png")
+	}
+	certificates := this.certificateDao.FindByTrackId(this.formInt64(request, "trackId"), request.FormValue("type"))
+	this.certificateService.DownloadZip(writer, request, certificates, format, "certificates.zip")
+	return nil
+}
+
+func (this *CertificateController) My(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	return this.Success(this.certificateDao.FindByUserUuid(user.Uuid))
+}
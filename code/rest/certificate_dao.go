@@ -0,0 +1,103 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+type CertificateDao struct {
+	BaseDao
+}
+
+func (this *CertificateDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *CertificateDao) CreateTemplate(template *CertificateTemplate) *CertificateTemplate {
+	template.CreateTime = time.Now()
+	template.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(template)
+	this.PanicError(db.Error)
+	return template
+}
+
+func (this *CertificateDao) SaveTemplate(template *CertificateTemplate) *CertificateTemplate {
+	template.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Save(template)
+	this.PanicError(db.Error)
+	return template
+}
+
+func (this *CertificateDao) FindTemplate(id int64) *CertificateTemplate {
+	var entity = &CertificateTemplate{}
+	db := core.CONTEXT.GetDB().Where("id = ?", id).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+func (this *CertificateDao) FindAllTemplates() []*CertificateTemplate {
+	var entities []*CertificateTemplate
+	db := core.CONTEXT.GetDB().Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *CertificateDao) DeleteTemplate(template *CertificateTemplate) {
+	db := core.CONTEXT.GetDB().Delete(template)
+	this.PanicError(db.Error)
+}
+
+func (this *CertificateDao) Create(certificate *Certificate) *Certificate {
+	certificate.CreateTime = time.Now()
+	certificate.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(certificate)
+	this.PanicError(db.Error)
+	return certificate
+}
+
+func (this *CertificateDao) Save(certificate *Certificate) *Certificate {
+	certificate.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Save(certificate)
+	this.PanicError(db.Error)
+	return certificate
+}
+
+func (this *CertificateDao) FindByCode(code string) *Certificate {
+	var entity = &Certificate{}
+	db := core.CONTEXT.GetDB().Where("code = ?", code).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+func (this *CertificateDao) FindBySubmissionAndUserAndType(submissionId int64, userUuid string, certificateType string) *Certificate {
+	var entity = &Certificate{}
+	db := core.CONTEXT.GetDB().Where("submission_id = ? AND user_uuid = ? AND type = ?", submissionId, userUuid, certificateType).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+// certificates of the track, of any type if certificateType is empty.
+func (this *CertificateDao) FindByTrackId(trackId int64, certificateType string) []*Certificate {
+	var entities []*Certificate
+	db := core.CONTEXT.GetDB().Where("track_id = ?", trackId)
+	if certificateType != "" {
+		db = db.Where("type = ?", certificateType)
+	}
+	db = db.Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *CertificateDao) FindByUserUuid(userUuid string) []*Certificate {
+	var entities []*Certificate
+	db := core.CONTEXT.GetDB().Where("user_uuid = ?", userUuid).Order("id DESC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"strconv"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	//for the submissions awarded in the leaderboard.
+	CERTIFICATE_TYPE_AWARD = "AWARD"
+	//for every submission of the track.
+	CERTIFICATE_TYPE_PARTICIPATION = "PARTICIPATION"
+)
+
+const (
+	CERTIFICATE_ALIGN_LEFT   = "LEFT"
+	CERTIFICATE_ALIGN_CENTER = "CENTER"
+	CERTIFICATE_ALIGN_RIGHT  = "RIGHT"
+)
+
+// certificates are put in this folder of the author's space.
+const CERTIFICATE_DIR = "/证书"
+
+// CertificateTemplate is a background image with text fields drawn on it.
+// the text of a field can have placeholders: {name} {team} {college} {track} {award} {title} {code} {year} {date}.
+type CertificateTemplate struct {
+	Id   int64  `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	Name string `json:"name" gorm:"type:varchar(100) not null"`
+	Type string `json:"type" gorm:"type:varchar(20) not null"`
+	//uuid of the background image uploaded by the administrator.
+	BackgroundUuid string `json:"backgroundUuid" gorm:"type:char(36) not null"`
+	//uuid of a ttf or otf font uploaded by the administrator. chinese text needs one, the built-in font has latin letters only.
+	FontUuid string `json:"fontUuid" gorm:"type:char(36)"`
+	//json of the text fields.
+	Fields string `json:"fields" gorm:"type:text"`
+	//dots per inch of the background, which decides the page size of the pdf.
+	Dpi        float64   `json:"dpi" gorm:"type:double not null;default:150"`
+	CreateTime time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
+
+// text fields of the template.
+func (this *CertificateTemplate) FieldList() []*CertificateField {
+	var fields []*CertificateField
+	if this.Fields != "" {
+		_ = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(this.Fields), &fields)
+	}
+	return fields
+}
+
+// CertificateField is a line of text. X and Y are in pixels of the background, Y is the baseline.
+type CertificateField struct {
+	Text string  `json:"text"`
+	X    int     `json:"x"`
+	Y    int     `json:"y"`
+	Size float64 `json:"size"`
+	//#RRGGBB, black if empty.
+	Color string `json:"color"`
+	//LEFT, CENTER or RIGHT of X.
+	Align string `json:"align"`
+}
+
+// Certificate is issued to an author of a submission. its code is printed on it, anyone can verify the certificate by the code.
+type Certificate struct {
+	Id           int64  `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	Code         string `json:"code" gorm:"type:varchar(20) not null;unique"`
+	Type         string `json:"type" gorm:"type:varchar(20) not null"`
+	TemplateId   int64  `json:"templateId" gorm:"type:bigint(20) not null"`
+	SubmissionId int64  `json:"submissionId" gorm:"type:bigint(20) not null;index:idx_certificate_si"`
+	TrackId      int64  `json:"trackId" gorm:"type:bigint(20) not null;index:idx_certificate_ti"`
+	UserUuid     string `json:"userUuid" gorm:"type:char(36) not null;index:idx_certificate_uu"`
+	//what is printed on the certificate.
+	Name    string `json:"name" gorm:"type:varchar(100)"`
+	Team    string `json:"team" gorm:"type:varchar(100)"`
+	College string `json:"college" gorm:"type:varchar(100)"`
+	Track   string `json:"track" gorm:"type:varchar(100)"`
+	Award   string `json:"award" gorm:"type:varchar(100)"`
+	Title   string `json:"title" gorm:"type:varchar(200)"`
+	Year    int    `json:"year" gorm:"type:int not null;default:0"`
+	//the generated files in the author's space.
+	PdfUuid    string    `json:"pdfUuid" gorm:"type:char(36)"`
+	PngUuid    string    `json:"pngUuid" gorm:"type:char(36)"`
+	CreateTime time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
+
+// values of the placeholders.
+func (this *Certificate) Placeholders() map[string]string {
+	return map[string]string{
+		"name":    this.Name,
+		"team":    this.Team,
+		"college": this.College,
+		"track":   this.Track,
+		"award":   this.Award,
+		"title":   this.Title,
+		"code":    this.Code,
+		"year":    strconv.Itoa(this.Year),
+		"date":    this.UpdateTime.Format("2006年01月02日"),
+	}
+}
+
+// CertificateReport is the result of generating the certificates of a track.
+type CertificateReport struct {
+	Generated []*Certificate `json:"generated"`
+	//authors skipped, eg. without a space.
+	Skipped []string `json:"skipped"`
+}
+
+// CertificateVerification is what anyone with the code may learn of a certificate, nothing else of the holder is shown.
+type CertificateVerification struct {
+	Name  string `json:"name"`
+	Award string `json:"award"`
+	Track string `json:"track"`
+	Year  int    `json:"year"`
+}
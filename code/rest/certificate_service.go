@@ -0,0 +1,459 @@
+package rest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/pdf"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/util"
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// @Service
+type CertificateService struct {
+	BaseBean
+	certificateDao *CertificateDao
+	submissionDao  *SubmissionDao
+	matterDao      *MatterDao
+	matterService  *MatterService
+	rankingService *RankingService
+	teamService    *TeamService
+	teamDao        *TeamDao
+	trackDao       *TrackDao
+	competitionDao *CompetitionDao
+	collegeDao     *CollegeDao
+	userDao        *UserDao
+	spaceDao       *SpaceDao
+	userService    *UserService
+	blobService    *BlobService
+	storageService *StorageService
+}
+
+func (this *CertificateService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.certificateDao)
+	if b, ok := b.(*CertificateDao); ok {
+		this.certificateDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterService)
+	if b, ok := b.(*MatterService); ok {
+		this.matterService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.blobService)
+	if b, ok := b.(*BlobService); ok {
+		this.blobService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.storageService)
+	if b, ok := b.(*StorageService); ok {
+		this.storageService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.rankingService)
+	if b, ok := b.(*RankingService); ok {
+		this.rankingService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.teamService)
+	if b, ok := b.(*TeamService); ok {
+		this.teamService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.teamDao)
+	if b, ok := b.(*TeamDao); ok {
+		this.teamDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.trackDao)
+	if b, ok := b.(*TrackDao); ok {
+		this.trackDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.competitionDao)
+	if b, ok := b.(*CompetitionDao); ok {
+		this.competitionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.collegeDao)
+	if b, ok := b.(*CollegeDao); ok {
+		this.collegeDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userDao)
+	if b, ok := b.(*UserDao); ok {
+		this.userDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceDao)
+	if b, ok := b.(*SpaceDao); ok {
+		this.spaceDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userService)
+	if b, ok := b.(*UserService); ok {
+		this.userService = b
+	}
+}
+
+// check the template before saving it.
+func (this *CertificateService) ValidTemplate(template *CertificateTemplate) *result.WebResult {
+	if strings.TrimSpace(template.Name) == "" {
+		return result.BadRequest("模板名称不能为空")
+	}
+	if template.Type != CERTIFICATE_TYPE_AWARD && template.Type != CERTIFICATE_TYPE_PARTICIPATION {
+		return result.BadRequest("证书类型错误")
+	}
+	if template.Dpi <= 0 {
+		return result.BadRequest("dpi必须大于0")
+	}
+
+	background := this.matterDao.FindByUuid(template.BackgroundUuid)
+	if background == nil || background.Dir {
+		return result.BadRequest("背景图片不存在")
+	}
+	if _, err := this.openImage(background); err != nil {
+		return result.BadRequest("背景图片无法识别：%s", err.Error())
+	}
+	if template.FontUuid != "" {
+		fontMatter := this.matterDao.FindByUuid(template.FontUuid)
+		if fontMatter == nil || fontMatter.Dir {
+			return result.BadRequest("字体文件不存在")
+		}
+		data, err := this.readFile(fontMatter)
+		this.PanicError(err)
+		if _, err = opentype.Parse(data); err != nil {
+			return result.BadRequest("字体文件无法识别：%s", err.Error())
+		}
+	}
+
+	if template.Fields == "" {
+		return result.BadRequest("文字内容不能为空")
+	}
+	var fields []*CertificateField
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(template.Fields), &fields); err != nil {
+		return result.BadRequest("文字内容格式错误")
+	}
+	for _, field := range fields {
+		if field.Size <= 0 {
+			return result.BadRequest("文字大小必须大于0")
+		}
+		if _, ok := this.parseColor(field.Color); !ok {
+			return result.BadRequest("颜色 %s 格式错误，应为 #RRGGBB", field.Color)
+		}
+		if field.Align != "" && field.Align != CERTIFICATE_ALIGN_LEFT && field.Align != CERTIFICATE_ALIGN_CENTER && field.Align != CERTIFICATE_ALIGN_RIGHT {
+			return result.BadRequest("对齐方式 %s 错误", field.Align)
+		}
+	}
+	return nil
+}
+
+// the file of the matter from its storage.
+func (this *CertificateService) readFile(matter *Matter) ([]byte, error) {
+	file, err := this.storageService.Open(matter.SpaceName, matter.StorageKey())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return io.ReadAll(file)
+}
+
+func (this *CertificateService) openImage(matter *Matter) (image.Image, error) {
+	file, err := this.storageService.Open(matter.SpaceName, matter.StorageKey())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return imaging.Decode(file)
+}
+
+// #RRGGBB, black if empty.
+func (this *CertificateService) parseColor(str string) (color.Color, bool) {
+	if str == "" {
+		return color.Black, true
+	}
+	if len(str) != 7 || str[0] != '#' {
+		return nil, false
+	}
+	value, err := strconv.ParseUint(str[1:], 16, 32)
+	if err != nil {
+		return nil, false
+	}
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 0xff}, true
+}
+
+func (this *CertificateService) loadFont(template *CertificateTemplate) *opentype.Font {
+	data := goregular.TTF
+	if template.FontUuid != "" {
+		fontMatter := this.matterDao.CheckByUuid(template.FontUuid)
+		var err error
+		data, err = this.readFile(fontMatter)
+		this.PanicError(err)
+	}
+	parsed, err := opentype.Parse(data)
+	this.PanicError(err)
+	return parsed
+}
+
+// draw the fields of the template filled with the values on its background.
+func (this *CertificateService) Render(template *CertificateTemplate, values map[string]string) image.Image {
+	background, err := this.openImage(this.matterDao.CheckByUuid(template.BackgroundUuid))
+	this.PanicError(err)
+
+	bounds := background.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), background, bounds.Min, draw.Src)
+
+	parsed := this.loadFont(template)
+	for _, field := range template.FieldList() {
+		face, err := opentype.NewFace(parsed, &opentype.FaceOptions{Size: field.Size, DPI: 72, Hinting: font.HintingFull})
+		this.PanicError(err)
+
+		textColor, ok := this.parseColor(field.Color)
+		if !ok {
+			textColor = color.Black
+		}
+		drawer := &font.Drawer{Dst: canvas, Src: image.NewUniform(textColor), Face: face}
+
+		text := util.FillPlaceholders(field.Text, values)
+		x := fixed.I(field.X)
+		switch field.Align {
+		case CERTIFICATE_ALIGN_CENTER:
+			x -= drawer.MeasureString(text) / 2
+		case CERTIFICATE_ALIGN_RIGHT:
+			x -= drawer.MeasureString(text)
+		}
+		drawer.Dot = fixed.Point26_6{X: x, Y: fixed.I(field.Y)}
+		drawer.DrawString(text)
+
+		_ = face.Close()
+	}
+	return canvas
+}
+
+// render the template with example values, to check the layout.
+func (this *CertificateService) Preview(template *CertificateTemplate) []byte {
+	certificate := &Certificate{
+		Code:       "C0123456789",
+		Name:       "张三",
+		Team:       "示例团队",
+		College:    "示例学院",
+		Track:      "示例赛道",
+		Award:      "一等奖",
+		Title:      "示例作品",
+		Year:       time.Now().Year(),
+		UpdateTime: time.Now(),
+	}
+	buffer := &bytes.Buffer{}
+	this.PanicError(png.Encode(buffer, this.Render(template, certificate.Placeholders())))
+	return buffer.Bytes()
+}
+
+// a new verification code.
+func (this *CertificateService) code() string {
+	for {
+		bytes := make([]byte, 5)
+		_, err := rand.Read(bytes)
+		this.PanicError(err)
+		code := "C" + strings.ToUpper(hex.EncodeToString(bytes))
+		if this.certificateDao.FindByCode(code) == nil {
+			return code
+		}
+	}
+}
+
+// the submissions to certify and their awards. award certificates go to the awarded submissions of the leaderboard,
+// participation certificates to every submission of the track.
+func (this *CertificateService) candidates(template *CertificateTemplate, trackId int64, roundId int64) (map[*Submission]string, []*Submission, *result.WebResult) {
+	awards := make(map[*Submission]string)
+	var submissions []*Submission
+	if template.Type == CERTIFICATE_TYPE_AWARD {
+		leaderboard, webResult := this.rankingService.Rank(trackId, roundId, this.rankingService.FetchConfig())
+		if webResult != nil {
+			return nil, nil, webResult
+		}
+		for _, entry := range leaderboard.Entries {
+			if entry.Award != "" {
+				awards[entry.Submission] = entry.Award
+				submissions = append(submissions, entry.Submission)
+			}
+		}
+	} else {
+		submissions = this.submissionDao.FindByFilter(&SubmissionFilter{TrackId: trackId})
+	}
+	return awards, submissions, nil
+}
+
+// generate the certificates of the track for every author, and put them in the authors' spaces.
+// generating again keeps the verification codes and overwrites the files.
+func (this *CertificateService) Generate(request *http.Request, template *CertificateTemplate, trackId int64, roundId int64) (*CertificateReport, *result.WebResult) {
+	track := this.trackDao.Find(trackId)
+	if track == nil {
+		return nil, result.BadRequest("赛道不存在")
+	}
+	awards, submissions, webResult := this.candidates(template, trackId, roundId)
+	if webResult != nil {
+		return nil, webResult
+	}
+	//the year of the competition, so that generating again later keeps it. the legacy competition has none.
+	year := time.Now().Year()
+	if competition := this.competitionDao.Find(track.CompetitionId); competition != nil && competition.Year != 0 {
+		year = competition.Year
+	}
+
+	report := &CertificateReport{Generated: []*Certificate{}, Skipped: []string{}}
+	for _, submission := range submissions {
+		team := ""
+		if submission.TeamId != 0 {
+			if t := this.teamDao.Find(submission.TeamId); t != nil {
+				team = t.Name
+			}
+		}
+		college := ""
+		if c := this.collegeDao.Find(submission.CollegeId); c != nil {
+			college = c.Name
+		}
+
+		for _, userProfile := range this.teamService.Profiles(submission) {
+			user := this.userDao.FindByUuid(userProfile.UserUuid)
+			if user == nil {
+				report.Skipped = append(report.Skipped, fmt.Sprintf("%s（%s）：用户不存在", userProfile.RealName, submission.Title))
+				continue
+			}
+			space := this.spaceDao.FindByUuid(user.SpaceUuid)
+			if space == nil {
+				report.Skipped = append(report.Skipped, fmt.Sprintf("%s（%s）：没有个人空间", userProfile.RealName, submission.Title))
+				continue
+			}
+
+			certificate := this.certificateDao.FindBySubmissionAndUserAndType(submission.Id, user.Uuid, template.Type)
+			if certificate == nil {
+				certificate = &Certificate{Code: this.code(), Type: template.Type, SubmissionId: submission.Id, UserUuid: user.Uuid}
+			}
+			certificate.TemplateId = template.Id
+			certificate.TrackId = trackId
+			certificate.Name = userProfile.RealName
+			certificate.Team = team
+			certificate.College = college
+			if certificate.College == "" {
+				certificate.College = userProfile.College
+			}
+			certificate.Track = track.Name
+			certificate.Award = awards[submission]
+			certificate.Title = submission.Title
+			certificate.Year = year
+			certificate.UpdateTime = time.Now()
+
+			this.issue(request, template, certificate, user, space)
+			report.Generated = append(report.Generated, certificate)
+		}
+	}
+	return report, nil
+}
+
+// render the certificate as png and pdf into the certificate folder of the author.
+func (this *CertificateService) issue(request *http.Request, template *CertificateTemplate, certificate *Certificate, user *User, space *Space) {
+	img := this.Render(template, certificate.Placeholders())
+
+	pngBuffer := &bytes.Buffer{}
+	this.PanicError(png.Encode(pngBuffer, img))
+	pdfBuffer := &bytes.Buffer{}
+	this.PanicError(pdf.WriteImage(pdfBuffer, img, template.Dpi))
+
+	this.userService.MatterLock(user.Uuid)
+	defer this.userService.MatterUnlock(user.Uuid)
+
+	dirMatter := this.matterService.CreateDirectories(request, user, space, CERTIFICATE_DIR)
+	name := this.fileName(certificate)
+	certificate.PngUuid = this.writeFile(dirMatter, name+".png", pngBuffer.Bytes(), user, space).Uuid
+	certificate.PdfUuid = this.writeFile(dirMatter, name+".pdf", pdfBuffer.Bytes(), user, space).Uuid
+
+	if certificate.Id == 0 {
+		this.certificateDao.Create(certificate)
+	} else {
+		this.certificateDao.Save(certificate)
+	}
+}
+
+// file name without extension. the code keeps names of the same author apart.
+func (this *CertificateService) fileName(certificate *Certificate) string {
+	if certificate.Type == CERTIFICATE_TYPE_AWARD {
+		return "获奖证书-" + certificate.Code
+	}
+	return "参赛证明-" + certificate.Code
+}
+
+// certificates are issued by the competition, they do not count against the space's size limits.
+func (this *CertificateService) writeFile(dirMatter *Matter, filename string, data []byte, user *User, space *Space) *Matter {
+	//the old file may share its bytes with a blob, so it is replaced rather than written over.
+	this.storageService.Write(space.Name, dirMatter.StorageKey()+"/"+filename, bytes.NewReader(data))
+
+	matter := this.matterDao.FindBySpaceUuidAndPuuidAndDirAndName(space.Uuid, dirMatter.Uuid, false, filename)
+	if matter != nil {
+		matter.Deleted = false
+		this.blobService.Unref(matter)
+		matter = this.matterService.updateNonDirMatter(matter, int64(len(data)), user, space)
+	} else {
+		matter = this.matterService.createNonDirMatter(dirMatter, filename, int64(len(data)), false, user, space)
+	}
+	return this.blobService.Store(matter, "", "")
+}
+
+// download the pdf or png files of the certificates in one zip, named after the authors.
+func (this *CertificateService) DownloadZip(writer http.ResponseWriter, request *http.Request, certificates []*Certificate, format string, zipName string) {
+	names := make(map[string]string)
+	var matters []*Matter
+	for _, certificate := range certificates {
+		uuid := certificate.PdfUuid
+		if format == "png" {
+			uuid = certificate.PngUuid
+		}
+		matter := this.matterDao.FindByUuid(uuid)
+		if matter == nil || matter.Deleted {
+			continue
+		}
+		names[matter.Uuid] = fmt.Sprintf("%s-%s-%s.%s", certificate.Name, certificate.Title, certificate.Code, format)
+		matters = append(matters, matter)
+	}
+	if len(matters) == 0 {
+		panic(result.BadRequest("没有可下载的证书"))
+	}
+
+	this.matterService.DownloadZipOf(writer, request, matters, zipName, func(matter *Matter) string {
+		return names[matter.Uuid]
+	})
+}
@@ -0,0 +1,146 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+type CompetitionController struct {
+	BaseController
+	competitionDao     *CompetitionDao
+	competitionService *CompetitionService
+	auditService       *AuditService
+}
+
+func (this *CompetitionController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.competitionDao)
+	if b, ok := b.(*CompetitionDao); ok {
+		this.competitionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.competitionService)
+	if b, ok := b.(*CompetitionService); ok {
+		this.competitionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.auditService)
+	if b, ok := b.(*AuditService); ok {
+		this.auditService = b
+	}
+}
+
+func (this *CompetitionController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/competition/list"] = this.Wrap(this.List, USER_ROLE_USER)
+	routeMap["/api/competition/detail"] = this.Wrap(this.Detail, USER_ROLE_USER)
+	routeMap["/api/competition/create"] = this.Wrap(this.Create, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/competition/edit"] = this.Wrap(this.Edit, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/competition/delete"] = this.Wrap(this.Delete, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/competition/archive"] = this.Wrap(this.Archive, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/competition/unarchive"] = this.Wrap(this.Unarchive, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/competition/adopt"] = this.Wrap(this.Adopt, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+func (this *CompetitionController) checkCompetition(request *http.Request) *Competition {
+	id, err := strconv.ParseInt(request.FormValue("id"), 10, 64)
+	if err != nil {
+		panic(result.BadRequest("id格式错误"))
+	}
+	competition := this.competitionDao.Find(id)
+	if competition == nil {
+		panic(result.BadRequest("赛事不存在"))
+	}
+	return competition
+}
+
+// read the editable fields of a competition from the request.
+func (this *CompetitionController) fillCompetition(request *http.Request, competition *Competition) *result.WebResult {
+	year, err := strconv.Atoi(request.FormValue("year"))
+	if err != nil {
+		return result.BadRequest("year格式错误")
+	}
+	competition.Name = request.FormValue("name")
+	competition.Year = year
+	competition.Description = request.FormValue("description")
+	competition.EnabledColleges = request.FormValue("enabledColleges")
+	return nil
+}
+
+// competitions of the status, all if status is empty. the latest first.
+func (this *CompetitionController) List(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	return this.Success(this.competitionDao.FindByStatus(request.FormValue("status")))
+}
+
+func (this *CompetitionController) Detail(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	return this.Success(this.checkCompetition(request))
+}
+
+func (this *CompetitionController) Create(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	competition := &Competition{}
+	if webResult := this.fillCompetition(request, competition); webResult != nil {
+		return webResult
+	}
+
+	competition, webResult := this.competitionService.Create(competition)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(competition)
+}
+
+func (this *CompetitionController) Edit(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	competition := this.checkCompetition(request)
+	if webResult := this.fillCompetition(request, competition); webResult != nil {
+		return webResult
+	}
+
+	competition, webResult := this.competitionService.Edit(competition)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(competition)
+}
+
+func (this *CompetitionController) Delete(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	if webResult := this.competitionService.Delete(this.checkCompetition(request)); webResult != nil {
+		return webResult
+	}
+	return this.Success("删除成功")
+}
+
+func (this *CompetitionController) Archive(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	competition := this.checkCompetition(request)
+	if webResult := this.competitionService.Archive(competition); webResult != nil {
+		return webResult
+	}
+	this.auditService.Log(request, this.findUser(request), AUDIT_ACTION_COMPETITION_ARCHIVE, AUDIT_TARGET_COMPETITION, competition.Id,
+		map[string]any{"status": COMPETITION_STATUS_ACTIVE}, map[string]any{"status": competition.Status})
+	return this.Success(competition)
+}
+
+func (this *CompetitionController) Unarchive(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	competition := this.checkCompetition(request)
+	if webResult := this.competitionService.Unarchive(competition); webResult != nil {
+		return webResult
+	}
+	this.auditService.Log(request, this.findUser(request), AUDIT_ACTION_COMPETITION_ARCHIVE, AUDIT_TARGET_COMPETITION, competition.Id,
+		map[string]any{"status": COMPETITION_STATUS_ARCHIVED}, map[string]any{"status": competition.Status})
+	return this.Success(competition)
+}
+
+// move the tracks, rounds and submissions created before competitions existed into the competition.
+func (this *CompetitionController) Adopt(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	adoption, webResult := this.competitionService.Adopt(this.checkCompetition(request))
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(adoption)
+}
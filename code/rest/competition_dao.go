@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+type CompetitionDao struct {
+	BaseDao
+}
+
+func (this *CompetitionDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *CompetitionDao) Create(competition *Competition) *Competition {
+	if competition == nil {
+		panic(result.BadRequest("competition cannot be nil"))
+	}
+
+	competition.CreateTime = time.Now()
+	competition.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(competition)
+	this.PanicError(db.Error)
+	return competition
+}
+
+func (this *CompetitionDao) Save(competition *Competition) *Competition {
+	if competition == nil {
+		panic(result.BadRequest("competition cannot be nil"))
+	}
+
+	competition.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Save(competition)
+	this.PanicError(db.Error)
+	return competition
+}
+
+func (this *CompetitionDao) Find(id int64) *Competition {
+	var entity = &Competition{}
+	db := core.CONTEXT.GetDB().Where("id = ?", id).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+func (this *CompetitionDao) FindByNameAndYear(name string, year int) *Competition {
+	var entity = &Competition{}
+	db := core.CONTEXT.GetDB().Where("name = ? AND year = ?", name, year).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+// competitions of the status, of any status if empty. the latest first.
+func (this *CompetitionDao) FindByStatus(status string) []*Competition {
+	var entities []*Competition
+	db := core.CONTEXT.GetDB()
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	db = db.Order("year DESC, id DESC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *CompetitionDao) CountTracks(competitionId int64) int64 {
+	var count int64
+	db := core.CONTEXT.GetDB().Model(&Track{}).Where("competition_id = ?", competitionId).Count(&count)
+	this.PanicError(db.Error)
+	return count
+}
+
+func (this *CompetitionDao) CountRounds(competitionId int64) int64 {
+	var count int64
+	db := core.CONTEXT.GetDB().Model(&Round{}).Where("competition_id = ?", competitionId).Count(&count)
+	this.PanicError(db.Error)
+	return count
+}
+
+// move the tracks, rounds and submissions without competition into the competition.
+func (this *CompetitionDao) Adopt(competitionId int64) *CompetitionAdoption {
+	adoption := &CompetitionAdoption{}
+
+	db := core.CONTEXT.GetDB().Model(&Track{}).Where("competition_id = ?", 0).Update("competition_id", competitionId)
+	this.PanicError(db.Error)
+	adoption.Tracks = db.RowsAffected
+
+	db = core.CONTEXT.GetDB().Model(&Round{}).Where("competition_id = ?", 0).Update("competition_id", competitionId)
+	this.PanicError(db.Error)
+	adoption.Rounds = db.RowsAffected
+
+	db = core.CONTEXT.GetDB().Model(&Submission{}).Where("competition_id = ?", 0).Update("competition_id", competitionId)
+	this.PanicError(db.Error)
+	adoption.Submissions = db.RowsAffected
+
+	return adoption
+}
+
+func (this *CompetitionDao) Delete(competition *Competition) {
+	if competition == nil {
+		panic(result.BadRequest("competition cannot be nil"))
+	}
+
+	db := core.CONTEXT.GetDB().Delete(competition)
+	this.PanicError(db.Error)
+}
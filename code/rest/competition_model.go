@@ -0,0 +1,63 @@
+package rest
+
+import (
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	COMPETITION_STATUS_ACTIVE = "ACTIVE"
+	//an archived competition is read only: no submission, rating or change of its tracks and rounds.
+	COMPETITION_STATUS_ARCHIVED = "ARCHIVED"
+)
+
+// Competition is an edition of a contest, eg. the innovation contest of 2025. It owns its tracks and rounds,
+// and through the tracks their rubrics, submission windows and submissions.
+// Tracks, rounds and submissions with CompetitionId 0 were created before competitions existed, see Adopt.
+type Competition struct {
+	Id          int64  `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	Name        string `json:"name" gorm:"type:varchar(100) not null"`
+	Year        int    `json:"year" gorm:"type:int not null;default:0"`
+	Description string `json:"description" gorm:"type:text"`
+	Status      string `json:"status" gorm:"type:varchar(20) not null;default:'ACTIVE'"`
+	//json array of the ids of the colleges which can submit. empty means every college.
+	EnabledColleges string    `json:"enabledColleges" gorm:"type:text"`
+	ArchiveTime     time.Time `json:"archiveTime" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"`
+	CreateTime      time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime      time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (this *Competition) IsArchived() bool {
+	return this.Status == COMPETITION_STATUS_ARCHIVED
+}
+
+// ids of the enabled colleges.
+func (this *Competition) CollegeIds() []int64 {
+	collegeIds := []int64{}
+	if this.EnabledColleges != "" {
+		_ = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(this.EnabledColleges), &collegeIds)
+	}
+	return collegeIds
+}
+
+// whether the college can submit to the competition.
+func (this *Competition) CollegeEnabled(collegeId int64) bool {
+	collegeIds := this.CollegeIds()
+	if len(collegeIds) == 0 {
+		return true
+	}
+	for _, id := range collegeIds {
+		if id == collegeId {
+			return true
+		}
+	}
+	return false
+}
+
+// CompetitionAdoption counts what Adopt moved into the competition.
+type CompetitionAdoption struct {
+	Tracks      int64 `json:"tracks"`
+	Rounds      int64 `json:"rounds"`
+	Submissions int64 `json:"submissions"`
+}
@@ -0,0 +1,160 @@
+package rest
+
+import (
+	"strings"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// @Service
+type CompetitionService struct {
+	BaseBean
+	competitionDao    *CompetitionDao
+	collegeDao        *CollegeDao
+	preferenceService *PreferenceService
+}
+
+func (this *CompetitionService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.competitionDao)
+	if b, ok := b.(*CompetitionDao); ok {
+		this.competitionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.collegeDao)
+	if b, ok := b.(*CollegeDao); ok {
+		this.collegeDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.preferenceService)
+	if b, ok := b.(*PreferenceService); ok {
+		this.preferenceService = b
+	}
+}
+
+// validate the editable fields of a competition.
+func (this *CompetitionService) validCompetition(competition *Competition) *result.WebResult {
+	competition.Name = strings.TrimSpace(competition.Name)
+	if competition.Name == "" {
+		return result.BadRequest("赛事名称不能为空")
+	}
+	if competition.Year < 2000 || competition.Year > 9999 {
+		return result.BadRequest("年份格式错误")
+	}
+	if existing := this.competitionDao.FindByNameAndYear(competition.Name, competition.Year); existing != nil && existing.Id != competition.Id {
+		return result.BadRequest("%d年的 %s 已存在", competition.Year, competition.Name)
+	}
+
+	if competition.EnabledColleges != "" {
+		var collegeIds []int64
+		if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(competition.EnabledColleges), &collegeIds); err != nil {
+			return result.BadRequest("开放学院格式错误")
+		}
+		for _, collegeId := range collegeIds {
+			if this.collegeDao.Find(collegeId) == nil {
+				return result.BadRequest("学院 %d 不存在", collegeId)
+			}
+		}
+	}
+	return nil
+}
+
+func (this *CompetitionService) Create(competition *Competition) (*Competition, *result.WebResult) {
+	if webResult := this.validCompetition(competition); webResult != nil {
+		return nil, webResult
+	}
+	competition.Status = COMPETITION_STATUS_ACTIVE
+	return this.competitionDao.Create(competition), nil
+}
+
+func (this *CompetitionService) Edit(competition *Competition) (*Competition, *result.WebResult) {
+	if competition.IsArchived() {
+		return nil, result.BadRequest("赛事已归档，不能修改")
+	}
+	if webResult := this.validCompetition(competition); webResult != nil {
+		return nil, webResult
+	}
+	return this.competitionDao.Save(competition), nil
+}
+
+// archive the competition of last year. it stays readable, nothing of it can be changed any more.
+func (this *CompetitionService) Archive(competition *Competition) *result.WebResult {
+	if competition.IsArchived() {
+		return result.BadRequest("赛事已归档")
+	}
+	competition.Status = COMPETITION_STATUS_ARCHIVED
+	competition.ArchiveTime = time.Now()
+	this.competitionDao.Save(competition)
+	return nil
+}
+
+func (this *CompetitionService) Unarchive(competition *Competition) *result.WebResult {
+	if !competition.IsArchived() {
+		return result.BadRequest("赛事未归档")
+	}
+	competition.Status = COMPETITION_STATUS_ACTIVE
+	this.competitionDao.Save(competition)
+	return nil
+}
+
+// a competition can only be deleted while it has no track or round.
+func (this *CompetitionService) Delete(competition *Competition) *result.WebResult {
+	if this.competitionDao.CountTracks(competition.Id) > 0 || this.competitionDao.CountRounds(competition.Id) > 0 {
+		return result.BadRequest("该赛事已有赛道或轮次，不能删除")
+	}
+	this.competitionDao.Delete(competition)
+	return nil
+}
+
+// move what was created before competitions existed into the competition.
+// the enabled colleges of the preference become the enabled colleges of the competition, if it has none.
+func (this *CompetitionService) Adopt(competition *Competition) (*CompetitionAdoption, *result.WebResult) {
+	if competition.IsArchived() {
+		return nil, result.BadRequest("赛事已归档，不能修改")
+	}
+
+	if competition.EnabledColleges == "" {
+		if collegeIds := this.preferenceService.Fetch().FetchCollegeConfig().EnabledColleges; len(collegeIds) > 0 {
+			data, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(collegeIds)
+			this.PanicError(err)
+			competition.EnabledColleges = string(data)
+			this.competitionDao.Save(competition)
+		}
+	}
+	return this.competitionDao.Adopt(competition.Id), nil
+}
+
+// whether tracks, rounds and submissions of the competition can be changed.
+// 0 is the legacy competition of what was created before competitions existed, it is always editable.
+func (this *CompetitionService) CheckEditable(competitionId int64) *result.WebResult {
+	if competitionId == 0 {
+		return nil
+	}
+	competition := this.competitionDao.Find(competitionId)
+	if competition == nil {
+		return result.BadRequest("赛事不存在")
+	}
+	if competition.IsArchived() {
+		return result.BadRequest("%s 已归档", competition.Name)
+	}
+	return nil
+}
+
+// whether the college can submit to the track.
+func (this *CompetitionService) CheckSubmittable(track *Track, collegeId int64) *result.WebResult {
+	if webResult := this.CheckEditable(track.CompetitionId); webResult != nil {
+		return webResult
+	}
+	if track.CompetitionId == 0 {
+		return nil
+	}
+	competition := this.competitionDao.Find(track.CompetitionId)
+	if !competition.CollegeEnabled(collegeId) {
+		return result.BadRequest("%s 未向您所在的学院开放", competition.Name)
+	}
+	return nil
+}
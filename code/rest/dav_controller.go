@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+// mount the spaces at /api/dav. clients log in with basic auth.
+type DavController struct {
+	BaseController
+	davService *DavService
+}
+
+func (this *DavController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.davService)
+	if b, ok := b.(*DavService); ok {
+		this.davService = b
+	}
+}
+
+// handle /api/dav and everything under it. the webdav methods are not the usual apis, so no route is registered.
+func (this *DavController) HandleRoutes(writer http.ResponseWriter, request *http.Request) (func(writer http.ResponseWriter, request *http.Request), bool) {
+	path := request.URL.Path
+	if path == DAV_PREFIX || strings.HasPrefix(path, DAV_PREFIX+"/") {
+		return this.Dav, true
+	}
+	return nil, false
+}
+
+func (this *DavController) Dav(writer http.ResponseWriter, request *http.Request) {
+	user := this.findUser(request)
+	if user == nil {
+		//ask the client for the username and password.
+		writer.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, DAV_REALM))
+		writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if user.Status == USER_STATUS_DISABLED {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	this.davService.HandleDav(writer, request, user)
+}
@@ -0,0 +1,381 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/storage"
+	"github.com/eyebluecn/tank/code/tool/util"
+	"github.com/eyebluecn/tank/code/tool/webdav"
+)
+
+// DavFileSystem serves the spaces of a user as a webdav file system.
+// The root lists the readable spaces, each one is a directory named after the space.
+// Changes go through MatterService, so the size limits and the submission windows are the same as on the web page.
+type DavFileSystem struct {
+	davService *DavService
+	request    *http.Request
+	user       *User
+}
+
+// run f and turn the panic of the services into an error of the file system.
+func (this *DavFileSystem) catch(f func()) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = davError(e)
+		}
+	}()
+	f()
+	return nil
+}
+
+// the handler judges the status by os.IsNotExist and os.IsExist, so those two are kept recognizable.
+func davError(e any) error {
+	switch value := e.(type) {
+	case *result.WebResult:
+		if value.Code == result.NOT_FOUND.Code {
+			return os.ErrNotExist
+		} else if value.Code == result.PRECONDITION_FAILED.Code {
+			return os.ErrExist
+		}
+		return value
+	case *result.CodeWrapper:
+		return errors.New(value.Description)
+	case error:
+		return value
+	default:
+		return fmt.Errorf("%v", value)
+	}
+}
+
+// the space and the matter of the name. the root of the file system has neither.
+func (this *DavFileSystem) find(name string) (*Space, *Matter, error) {
+	spaceName, subPath := SplitDavPath(name)
+	if spaceName == "" {
+		return nil, nil, nil
+	}
+	space := this.davService.spaceDao.FindByName(spaceName)
+	if space == nil {
+		return nil, nil, os.ErrNotExist
+	}
+	if subPath == "/" {
+		return space, NewRootMatter(space), nil
+	}
+	if path.Dir(subPath) != "/" {
+		//what is in a directory of the recycle bin is gone with it.
+		if _, _, err := this.find(path.Join("/", spaceName, path.Dir(subPath))); err != nil {
+			return space, nil, err
+		}
+	}
+	matter := this.davService.matterDao.FindBySpaceUuidAndPath(space.Uuid, subPath)
+	if matter == nil {
+		return space, nil, os.ErrNotExist
+	}
+	return space, matter, nil
+}
+
+// the directory the name is created in, and the last element of the name.
+func (this *DavFileSystem) findParent(name string) (*Space, *Matter, string, error) {
+	spaceName, subPath := SplitDavPath(name)
+	if spaceName == "" || subPath == "/" {
+		//spaces are not created, renamed or deleted through dav.
+		return nil, nil, "", os.ErrPermission
+	}
+	space, dirMatter, err := this.find(path.Join("/", spaceName, path.Dir(subPath)))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if !dirMatter.Dir {
+		return nil, nil, "", os.ErrNotExist
+	}
+	return space, dirMatter, path.Base(subPath), nil
+}
+
+// free the name in the directory from the recycle bin. dav clients overwrite by deleting first,
+// so what the recycle bin holds under the name is removed for good.
+func (this *DavFileSystem) release(space *Space, dirMatter *Matter, name string) error {
+	holder := this.davService.matterDao.FindBySpaceNameAndPuuidAndDirAndName(space.Name, dirMatter.Uuid, "", name)
+	if holder == nil {
+		return nil
+	}
+	if !holder.Deleted {
+		return os.ErrExist
+	}
+	return this.catch(func() {
+		this.davService.matterService.Delete(this.request, holder, this.user, space)
+	})
+}
+
+func (this *DavFileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if _, _, err := this.find(name); err == nil {
+		return os.ErrExist
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	space, dirMatter, dirName, err := this.findParent(name)
+	if err != nil {
+		return err
+	}
+	if err := this.release(space, dirMatter, dirName); err != nil {
+		return err
+	}
+	return this.catch(func() {
+		this.davService.matterService.AtomicCreateDirectory(this.request, dirMatter, dirName, this.user, space)
+	})
+}
+
+func (this *DavFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return this.open(name)
+	}
+	return this.create(name)
+}
+
+// open the name for reading.
+func (this *DavFileSystem) open(name string) (webdav.File, error) {
+	space, matter, err := this.find(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if space == nil {
+		var children []os.FileInfo
+		for _, readable := range this.davService.ReadableSpaces(this.user) {
+			children = append(children, newDavFileInfo(readable, NewRootMatter(readable)))
+		}
+		return &davDir{info: &davFileInfo{name: "/", dir: true, modTime: time.Now()}, children: children}, nil
+	}
+
+	info := newDavFileInfo(space, matter)
+	if matter.Dir {
+		var children []os.FileInfo
+		for _, child := range this.davService.matterDao.FindByPuuidAndSpaceUuid(matter.Uuid, space.Uuid) {
+			children = append(children, newDavFileInfo(space, child))
+		}
+		return &davDir{info: info, children: children}, nil
+	}
+
+	file, err := this.davService.storageService.Open(space.Name, matter.StorageKey())
+	if err != nil {
+		return nil, err
+	}
+	return &davFile{File: file, info: info}, nil
+}
+
+// open the name for writing. what is written is put when the file is closed.
+func (this *DavFileSystem) create(name string) (webdav.File, error) {
+	space, matter, err := this.find(name)
+	if err == nil {
+		if space == nil || matter.Dir {
+			return nil, os.ErrExist
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	space, dirMatter, filename, err := this.findParent(name)
+	if err != nil {
+		return nil, err
+	}
+
+	//a file of the name in the recycle bin is recovered and overwritten by the put.
+	reader, writer := io.Pipe()
+	file := &davUploadFile{name: filename, writer: writer, done: make(chan error, 1)}
+	go func() {
+		err := this.catch(func() {
+			this.davService.matterService.AtomicPut(this.request, reader, this.user, space, dirMatter, filename, true)
+		})
+		//unblock the writer if the put stopped early.
+		if err != nil {
+			_ = reader.CloseWithError(err)
+		} else {
+			_ = reader.Close()
+		}
+		file.done <- err
+	}()
+	return file, nil
+}
+
+func (this *DavFileSystem) RemoveAll(ctx context.Context, name string) error {
+	space, matter, err := this.find(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if space == nil || matter.Uuid == MATTER_ROOT {
+		return os.ErrPermission
+	}
+
+	//same as deleting on the web page, the matter goes to the recycle bin if it is enabled.
+	return this.catch(func() {
+		this.davService.matterService.AtomicSoftDelete(this.request, matter, this.user, space)
+	})
+}
+
+func (this *DavFileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	space, matter, err := this.find(oldName)
+	if err != nil {
+		return err
+	}
+	if space == nil || matter.Uuid == MATTER_ROOT {
+		return os.ErrPermission
+	}
+
+	destSpace, destDirMatter, name, err := this.findParent(newName)
+	if err != nil {
+		return err
+	}
+	if destSpace.Uuid != space.Uuid {
+		//matters cannot move between spaces, they can be copied.
+		return os.ErrPermission
+	}
+
+	if err := this.release(space, destDirMatter, name); err != nil {
+		return err
+	}
+	moved := destDirMatter.Uuid != matter.Puuid
+	renamed := name != matter.Name
+	if moved && renamed {
+		//it is moved under its own name first.
+		if err := this.release(space, destDirMatter, matter.Name); err != nil {
+			return err
+		}
+	}
+
+	return this.catch(func() {
+		if moved {
+			this.davService.matterService.AtomicMove(this.request, matter, destDirMatter, false, this.user, space)
+		}
+		if renamed {
+			this.davService.matterService.AtomicRename(this.request, matter, name, false, this.user, space)
+		}
+	})
+}
+
+func (this *DavFileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	space, matter, err := this.find(name)
+	if err != nil {
+		return nil, err
+	}
+	if space == nil {
+		return &davFileInfo{name: "/", dir: true, modTime: time.Now()}, nil
+	}
+	return newDavFileInfo(space, matter), nil
+}
+
+// davFileInfo describes a matter, or a space as the directory of its root.
+type davFileInfo struct {
+	name    string
+	size    int64
+	dir     bool
+	modTime time.Time
+}
+
+func newDavFileInfo(space *Space, matter *Matter) *davFileInfo {
+	if matter.Uuid == MATTER_ROOT {
+		return &davFileInfo{name: space.Name, dir: true, modTime: space.UpdateTime}
+	}
+	return &davFileInfo{name: matter.Name, size: matter.Size, dir: matter.Dir, modTime: matter.UpdateTime}
+}
+
+func (this *davFileInfo) Name() string       { return this.name }
+func (this *davFileInfo) Size() int64        { return this.size }
+func (this *davFileInfo) ModTime() time.Time { return this.modTime }
+func (this *davFileInfo) IsDir() bool        { return this.dir }
+func (this *davFileInfo) Sys() any           { return nil }
+
+func (this *davFileInfo) Mode() os.FileMode {
+	if this.dir {
+		return os.ModeDir | 0777
+	}
+	return 0666
+}
+
+// guess by the extension, so that PROPFIND does not open every file.
+func (this *davFileInfo) ContentType(ctx context.Context) (string, error) {
+	if this.dir {
+		return "", webdav.ErrNotImplemented
+	}
+	return util.GetMimeType(this.name), nil
+}
+
+// davFile is a file opened for reading.
+type davFile struct {
+	storage.File
+	info *davFileInfo
+}
+
+func (this *davFile) Write(p []byte) (int, error)              { return 0, os.ErrInvalid }
+func (this *davFile) Readdir(count int) ([]os.FileInfo, error) { return nil, os.ErrInvalid }
+func (this *davFile) Stat() (os.FileInfo, error)               { return this.info, nil }
+
+// davDir is a directory opened for reading.
+type davDir struct {
+	info     *davFileInfo
+	children []os.FileInfo
+	offset   int
+}
+
+func (this *davDir) Close() error                                 { return nil }
+func (this *davDir) Read(p []byte) (int, error)                   { return 0, os.ErrInvalid }
+func (this *davDir) Write(p []byte) (int, error)                  { return 0, os.ErrInvalid }
+func (this *davDir) Seek(offset int64, whence int) (int64, error) { return 0, os.ErrInvalid }
+func (this *davDir) Stat() (os.FileInfo, error)                   { return this.info, nil }
+
+func (this *davDir) Readdir(count int) ([]os.FileInfo, error) {
+	rest := this.children[this.offset:]
+	if count <= 0 {
+		this.offset = len(this.children)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(rest) {
+		count = len(rest)
+	}
+	this.offset += count
+	return rest[:count], nil
+}
+
+// davUploadFile streams what is written to MatterService.AtomicPut. the put completes on Close.
+type davUploadFile struct {
+	name   string
+	size   int64
+	writer *io.PipeWriter
+	done   chan error
+	err    error
+}
+
+func (this *davUploadFile) Write(p []byte) (int, error) {
+	n, err := this.writer.Write(p)
+	this.size += int64(n)
+	return n, err
+}
+
+func (this *davUploadFile) Close() error {
+	if this.done != nil {
+		_ = this.writer.Close()
+		this.err = <-this.done
+		this.done = nil
+	}
+	return this.err
+}
+
+func (this *davUploadFile) Read(p []byte) (int, error)                   { return 0, os.ErrInvalid }
+func (this *davUploadFile) Seek(offset int64, whence int) (int64, error) { return 0, os.ErrInvalid }
+func (this *davUploadFile) Readdir(count int) ([]os.FileInfo, error)     { return nil, os.ErrInvalid }
+
+func (this *davUploadFile) Stat() (os.FileInfo, error) {
+	return &davFileInfo{name: this.name, size: this.size, modTime: time.Now()}, nil
+}
@@ -0,0 +1,36 @@
+package rest
+
+import (
+	"path"
+	"strings"
+)
+
+const (
+	//every space is mounted under the prefix, eg. /api/dav/{spaceName}/a.txt
+	DAV_PREFIX = "/api/dav"
+	//realm of the basic auth challenge.
+	DAV_REALM = "tank"
+)
+
+// methods which do not change anything. the others need the space to be writable.
+var DAV_READ_METHODS = map[string]bool{
+	"OPTIONS":  true,
+	"GET":      true,
+	"HEAD":     true,
+	"POST":     true,
+	"PROPFIND": true,
+}
+
+// split a path of the dav file system into the space name and the path in the space.
+// eg. /team/a/b.txt -> team, /a/b.txt. the root of the file system has no space name.
+func SplitDavPath(name string) (spaceName string, subPath string) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "", "/"
+	}
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 1 {
+		return parts[0], "/"
+	}
+	return parts[0], "/" + parts[1]
+}
@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/webdav"
+)
+
+// serve the spaces over webdav, so that they can be mapped as network drives.
+// @Service
+type DavService struct {
+	BaseBean
+	matterDao      *MatterDao
+	matterService  *MatterService
+	spaceDao       *SpaceDao
+	spaceService   *SpaceService
+	spaceMemberDao *SpaceMemberDao
+	storageService *StorageService
+	//locks of LOCK and UNLOCK, they live in memory and are gone after restart.
+	lockSystem webdav.LockSystem
+}
+
+func (this *DavService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterService)
+	if b, ok := b.(*MatterService); ok {
+		this.matterService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceDao)
+	if b, ok := b.(*SpaceDao); ok {
+		this.spaceDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceService)
+	if b, ok := b.(*SpaceService); ok {
+		this.spaceService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceMemberDao)
+	if b, ok := b.(*SpaceMemberDao); ok {
+		this.spaceMemberDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.storageService)
+	if b, ok := b.(*StorageService); ok {
+		this.storageService = b
+	}
+
+	this.lockSystem = webdav.NewMemLS()
+}
+
+// the own space of the user and the spaces the user is a member of.
+func (this *DavService) ReadableSpaces(user *User) []*Space {
+	var spaces []*Space
+	if user.SpaceUuid != "" {
+		if space := this.spaceDao.FindByUuid(user.SpaceUuid); space != nil {
+			spaces = append(spaces, space)
+		}
+	}
+	for _, member := range this.spaceMemberDao.FindByUserUuid(user.Uuid) {
+		if member.SpaceUuid == user.SpaceUuid {
+			continue
+		}
+		if space := this.spaceDao.FindByUuid(member.SpaceUuid); space != nil {
+			spaces = append(spaces, space)
+		}
+	}
+	return spaces
+}
+
+// status to reject the request with by the role of the user in the space. 0 if the space is accessible.
+func (this *DavService) checkSpace(user *User, spaceName string, write bool) int {
+	if spaceName == "" {
+		//the root only lists the spaces.
+		if write {
+			return http.StatusMethodNotAllowed
+		}
+		return 0
+	}
+	space := this.spaceDao.FindByName(spaceName)
+	if space == nil {
+		//let the handler answer what is missing.
+		return 0
+	}
+	if write && !this.spaceService.Writable(user, space) {
+		return http.StatusForbidden
+	}
+	if !write && !this.spaceService.Readable(user, space) {
+		return http.StatusForbidden
+	}
+	return 0
+}
+
+// status to reject the request with before it reaches the file system. 0 if it can go on.
+func (this *DavService) preflight(request *http.Request, user *User) int {
+	spaceName, subPath := SplitDavPath(strings.TrimPrefix(request.URL.Path, DAV_PREFIX))
+
+	//the source of COPY is only read.
+	write := !DAV_READ_METHODS[request.Method] && request.Method != "COPY"
+	if status := this.checkSpace(user, spaceName, write); status != 0 {
+		return status
+	}
+
+	if request.Method == "COPY" || request.Method == "MOVE" {
+		if u, err := url.Parse(request.Header.Get("Destination")); err == nil && strings.HasPrefix(u.Path, DAV_PREFIX) {
+			destSpaceName, _ := SplitDavPath(strings.TrimPrefix(u.Path, DAV_PREFIX))
+			if status := this.checkSpace(user, destSpaceName, true); status != 0 {
+				return status
+			}
+		}
+	}
+
+	//refuse what is too large in advance. uploads without length are checked after being received.
+	if request.Method == "PUT" && request.ContentLength > 0 {
+		if space := this.spaceDao.FindByName(spaceName); space != nil {
+			if space.SizeLimit >= 0 && request.ContentLength > space.SizeLimit {
+				return http.StatusRequestEntityTooLarge
+			}
+			//an overwritten file gives its size back.
+			totalSize := space.TotalSize
+			if matter := this.matterDao.FindBySpaceUuidAndPath(space.Uuid, subPath); matter != nil && !matter.Dir {
+				totalSize -= matter.Size
+			}
+			if space.TotalSizeLimit >= 0 && totalSize+request.ContentLength > space.TotalSizeLimit {
+				return http.StatusInsufficientStorage
+			}
+		}
+	}
+	return 0
+}
+
+// serve a webdav request of the user.
+func (this *DavService) HandleDav(writer http.ResponseWriter, request *http.Request, user *User) {
+	if status := this.preflight(request, user); status != 0 {
+		writer.WriteHeader(status)
+		_, _ = writer.Write([]byte(webdav.StatusText(status)))
+		return
+	}
+
+	handler := &webdav.Handler{
+		Prefix: DAV_PREFIX,
+		FileSystem: &DavFileSystem{
+			davService: this,
+			request:    request,
+			user:       user,
+		},
+		LockSystem: this.lockSystem,
+		Logger: func(request *http.Request, err error) {
+			if err != nil {
+				this.logger.Error("dav %s %s %v", request.Method, request.URL.Path, err)
+			}
+		},
+	}
+	handler.ServeHTTP(writer, request)
+}
@@ -0,0 +1,154 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/sheet"
+)
+
+// spreadsheets for the competition office and the college admins.
+type ExportController struct {
+	BaseController
+	exportService     *ExportService
+	rankingService    *RankingService
+	submissionService *SubmissionService
+}
+
+func (this *ExportController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.exportService)
+	if b, ok := b.(*ExportService); ok {
+		this.exportService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.rankingService)
+	if b, ok := b.(*RankingService); ok {
+		this.rankingService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionService)
+	if b, ok := b.(*SubmissionService); ok {
+		this.submissionService = b
+	}
+}
+
+func (this *ExportController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/export/submissions"] = this.Wrap(this.Submissions, USER_ROLE_COLLEGE_ADMIN)
+	routeMap["/api/export/ratings"] = this.Wrap(this.Ratings, USER_ROLE_COLLEGE_ADMIN)
+	routeMap["/api/export/ranking"] = this.Wrap(this.Ranking, USER_ROLE_COLLEGE_ADMIN)
+
+	return routeMap
+}
+
+func (this *ExportController) formInt64(request *http.Request, key string) int64 {
+	str := request.FormValue(key)
+	if str == "" {
+		return 0
+	}
+	value, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		panic(result.BadRequest("%s格式错误", key))
+	}
+	return value
+}
+
+// the college a college admin manages. administrators are not restricted, others cannot export.
+func (this *ExportController) restrictCollege(request *http.Request) int64 {
+	user := this.checkUser(request)
+	switch user.Role {
+	case USER_ROLE_ADMINISTRATOR:
+		return 0
+	case USER_ROLE_COLLEGE_ADMIN:
+		college := this.submissionService.ManagedCollege(user)
+		if college == nil {
+			panic(result.BadRequest("未找到您所在的学院"))
+		}
+		return college.Id
+	default:
+		panic(result.UNAUTHORIZED)
+	}
+}
+
+func (this *ExportController) checkFilter(request *http.Request) *SubmissionFilter {
+	filter := &SubmissionFilter{
+		CompetitionId: this.formInt64(request, "competitionId"),
+		TrackId:       this.formInt64(request, "trackId"),
+		CollegeId:     this.formInt64(request, "collegeId"),
+		RoundId:       this.formInt64(request, "roundId"),
+		Recommended:   request.FormValue("recommended"),
+		Completeness:  request.FormValue("completeness"),
+	}
+	if filter.Recommended != "" && filter.Recommended != TRUE && filter.Recommended != FALSE {
+		panic(result.BadRequest("recommended格式错误"))
+	}
+	//a college admin sees the submissions of the college, team submissions with a member of it included.
+	if collegeId := this.restrictCollege(request); collegeId != 0 {
+		filter.CollegeId = collegeId
+	}
+	return filter
+}
+
+// set the download headers, and stream the sheet written by writeFunc.
+func (this *ExportController) export(writer http.ResponseWriter, request *http.Request, name string, writeFunc func(sheetWriter sheet.Writer)) {
+	format := request.FormValue("format")
+	if format == "" {
+		format = sheet.FORMAT_XLSX
+	}
+	if format != sheet.FORMAT_XLSX && format != sheet.FORMAT_CSV {
+		panic(result.BadRequest("format只能是xlsx或csv"))
+	}
+
+	fileName := fmt.Sprintf("%s_%s.%s", name, time.Now().Format("20060102150405"), format)
+	writer.Header().Set("Content-Type", sheet.ContentType(format))
+	writer.Header().Set("content-disposition", "attachment; filename=\""+url.QueryEscape(fileName)+"\"")
+
+	sheetWriter, err := sheet.NewWriter(format, writer, name)
+	this.PanicError(err)
+	writeFunc(sheetWriter)
+	err = sheetWriter.Close()
+	this.PanicError(err)
+}
+
+func (this *ExportController) Submissions(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	filter := this.checkFilter(request)
+	this.export(writer, request, "submissions", func(sheetWriter sheet.Writer) {
+		this.exportService.WriteSubmissions(sheetWriter, filter)
+	})
+	return nil
+}
+
+func (this *ExportController) Ratings(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	filter := this.checkFilter(request)
+	this.export(writer, request, "ratings", func(sheetWriter sheet.Writer) {
+		this.exportService.WriteRatings(sheetWriter, filter)
+	})
+	return nil
+}
+
+// the leaderboard of a track. college admins get the entries of their college only.
+func (this *ExportController) Ranking(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	collegeId := this.restrictCollege(request)
+
+	trackId := this.formInt64(request, "trackId")
+	if trackId == 0 {
+		return result.BadRequest("trackId不能为空")
+	}
+	leaderboard, webResult := this.rankingService.Rank(trackId, this.formInt64(request, "roundId"), this.rankingService.FetchConfig())
+	if webResult != nil {
+		return webResult
+	}
+
+	this.export(writer, request, "ranking", func(sheetWriter sheet.Writer) {
+		this.exportService.WriteRanking(sheetWriter, leaderboard, collegeId)
+	})
+	return nil
+}
@@ -0,0 +1,314 @@
+package rest
+
+import (
+	"strings"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/sheet"
+)
+
+// @Service
+type ExportService struct {
+	BaseBean
+	submissionDao     *SubmissionDao
+	ratingDao         *RatingDao
+	userDao           *UserDao
+	userProfileDao    *UserProfileDao
+	collegeDao        *CollegeDao
+	trackDao          *TrackDao
+	roundDao          *RoundDao
+	teamDao           *TeamDao
+	teamService       *TeamService
+	submissionService *SubmissionService
+	formDao           *FormDao
+	matterDao         *MatterDao
+}
+
+func (this *ExportService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.ratingDao)
+	if b, ok := b.(*RatingDao); ok {
+		this.ratingDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userDao)
+	if b, ok := b.(*UserDao); ok {
+		this.userDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userProfileDao)
+	if b, ok := b.(*UserProfileDao); ok {
+		this.userProfileDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.collegeDao)
+	if b, ok := b.(*CollegeDao); ok {
+		this.collegeDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.trackDao)
+	if b, ok := b.(*TrackDao); ok {
+		this.trackDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.roundDao)
+	if b, ok := b.(*RoundDao); ok {
+		this.roundDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.teamDao)
+	if b, ok := b.(*TeamDao); ok {
+		this.teamDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.teamService)
+	if b, ok := b.(*TeamService); ok {
+		this.teamService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionService)
+	if b, ok := b.(*SubmissionService); ok {
+		this.submissionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.formDao)
+	if b, ok := b.(*FormDao); ok {
+		this.formDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+}
+
+// names and profiles looked up during one export.
+type exportLookup struct {
+	tracks   map[int64]string
+	colleges map[int64]string
+	rounds   map[int64]string
+	authors  map[string]*UserProfile
+	judges   map[string]string
+	teams    map[int64]*Team
+}
+
+func (this *ExportService) newLookup() *exportLookup {
+	lookup := &exportLookup{
+		tracks:   make(map[int64]string),
+		colleges: make(map[int64]string),
+		rounds:   make(map[int64]string),
+		authors:  make(map[string]*UserProfile),
+		judges:   make(map[string]string),
+		teams:    make(map[int64]*Team),
+	}
+	for _, track := range this.trackDao.FindAll() {
+		lookup.tracks[track.Id] = track.Name
+	}
+	for _, college := range this.collegeDao.FindAll() {
+		lookup.colleges[college.Id] = college.Name
+	}
+	for _, round := range this.roundDao.FindAll() {
+		lookup.rounds[round.Id] = round.Name
+	}
+	return lookup
+}
+
+// profile of the author. an empty profile if the author has no account.
+func (this *ExportService) author(lookup *exportLookup, submission *Submission) *UserProfile {
+	userProfile, ok := lookup.authors[submission.AuthorId]
+	if !ok {
+		if submission.AuthorId != "" {
+			userProfile = this.userProfileDao.FindByStudentId(submission.AuthorId)
+		}
+		if userProfile == nil {
+			userProfile = &UserProfile{}
+		}
+		lookup.authors[submission.AuthorId] = userProfile
+	}
+	return userProfile
+}
+
+// the team of a team submission with its members, nil for a personal submission.
+func (this *ExportService) team(lookup *exportLookup, submission *Submission) *Team {
+	if submission.TeamId == 0 {
+		return nil
+	}
+	team, ok := lookup.teams[submission.TeamId]
+	if !ok {
+		team = this.teamService.Wrap(this.teamDao.Find(submission.TeamId))
+		lookup.teams[submission.TeamId] = team
+	}
+	return team
+}
+
+// team name, and "real name(student id)" of the accepted members.
+func (this *ExportService) teamColumns(lookup *exportLookup, submission *Submission) (string, string) {
+	team := this.team(lookup, submission)
+	if team == nil {
+		return "", ""
+	}
+	var members []string
+	for _, member := range team.Members {
+		if member.Status != TEAM_MEMBER_STATUS_ACCEPTED || member.UserProfile == nil {
+			continue
+		}
+		members = append(members, member.UserProfile.RealName+"("+member.UserProfile.StudentId+")")
+	}
+	return team.Name, strings.Join(members, "、")
+}
+
+// "username(real name)" of the judge.
+func (this *ExportService) judge(lookup *exportLookup, judgeUuid string) string {
+	name, ok := lookup.judges[judgeUuid]
+	if !ok {
+		name = judgeUuid
+		if user := this.userDao.FindByUuid(judgeUuid); user != nil {
+			name = user.Username
+			if userProfile := this.userProfileDao.FindByUserUuid(judgeUuid); userProfile != nil && userProfile.RealName != "" {
+				name = user.Username + "(" + userProfile.RealName + ")"
+			}
+		}
+		lookup.judges[judgeUuid] = name
+	}
+	return name
+}
+
+// ratings of the submission, only those of the filtered round if any.
+func (this *ExportService) ratings(submission *Submission, filter *SubmissionFilter) []*Rating {
+	var ratings []*Rating
+	for _, rating := range this.ratingDao.FindBySubmissionId(submission.Id) {
+		if !rating.Superseded && (filter.RoundId == 0 || rating.RoundId == filter.RoundId) {
+			ratings = append(ratings, rating)
+		}
+	}
+	return ratings
+}
+
+func (this *ExportService) recommendedAt(submission *Submission) any {
+	if !submission.IsRecommended {
+		return ""
+	}
+	return submission.RecommendedAt
+}
+
+// form fields exported as columns: those of the filtered track, or of all tracks. fields of different tracks sharing a key share the column.
+func (this *ExportService) formFields(filter *SubmissionFilter) []*FormField {
+	var fields []*FormField
+	if filter.TrackId != 0 {
+		fields = this.formDao.FindFieldsByTrackId(filter.TrackId)
+	} else {
+		fields = this.formDao.FindAllFields()
+	}
+
+	var columns []*FormField
+	keys := make(map[string]bool)
+	for _, field := range fields {
+		if !keys[field.Key] {
+			keys[field.Key] = true
+			columns = append(columns, field)
+		}
+	}
+	return columns
+}
+
+// one row per submission, with the rating summary and the form values.
+func (this *ExportService) WriteSubmissions(writer sheet.Writer, filter *SubmissionFilter) {
+	lookup := this.newLookup()
+	fields := this.formFields(filter)
+
+	header := []any{"作品ID", "作品标题", "赛道", "学院", "作者", "学号", "作者姓名", "作者学院", "联系电话",
+		"团队", "团队成员", "提交时间", "是否推荐", "推荐时间", "轮次", "轮次状态", "评分数", "平均分"}
+	for _, field := range fields {
+		header = append(header, field.Label)
+	}
+	err := writer.WriteRow(header...)
+	this.PanicError(err)
+
+	for _, submission := range this.submissionService.FindByFilter(filter) {
+		author := this.author(lookup, submission)
+		teamName, teamMembers := this.teamColumns(lookup, submission)
+
+		var total float64
+		ratings := this.ratings(submission, filter)
+		for _, rating := range ratings {
+			total += rating.TotalScore()
+		}
+		var average any = ""
+		if len(ratings) > 0 {
+			average = total / float64(len(ratings))
+		}
+
+		row := []any{submission.Id, submission.Title, lookup.tracks[submission.TrackId], lookup.colleges[submission.CollegeId],
+			submission.AuthorName, submission.AuthorId, author.RealName, author.College, author.PhoneNumber,
+			teamName, teamMembers, submission.CreateTime, submission.IsRecommended, this.recommendedAt(submission),
+			lookup.rounds[submission.RoundId], submission.RoundStatus, len(ratings), average}
+		if len(fields) > 0 {
+			values := make(map[string]string)
+			for _, value := range this.formDao.FindValuesBySubmissionId(submission.Id) {
+				values[value.FieldKey] = value.Value
+			}
+			for _, field := range fields {
+				value := values[field.Key]
+				//file slots are exported by the file name.
+				if field.Type == FORM_FIELD_TYPE_FILE_SLOT && value != "" {
+					if matter := this.matterDao.FindByUuid(value); matter != nil {
+						value = matter.Name
+					}
+				}
+				row = append(row, value)
+			}
+		}
+		err = writer.WriteRow(row...)
+		this.PanicError(err)
+	}
+}
+
+// one row per rating.
+func (this *ExportService) WriteRatings(writer sheet.Writer, filter *SubmissionFilter) {
+	lookup := this.newLookup()
+
+	err := writer.WriteRow("作品ID", "作品标题", "赛道", "学院", "作者", "学号", "轮次", "评委", "分数", "评语", "评分时间")
+	this.PanicError(err)
+
+	for _, submission := range this.submissionService.FindByFilter(filter) {
+		for _, rating := range this.ratings(submission, filter) {
+			err = writer.WriteRow(submission.Id, submission.Title, lookup.tracks[submission.TrackId], lookup.colleges[submission.CollegeId],
+				submission.AuthorName, submission.AuthorId, lookup.rounds[rating.RoundId], this.judge(lookup, rating.JudgeUuid),
+				rating.TotalScore(), rating.Comment, rating.UpdateTime)
+			this.PanicError(err)
+		}
+	}
+}
+
+// the leaderboard. collegeId other than 0 keeps only the entries of that college, with their overall ranks.
+func (this *ExportService) WriteRanking(writer sheet.Writer, leaderboard *Leaderboard, collegeId int64) {
+	lookup := this.newLookup()
+
+	//the same college filter as the submission export, team submissions included.
+	var inCollege map[int64]bool
+	if collegeId != 0 {
+		inCollege = make(map[int64]bool)
+		for _, submission := range this.submissionDao.FindByFilter(&SubmissionFilter{TrackId: leaderboard.TrackId, CollegeId: collegeId}) {
+			inCollege[submission.Id] = true
+		}
+	}
+
+	err := writer.WriteRow("排名", "作品ID", "作品标题", "赛道", "学院", "作者", "学号", "最终得分", "原始平均分", "最高分", "最低分", "评分数", "奖项")
+	this.PanicError(err)
+
+	for _, entry := range leaderboard.Entries {
+		submission := entry.Submission
+		if collegeId != 0 && !inCollege[submission.Id] {
+			continue
+		}
+		err = writer.WriteRow(entry.Rank, submission.Id, submission.Title, lookup.tracks[submission.TrackId], lookup.colleges[submission.CollegeId],
+			submission.AuthorName, submission.AuthorId, entry.FinalScore, entry.RawMean, entry.MaxScore, entry.MinScore, entry.RatingCount, entry.Award)
+		this.PanicError(err)
+	}
+}
@@ -0,0 +1,112 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	jsoniter "github.com/json-iterator/go"
+)
+
+type FileRuleController struct {
+	BaseController
+	fileRuleDao       *FileRuleDao
+	fileRuleService   *FileRuleService
+	submissionDao     *SubmissionDao
+	submissionService *SubmissionService
+}
+
+func (this *FileRuleController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.fileRuleDao)
+	if b, ok := b.(*FileRuleDao); ok {
+		this.fileRuleDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.fileRuleService)
+	if b, ok := b.(*FileRuleService); ok {
+		this.fileRuleService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionService)
+	if b, ok := b.(*SubmissionService); ok {
+		this.submissionService = b
+	}
+}
+
+func (this *FileRuleController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/file/rule/list"] = this.Wrap(this.List, USER_ROLE_USER)
+	routeMap["/api/file/rule/save"] = this.Wrap(this.Save, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/file/rule/check"] = this.Wrap(this.Check, USER_ROLE_USER)
+	routeMap["/api/file/rule/check/track"] = this.Wrap(this.CheckTrack, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+// the file rules of a track.
+func (this *FileRuleController) List(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	trackId, err := strconv.ParseInt(request.FormValue("trackId"), 10, 64)
+	if err != nil {
+		return result.BadRequest("trackId格式错误")
+	}
+	return this.Success(this.fileRuleDao.FindByTrackId(trackId))
+}
+
+// replace the file rules of a track. rules is a json array of FileRule.
+// the completeness of the submissions in the track is refreshed.
+func (this *FileRuleController) Save(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	trackId, err := strconv.ParseInt(request.FormValue("trackId"), 10, 64)
+	if err != nil {
+		return result.BadRequest("trackId格式错误")
+	}
+
+	var rules []*FileRule
+	err = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(request.FormValue("rules")), &rules)
+	if err != nil {
+		return result.BadRequest("文件要求数据格式错误")
+	}
+
+	rules, webResult := this.fileRuleService.SaveRules(trackId, rules)
+	if webResult != nil {
+		return webResult
+	}
+	this.fileRuleService.CheckTrack(trackId)
+	return this.Success(rules)
+}
+
+// check the folder of a submission against the file rules of its track.
+func (this *FileRuleController) Check(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	submissionId, err := strconv.ParseInt(request.FormValue("submissionId"), 10, 64)
+	if err != nil {
+		return result.BadRequest("submissionId格式错误")
+	}
+	submission := this.submissionDao.FindById(submissionId)
+	if submission == nil {
+		return result.BadRequest("提交作品不存在")
+	}
+	if !this.submissionService.CanView(user, submission) {
+		panic(result.UNAUTHORIZED)
+	}
+
+	return this.Success(this.fileRuleService.Check(submission))
+}
+
+// check all the submissions of a track. the reports of the incomplete ones are returned.
+func (this *FileRuleController) CheckTrack(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	trackId, err := strconv.ParseInt(request.FormValue("trackId"), 10, 64)
+	if err != nil {
+		return result.BadRequest("trackId格式错误")
+	}
+	return this.Success(this.fileRuleService.CheckTrack(trackId))
+}
@@ -0,0 +1,35 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+// @Service
+type FileRuleDao struct {
+	BaseDao
+}
+
+func (this *FileRuleDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *FileRuleDao) Create(rule *FileRule) *FileRule {
+	rule.CreateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(rule)
+	this.PanicError(db.Error)
+	return rule
+}
+
+func (this *FileRuleDao) FindByTrackId(trackId int64) []*FileRule {
+	var rules []*FileRule
+	db := core.CONTEXT.GetDB().Where("track_id = ?", trackId).Order("sort ASC").Find(&rules)
+	this.PanicError(db.Error)
+	return rules
+}
+
+func (this *FileRuleDao) DeleteByTrackId(trackId int64) {
+	db := core.CONTEXT.GetDB().Where("track_id = ?", trackId).Delete(&FileRule{})
+	this.PanicError(db.Error)
+}
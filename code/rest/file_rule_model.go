@@ -0,0 +1,81 @@
+package rest
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	//every rule of the track is satisfied by the submission folder.
+	SUBMISSION_COMPLETENESS_COMPLETE = "COMPLETE"
+	//some rule is not satisfied. empty completeness means not checked yet.
+	SUBMISSION_COMPLETENESS_INCOMPLETE = "INCOMPLETE"
+)
+
+// FileRule is a slot of files the submission folder of a track must or may contain,
+// eg. exactly one pdf named plan, at most one video under 500MB.
+type FileRule struct {
+	Id      int64  `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	TrackId int64  `json:"trackId" gorm:"type:bigint(20) not null;index:idx_file_rule_ti"`
+	Name    string `json:"name" gorm:"type:varchar(100) not null"`
+	//the file name (without extension) must contain the keyword, case insensitive. empty matches any name.
+	Keyword string `json:"keyword" gorm:"type:varchar(100)"`
+	//comma separated extensions, eg. pdf,docx. empty matches any extension.
+	Extensions string `json:"extensions" gorm:"type:varchar(255)"`
+	MinCount   int64  `json:"minCount" gorm:"type:bigint(20) not null;default:0"`
+	//0 means unlimited.
+	MaxCount int64 `json:"maxCount" gorm:"type:bigint(20) not null;default:0"`
+	//max size of each file in bytes. 0 means unlimited.
+	MaxSize    int64     `json:"maxSize" gorm:"type:bigint(20) not null;default:0"`
+	Sort       int64     `json:"sort" gorm:"type:bigint(20) not null;default:0"`
+	CreateTime time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
+
+// extensions of the rule, lower cased without the dot.
+func (this *FileRule) ExtensionList() []string {
+	var extensions []string
+	for _, extension := range strings.Split(this.Extensions, ",") {
+		extension = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(extension), "."))
+		if extension != "" {
+			extensions = append(extensions, extension)
+		}
+	}
+	return extensions
+}
+
+// whether a file of the name falls into the slot.
+func (this *FileRule) Matches(name string) bool {
+	extension := path.Ext(name)
+	if this.Keyword != "" && !strings.Contains(strings.ToLower(strings.TrimSuffix(name, extension)), strings.ToLower(this.Keyword)) {
+		return false
+	}
+	extensions := this.ExtensionList()
+	if len(extensions) == 0 {
+		return true
+	}
+	extension = strings.ToLower(strings.TrimPrefix(extension, "."))
+	for _, allowed := range extensions {
+		if extension == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// RuleCheck is how the submission folder does on one rule.
+type RuleCheck struct {
+	Rule     *FileRule `json:"rule"`
+	Files    []string  `json:"files"`
+	Problems []string  `json:"problems"`
+}
+
+// CompletenessReport tells what the submission folder lacks.
+type CompletenessReport struct {
+	SubmissionId int64        `json:"submissionId"`
+	Completeness string       `json:"completeness"`
+	Checks       []*RuleCheck `json:"checks"`
+	//problems not of a single rule, eg. the folder is missing.
+	Problems  []string  `json:"problems"`
+	CheckTime time.Time `json:"checkTime"`
+}
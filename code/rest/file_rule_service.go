@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+// @Service
+type FileRuleService struct {
+	BaseBean
+	fileRuleDao   *FileRuleDao
+	trackDao      *TrackDao
+	matterDao     *MatterDao
+	submissionDao *SubmissionDao
+}
+
+func (this *FileRuleService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.fileRuleDao)
+	if b, ok := b.(*FileRuleDao); ok {
+		this.fileRuleDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.trackDao)
+	if b, ok := b.(*TrackDao); ok {
+		this.trackDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+}
+
+// replace the file rules of the track.
+func (this *FileRuleService) SaveRules(trackId int64, rules []*FileRule) ([]*FileRule, *result.WebResult) {
+	if this.trackDao.Find(trackId) == nil {
+		return nil, result.BadRequest("赛道不存在")
+	}
+
+	for _, rule := range rules {
+		rule.Name = strings.TrimSpace(rule.Name)
+		rule.Keyword = strings.TrimSpace(rule.Keyword)
+		if rule.Name == "" {
+			return nil, result.BadRequest("文件要求名称不能为空")
+		}
+		if rule.MinCount < 0 || rule.MaxCount < 0 || rule.MaxSize < 0 {
+			return nil, result.BadRequest("文件要求 %s 的数量和大小不能为负数", rule.Name)
+		}
+		if rule.MaxCount != 0 && rule.MaxCount < rule.MinCount {
+			return nil, result.BadRequest("文件要求 %s 的最多数量不能小于最少数量", rule.Name)
+		}
+		rule.Extensions = strings.Join(rule.ExtensionList(), ",")
+	}
+
+	this.fileRuleDao.DeleteByTrackId(trackId)
+	for i, rule := range rules {
+		rule.Id = 0
+		rule.TrackId = trackId
+		rule.Sort = int64(i)
+		this.fileRuleDao.Create(rule)
+	}
+	return rules, nil
+}
+
+// the files in the submission folder, at any depth.
+func (this *FileRuleService) files(root *Matter) []*Matter {
+	var files []*Matter
+	var walkFunc func(dirMatter *Matter)
+	walkFunc = func(dirMatter *Matter) {
+		for _, matter := range this.matterDao.FindByPuuidAndSpaceUuid(dirMatter.Uuid, dirMatter.SpaceUuid) {
+			if matter.Dir {
+				walkFunc(matter)
+			} else {
+				files = append(files, matter)
+			}
+		}
+	}
+	walkFunc(root)
+	return files
+}
+
+// evaluate the file rules of the track on the submission folder, and keep the completeness on the submission.
+func (this *FileRuleService) Check(submission *Submission) *CompletenessReport {
+	report := &CompletenessReport{
+		SubmissionId: submission.Id,
+		Checks:       []*RuleCheck{},
+		Problems:     []string{},
+		CheckTime:    time.Now(),
+	}
+
+	rules := this.fileRuleDao.FindByTrackId(submission.TrackId)
+	root := this.matterDao.FindByUuid(submission.MatterUuid)
+	if root == nil || root.Deleted || !root.Dir {
+		report.Problems = append(report.Problems, "作品文件夹不存在")
+	} else if len(rules) > 0 {
+		files := this.files(root)
+		for _, rule := range rules {
+			check := &RuleCheck{Rule: rule, Files: []string{}, Problems: []string{}}
+			for _, file := range files {
+				if !rule.Matches(file.Name) {
+					continue
+				}
+				check.Files = append(check.Files, file.Path[len(root.Path)+1:])
+				if rule.MaxSize > 0 && file.Size > rule.MaxSize {
+					check.Problems = append(check.Problems, fmt.Sprintf("%s 超过 %s 的大小限制 %s", file.Name, rule.Name, util.HumanFileSize(rule.MaxSize)))
+				}
+			}
+			count := int64(len(check.Files))
+			if count < rule.MinCount {
+				check.Problems = append(check.Problems, fmt.Sprintf("%s 至少需要 %d 个，当前 %d 个", rule.Name, rule.MinCount, count))
+			}
+			if rule.MaxCount > 0 && count > rule.MaxCount {
+				check.Problems = append(check.Problems, fmt.Sprintf("%s 最多 %d 个，当前 %d 个", rule.Name, rule.MaxCount, count))
+			}
+			report.Checks = append(report.Checks, check)
+		}
+	}
+
+	report.Completeness = SUBMISSION_COMPLETENESS_COMPLETE
+	if len(report.Problems) > 0 {
+		report.Completeness = SUBMISSION_COMPLETENESS_INCOMPLETE
+	}
+	for _, check := range report.Checks {
+		if len(check.Problems) > 0 {
+			report.Completeness = SUBMISSION_COMPLETENESS_INCOMPLETE
+		}
+	}
+
+	if submission.Completeness != report.Completeness {
+		submission.Completeness = report.Completeness
+		this.submissionDao.Save(submission)
+	}
+	return report
+}
+
+// all the problems of the report in one line.
+func (this *FileRuleService) Summary(report *CompletenessReport) string {
+	problems := append([]string{}, report.Problems...)
+	for _, check := range report.Checks {
+		problems = append(problems, check.Problems...)
+	}
+	return strings.Join(problems, "；")
+}
+
+// check every submission of the track again, after the rules change. returns the incomplete ones.
+func (this *FileRuleService) CheckTrack(trackId int64) []*CompletenessReport {
+	reports := []*CompletenessReport{}
+	for _, submission := range this.submissionDao.FindByFilter(&SubmissionFilter{TrackId: trackId}) {
+		report := this.Check(submission)
+		if report.Completeness != SUBMISSION_COMPLETENESS_COMPLETE {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
@@ -0,0 +1,186 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// form field values in search parameters are named like field.abstract
+const FORM_SEARCH_FIELD_PREFIX = "field."
+
+type FormController struct {
+	BaseController
+	formDao           *FormDao
+	formService       *FormService
+	submissionDao     *SubmissionDao
+	submissionService *SubmissionService
+	blindService      *BlindService
+}
+
+func (this *FormController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.formDao)
+	if b, ok := b.(*FormDao); ok {
+		this.formDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.formService)
+	if b, ok := b.(*FormService); ok {
+		this.formService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionService)
+	if b, ok := b.(*SubmissionService); ok {
+		this.submissionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.blindService)
+	if b, ok := b.(*BlindService); ok {
+		this.blindService = b
+	}
+}
+
+func (this *FormController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/form/fields"] = this.Wrap(this.Fields, USER_ROLE_USER)
+	routeMap["/api/form/save"] = this.Wrap(this.Save, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/form/values"] = this.Wrap(this.Values, USER_ROLE_USER)
+	routeMap["/api/form/fill"] = this.Wrap(this.Fill, USER_ROLE_USER)
+	routeMap["/api/form/search"] = this.Wrap(this.Search, USER_ROLE_JUDGE)
+
+	return routeMap
+}
+
+func (this *FormController) formInt64(request *http.Request, key string) int64 {
+	str := request.FormValue(key)
+	if str == "" {
+		return 0
+	}
+	value, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		panic(result.BadRequest("%s格式错误", key))
+	}
+	return value
+}
+
+func (this *FormController) checkSubmission(request *http.Request) *Submission {
+	submission := this.submissionDao.FindById(this.formInt64(request, "submissionId"))
+	if submission == nil {
+		panic(result.BadRequest("提交作品不存在"))
+	}
+	return submission
+}
+
+// the form of a track.
+func (this *FormController) Fields(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	trackId := this.formInt64(request, "trackId")
+	if trackId == 0 {
+		return result.BadRequest("trackId不能为空")
+	}
+	return this.Success(this.formDao.FindFieldsByTrackId(trackId))
+}
+
+// replace the form of a track. fields is a json array of FormField.
+func (this *FormController) Save(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	trackId := this.formInt64(request, "trackId")
+
+	var fields []*FormField
+	err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(request.FormValue("fields")), &fields)
+	if err != nil {
+		return result.BadRequest("表单字段数据格式错误")
+	}
+
+	fields, webResult := this.formService.SaveFields(trackId, fields)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(fields)
+}
+
+// the form of the submission with the values filled, visible to whoever can view the submission.
+func (this *FormController) Values(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	submission := this.checkSubmission(request)
+	if !this.submissionService.CanView(user, submission) {
+		panic(result.UNAUTHORIZED)
+	}
+
+	return this.Success(map[string]any{
+		"fields":  this.formDao.FindFieldsByTrackId(submission.TrackId),
+		"values":  this.formService.Values(submission),
+		"missing": this.formService.Missing(submission),
+	})
+}
+
+// fill the form of a submission. values is a json object of field key to value.
+func (this *FormController) Fill(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	submission := this.checkSubmission(request)
+
+	var values map[string]string
+	err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(request.FormValue("values")), &values)
+	if err != nil {
+		return result.BadRequest("表单数据格式错误")
+	}
+
+	merged, webResult := this.formService.Fill(request, user, submission, values)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(merged)
+}
+
+// search submissions by title and form values. judges search the recommended submissions,
+// college admins the submissions of their college.
+func (this *FormController) Search(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	filter := &SubmissionFilter{
+		CompetitionId: this.formInt64(request, "competitionId"),
+		TrackId:       this.formInt64(request, "trackId"),
+		CollegeId:     this.formInt64(request, "collegeId"),
+		RoundId:       this.formInt64(request, "roundId"),
+		Recommended:   request.FormValue("recommended"),
+		Completeness:  request.FormValue("completeness"),
+		Keyword:       strings.TrimSpace(request.FormValue("keyword")),
+		Fields:        make(map[string]string),
+	}
+	for key, values := range request.Form {
+		if strings.HasPrefix(key, FORM_SEARCH_FIELD_PREFIX) && len(values) > 0 && strings.TrimSpace(values[0]) != "" {
+			filter.Fields[strings.TrimPrefix(key, FORM_SEARCH_FIELD_PREFIX)] = strings.TrimSpace(values[0])
+		}
+	}
+
+	switch user.Role {
+	case USER_ROLE_ADMINISTRATOR:
+	case USER_ROLE_JUDGE:
+		filter.Recommended = TRUE
+	case USER_ROLE_COLLEGE_ADMIN:
+		college := this.submissionService.ManagedCollege(user)
+		if college == nil {
+			return result.BadRequest("未找到您所在的学院")
+		}
+		filter.CollegeId = college.Id
+	default:
+		panic(result.UNAUTHORIZED)
+	}
+
+	submissions := this.submissionService.FindByFilter(filter)
+	for i, submission := range submissions {
+		submission.FormValues = this.formService.Values(submission)
+		submissions[i] = this.blindService.ForUser(user, submission)
+	}
+	return this.Success(submissions)
+}
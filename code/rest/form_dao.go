@@ -0,0 +1,75 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+// @Service
+type FormDao struct {
+	BaseDao
+}
+
+func (this *FormDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *FormDao) CreateField(field *FormField) *FormField {
+	field.CreateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(field)
+	this.PanicError(db.Error)
+	return field
+}
+
+func (this *FormDao) FindFieldsByTrackId(trackId int64) []*FormField {
+	var fields []*FormField
+	db := core.CONTEXT.GetDB().Where("track_id = ?", trackId).Order("sort ASC").Find(&fields)
+	this.PanicError(db.Error)
+	return fields
+}
+
+func (this *FormDao) FindAllFields() []*FormField {
+	var fields []*FormField
+	db := core.CONTEXT.GetDB().Order("track_id ASC, sort ASC").Find(&fields)
+	this.PanicError(db.Error)
+	return fields
+}
+
+func (this *FormDao) DeleteFieldsByTrackId(trackId int64) {
+	db := core.CONTEXT.GetDB().Where("track_id = ?", trackId).Delete(&FormField{})
+	this.PanicError(db.Error)
+}
+
+func (this *FormDao) FindValuesBySubmissionId(submissionId int64) []*FormValue {
+	var values []*FormValue
+	db := core.CONTEXT.GetDB().Where("submission_id = ?", submissionId).Find(&values)
+	this.PanicError(db.Error)
+	return values
+}
+
+func (this *FormDao) FindValue(submissionId int64, fieldKey string) *FormValue {
+	var value FormValue
+	db := core.CONTEXT.GetDB().Where("submission_id = ? AND field_key = ?", submissionId, fieldKey).First(&value)
+	if db.Error != nil {
+		return nil
+	}
+	return &value
+}
+
+func (this *FormDao) SaveValue(value *FormValue) *FormValue {
+	value.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Save(value)
+	this.PanicError(db.Error)
+	return value
+}
+
+func (this *FormDao) DeleteValue(value *FormValue) {
+	db := core.CONTEXT.GetDB().Delete(value)
+	this.PanicError(db.Error)
+}
+
+func (this *FormDao) DeleteValuesBySubmissionId(submissionId int64) {
+	db := core.CONTEXT.GetDB().Where("submission_id = ?", submissionId).Delete(&FormValue{})
+	this.PanicError(db.Error)
+}
@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	FORM_FIELD_TYPE_TEXT      = "TEXT"
+	FORM_FIELD_TYPE_LONG_TEXT = "LONG_TEXT"
+	FORM_FIELD_TYPE_SELECT    = "SELECT"
+	FORM_FIELD_TYPE_NUMBER    = "NUMBER"
+	FORM_FIELD_TYPE_DATE      = "DATE"
+	//the value is the uuid of a file in the submission folder.
+	FORM_FIELD_TYPE_FILE_SLOT = "FILE_SLOT"
+
+	FORM_FIELD_KEY_PATTERN = `^[a-zA-Z][0-9a-zA-Z_]{0,49}$`
+	FORM_DATE_LAYOUT       = "2006-01-02"
+	//max length of a text field without its own limit.
+	FORM_TEXT_MAX_LENGTH = 200
+	//max length of a long text field without its own limit.
+	FORM_LONG_TEXT_MAX_LENGTH = 5000
+)
+
+var FORM_FIELD_TYPES = []string{
+	FORM_FIELD_TYPE_TEXT,
+	FORM_FIELD_TYPE_LONG_TEXT,
+	FORM_FIELD_TYPE_SELECT,
+	FORM_FIELD_TYPE_NUMBER,
+	FORM_FIELD_TYPE_DATE,
+	FORM_FIELD_TYPE_FILE_SLOT,
+}
+
+// FormField is a field of the submission form of a track, eg. project abstract or advisor name.
+type FormField struct {
+	Id          int64  `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	TrackId     int64  `json:"trackId" gorm:"type:bigint(20) not null;index:idx_form_field_ti"`
+	Key         string `json:"key" gorm:"type:varchar(50) not null"`
+	Label       string `json:"label" gorm:"type:varchar(100) not null"`
+	Type        string `json:"type" gorm:"type:varchar(20) not null"`
+	Description string `json:"description" gorm:"type:text"`
+	Required    bool   `json:"required" gorm:"type:tinyint(1) not null;default:0"`
+	//comma separated choices of a select field, or allowed extensions of a file slot, eg. pdf,docx.
+	Options string `json:"options" gorm:"type:text"`
+	//max length of a text field. 0 means the default.
+	MaxLength int64 `json:"maxLength" gorm:"type:bigint(20) not null;default:0"`
+	//regular expression a text field must match.
+	Pattern string `json:"pattern" gorm:"type:varchar(255)"`
+	//bounds of a number or a date field. empty means unbounded.
+	Min        string    `json:"min" gorm:"type:varchar(20)"`
+	Max        string    `json:"max" gorm:"type:varchar(20)"`
+	Sort       int64     `json:"sort" gorm:"type:bigint(20) not null;default:0"`
+	CreateTime time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
+
+// choices of a select field, or allowed extensions of a file slot, trimmed and lower cased for extensions.
+func (this *FormField) OptionList() []string {
+	var options []string
+	for _, option := range strings.Split(this.Options, ",") {
+		option = strings.TrimSpace(option)
+		if this.Type == FORM_FIELD_TYPE_FILE_SLOT {
+			option = strings.ToLower(strings.TrimPrefix(option, "."))
+		}
+		if option != "" {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+// FormValue is the value a submission filled in a form field. Values are kept by field key,
+// so they survive the admin editing the form.
+type FormValue struct {
+	Id           int64     `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	SubmissionId int64     `json:"submissionId" gorm:"type:bigint(20) not null;index:idx_form_value_si"`
+	FieldKey     string    `json:"fieldKey" gorm:"type:varchar(50) not null"`
+	Value        string    `json:"value" gorm:"type:text"`
+	UpdateTime   time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
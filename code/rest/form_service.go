@@ -0,0 +1,298 @@
+package rest
+
+import (
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+// @Service
+type FormService struct {
+	BaseBean
+	formDao                 *FormDao
+	trackDao                *TrackDao
+	matterDao               *MatterDao
+	submissionService       *SubmissionService
+	submissionWindowService *SubmissionWindowService
+}
+
+func (this *FormService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.formDao)
+	if b, ok := b.(*FormDao); ok {
+		this.formDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.trackDao)
+	if b, ok := b.(*TrackDao); ok {
+		this.trackDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionService)
+	if b, ok := b.(*SubmissionService); ok {
+		this.submissionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionWindowService)
+	if b, ok := b.(*SubmissionWindowService); ok {
+		this.submissionWindowService = b
+	}
+}
+
+func (this *FormService) checkBound(field *FormField, bound string) *result.WebResult {
+	if bound == "" {
+		return nil
+	}
+	var err error
+	switch field.Type {
+	case FORM_FIELD_TYPE_NUMBER:
+		_, err = strconv.ParseFloat(bound, 64)
+	case FORM_FIELD_TYPE_DATE:
+		_, err = time.Parse(FORM_DATE_LAYOUT, bound)
+	default:
+		return result.BadRequest("字段 %s 不支持设置范围", field.Label)
+	}
+	if err != nil {
+		return result.BadRequest("字段 %s 的范围 %s 格式错误", field.Label, bound)
+	}
+	return nil
+}
+
+// replace the form of the track. values already filled are kept by field key.
+func (this *FormService) SaveFields(trackId int64, fields []*FormField) ([]*FormField, *result.WebResult) {
+	if this.trackDao.Find(trackId) == nil {
+		return nil, result.BadRequest("赛道不存在")
+	}
+
+	keys := make(map[string]bool)
+	for _, field := range fields {
+		field.Key = strings.TrimSpace(field.Key)
+		field.Label = strings.TrimSpace(field.Label)
+		if m, _ := regexp.MatchString(FORM_FIELD_KEY_PATTERN, field.Key); !m {
+			return nil, result.BadRequest("字段标识 %s 只能包含字母、数字和下划线，且以字母开头", field.Key)
+		}
+		if keys[field.Key] {
+			return nil, result.BadRequest("字段标识 %s 重复", field.Key)
+		}
+		keys[field.Key] = true
+		if field.Label == "" {
+			return nil, result.BadRequest("字段 %s 的名称不能为空", field.Key)
+		}
+
+		supported := false
+		for _, fieldType := range FORM_FIELD_TYPES {
+			supported = supported || field.Type == fieldType
+		}
+		if !supported {
+			return nil, result.BadRequest("字段 %s 的类型 %s 不支持", field.Label, field.Type)
+		}
+		if field.Type == FORM_FIELD_TYPE_SELECT && len(field.OptionList()) == 0 {
+			return nil, result.BadRequest("选择字段 %s 的选项不能为空", field.Label)
+		}
+		if field.MaxLength < 0 {
+			return nil, result.BadRequest("字段 %s 的最大长度不能为负数", field.Label)
+		}
+		if field.Pattern != "" {
+			if _, err := regexp.Compile(field.Pattern); err != nil {
+				return nil, result.BadRequest("字段 %s 的校验规则格式错误", field.Label)
+			}
+		}
+		if webResult := this.checkBound(field, field.Min); webResult != nil {
+			return nil, webResult
+		}
+		if webResult := this.checkBound(field, field.Max); webResult != nil {
+			return nil, webResult
+		}
+	}
+
+	this.formDao.DeleteFieldsByTrackId(trackId)
+	for i, field := range fields {
+		field.Id = 0
+		field.TrackId = trackId
+		field.Sort = int64(i)
+		this.formDao.CreateField(field)
+	}
+	return fields, nil
+}
+
+// check a text value against the length and the pattern of the field.
+func (this *FormService) validateText(field *FormField, value string, defaultMaxLength int64) *result.WebResult {
+	maxLength := field.MaxLength
+	if maxLength == 0 {
+		maxLength = defaultMaxLength
+	}
+	if int64(utf8.RuneCountInString(value)) > maxLength {
+		return result.BadRequest("%s 不能超过 %d 个字", field.Label, maxLength)
+	}
+	if field.Pattern != "" {
+		if m, _ := regexp.MatchString(field.Pattern, value); !m {
+			return result.BadRequest("%s 格式不正确", field.Label)
+		}
+	}
+	return nil
+}
+
+// the file must be in the submission folder, with an allowed extension.
+func (this *FormService) validateFile(field *FormField, submission *Submission, value string) *result.WebResult {
+	matter := this.matterDao.FindByUuid(value)
+	folder := this.matterDao.FindByUuid(submission.MatterUuid)
+	if matter == nil || matter.Dir || folder == nil || matter.SpaceUuid != folder.SpaceUuid || !strings.HasPrefix(matter.Path, folder.Path+"/") {
+		return result.BadRequest("%s 必须是作品文件夹中的文件", field.Label)
+	}
+
+	extensions := field.OptionList()
+	if len(extensions) == 0 {
+		return nil
+	}
+	extension := strings.ToLower(strings.TrimPrefix(path.Ext(matter.Name), "."))
+	for _, allowed := range extensions {
+		if extension == allowed {
+			return nil
+		}
+	}
+	return result.BadRequest("%s 只能是 %s 文件", field.Label, strings.Join(extensions, "、"))
+}
+
+// validate a non empty value of the field, and normalize it.
+func (this *FormService) Validate(field *FormField, submission *Submission, value string) (string, *result.WebResult) {
+	switch field.Type {
+	case FORM_FIELD_TYPE_TEXT:
+		value = strings.TrimSpace(value)
+		return value, this.validateText(field, value, FORM_TEXT_MAX_LENGTH)
+	case FORM_FIELD_TYPE_LONG_TEXT:
+		return value, this.validateText(field, value, FORM_LONG_TEXT_MAX_LENGTH)
+	case FORM_FIELD_TYPE_SELECT:
+		value = strings.TrimSpace(value)
+		for _, option := range field.OptionList() {
+			if value == option {
+				return value, nil
+			}
+		}
+		return "", result.BadRequest("%s 只能是 %s 之一", field.Label, strings.Join(field.OptionList(), "、"))
+	case FORM_FIELD_TYPE_NUMBER:
+		value = strings.TrimSpace(value)
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", result.BadRequest("%s 必须是数字", field.Label)
+		}
+		if min, err := strconv.ParseFloat(field.Min, 64); err == nil && number < min {
+			return "", result.BadRequest("%s 不能小于 %s", field.Label, field.Min)
+		}
+		if max, err := strconv.ParseFloat(field.Max, 64); err == nil && number > max {
+			return "", result.BadRequest("%s 不能大于 %s", field.Label, field.Max)
+		}
+		return value, nil
+	case FORM_FIELD_TYPE_DATE:
+		value = strings.TrimSpace(value)
+		date, err := time.Parse(FORM_DATE_LAYOUT, value)
+		if err != nil {
+			return "", result.BadRequest("%s 必须是 yyyy-MM-dd 格式的日期", field.Label)
+		}
+		if min, err := time.Parse(FORM_DATE_LAYOUT, field.Min); err == nil && date.Before(min) {
+			return "", result.BadRequest("%s 不能早于 %s", field.Label, field.Min)
+		}
+		if max, err := time.Parse(FORM_DATE_LAYOUT, field.Max); err == nil && date.After(max) {
+			return "", result.BadRequest("%s 不能晚于 %s", field.Label, field.Max)
+		}
+		return value, nil
+	case FORM_FIELD_TYPE_FILE_SLOT:
+		value = strings.TrimSpace(value)
+		return value, this.validateFile(field, submission, value)
+	}
+	return "", result.BadRequest("字段 %s 的类型 %s 不支持", field.Label, field.Type)
+}
+
+// values of the submission by field key.
+func (this *FormService) Values(submission *Submission) map[string]string {
+	values := make(map[string]string)
+	for _, value := range this.formDao.FindValuesBySubmissionId(submission.Id) {
+		values[value.FieldKey] = value.Value
+	}
+	return values
+}
+
+// labels of the required fields the submission has not filled.
+func (this *FormService) Missing(submission *Submission) []string {
+	missing := []string{}
+	values := this.Values(submission)
+	for _, field := range this.formDao.FindFieldsByTrackId(submission.TrackId) {
+		if field.Required && values[field.Key] == "" {
+			missing = append(missing, field.Label)
+		}
+	}
+	return missing
+}
+
+// validate the values against the form of the track, and merge them into the values filled. nothing is saved.
+// a submission not saved yet has no values filled.
+func (this *FormService) Merge(submission *Submission, values map[string]string) (map[string]string, *result.WebResult) {
+	fields := make(map[string]*FormField)
+	for _, field := range this.formDao.FindFieldsByTrackId(submission.TrackId) {
+		fields[field.Key] = field
+	}
+
+	merged := this.Values(submission)
+	for key, value := range values {
+		field := fields[key]
+		if field == nil {
+			return nil, result.BadRequest("赛道表单中没有字段 %s", key)
+		}
+		if strings.TrimSpace(value) != "" {
+			normalized, webResult := this.Validate(field, submission, value)
+			if webResult != nil {
+				return nil, webResult
+			}
+			value = normalized
+		} else {
+			value = ""
+		}
+		merged[key] = value
+	}
+	return merged, nil
+}
+
+// fill the form of the submission. keys not in values are left as they are, an empty value clears the field.
+// required fields may be filled step by step, they are checked when the submission is recommended.
+// students fill their own submissions within the submission window, administrators can always edit.
+func (this *FormService) Fill(request *http.Request, user *User, submission *Submission, values map[string]string) (map[string]string, *result.WebResult) {
+	if user.Role != USER_ROLE_ADMINISTRATOR {
+		if !this.submissionService.IsAuthor(user, submission) {
+			return nil, result.BadRequest("只能填写自己的作品信息")
+		}
+		this.submissionWindowService.CheckSubmission(request, submission)
+	}
+
+	merged, webResult := this.Merge(submission, values)
+	if webResult != nil {
+		return nil, webResult
+	}
+	for key := range values {
+		value := this.formDao.FindValue(submission.Id, key)
+		if merged[key] == "" {
+			if value != nil {
+				this.formDao.DeleteValue(value)
+			}
+			delete(merged, key)
+			continue
+		}
+		if value == nil {
+			value = &FormValue{SubmissionId: submission.Id, FieldKey: key}
+		}
+		value.Value = merged[key]
+		this.formDao.SaveValue(value)
+	}
+	return merged, nil
+}
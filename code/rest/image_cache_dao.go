@@ -2,8 +2,6 @@ package rest
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/eyebluecn/tank/code/core"
@@ -16,6 +14,16 @@ import (
 
 type ImageCacheDao struct {
 	BaseDao
+	storageService *StorageService
+}
+
+func (this *ImageCacheDao) Init() {
+	this.BaseDao.Init()
+
+	b := core.CONTEXT.GetBean(this.storageService)
+	if b, ok := b.(*StorageService); ok {
+		this.storageService = b
+	}
 }
 
 // find by uuid. if not found return nil.
@@ -138,18 +146,23 @@ func (this *ImageCacheDao) Save(imageCache *ImageCache) *ImageCache {
 
 func (this *ImageCacheDao) deleteFileAndDir(imageCache *ImageCache) {
 
-	filePath := GetSpaceCacheRootDir(imageCache.Username) + imageCache.Path
+	spaceName := imageCache.StorageSpaceName()
+	fileKey := imageCache.StorageKey()
+	driver := this.storageService.SpaceDriver(spaceName)
 
-	dirPath := filepath.Dir(filePath)
-
-	//delete file from disk.
-	err := os.Remove(filePath)
+	//delete file from the storage.
+	err := driver.Remove(fileKey)
 	if err != nil {
-		this.logger.Error(fmt.Sprintf("error while deleting %s from disk %s", filePath, err.Error()))
+		this.logger.Error(fmt.Sprintf("error while deleting %s from storage %s", fileKey, err.Error()))
 	}
 
-	//if this level is empty. Delete the directory
-	util.DeleteEmptyDirRecursive(dirPath)
+	//if this level is empty. Delete the directory, up to the cache root.
+	cacheRootKey := spaceName + "/" + MATTER_CACHE
+	for dirKey := util.GetDirOfPath(fileKey); len(dirKey) > len(cacheRootKey); dirKey = util.GetDirOfPath(dirKey) {
+		if driver.RemoveDir(dirKey) != nil {
+			break
+		}
+	}
 
 }
 
@@ -13,6 +13,7 @@ type ImageCache struct {
 	Name       string    `json:"name" gorm:"type:varchar(255) not null"`
 	UserUuid   string    `json:"userUuid" gorm:"type:char(36)"`
 	Username   string    `json:"username" gorm:"type:varchar(45) not null"`
+	SpaceName  string    `json:"spaceName" gorm:"type:varchar(100)"`
 	MatterUuid string    `json:"matterUuid" gorm:"type:char(36);index:idx_image_cache_mu"` //index should unique globally.
 	MatterName string    `json:"matterName" gorm:"type:varchar(255) not null"`
 	Mode       string    `json:"mode" gorm:"type:varchar(512)"`
@@ -22,7 +23,15 @@ type ImageCache struct {
 	Matter     *Matter   `json:"matter" gorm:"-"`
 }
 
-// get the absolute path. path in db means relative path.
-func (this *ImageCache) AbsolutePath() string {
-	return GetSpaceCacheRootDir(this.Username) + this.Path
+// the space whose storage keeps the cache. caches of the old days have no space name, they are in the space of the user.
+func (this *ImageCache) StorageSpaceName() string {
+	if this.SpaceName == "" {
+		return this.Username
+	}
+	return this.SpaceName
+}
+
+// get the key in the storage of the space.
+func (this *ImageCache) StorageKey() string {
+	return this.StorageSpaceName() + "/" + MATTER_CACHE + this.Path
 }
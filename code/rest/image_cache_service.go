@@ -2,6 +2,8 @@ package rest
 
 import (
 	"fmt"
+	"io"
+
 	"github.com/disintegration/imaging"
 	"github.com/eyebluecn/tank/code/core"
 	"github.com/eyebluecn/tank/code/tool/result"
@@ -9,7 +11,6 @@ import (
 	"image"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -17,9 +18,10 @@ import (
 // @Service
 type ImageCacheService struct {
 	BaseBean
-	imageCacheDao *ImageCacheDao
-	userDao       *UserDao
-	matterDao     *MatterDao
+	imageCacheDao  *ImageCacheDao
+	userDao        *UserDao
+	matterDao      *MatterDao
+	storageService *StorageService
 }
 
 func (this *ImageCacheService) Init() {
@@ -40,6 +42,11 @@ func (this *ImageCacheService) Init() {
 		this.matterDao = b
 	}
 
+	b = core.CONTEXT.GetBean(this.storageService)
+	if b, ok := b.(*StorageService); ok {
+		this.storageService = b
+	}
+
 }
 
 func (this *ImageCacheService) Detail(uuid string) *ImageCache {
@@ -93,14 +100,7 @@ func (this *ImageCacheService) ResizeParams(request *http.Request) (needProcess
 }
 
 // resize image.
-func (this *ImageCacheService) ResizeImage(request *http.Request, filePath string) *image.NRGBA {
-
-	diskFile, err := os.Open(filePath)
-	this.PanicError(err)
-	defer func() {
-		e := diskFile.Close()
-		this.PanicError(e)
-	}()
+func (this *ImageCacheService) ResizeImage(request *http.Request, diskFile io.Reader) *image.NRGBA {
 
 	_, imageResizeM, imageResizeW, imageResizeH := this.ResizeParams(request)
 
@@ -173,40 +173,47 @@ func (this *ImageCacheService) cacheImage(writer http.ResponseWriter, request *h
 
 	user := this.userDao.FindByUuid(matter.UserUuid)
 
-	dstImage := this.ResizeImage(request, matter.AbsolutePath())
+	diskFile, err := this.storageService.Open(matter.SpaceName, matter.StorageKey())
+	this.PanicError(err)
+	defer func() {
+		e := diskFile.Close()
+		this.PanicError(e)
+	}()
+
+	dstImage := this.ResizeImage(request, diskFile)
 
 	cacheImageName := util.GetSimpleFileName(matter.Name) + "_" + mode + extension
 	cacheImageRelativePath := util.GetSimpleFileName(matter.Path) + "_" + mode + extension
-	cacheImageAbsolutePath := GetSpaceCacheRootDir(user.Username) + util.GetSimpleFileName(matter.Path) + "_" + mode + extension
 
-	//create directory
-	dir := filepath.Dir(cacheImageAbsolutePath)
-	util.MakeDirAll(dir)
-
-	fileWriter, err := os.Create(cacheImageAbsolutePath)
-	this.PanicError(err)
+	fileWriter := this.storageService.CreateStagingFile()
 	defer func() {
-		e := fileWriter.Close()
-		this.PanicError(e)
+		_ = os.Remove(fileWriter.Name())
 	}()
 
-	//store on disk after handle
+	//store into the storage after handle
 	err = imaging.Encode(fileWriter, dstImage, format)
+	if err == nil {
+		err = fileWriter.Close()
+	} else {
+		_ = fileWriter.Close()
+	}
 	this.PanicError(err)
 
-	fileInfo, err := fileWriter.Stat()
+	fileInfo, err := os.Stat(fileWriter.Name())
 	this.PanicError(err)
 
 	imageCache := &ImageCache{
 		Name:       cacheImageName,
 		UserUuid:   matter.UserUuid,
 		Username:   user.Username,
+		SpaceName:  matter.SpaceName,
 		MatterUuid: matter.Uuid,
 		MatterName: matter.Name,
 		Mode:       mode,
 		Size:       fileInfo.Size(),
 		Path:       cacheImageRelativePath,
 	}
+	this.storageService.Put(imageCache.SpaceName, imageCache.StorageKey(), fileWriter.Name())
 	this.imageCacheDao.Create(imageCache)
 
 	return imageCache
@@ -76,21 +76,49 @@ func (this *InstallController) Init() {
 		&Footprint{},
 		&ImageCache{},
 		&Matter{},
+		&Blob{},
+		&Storage{},
+		&MatterVersion{},
 		&Preference{},
 		&Session{},
 		&Share{},
 		&Space{},
 		&SpaceMember{},
 		&UploadToken{},
+		&UploadSession{},
+		&UploadChunk{},
 		&User{},
 		&Label{},
 		&Labeled{},
 		&Group{},
 		&UserProfile{},
 		&College{},
+		&Competition{},
 		&Track{},
 		&Submission{},
 		&Rating{},
+		&Round{},
+		&RoundJudge{},
+		&RoundHistory{},
+		&LateGrant{},
+		&Snapshot{},
+		&SnapshotEntry{},
+		&Rubric{},
+		&RubricCriterion{},
+		&RatingItem{},
+		&Assignment{},
+		&JudgeConflict{},
+		&Team{},
+		&TeamMember{},
+		&FormField{},
+		&FormValue{},
+		&FileRule{},
+		&Appeal{},
+		&AppealHistory{},
+		&AuditLog{},
+		&RatingDraft{},
+		&CertificateTemplate{},
+		&Certificate{},
 	}
 
 }
@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+// late-submission grants made by administrators.
+type LateGrantController struct {
+	BaseController
+	lateGrantDao            *LateGrantDao
+	submissionWindowService *SubmissionWindowService
+	auditService            *AuditService
+}
+
+func (this *LateGrantController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.lateGrantDao)
+	if b, ok := b.(*LateGrantDao); ok {
+		this.lateGrantDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionWindowService)
+	if b, ok := b.(*SubmissionWindowService); ok {
+		this.submissionWindowService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.auditService)
+	if b, ok := b.(*AuditService); ok {
+		this.auditService = b
+	}
+}
+
+func (this *LateGrantController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/late-grant/create"] = this.Wrap(this.Create, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/late-grant/list"] = this.Wrap(this.List, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/late-grant/delete"] = this.Wrap(this.Delete, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+func (this *LateGrantController) Create(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	submissionId, err := strconv.ParseInt(request.FormValue("submissionId"), 10, 64)
+	if err != nil {
+		return result.BadRequest("submissionId格式错误")
+	}
+
+	local, _ := time.LoadLocation("Local")
+	expireTime, err := time.ParseInLocation("2006-01-02 15:04:05", request.FormValue("expireTime"), local)
+	if err != nil {
+		return result.BadRequest("延期截止时间格式错误")
+	}
+
+	lateGrant, webResult := this.submissionWindowService.Grant(submissionId, expireTime, request.FormValue("reason"), user)
+	if webResult != nil {
+		return webResult
+	}
+	this.auditService.Log(request, user, AUDIT_ACTION_LATE_GRANT_CREATE, AUDIT_TARGET_LATE_GRANT, lateGrant.Id, nil, lateGrant)
+	return this.Success(lateGrant)
+}
+
+// list grants. filter by submissionId if provided.
+func (this *LateGrantController) List(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	submissionIdStr := request.FormValue("submissionId")
+	if submissionIdStr == "" {
+		return this.Success(this.lateGrantDao.FindAll())
+	}
+
+	submissionId, err := strconv.ParseInt(submissionIdStr, 10, 64)
+	if err != nil {
+		return result.BadRequest("submissionId格式错误")
+	}
+	return this.Success(this.lateGrantDao.FindBySubmissionId(submissionId))
+}
+
+func (this *LateGrantController) Delete(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	id, err := strconv.ParseInt(request.FormValue("id"), 10, 64)
+	if err != nil {
+		return result.BadRequest("ID格式错误")
+	}
+
+	lateGrant := this.lateGrantDao.Find(id)
+	if lateGrant == nil {
+		return result.BadRequest("延期授权不存在")
+	}
+
+	this.lateGrantDao.Delete(lateGrant)
+	this.auditService.Log(request, this.findUser(request), AUDIT_ACTION_LATE_GRANT_DELETE, AUDIT_TARGET_LATE_GRANT, lateGrant.Id, lateGrant, nil)
+	return this.Success("删除成功")
+}
@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+type LateGrantDao struct {
+	BaseDao
+}
+
+func (this *LateGrantDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *LateGrantDao) Create(lateGrant *LateGrant) *LateGrant {
+	lateGrant.CreateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(lateGrant)
+	this.PanicError(db.Error)
+	return lateGrant
+}
+
+func (this *LateGrantDao) Find(id int64) *LateGrant {
+	var entity = &LateGrant{}
+	db := core.CONTEXT.GetDB().Where("id = ?", id).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+func (this *LateGrantDao) FindBySubmissionId(submissionId int64) []*LateGrant {
+	var entities []*LateGrant
+	db := core.CONTEXT.GetDB().Where("submission_id = ?", submissionId).Order("id DESC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+// whether the submission has a grant not expired at the moment.
+func (this *LateGrantDao) ExistValid(submissionId int64, now time.Time) bool {
+	var count int64
+	db := core.CONTEXT.GetDB().Model(&LateGrant{}).Where("submission_id = ? AND expire_time > ?", submissionId, now).Count(&count)
+	this.PanicError(db.Error)
+	return count > 0
+}
+
+func (this *LateGrantDao) FindAll() []*LateGrant {
+	var entities []*LateGrant
+	db := core.CONTEXT.GetDB().Order("id DESC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *LateGrantDao) Delete(lateGrant *LateGrant) {
+	db := core.CONTEXT.GetDB().Delete(lateGrant)
+	this.PanicError(db.Error)
+}
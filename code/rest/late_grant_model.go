@@ -0,0 +1,15 @@
+package rest
+
+import (
+	"time"
+)
+
+// LateGrant allows a submission to be changed after its window closed, until ExpireTime.
+type LateGrant struct {
+	Id           int64     `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	SubmissionId int64     `json:"submissionId" gorm:"type:bigint(20) not null;index:idx_late_grant_si"`
+	ExpireTime   time.Time `json:"expireTime" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"`
+	Reason       string    `json:"reason" gorm:"type:varchar(1024)"`
+	OperatorUuid string    `json:"operatorUuid" gorm:"type:char(36)"`
+	CreateTime   time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
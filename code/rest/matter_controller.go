@@ -6,6 +6,7 @@ import (
 	"github.com/eyebluecn/tank/code/tool/i18n"
 	"github.com/eyebluecn/tank/code/tool/result"
 	"github.com/eyebluecn/tank/code/tool/util"
+	jsoniter "github.com/json-iterator/go"
 	"net/http"
 	"sort"
 	"strings"
@@ -13,20 +14,25 @@ import (
 
 type MatterController struct {
 	BaseController
-	matterDao         *MatterDao
-	matterService     *MatterService
-	preferenceService *PreferenceService
-	downloadTokenDao  *DownloadTokenDao
-	imageCacheDao     *ImageCacheDao
-	shareDao          *ShareDao
-	spaceDao          *SpaceDao
-	shareService      *ShareService
-	bridgeDao         *BridgeDao
-	imageCacheService *ImageCacheService
-	userProfileDao    *UserProfileDao
-	submissionDao     *SubmissionDao
-	collegeDao        *CollegeDao
-	trackDao          *TrackDao
+	matterDao          *MatterDao
+	matterService      *MatterService
+	preferenceService  *PreferenceService
+	downloadTokenDao   *DownloadTokenDao
+	imageCacheDao      *ImageCacheDao
+	shareDao           *ShareDao
+	spaceDao           *SpaceDao
+	shareService       *ShareService
+	bridgeDao          *BridgeDao
+	imageCacheService  *ImageCacheService
+	userProfileDao     *UserProfileDao
+	submissionDao      *SubmissionDao
+	collegeDao         *CollegeDao
+	trackDao           *TrackDao
+	teamService        *TeamService
+	formService        *FormService
+	blindService       *BlindService
+	auditService       *AuditService
+	competitionService *CompetitionService
 }
 
 func (this *MatterController) Init() {
@@ -96,6 +102,31 @@ func (this *MatterController) Init() {
 	if b, ok := b.(*TrackDao); ok {
 		this.trackDao = b
 	}
+
+	b = core.CONTEXT.GetBean(this.teamService)
+	if b, ok := b.(*TeamService); ok {
+		this.teamService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.formService)
+	if b, ok := b.(*FormService); ok {
+		this.formService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.blindService)
+	if b, ok := b.(*BlindService); ok {
+		this.blindService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.auditService)
+	if b, ok := b.(*AuditService); ok {
+		this.auditService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.competitionService)
+	if b, ok := b.(*CompetitionService); ok {
+		this.competitionService = b
+	}
 }
 
 func (this *MatterController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
@@ -107,6 +138,7 @@ func (this *MatterController) RegisterRoutes() map[string]func(writer http.Respo
 
 	routeMap["/api/matter/create/directory"] = this.Wrap(this.CreateDirectory, USER_ROLE_USER)
 	routeMap["/api/matter/upload"] = this.Wrap(this.Upload, USER_ROLE_USER)
+	routeMap["/api/matter/instant/upload"] = this.Wrap(this.InstantUpload, USER_ROLE_USER)
 	routeMap["/api/matter/crawl"] = this.Wrap(this.Crawl, USER_ROLE_USER)
 	routeMap["/api/matter/soft/delete"] = this.Wrap(this.SoftDelete, USER_ROLE_USER)
 	routeMap["/api/matter/soft/delete/batch"] = this.Wrap(this.SoftDeleteBatch, USER_ROLE_USER)
@@ -150,7 +182,7 @@ func (this *MatterController) Detail(writer http.ResponseWriter, request *http.R
 		matter.User = this.userDao.FindByUuid(matter.UserUuid)
 	}
 
-	return this.Success(matter)
+	return this.Success(this.blindService.MaskMatter(user, matter.ShowHash(user)))
 
 }
 
@@ -206,6 +238,13 @@ func (this *MatterController) Page(writer http.ResponseWriter, request *http.Req
 		user.Uuid,
 	)
 
+	if matters, ok := pager.Data.([]*Matter); ok {
+		for _, matter := range matters {
+			matter.ShowHash(user)
+		}
+		pager.Data = this.blindService.MaskMatters(user, matters)
+	}
+
 	return this.Success(pager)
 }
 
@@ -257,7 +296,11 @@ func (this *MatterController) Search(writer http.ResponseWriter, request *http.R
 		}
 	})
 
-	return this.Success(matters)
+	for _, matter := range matters {
+		matter.ShowHash(user)
+	}
+
+	return this.Success(this.blindService.MaskMatters(user, matters))
 }
 
 func (this *MatterController) CreateDirectory(writer http.ResponseWriter, request *http.Request) *result.WebResult {
@@ -293,7 +336,34 @@ func (this *MatterController) CreateDirectory(writer http.ResponseWriter, reques
 		}
 	}
 
-	fmt.Println(">>>>>> %v %v %v ", name, trackIdStr, workName);
+	// 提交到赛道时，赛道所在赛事须未归档且向用户所在学院开放
+	var track *Track
+	if user.Role == USER_ROLE_USER && trackId > 0 && workName != "" && isRootDirectory {
+		track = this.trackDao.Find(trackId)
+		if track == nil {
+			return result.BadRequest("赛道不存在")
+		}
+		var collegeId int64 = 0
+		if userProfile := this.userProfileDao.FindByUserUuid(user.Uuid); userProfile != nil && userProfile.College != "" {
+			if college := this.collegeDao.FindByName(userProfile.College); college != nil {
+				collegeId = college.Id
+			}
+		}
+		if webResult := this.competitionService.CheckSubmittable(track, collegeId); webResult != nil {
+			return webResult
+		}
+	}
+
+	// 作品信息表单须在创建文件夹前校验，校验失败时不留下文件夹和提交记录
+	var formValues map[string]string
+	if track != nil && request.FormValue("formValues") != "" {
+		if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(request.FormValue("formValues")), &formValues); err != nil {
+			return result.BadRequest("表单数据格式错误")
+		}
+		if _, webResult := this.formService.Merge(&Submission{TrackId: trackId}, formValues); webResult != nil {
+			return webResult
+		}
+	}
 
 	matter := this.matterService.AtomicCreateDirectory(request, dirMatter, name, user, space)
 	
@@ -316,6 +386,7 @@ func (this *MatterController) CreateDirectory(writer http.ResponseWriter, reques
 			if submission == nil {
 				submission = &Submission{
 					MatterUuid: matter.Uuid,
+					CompetitionId: track.CompetitionId,
 					TrackId:     trackId,
 					CollegeId:   collegeId,
 					Title:       workName,
@@ -327,11 +398,25 @@ func (this *MatterController) CreateDirectory(writer http.ResponseWriter, reques
 				}
 			} else {
 				// 更新现有提交
+				submission.CompetitionId = track.CompetitionId
 				submission.TrackId = trackId
+				submission.CollegeId = collegeId
 				submission.Title = workName
 				submission.UpdateTime = matter.UpdateTime
 			}
+			// 团队文件夹中的作品以队长为作者
+			if team := this.teamService.FindBySpaceUuid(space.Uuid); team != nil {
+				this.teamService.Bind(submission, team)
+			}
 			this.submissionDao.Save(submission)
+
+			// 创建时可以一并填写赛道的作品信息表单，保存失败时删除刚创建的文件夹及其提交记录
+			if formValues != nil {
+				if _, webResult := this.formService.Fill(request, user, submission, formValues); webResult != nil {
+					this.matterService.AtomicDelete(request, matter, user, space)
+					return webResult
+				}
+			}
 		}
 	}
 	
@@ -341,6 +426,7 @@ func (this *MatterController) CreateDirectory(writer http.ResponseWriter, reques
 func (this *MatterController) Upload(writer http.ResponseWriter, request *http.Request) *result.WebResult {
 	puuid := util.ExtractRequestString(request, "puuid")
 	privacy := util.ExtractRequestOptionalBool(request, "privacy", true)
+	overwrite := util.ExtractRequestOptionalBool(request, "overwrite", false)
 	trackIdStr := util.ExtractRequestOptionalString(request, "trackId", "")
 	workName := util.ExtractRequestOptionalString(request, "workName", "")
 
@@ -370,32 +456,49 @@ func (this *MatterController) Upload(writer http.ResponseWriter, request *http.R
 	}
 
 	// 如果提供了赛道和作品名，则重命名文件为 赛道-作品名-学号 格式
-	if trackIdStr != "" && workName != "" && user.Role == USER_ROLE_USER {
-		trackId := util.ExtractRequestInt64(request, "trackId")
-		
-		// 获取用户档案信息
-		userProfile := this.userProfileDao.FindByUserUuid(user.Uuid)
-		if userProfile != nil && userProfile.StudentId != "" {
-			// 获取赛道名称
-			track := this.trackDao.Find(trackId)
-			if track != nil {
-				// 构建新文件名: 赛道-作品名-学号.扩展名
-				extension := ""
-				if dotIndex := strings.LastIndex(fileName, "."); dotIndex != -1 {
-					extension = fileName[dotIndex:]
-					fileName = fileName[:dotIndex]
-				}
-				newFileName := fmt.Sprintf("%s-%s-%s%s", track.Name, workName, userProfile.StudentId, extension)
-				fileName = newFileName
-			}
-		}
+	if trackIdStr != "" && workName != "" {
+		fileName = this.matterService.SubmissionFilename(user, util.ExtractRequestInt64(request, "trackId"), workName, fileName)
 	}
 
 	dirMatter := this.matterDao.CheckWithRootByUuid(puuid, space)
 
+	//a file of the name is overwritten and kept as a version.
+	if overwrite {
+		return this.Success(this.matterService.AtomicPut(request, file, user, space, dirMatter, fileName, privacy).ShowHash(user))
+	}
+
 	//support upload simultaneously
 	matter := this.matterService.Upload(request, file, handler, user, space, dirMatter, fileName, privacy)
 
+	return this.Success(matter.ShowHash(user))
+}
+
+// create the file from the same content stored before, without receiving it. the content must be of a file in a space
+// the user can read, so that the hashes alone never give away a file of others.
+// data is null if there is no such content, then the file should be uploaded as usual.
+func (this *MatterController) InstantUpload(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	puuid := util.ExtractRequestString(request, "puuid")
+	filename := util.ExtractRequestString(request, "filename")
+	size := util.ExtractRequestInt64(request, "size")
+	sha256 := util.ExtractRequestString(request, "sha256")
+	md5 := util.ExtractRequestString(request, "md5")
+	privacy := util.ExtractRequestOptionalBool(request, "privacy", true)
+	trackId := util.ExtractRequestOptionalInt64(request, "trackId", 0)
+	workName := util.ExtractRequestOptionalString(request, "workName", "")
+
+	user := this.checkUser(request)
+	spaceUuid := util.ExtractRequestOptionalString(request, "spaceUuid", user.SpaceUuid)
+	space := this.spaceService.CheckWritableByUuid(request, user, spaceUuid)
+
+	filename = this.matterService.SubmissionFilename(user, trackId, workName, filename)
+
+	dirMatter := this.matterDao.CheckWithRootByUuid(puuid, space)
+
+	matter := this.matterService.InstantUpload(request, user, space, dirMatter, filename, size, sha256, md5, privacy)
+	if matter != nil {
+		matter.ShowHash(user)
+	}
+
 	return this.Success(matter)
 }
 
@@ -433,7 +536,7 @@ func (this *MatterController) Crawl(writer http.ResponseWriter, request *http.Re
 
 	matter := this.matterService.AtomicCrawl(request, url, filename, user, space, dirMatter, true)
 
-	return this.Success(matter)
+	return this.Success(matter.ShowHash(user))
 }
 
 // soft delete.
@@ -464,7 +567,9 @@ func (this *MatterController) AddLabel(writer http.ResponseWriter, request *http
 	if !matter.Dir {
 		panic(result.BadRequest("can't attach label on file"))
 	}
+	before := this.matterDao.FindLabeled(labelName, target)
 	this.matterService.AddLabel(labelName, target, userUuid, int(value))
+	this.auditService.Log(request, this.findUser(request), AUDIT_ACTION_LABEL_ADD, AUDIT_TARGET_MATTER, target, before, &Labeled{Name: labelName, Target: target, Value: int(value)})
 	return this.Success("OK")
 }
 
@@ -475,7 +580,11 @@ func (this *MatterController) DeleteLabel(writer http.ResponseWriter, request *h
 	if !matter.Dir {
 		panic(result.BadRequest("can't attach label on file"))
 	}
+	before := this.matterDao.FindLabeled(name, target)
 	this.matterDao.DeleteLabel(name, target)
+	if before != nil {
+		this.auditService.Log(request, this.findUser(request), AUDIT_ACTION_LABEL_DELETE, AUDIT_TARGET_MATTER, target, before, nil)
+	}
 	return this.Success("OK")
 }
 
@@ -647,7 +756,7 @@ func (this *MatterController) Rename(writer http.ResponseWriter, request *http.R
 
 	this.matterService.AtomicRename(request, matter, name, false, user, space)
 
-	return this.Success(matter)
+	return this.Success(matter.ShowHash(user))
 }
 
 func (this *MatterController) ChangePrivacy(writer http.ResponseWriter, request *http.Request) *result.WebResult {
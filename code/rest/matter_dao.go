@@ -9,15 +9,17 @@ import (
 	"github.com/eyebluecn/tank/code/core"
 	"github.com/eyebluecn/tank/code/tool/builder"
 	"github.com/eyebluecn/tank/code/tool/result"
-	"github.com/eyebluecn/tank/code/tool/util"
 	"github.com/eyebluecn/tank/code/tool/uuid"
 	"gorm.io/gorm"
 )
 
 type MatterDao struct {
 	BaseDao
-	imageCacheDao *ImageCacheDao
-	bridgeDao     *BridgeDao
+	imageCacheDao    *ImageCacheDao
+	bridgeDao        *BridgeDao
+	blobService      *BlobService
+	matterVersionDao *MatterVersionDao
+	storageService   *StorageService
 }
 
 func (this *MatterDao) Init() {
@@ -33,6 +35,21 @@ func (this *MatterDao) Init() {
 		this.bridgeDao = b
 	}
 
+	b = core.CONTEXT.GetBean(this.blobService)
+	if b, ok := b.(*BlobService); ok {
+		this.blobService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterVersionDao)
+	if b, ok := b.(*MatterVersionDao); ok {
+		this.matterVersionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.storageService)
+	if b, ok := b.(*StorageService); ok {
+		this.storageService = b
+	}
+
 }
 
 func (this *MatterDao) FindByUuid(uuid string) *Matter {
@@ -107,6 +124,16 @@ func (this *MatterDao) GetMatterLabel(uuid string) []Label {
 	return res
 }
 
+// the label attached to the target, nil if there is none.
+func (this *MatterDao) FindLabeled(name, target string) *Labeled {
+	var labeled = &Labeled{}
+	db := core.CONTEXT.GetDB().Where("name = ? AND target = ?", name, target).First(labeled)
+	if db.Error != nil {
+		return nil
+	}
+	return labeled
+}
+
 func (this *MatterDao) DeleteLabel(name, target string) {
 	core.CONTEXT.GetDB().Where("name = ? AND target = ?", name, target).Delete(&Labeled{})
 }
@@ -382,6 +409,30 @@ func (this *MatterDao) FindByPuuidAndUserUuidAndDeleted(puuid string, userUuid s
 	return matters
 }
 
+// not deleted children of a directory in the space, whoever uploaded them.
+func (this *MatterDao) FindByPuuidAndSpaceUuid(puuid string, spaceUuid string) []*Matter {
+	var matters []*Matter
+
+	db := core.CONTEXT.GetDB().Where("puuid = ? AND space_uuid = ? AND deleted = 0", puuid, spaceUuid).Order("dir DESC, name ASC").Find(&matters)
+	this.PanicError(db.Error)
+
+	return matters
+}
+
+// the not deleted matter at the path of the space, whoever uploaded it. if not found, return nil
+func (this *MatterDao) FindBySpaceUuidAndPath(spaceUuid string, path string) *Matter {
+	var matter = &Matter{}
+	db := core.CONTEXT.GetDB().Where("space_uuid = ? AND path = ? AND deleted = 0", spaceUuid, path).First(matter)
+	if db.Error != nil {
+		if db.Error.Error() == result.DB_ERROR_NOT_FOUND {
+			return nil
+		} else {
+			this.PanicError(db.Error)
+		}
+	}
+	return matter
+}
+
 func (this *MatterDao) FindByUuids(uuids []string, sortArray []builder.OrderPair) []*Matter {
 	var matters []*Matter
 
@@ -680,6 +731,44 @@ func (this *MatterDao) Save(matter *Matter) *Matter {
 	return matter
 }
 
+// uuids of the spaces having files of the blob, including those in the recycle bin.
+func (this *MatterDao) FindSpaceUuidsBySha256(sha256 string) []string {
+	var spaceUuids []string
+	db := core.CONTEXT.GetDB().Model(&Matter{}).Where("sha256 = ?", sha256).Distinct().Pluck("space_uuid", &spaceUuids)
+	this.PanicError(db.Error)
+	return spaceUuids
+}
+
+// record the hashes of the file and the blob it shares its bytes with.
+func (this *MatterDao) UpdateHash(matter *Matter) {
+	db := core.CONTEXT.GetDB().Model(&Matter{}).Where("uuid = ?", matter.Uuid).Updates(map[string]any{"md5": matter.Md5, "sha256": matter.Sha256})
+	this.PanicError(db.Error)
+}
+
+// files not in the blob store, including those in the recycle bin. ordered by uuid, start after afterUuid.
+func (this *MatterDao) FindFilesWithoutBlob(afterUuid string, limit int) []*Matter {
+	var matters []*Matter
+	db := core.CONTEXT.GetDB().
+		Where("dir = ? AND (sha256 IS NULL OR sha256 = '') AND uuid > ?", false, afterUuid).
+		Order("uuid ASC").
+		Limit(limit).
+		Find(&matters)
+	this.PanicError(db.Error)
+	return matters
+}
+
+// matters of the space, including those in the recycle bin. ordered by uuid, start after afterUuid.
+func (this *MatterDao) FindBySpaceUuidAfter(spaceUuid string, afterUuid string, limit int) []*Matter {
+	var matters []*Matter
+	db := core.CONTEXT.GetDB().
+		Where("space_uuid = ? AND uuid > ?", spaceUuid, afterUuid).
+		Order("uuid ASC").
+		Limit(limit).
+		Find(&matters)
+	this.PanicError(db.Error)
+	return matters
+}
+
 // download time add 1
 func (this *MatterDao) TimesIncrement(matterUuid string) {
 	db := core.CONTEXT.GetDB().Model(&Matter{}).Where("uuid = ?", matterUuid).Updates(map[string]any{"times": gorm.Expr("times + 1"), "visit_time": time.Now()})
@@ -740,10 +829,8 @@ func (this *MatterDao) Delete(matter *Matter) {
 		//delete from db.
 		db := core.CONTEXT.GetDB().Delete(&matter)
 		this.PanicError(db.Error)
-		if util.PathExists(matter.AbsolutePath()) {
-			//delete dir from disk.
-			util.DeleteEmptyDir(matter.AbsolutePath())
-		}
+		//delete dir from the storage.
+		this.storageService.RemoveDir(matter.SpaceName, matter.StorageKey())
 
 	} else {
 
@@ -757,11 +844,14 @@ func (this *MatterDao) Delete(matter *Matter) {
 		//delete all the share.
 		this.bridgeDao.DeleteByMatterUuid(matter.Uuid)
 
-		//delete from disk.
-		err := os.Remove(matter.AbsolutePath())
-		if err != nil {
-			this.logger.Error("occur error when deleting file. %v", err)
-		}
+		//delete from the storage.
+		this.storageService.Remove(matter.SpaceName, matter.StorageKey())
+
+		//delete its versions.
+		this.matterVersionDao.DeleteByMatter(matter)
+
+		//the blob is removed when no matter references it.
+		this.blobService.Release(matter.Sha256)
 
 	}
 }
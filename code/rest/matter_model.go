@@ -24,6 +24,8 @@ const (
 	MATTER_NAME_MAX_DEPTH  = 32
 	//matter name pattern
 	MATTER_NAME_PATTERN = `[\\/:*?"<>|]`
+	//submission snapshot directory. space name cannot start with dot, so it never conflicts with spaces.
+	MATTER_SNAPSHOT = ".snapshot"
 )
 
 /**
@@ -36,11 +38,13 @@ type Matter struct {
 	CreateTime time.Time `json:"createTime" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"`
 	Puuid      string    `json:"puuid" gorm:"type:char(36);index:idx_matter_puuid"` //index should unique globally for sqlite.
 	UserUuid   string    `json:"userUuid" gorm:"type:char(36);index:idx_matter_uu"`
+	EditorUuid string    `json:"editorUuid" gorm:"type:char(36)"` //the user who wrote the current content. the owner if empty.
 	//TODO: check field usage.
 	SpaceName  string    `json:"space_name" gorm:"type:varchar(45) not null"`
 	Dir        bool      `json:"dir" gorm:"type:tinyint(1) not null;default:0"`
 	Name       string    `json:"name" gorm:"type:varchar(255) not null"`
-	Md5        string    `json:"md5" gorm:"type:varchar(45)"`
+	Md5        string    `json:"-" gorm:"type:varchar(45)"`
+	Sha256     string    `json:"-" gorm:"type:char(64);index:idx_matter_sha256"` //the blob sharing its bytes. empty if not in the blob store.
 	Size       int64     `json:"size" gorm:"type:bigint(20) not null;default:0"`
 	Privacy    bool      `json:"privacy" gorm:"type:tinyint(1) not null;default:0"`
 	Path       string    `json:"path" gorm:"type:varchar(1024)"`
@@ -53,6 +57,18 @@ type Matter struct {
 	User       *User     `json:"user" gorm:"-"`
 	Parent     *Matter   `json:"parent" gorm:"-"`
 	Children   []*Matter `json:"-" gorm:"-"`
+	//the hashes identify the content across the spaces, so they are shown to the owner only. see ShowHash.
+	OwnerMd5    string `json:"md5,omitempty" gorm:"-"`
+	OwnerSha256 string `json:"sha256,omitempty" gorm:"-"`
+}
+
+// show the hashes of the content if the user owns the matter.
+func (this *Matter) ShowHash(user *User) *Matter {
+	if user != nil && this.UserUuid == user.Uuid {
+		this.OwnerMd5 = this.Md5
+		this.OwnerSha256 = this.Sha256
+	}
+	return this
 }
 
 // get matter's absolute path. the Path property is relative path in db.
@@ -60,6 +76,11 @@ func (this *Matter) AbsolutePath() string {
 	return GetSpaceMatterRootDir(this.SpaceName) + this.Path
 }
 
+// get matter's key in the storage of its space.
+func (this *Matter) StorageKey() string {
+	return fmt.Sprintf("%s/%s%s", this.SpaceName, MATTER_ROOT, this.Path)
+}
+
 func (this *Matter) MimeType() string {
 	return util.GetMimeType(util.GetExtension(this.Name))
 }
@@ -112,6 +133,14 @@ func GetSpaceZipRootDir(spaceName string) (rootDirPath string) {
 	return rootDirPath
 }
 
+// get submission snapshot absolute path. the zips of snapshots are made under it.
+func GetSnapshotRootDir() (rootDirPath string) {
+
+	rootDirPath = fmt.Sprintf("%s/%s", core.CONFIG.MatterPath(), MATTER_SNAPSHOT)
+
+	return rootDirPath
+}
+
 // check matter's name. If error, panic.
 func CheckMatterName(request *http.Request, name string) string {
 
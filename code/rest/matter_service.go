@@ -2,7 +2,6 @@ package rest
 
 import (
 	"archive/zip"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,7 +9,6 @@ import (
 	"net/http"
 	"os"
 	"path"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -20,6 +18,7 @@ import (
 	"github.com/eyebluecn/tank/code/tool/download"
 	"github.com/eyebluecn/tank/code/tool/i18n"
 	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/storage"
 	"github.com/eyebluecn/tank/code/tool/util"
 )
 
@@ -38,6 +37,15 @@ type MatterService struct {
 	preferenceService *PreferenceService
 	submissionDao     *SubmissionDao
 	userProfileDao    *UserProfileDao
+	trackDao          *TrackDao
+	teamService       *TeamService
+	blobService       *BlobService
+	storageService    *StorageService
+	spaceService      *SpaceService
+
+	submissionWindowService *SubmissionWindowService
+	matterVersionService    *MatterVersionService
+	matterVersionDao        *MatterVersionDao
 }
 
 func (this *MatterService) Init() {
@@ -88,6 +96,45 @@ func (this *MatterService) Init() {
 		this.userProfileDao = b
 	}
 
+	b = core.CONTEXT.GetBean(this.trackDao)
+	if b, ok := b.(*TrackDao); ok {
+		this.trackDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.teamService)
+	if b, ok := b.(*TeamService); ok {
+		this.teamService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.blobService)
+	if b, ok := b.(*BlobService); ok {
+		this.blobService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.storageService)
+	if b, ok := b.(*StorageService); ok {
+		this.storageService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterVersionService)
+	if b, ok := b.(*MatterVersionService); ok {
+		this.matterVersionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterVersionDao)
+	if b, ok := b.(*MatterVersionDao); ok {
+		this.matterVersionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionWindowService)
+	if b, ok := b.(*SubmissionWindowService); ok {
+		this.submissionWindowService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceService)
+	if b, ok := b.(*SpaceService); ok {
+		this.spaceService = b
+	}
 }
 
 // get the page of matters.
@@ -228,11 +275,10 @@ func (this *MatterService) DfsSearch(
 func (this *MatterService) DownloadFile(
 	writer http.ResponseWriter,
 	request *http.Request,
-	filePath string,
-	filename string,
+	matter *Matter,
 	withContentDisposition bool) {
 
-	download.DownloadFile(writer, request, filePath, filename, withContentDisposition)
+	this.storageService.Download(writer, request, matter.SpaceName, matter.StorageKey(), matter.Name, withContentDisposition)
 }
 
 func (this *MatterService) AddLabel(labelname, target, userUuid string, value int) {
@@ -246,6 +292,18 @@ func (this *MatterService) DownloadZip(
 	request *http.Request,
 	matters []*Matter) {
 
+	this.DownloadZipAs(writer, request, matters, func(matter *Matter) string {
+		return matter.Name
+	})
+}
+
+// download zip, naming the entries by nameFunc. eg. judges of a blind round see the blind names.
+func (this *MatterService) DownloadZipAs(
+	writer http.ResponseWriter,
+	request *http.Request,
+	matters []*Matter,
+	nameFunc func(matter *Matter) string) {
+
 	if matters == nil || len(matters) == 0 {
 		panic(result.BadRequest("matters cannot be nil."))
 	}
@@ -260,6 +318,26 @@ func (this *MatterService) DownloadZip(
 		}
 	}
 
+	destZipName := fmt.Sprintf("%s.zip", nameFunc(matters[0]))
+	if len(matters) > 1 || !matters[0].Dir {
+		destZipName = "archive.zip"
+	}
+
+	this.DownloadZipOf(writer, request, matters, destZipName, nameFunc)
+}
+
+// download matters from any folders or spaces in one zip. nameFunc must give every matter a different name.
+func (this *MatterService) DownloadZipOf(
+	writer http.ResponseWriter,
+	request *http.Request,
+	matters []*Matter,
+	destZipName string,
+	nameFunc func(matter *Matter) string) {
+
+	if matters == nil || len(matters) == 0 {
+		panic(result.BadRequest("matters cannot be nil."))
+	}
+
 	preference := this.preferenceService.Fetch()
 
 	//count the num of files will be downloaded.
@@ -290,14 +368,9 @@ func (this *MatterService) DownloadZip(
 	destZipDirPath := fmt.Sprintf("%s/%d", GetSpaceZipRootDir(matters[0].SpaceName), time.Now().UnixNano()/1e6)
 	util.MakeDirAll(destZipDirPath)
 
-	destZipName := fmt.Sprintf("%s.zip", matters[0].Name)
-	if len(matters) > 1 || !matters[0].Dir {
-		destZipName = "archive.zip"
-	}
-
 	destZipPath := fmt.Sprintf("%s/%s", destZipDirPath, destZipName)
 
-	this.zipMatters(request, matters, destZipPath)
+	this.zipMatters(request, matters, destZipPath, nameFunc)
 
 	download.DownloadFile(writer, request, destZipPath, destZipName, true)
 
@@ -311,27 +384,15 @@ func (this *MatterService) DownloadZip(
 }
 
 // zip matters.
-func (this *MatterService) zipMatters(request *http.Request, matters []*Matter, destPath string) {
+func (this *MatterService) zipMatters(request *http.Request, matters []*Matter, destPath string, nameFunc func(matter *Matter) string) {
 
 	if util.PathExists(destPath) {
 		panic(result.BadRequest("%s exists", destPath))
 	}
 
-	//matters must have the same puuid.
 	if matters == nil || len(matters) == 0 {
 		panic(result.BadRequest("matters cannot be nil."))
 	}
-	spaceUuid := matters[0].SpaceUuid
-	puuid := matters[0].Puuid
-	baseDirPath := util.GetDirOfPath(matters[0].AbsolutePath()) + "/"
-
-	for _, m := range matters {
-		if m.SpaceUuid != spaceUuid {
-			panic(result.BadRequest("spaceUuid not same"))
-		} else if m.Puuid != puuid {
-			panic(result.BadRequest("puuid not same"))
-		}
-	}
 
 	//wrap children for every matter.
 	for _, m := range matters {
@@ -354,20 +415,29 @@ func (this *MatterService) zipMatters(request *http.Request, matters []*Matter,
 	}()
 
 	//DFS algorithm
-	var walkFunc func(matter *Matter)
-	walkFunc = func(matter *Matter) {
-
-		path := matter.AbsolutePath()
+	var walkFunc func(matter *Matter, prefix string)
+	walkFunc = func(matter *Matter, prefix string) {
 
-		fileInfo, err := os.Stat(path)
-		this.PanicError(err)
+		// directories may not be in the storage, eg. an empty one of s3.
+		var fileToBeZip storage.File
+		var fileInfo os.FileInfo = storage.NewFileInfo(matter.Name, 0, matter.UpdateTime, true)
+		if !matter.Dir {
+			var err error
+			fileToBeZip, err = this.storageService.Open(matter.SpaceName, matter.StorageKey())
+			this.PanicError(err)
+			defer func() {
+				err := fileToBeZip.Close()
+				this.PanicError(err)
+			}()
+			fileInfo = fileToBeZip.Info()
+		}
 
 		// Create file info.
 		fileHeader, err := zip.FileInfoHeader(fileInfo)
 		this.PanicError(err)
 
-		// Trim the baseDirPath
-		fileHeader.Name = strings.TrimPrefix(path, baseDirPath)
+		// the path relative to the zipped matters.
+		fileHeader.Name = prefix + nameFunc(matter)
 
 		// directory has prefix /
 		if matter.Dir {
@@ -378,14 +448,7 @@ func (this *MatterService) zipMatters(request *http.Request, matters []*Matter,
 		this.PanicError(err)
 
 		// only regular file has things to write.
-		if fileHeader.Mode().IsRegular() {
-
-			fileToBeZip, err := os.Open(path)
-			defer func() {
-				err = fileToBeZip.Close()
-				this.PanicError(err)
-			}()
-			this.PanicError(err)
+		if fileToBeZip != nil {
 
 			_, err = io.Copy(writer, fileToBeZip)
 			this.PanicError(err)
@@ -394,12 +457,12 @@ func (this *MatterService) zipMatters(request *http.Request, matters []*Matter,
 
 		//dfs.
 		for _, m := range matter.Children {
-			walkFunc(m)
+			walkFunc(m, fileHeader.Name)
 		}
 	}
 
 	for _, m := range matters {
-		walkFunc(m)
+		walkFunc(m, "")
 	}
 }
 
@@ -410,26 +473,11 @@ func (this *MatterService) Delete(request *http.Request, matter *Matter, user *U
 		panic(result.BadRequest("matter cannot be nil"))
 	}
 
-	data, err := json.Marshal(matter)
-	if err != nil {
-		this.logger.Error("Failed to marshal matter: %v", err)
-	} else {
-		fmt.Printf("%v", string(data))
-	}
-	// 删除对应的提交记录（如果是文件夹）
+	// 删除对应的提交记录（如果是文件夹），只删除绑定在该文件夹上的，与软删除一致
 	if matter.Dir {
-		userProfile := this.userProfileDao.FindByUserUuid(user.Uuid)
-		if userProfile != nil {
-			submissions := this.submissionDao.FindByAuthorId(userProfile.StudentId)
-			data, err := json.Marshal(submissions)
-			if err != nil {
-				this.logger.Error("Failed to marshal submissions: %v", err)
-			} else {
-				fmt.Printf("%v", string(data))
-			}
-			for _, s := range submissions {
-				this.submissionDao.Delete(s)
-			}
+		submission := this.submissionDao.FindByMatterUuid(matter.Uuid)
+		if submission != nil {
+			this.submissionDao.Delete(submission)
 		}
 	}
 
@@ -450,6 +498,9 @@ func (this *MatterService) SoftDelete(request *http.Request, matter *Matter, use
 		panic(result.BadRequest("matter has been deleted"))
 	}
 
+	//submission folder cannot be changed out of its window.
+	this.submissionWindowService.CheckWritable(request, user, matter)
+
 	// 软删除时也删除对应的提交记录（如果是文件夹）
 	if matter.Dir {
 		submission := this.submissionDao.FindByMatterUuid(matter.Uuid)
@@ -488,6 +539,8 @@ func (this *MatterService) AtomicDelete(request *http.Request, matter *Matter, u
 	this.userService.MatterLock(matter.UserUuid)
 	defer this.userService.MatterUnlock(matter.UserUuid)
 
+	this.submissionWindowService.CheckWritable(request, user, matter)
+
 	this.Delete(request, matter, user, space)
 }
 
@@ -509,6 +562,7 @@ func (this *MatterService) AtomicSoftDelete(request *http.Request, matter *Matte
 	//if disabled the recycle feature. then we hard delete.
 	preference := this.preferenceService.Fetch()
 	if preference.DeletedKeepDays == 0 {
+		this.submissionWindowService.CheckWritable(request, user, matter)
 		this.Delete(request, matter, user, space)
 	} else {
 		this.SoftDelete(request, matter, user)
@@ -531,6 +585,9 @@ func (this *MatterService) AtomicRecovery(request *http.Request, matter *Matter,
 	this.userService.MatterLock(matter.UserUuid)
 	defer this.userService.MatterUnlock(matter.UserUuid)
 
+	//the matter comes back into its folder, which cannot be changed out of its window.
+	this.submissionWindowService.CheckWritable(request, user, matter)
+
 	this.Recovery(request, matter, user)
 }
 
@@ -549,6 +606,9 @@ func (this *MatterService) Upload(request *http.Request, file io.Reader, fileHea
 		panic(result.BadRequest("Dir has been deleted. Cannot upload under it."))
 	}
 
+	//submission folder cannot be changed out of its window.
+	this.submissionWindowService.CheckWritable(request, user, dirMatter)
+
 	if len(filename) > MATTER_NAME_MAX_LENGTH {
 		panic(result.BadRequestI18n(request, i18n.MatterNameLengthExceedLimit, len(filename), MATTER_NAME_MAX_LENGTH))
 	}
@@ -570,8 +630,6 @@ func (this *MatterService) Upload(request *http.Request, file io.Reader, fileHea
 		}
 	}
 
-	dirAbsolutePath := dirMatter.AbsolutePath()
-
 	dbMatter := this.matterDao.FindBySpaceUuidAndPuuidAndDirAndName(space.Uuid, dirMatter.Uuid, false, filename)
 	if dbMatter != nil {
 		if dbMatter.Deleted {
@@ -582,26 +640,20 @@ func (this *MatterService) Upload(request *http.Request, file io.Reader, fileHea
 
 	}
 
-	fileAbsolutePath := dirAbsolutePath + "/" + filename
-
-	util.MakeDirAll(dirAbsolutePath)
+	this.storageService.CheckWritable(space.Name)
 
-	//if exist, overwrite it.
-	exist := util.PathExists(fileAbsolutePath)
-	if exist {
-		this.logger.Error("%s exits, overwrite it.", fileAbsolutePath)
-		removeError := os.Remove(fileAbsolutePath)
-		this.PanicError(removeError)
-	}
+	//received into a staging file, and put into the storage after the checks.
+	stagingFile := this.storageService.CreateStagingFile()
+	defer func() {
+		_ = os.Remove(stagingFile.Name())
+	}()
 
-	destFile, err := os.OpenFile(fileAbsolutePath, os.O_WRONLY|os.O_CREATE, 0777)
-	this.PanicError(err)
-	closeDestFile := func() {
-		err := destFile.Close()
-		this.PanicError(err)
+	blobHash := NewBlobHash()
+	fileSize, err := io.Copy(io.MultiWriter(stagingFile, blobHash), file)
+	closeErr := stagingFile.Close()
+	if err == nil {
+		err = closeErr
 	}
-
-	fileSize, err := io.Copy(destFile, file)
 	this.PanicError(err)
 
 	this.logger.Info("upload %s %v ", filename, util.HumanFileSize(fileSize))
@@ -610,12 +662,6 @@ func (this *MatterService) Upload(request *http.Request, file io.Reader, fileHea
 		//check the size limit.
 		if space.SizeLimit >= 0 {
 			if fileSize > space.SizeLimit {
-				closeDestFile()
-
-				//delete the file on disk.
-				err = os.Remove(fileAbsolutePath)
-				this.PanicError(err)
-
 				panic(result.BadRequestI18n(request, i18n.MatterSizeExceedLimit, util.HumanFileSize(fileSize), util.HumanFileSize(space.SizeLimit)))
 			}
 		}
@@ -623,24 +669,242 @@ func (this *MatterService) Upload(request *http.Request, file io.Reader, fileHea
 		//check total size.
 		if space.TotalSizeLimit >= 0 {
 			if space.TotalSize+fileSize > space.TotalSizeLimit {
-				closeDestFile()
-
-				//delete the file on disk.
-				err = os.Remove(fileAbsolutePath)
-				this.PanicError(err)
-
 				panic(result.BadRequestI18n(request, i18n.MatterSizeExceedTotalLimit, util.HumanFileSize(space.TotalSize), util.HumanFileSize(space.TotalSizeLimit)))
 			}
 		}
 	}
 
-	closeDestFile()
+	//if exist, overwrite it.
+	this.storageService.Put(space.Name, dirMatter.StorageKey()+"/"+filename, stagingFile.Name())
 
 	matter := this.createNonDirMatter(dirMatter, filename, fileSize, privacy, user, space)
 
+	//the same content uploaded before shares its bytes.
+	return this.blobService.Store(matter, blobHash.Sha256(), blobHash.Md5())
+}
+
+// 学生上传作品时，文件重命名为 赛道-作品名-学号 格式。没有赛道、作品名或学号时保持原名。
+func (this *MatterService) SubmissionFilename(user *User, trackId int64, workName string, filename string) string {
+	if trackId == 0 || workName == "" || user.Role != USER_ROLE_USER {
+		return filename
+	}
+	userProfile := this.userProfileDao.FindByUserUuid(user.Uuid)
+	if userProfile == nil || userProfile.StudentId == "" {
+		return filename
+	}
+	track := this.trackDao.Find(trackId)
+	if track == nil {
+		return filename
+	}
+	extension := ""
+	if dotIndex := strings.LastIndex(filename, "."); dotIndex != -1 {
+		extension = filename[dotIndex:]
+		filename = filename[:dotIndex]
+	}
+	return fmt.Sprintf("%s-%s-%s%s", track.Name, workName, userProfile.StudentId, extension)
+}
+
+// check whether a file of size can be uploaded under dirMatter, before it is received. return the checked filename.
+func (this *MatterService) CheckUploadable(request *http.Request, user *User, space *Space, dirMatter *Matter, filename string, size int64) string {
+
+	if dirMatter.Deleted {
+		panic(result.BadRequest("Dir has been deleted. Cannot upload under it."))
+	}
+
+	//submission folder cannot be changed out of its window.
+	this.submissionWindowService.CheckWritable(request, user, dirMatter)
+
+	this.storageService.CheckWritable(space.Name)
+
+	filename = CheckMatterName(request, filename)
+
+	if space.SizeLimit >= 0 && size > space.SizeLimit {
+		panic(result.BadRequestI18n(request, i18n.MatterSizeExceedLimit, util.HumanFileSize(size), util.HumanFileSize(space.SizeLimit)))
+	}
+	if space.TotalSizeLimit >= 0 && space.TotalSize+size > space.TotalSizeLimit {
+		panic(result.BadRequestI18n(request, i18n.MatterSizeExceedTotalLimit, util.HumanFileSize(space.TotalSize), util.HumanFileSize(space.TotalSizeLimit)))
+	}
+
+	dbMatter := this.matterDao.FindBySpaceUuidAndPuuidAndDirAndName(space.Uuid, dirMatter.Uuid, false, filename)
+	if dbMatter != nil {
+		if dbMatter.Deleted {
+			panic(result.BadRequestI18n(request, i18n.MatterRecycleBinExist, filename))
+		} else {
+			panic(result.BadRequestI18n(request, i18n.MatterExist, filename))
+		}
+	}
+
+	return filename
+}
+
+// create the file from the blob of the same content, without receiving it. the sha256, md5 and size must all match.
+// the file counts against the size limits like an uploaded one. nil if there is no such blob, or no file of it is in a space
+// the user can read, then the file should be uploaded.
+func (this *MatterService) InstantUpload(request *http.Request, user *User, space *Space, dirMatter *Matter, filename string, size int64, sha256 string, md5 string, privacy bool) *Matter {
+
+	filename = this.CheckUploadable(request, user, space, dirMatter, filename, size)
+
+	sha256 = strings.ToLower(sha256)
+	if !this.readsBlob(user, sha256) {
+		return nil
+	}
+
+	fileKey := dirMatter.StorageKey() + "/" + filename
+	this.storageService.Remove(space.Name, fileKey)
+
+	blob := this.blobService.Link(space.Name, fileKey, sha256, strings.ToLower(md5), size)
+	if blob == nil {
+		return nil
+	}
+
+	this.logger.Info("instant upload %s %v ", filename, util.HumanFileSize(size))
+
+	matter := this.createNonDirMatter(dirMatter, filename, size, privacy, user, space)
+	matter.Md5 = blob.Md5
+	matter.Sha256 = blob.Sha256
+	this.matterDao.UpdateHash(matter)
+
 	return matter
 }
 
+// whether the user can read a file of the blob already.
+func (this *MatterService) readsBlob(user *User, sha256 string) bool {
+	for _, spaceUuid := range this.matterDao.FindSpaceUuidsBySha256(sha256) {
+		space := this.spaceDao.FindByUuid(spaceUuid)
+		if space != nil && this.spaceService.Readable(user, space) {
+			return true
+		}
+	}
+	return false
+}
+
+// create or overwrite the file named filename under dirMatter. A file of the name in the recycle bin is recovered and overwritten.
+func (this *MatterService) AtomicPut(request *http.Request, file io.Reader, user *User, space *Space, dirMatter *Matter, filename string, privacy bool) *Matter {
+
+	if user == nil {
+		panic(result.BadRequest("user cannot be nil."))
+	}
+
+	this.userService.MatterLock(user.Uuid)
+	defer this.userService.MatterUnlock(user.Uuid)
+
+	return this.put(request, file, user, space, dirMatter, filename, privacy)
+}
+
+// create or overwrite the file. the content overwritten is kept as a version. invoker must handled the lock.
+func (this *MatterService) put(request *http.Request, file io.Reader, user *User, space *Space, dirMatter *Matter, filename string, privacy bool) *Matter {
+
+	//submission folder cannot be changed out of its window.
+	this.submissionWindowService.CheckWritable(request, user, dirMatter)
+
+	filename = CheckMatterName(request, filename)
+
+	matter := this.matterDao.FindBySpaceUuidAndPuuidAndDirAndName(space.Uuid, dirMatter.Uuid, false, filename)
+	if matter == nil {
+		return this.Upload(request, file, nil, user, space, dirMatter, filename, privacy)
+	}
+
+	this.storageService.CheckWritable(space.Name)
+
+	//write aside, the old content is kept until the new one passes the size limits.
+	stagingFile := this.storageService.CreateStagingFile()
+	defer func() {
+		_ = os.Remove(stagingFile.Name())
+	}()
+
+	blobHash := NewBlobHash()
+	fileSize, err := io.Copy(io.MultiWriter(stagingFile, blobHash), file)
+	closeErr := stagingFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	this.PanicError(err)
+
+	if space.SizeLimit >= 0 && fileSize > space.SizeLimit {
+		panic(result.BadRequestI18n(request, i18n.MatterSizeExceedLimit, util.HumanFileSize(fileSize), util.HumanFileSize(space.SizeLimit)))
+	}
+	//the versions count against the total size.
+	if space.TotalSizeLimit >= 0 && space.TotalSize-matter.Size+fileSize+this.matterVersionService.ArchiveSize(matter) > space.TotalSizeLimit {
+		panic(result.BadRequestI18n(request, i18n.MatterSizeExceedTotalLimit, util.HumanFileSize(space.TotalSize), util.HumanFileSize(space.TotalSizeLimit)))
+	}
+
+	version := this.matterVersionService.Archive(matter)
+	put := false
+	defer func() {
+		if !put {
+			this.matterVersionService.Discard(version)
+		}
+	}()
+	this.storageService.Put(space.Name, matter.StorageKey(), stagingFile.Name())
+	put = true
+
+	this.logger.Info("overwrite %s %v ", filename, util.HumanFileSize(fileSize))
+
+	//a file of the name in the recycle bin is recovered, its content is kept as a version.
+	if matter.Deleted {
+		this.matterDao.Recovery(matter)
+		matter.Deleted = false
+	}
+
+	//the content changed, so do the caches and the blob.
+	this.imageCacheDao.DeleteByMatterUuid(matter.Uuid)
+	this.blobService.Unref(matter)
+	this.matterVersionService.Prune(matter)
+
+	matter = this.updateNonDirMatter(matter, fileSize, user, space)
+
+	return this.blobService.Store(matter, blobHash.Sha256(), blobHash.Md5())
+}
+
+// make the content of the version current. the current content is kept as a new version.
+func (this *MatterService) AtomicRestoreVersion(request *http.Request, matter *Matter, version *MatterVersion, user *User, space *Space) *Matter {
+
+	if user == nil {
+		panic(result.BadRequest("user cannot be nil."))
+	}
+
+	this.userService.MatterLock(user.Uuid)
+	defer this.userService.MatterUnlock(user.Uuid)
+
+	if matter.Deleted {
+		panic(result.BadRequest("%s is in the recycle bin, recover it first", matter.Name))
+	}
+
+	//submission folder cannot be changed out of its window.
+	dirMatter := this.matterDao.CheckWithRootByUuid(matter.Puuid, space)
+	this.submissionWindowService.CheckWritable(request, user, dirMatter)
+
+	this.storageService.CheckWritable(space.Name)
+
+	if space.SizeLimit >= 0 && version.Size > space.SizeLimit {
+		panic(result.BadRequestI18n(request, i18n.MatterSizeExceedLimit, util.HumanFileSize(version.Size), util.HumanFileSize(space.SizeLimit)))
+	}
+	if space.TotalSizeLimit >= 0 && space.TotalSize-matter.Size+version.Size+this.matterVersionService.ArchiveSize(matter) > space.TotalSizeLimit {
+		panic(result.BadRequestI18n(request, i18n.MatterSizeExceedTotalLimit, util.HumanFileSize(space.TotalSize), util.HumanFileSize(space.TotalSizeLimit)))
+	}
+
+	archived := this.matterVersionService.Archive(matter)
+	put := false
+	defer func() {
+		if !put {
+			this.matterVersionService.Discard(archived)
+		}
+	}()
+	this.storageService.Copy(version.SpaceName, version.StorageKey(), space.Name, matter.StorageKey())
+	put = true
+
+	this.logger.Info("restore %s to version %d", matter.Path, version.Version)
+
+	//the content changed, so do the caches and the blob.
+	this.imageCacheDao.DeleteByMatterUuid(matter.Uuid)
+	this.blobService.Unref(matter)
+	this.matterVersionService.Prune(matter)
+
+	matter = this.updateNonDirMatter(matter, version.Size, user, space)
+
+	return this.blobService.Store(matter, version.Sha256, version.Md5)
+}
+
 // create a non dir matter.
 func (this *MatterService) createNonDirMatter(dirMatter *Matter, filename string, fileSize int64, privacy bool, user *User, space *Space) *Matter {
 	dirRelativePath := dirMatter.Path
@@ -671,10 +935,11 @@ func (this *MatterService) createNonDirMatter(dirMatter *Matter, filename string
 	return matter
 }
 
-// create a non dir matter.
+// update a non dir matter whose content is written by the user.
 func (this *MatterService) updateNonDirMatter(matter *Matter, fileSize int64, user *User, space *Space) *Matter {
 
 	matter.Size = fileSize
+	matter.EditorUuid = user.Uuid
 
 	matter = this.matterDao.Save(matter)
 
@@ -692,7 +957,7 @@ func (this *MatterService) ComputeRouteSize(matterUuid string, user *User, space
 	//if to root directory, then update to user's info.
 	if matterUuid == MATTER_ROOT {
 
-		size := this.matterDao.SizeByPuuidAndSpaceUuid(MATTER_ROOT, space.Uuid)
+		size := this.spaceTotalSize(space)
 
 		this.spaceDao.UpdateTotalSize(space.Uuid, size)
 
@@ -722,6 +987,11 @@ func (this *MatterService) ComputeRouteSize(matterUuid string, user *User, space
 	this.ComputeRouteSize(matter.Puuid, user, space)
 }
 
+// the files of the space and their versions.
+func (this *MatterService) spaceTotalSize(space *Space) int64 {
+	return this.matterDao.SizeByPuuidAndSpaceUuid(MATTER_ROOT, space.Uuid) + this.matterVersionDao.SizeBySpaceUuid(space.Uuid)
+}
+
 // compute all dir's size.
 func (this *MatterService) ComputeAllDirSize(user *User, space *Space) {
 
@@ -745,7 +1015,7 @@ func (this *MatterService) ComputeDirSize(dirMatter *Matter, user *User, space *
 	//if to root directory, then update to user's info.
 	if dirMatter.Uuid == MATTER_ROOT {
 
-		size := this.matterDao.SizeByPuuidAndSpaceUuid(MATTER_ROOT, space.Uuid)
+		size := this.spaceTotalSize(space)
 
 		this.spaceDao.UpdateTotalSize(space.Uuid, size)
 
@@ -811,13 +1081,12 @@ func (this *MatterService) createDirectory(request *http.Request, dirMatter *Mat
 		panic(result.BadRequestI18n(request, i18n.MatterDepthExceedLimit, len(parts), MATTER_NAME_MAX_DEPTH))
 	}
 
-	absolutePath := GetSpaceMatterRootDir(space.Name) + dirMatter.Path + "/" + name
-
 	relativePath := dirMatter.Path + "/" + name
 
-	//crate directory on disk.
-	dirPath := util.MakeDirAll(absolutePath)
-	this.logger.Info("Create Directory: %s", dirPath)
+	//crate directory in the storage.
+	dirKey := dirMatter.StorageKey() + "/" + name
+	this.storageService.MakeDir(space.Name, dirKey)
+	this.logger.Info("Create Directory: %s", dirKey)
 
 	//create in db
 	matter = &Matter{
@@ -851,6 +1120,10 @@ func (this *MatterService) createDirectory(request *http.Request, dirMatter *Mat
 			CreateTime:    time.Now(),
 			UpdateTime:    time.Now(),
 		}
+		// 团队文件夹中的作品属于整个团队
+		if team := this.teamService.FindBySpaceUuid(space.Uuid); team != nil {
+			this.teamService.Bind(submission, team)
+		}
 		this.submissionDao.Create(submission)
 		this.logger.Info("Created submission for folder: matterUuid=%s, authorId=%s, college=%s", matter.Uuid, userProfile.StudentId, userProfile.College)
 	} else {
@@ -869,6 +1142,8 @@ func (this *MatterService) AtomicCreateDirectory(request *http.Request, dirMatte
 	this.userService.MatterLock(user.Uuid)
 	defer this.userService.MatterUnlock(user.Uuid)
 
+	this.submissionWindowService.CheckWritable(request, user, dirMatter)
+
 	matter := this.createDirectory(request, dirMatter, name, user, space)
 
 	return matter
@@ -908,12 +1183,8 @@ func (this *MatterService) move(request *http.Request, srcMatter *Matter, destDi
 	if srcMatter.Dir {
 
 		//if src is dir.
-		destAbsolutePath := destDirMatter.AbsolutePath() + "/" + srcMatter.Name
-		srcAbsolutePath := srcMatter.AbsolutePath()
-
-		//move src to dest on disk.
-		err := os.Rename(srcAbsolutePath, destAbsolutePath)
-		this.PanicError(err)
+		//move src to dest in the storage.
+		this.storageService.Rename(space.Name, srcMatter.StorageKey(), destDirMatter.StorageKey()+"/"+srcMatter.Name)
 
 		//change info on db.
 		srcMatter.Puuid = destDirMatter.Uuid
@@ -929,12 +1200,8 @@ func (this *MatterService) move(request *http.Request, srcMatter *Matter, destDi
 	} else {
 
 		//if src is NOT dir.
-		destAbsolutePath := destDirMatter.AbsolutePath() + "/" + srcMatter.Name
-		srcAbsolutePath := srcMatter.AbsolutePath()
-
-		//move src to dest on disk.
-		err := os.Rename(srcAbsolutePath, destAbsolutePath)
-		this.PanicError(err)
+		//move src to dest in the storage.
+		this.storageService.Rename(space.Name, srcMatter.StorageKey(), destDirMatter.StorageKey()+"/"+srcMatter.Name)
 
 		//delete caches.
 		this.imageCacheDao.DeleteByMatterUuid(srcMatter.Uuid)
@@ -969,6 +1236,10 @@ func (this *MatterService) AtomicMove(request *http.Request, srcMatter *Matter,
 		panic(result.BadRequestI18n(request, i18n.MatterDestinationMustDirectory))
 	}
 
+	//both the source and the destination submission folders should be in their windows.
+	this.submissionWindowService.CheckWritable(request, user, srcMatter)
+	this.submissionWindowService.CheckWritable(request, user, destDirMatter)
+
 	//neither move to itself, nor move to its children.
 	destDirMatter = this.WrapParentDetail(request, destDirMatter)
 	tmpMatter := destDirMatter
@@ -1005,6 +1276,12 @@ func (this *MatterService) AtomicMoveBatch(request *http.Request, srcMatters []*
 		panic(result.BadRequestI18n(request, i18n.MatterDestinationMustDirectory))
 	}
 
+	//both the source and the destination submission folders should be in their windows.
+	this.submissionWindowService.CheckWritable(request, user, destDirMatter)
+	for _, srcMatter := range srcMatters {
+		this.submissionWindowService.CheckWritable(request, user, srcMatter)
+	}
+
 	//neither move to itself, nor move to its children.
 	destDirMatter = this.WrapParentDetail(request, destDirMatter)
 	for _, srcMatter := range srcMatters {
@@ -1035,6 +1312,7 @@ func (this *MatterService) copy(request *http.Request, srcMatter *Matter, destDi
 			Puuid:     destDirMatter.Uuid,
 			UserUuid:  srcMatter.UserUuid,
 			SpaceName: srcMatter.SpaceName,
+			SpaceUuid: destDirMatter.SpaceUuid,
 			Dir:       srcMatter.Dir,
 			Name:      name,
 			Md5:       "",
@@ -1048,7 +1326,7 @@ func (this *MatterService) copy(request *http.Request, srcMatter *Matter, destDi
 		newMatter = this.matterDao.Create(newMatter)
 
 		//make the dir
-		util.MakeDirAll(newMatter.AbsolutePath())
+		this.storageService.MakeDir(newMatter.SpaceName, newMatter.StorageKey())
 
 		//copy children
 		matters := this.matterDao.FindByPuuidAndUserUuid(srcMatter.Uuid, srcMatter.UserUuid, nil)
@@ -1058,16 +1336,19 @@ func (this *MatterService) copy(request *http.Request, srcMatter *Matter, destDi
 
 	} else {
 
-		destAbsolutePath := destDirMatter.AbsolutePath() + "/" + name
-		srcAbsolutePath := srcMatter.AbsolutePath()
+		destKey := destDirMatter.StorageKey() + "/" + name
 
-		//copy file on disk.
-		util.CopyFile(srcAbsolutePath, destAbsolutePath)
+		//the copy shares the blob of the file, or it is copied in the storage.
+		blob := this.blobService.Link(destDirMatter.SpaceName, destKey, srcMatter.Sha256, srcMatter.Md5, srcMatter.Size)
+		if blob == nil {
+			this.storageService.Copy(srcMatter.SpaceName, srcMatter.StorageKey(), destDirMatter.SpaceName, destKey)
+		}
 
 		newMatter := &Matter{
 			Puuid:     destDirMatter.Uuid,
 			UserUuid:  srcMatter.UserUuid,
 			SpaceName: srcMatter.SpaceName,
+			SpaceUuid: destDirMatter.SpaceUuid,
 			Dir:       srcMatter.Dir,
 			Name:      name,
 			Md5:       "",
@@ -1077,6 +1358,10 @@ func (this *MatterService) copy(request *http.Request, srcMatter *Matter, destDi
 			Prop:      EMPTY_JSON_MAP,
 			VisitTime: time.Now(),
 		}
+		if blob != nil {
+			newMatter.Md5 = blob.Md5
+			newMatter.Sha256 = blob.Sha256
+		}
 		newMatter = this.matterDao.Create(newMatter)
 
 	}
@@ -1096,6 +1381,8 @@ func (this *MatterService) AtomicCopy(request *http.Request, srcMatter *Matter,
 		panic(result.BadRequestI18n(request, i18n.MatterDestinationMustDirectory))
 	}
 
+	this.submissionWindowService.CheckWritable(request, user, destDirMatter)
+
 	destinationPath := destDirMatter.Path + "/" + name
 	this.handleOverwrite(request, user, space, destinationPath, overwrite)
 
@@ -1118,6 +1405,9 @@ func (this *MatterService) AtomicRename(request *http.Request, matter *Matter, n
 	this.userService.MatterLock(user.Uuid)
 	defer this.userService.MatterUnlock(user.Uuid)
 
+	//submission folder cannot be changed out of its window.
+	this.submissionWindowService.CheckWritable(request, user, matter)
+
 	name = CheckMatterName(request, name)
 
 	if name == matter.Name {
@@ -1139,14 +1429,12 @@ func (this *MatterService) AtomicRename(request *http.Request, matter *Matter, n
 	if matter.Dir {
 		//如果源是文件夹
 
-		oldAbsolutePath := matter.AbsolutePath()
-		absoluteDirPath := util.GetDirOfPath(oldAbsolutePath)
+		oldKey := matter.StorageKey()
 		relativeDirPath := util.GetDirOfPath(matter.Path)
-		newAbsolutePath := absoluteDirPath + "/" + name
+		newKey := util.GetDirOfPath(oldKey) + "/" + name
 
 		//物理文件一口气移动
-		err := os.Rename(oldAbsolutePath, newAbsolutePath)
-		this.PanicError(err)
+		this.storageService.Rename(space.Name, oldKey, newKey)
 
 		//修改数据库中信息
 		matter.Name = name
@@ -1162,14 +1450,12 @@ func (this *MatterService) AtomicRename(request *http.Request, matter *Matter, n
 	} else {
 		//如果源是普通文件
 
-		oldAbsolutePath := matter.AbsolutePath()
-		absoluteDirPath := util.GetDirOfPath(oldAbsolutePath)
+		oldKey := matter.StorageKey()
 		relativeDirPath := util.GetDirOfPath(matter.Path)
-		newAbsolutePath := absoluteDirPath + "/" + name
+		newKey := util.GetDirOfPath(oldKey) + "/" + name
 
 		//物理文件进行移动
-		err := os.Rename(oldAbsolutePath, newAbsolutePath)
-		this.PanicError(err)
+		this.storageService.Rename(space.Name, oldKey, newKey)
 
 		//删除对应的缓存。
 		this.imageCacheDao.DeleteByMatterUuid(matter.Uuid)
@@ -1253,14 +1539,9 @@ func (this *MatterService) mirror(request *http.Request, srcPath string, destDir
 
 		//判断当前文件夹下，文件是否已经存在了。
 		matter := this.matterDao.FindBySpaceNameAndPuuidAndDirAndName(space.Name, destDirMatter.Uuid, FALSE, fileStat.Name())
-		if matter != nil {
-			//如果是覆盖，那么删除之前的文件
-			if overwrite {
-				this.Delete(request, matter, user, space)
-			} else {
-				//直接完成。
-				return
-			}
+		if matter != nil && !overwrite {
+			//直接完成。
+			return
 		}
 
 		//准备直接从本地上传了。
@@ -1271,7 +1552,12 @@ func (this *MatterService) mirror(request *http.Request, srcPath string, destDir
 			this.PanicError(err)
 		}()
 
-		this.Upload(request, file, nil, user, space, destDirMatter, fileStat.Name(), true)
+		if matter != nil {
+			//如果是覆盖，那么之前的内容保留为历史版本
+			this.put(request, file, user, space, destDirMatter, fileStat.Name(), true)
+		} else {
+			this.Upload(request, file, nil, user, space, destDirMatter, fileStat.Name(), true)
+		}
 
 	}
 
@@ -1446,7 +1732,7 @@ func (this *MatterService) deleteFolderByPhysics(request *http.Request, dirMatte
 			this.deleteFolderByPhysics(request, matter, user, space)
 		}
 
-		if !util.PathExists(matter.AbsolutePath()) {
+		if _, err := this.storageService.Stat(space.Name, matter.StorageKey()); os.IsNotExist(err) {
 			this.logger.Info("physics file not exist. delete from tank. %s", matter.Name)
 			this.AtomicDelete(nil, matter, user, space)
 		}
@@ -1461,26 +1747,13 @@ func (this *MatterService) ScanPhysics(request *http.Request, user *User, space
 		panic(result.BadRequest("user cannot be nil."))
 	}
 
-	rootDirPath := GetSpaceMatterRootDir(user.Username)
-	this.logger.Info("scan %s's root dir %s", user.Username, rootDirPath)
-
-	rootExists := util.PathExists(rootDirPath)
-	if !rootExists {
-		util.MakeDirAll(rootDirPath)
-	}
-	rootFileInfo, err := os.Lstat(rootDirPath)
-	if err != nil {
-		panic(result.BadRequest("cannot get root file info."))
-	}
-
 	rootMatter := NewRootMatter(space)
-	this.scanPhysicsFolder(request, rootFileInfo, rootMatter, user, space)
+	this.logger.Info("scan %s's root dir %s", space.Name, rootMatter.StorageKey())
+
+	this.scanPhysicsFolder(request, rootMatter, user, space)
 }
 
-func (this *MatterService) scanPhysicsFolder(request *http.Request, dirInfo os.FileInfo, dirMatter *Matter, user *User, space *Space) {
-	if !dirInfo.IsDir() {
-		return
-	}
+func (this *MatterService) scanPhysicsFolder(request *http.Request, dirMatter *Matter, user *User, space *Space) {
 
 	//fetch all matters under this folder.
 	_, matters := this.matterDao.PlainPage(0, 1000, dirMatter.Uuid, "", space.Uuid, "", "", "", nil, nil, nil, nil)
@@ -1489,19 +1762,14 @@ func (this *MatterService) scanPhysicsFolder(request *http.Request, dirInfo os.F
 		nameMatterMap[m.Name] = m
 	}
 
-	dirPath := dirMatter.AbsolutePath()
-	names, err := util.ReadDirNames(dirPath)
+	dirKey := dirMatter.StorageKey()
+	fileInfos, err := this.storageService.ListDir(space.Name, dirKey)
 	if err != nil {
-		this.logger.Error("occur error when ReadDirNames %s %s", dirPath, err.Error())
+		this.logger.Error("occur error when List %s %s", dirKey, err.Error())
 		return
 	}
-	for _, name := range names {
-		fileFullPath := filepath.Join(dirPath, name)
-		fileInfo, err := os.Lstat(fileFullPath)
-		if err != nil {
-			this.logger.Error("occur error when Lstat %s %s", name, err.Error())
-			continue
-		}
+	for _, fileInfo := range fileInfos {
+		name := fileInfo.Name()
 
 		//find ther matter
 		var matter *Matter
@@ -1518,7 +1786,7 @@ func (this *MatterService) scanPhysicsFolder(request *http.Request, dirInfo os.F
 			} else {
 
 				//recursive scan this folder.
-				this.scanPhysicsFolder(request, fileInfo, matter, user, space)
+				this.scanPhysicsFolder(request, matter, user, space)
 
 			}
 
@@ -1530,7 +1798,7 @@ func (this *MatterService) scanPhysicsFolder(request *http.Request, dirInfo os.F
 				matter = this.createDirectory(request, dirMatter, name, user, space)
 
 				//recursive scan this folder.
-				this.scanPhysicsFolder(request, fileInfo, matter, user, space)
+				this.scanPhysicsFolder(request, matter, user, space)
 
 			} else {
 
@@ -1580,4 +1848,7 @@ func (this *MatterService) CleanExpiredDeletedMatters() {
 
 	})
 
+	//recount the references of the blobs, and collect those no file references.
+	this.blobService.Collect()
+
 }
@@ -0,0 +1,137 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+type MatterVersionController struct {
+	BaseController
+	matterDao            *MatterDao
+	matterService        *MatterService
+	matterVersionDao     *MatterVersionDao
+	matterVersionService *MatterVersionService
+	spaceService         *SpaceService
+	storageService       *StorageService
+}
+
+func (this *MatterVersionController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterService)
+	if b, ok := b.(*MatterService); ok {
+		this.matterService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterVersionDao)
+	if b, ok := b.(*MatterVersionDao); ok {
+		this.matterVersionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterVersionService)
+	if b, ok := b.(*MatterVersionService); ok {
+		this.matterVersionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceService)
+	if b, ok := b.(*SpaceService); ok {
+		this.spaceService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.storageService)
+	if b, ok := b.(*StorageService); ok {
+		this.storageService = b
+	}
+}
+
+func (this *MatterVersionController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/matter/version/list"] = this.Wrap(this.List, USER_ROLE_USER)
+	routeMap["/api/matter/version/download"] = this.Wrap(this.Download, USER_ROLE_USER)
+	routeMap["/api/matter/version/diff"] = this.Wrap(this.Diff, USER_ROLE_USER)
+	routeMap["/api/matter/version/restore"] = this.Wrap(this.Restore, USER_ROLE_USER)
+
+	return routeMap
+}
+
+// the file of the request, in a space the user can read, or write if writable.
+func (this *MatterVersionController) checkMatter(request *http.Request, user *User, matterUuid string, writable bool) (*Matter, *Space) {
+
+	spaceUuid := util.ExtractRequestOptionalString(request, "spaceUuid", user.SpaceUuid)
+	var space *Space
+	if writable {
+		space = this.spaceService.CheckWritableByUuid(request, user, spaceUuid)
+	} else {
+		space = this.spaceService.CheckReadableByUuid(request, user, spaceUuid)
+	}
+
+	matter := this.matterDao.CheckByUuid(matterUuid)
+	if matter.SpaceUuid != space.Uuid {
+		panic(result.UNAUTHORIZED)
+	}
+	if matter.Dir {
+		panic(result.BadRequest("directory has no version"))
+	}
+	return matter, space
+}
+
+// versions of a file, the latest first.
+func (this *MatterVersionController) List(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	matterUuid := util.ExtractRequestString(request, "matterUuid")
+
+	user := this.checkUser(request)
+	matter, _ := this.checkMatter(request, user, matterUuid, false)
+
+	return this.Success(this.matterVersionService.List(matter))
+}
+
+func (this *MatterVersionController) Download(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	uuid := util.ExtractRequestString(request, "uuid")
+
+	user := this.checkUser(request)
+	version := this.matterVersionDao.CheckByUuid(uuid)
+	matter, _ := this.checkMatter(request, user, version.MatterUuid, false)
+
+	this.storageService.Download(writer, request, version.SpaceName, version.StorageKey(), matter.Name, true)
+	return nil
+}
+
+// compare two versions of a file. an empty uuid means the current content.
+func (this *MatterVersionController) Diff(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	matterUuid := util.ExtractRequestString(request, "matterUuid")
+	fromUuid := util.ExtractRequestOptionalString(request, "fromUuid", "")
+	toUuid := util.ExtractRequestOptionalString(request, "toUuid", "")
+
+	user := this.checkUser(request)
+	matter, _ := this.checkMatter(request, user, matterUuid, false)
+
+	from := this.matterVersionService.Find(matter, fromUuid)
+	to := this.matterVersionService.Find(matter, toUuid)
+
+	return this.Success(this.matterVersionService.Diff(from, to))
+}
+
+// make a version the current content of its file.
+func (this *MatterVersionController) Restore(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	uuid := util.ExtractRequestString(request, "uuid")
+
+	user := this.checkUser(request)
+	version := this.matterVersionDao.CheckByUuid(uuid)
+	matter, space := this.checkMatter(request, user, version.MatterUuid, true)
+
+	return this.Success(this.matterService.AtomicRestoreVersion(request, matter, version, user, space).ShowHash(user))
+}
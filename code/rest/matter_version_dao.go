@@ -0,0 +1,156 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/uuid"
+)
+
+type MatterVersionDao struct {
+	BaseDao
+	blobService    *BlobService
+	storageService *StorageService
+}
+
+func (this *MatterVersionDao) Init() {
+	this.BaseDao.Init()
+
+	b := core.CONTEXT.GetBean(this.blobService)
+	if b, ok := b.(*BlobService); ok {
+		this.blobService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.storageService)
+	if b, ok := b.(*StorageService); ok {
+		this.storageService = b
+	}
+}
+
+// find by uuid. if not found return nil.
+func (this *MatterVersionDao) FindByUuid(uuid string) *MatterVersion {
+	var entity = &MatterVersion{}
+	db := core.CONTEXT.GetDB().Where("uuid = ?", uuid).First(entity)
+	if db.Error != nil {
+		if db.Error.Error() == result.DB_ERROR_NOT_FOUND {
+			return nil
+		} else {
+			panic(db.Error)
+		}
+	}
+	return entity
+}
+
+// find by uuid. if not found panic NotFound error
+func (this *MatterVersionDao) CheckByUuid(uuid string) *MatterVersion {
+	entity := this.FindByUuid(uuid)
+	if entity == nil {
+		panic(result.NotFound("not found record with uuid = %s", uuid))
+	}
+	return entity
+}
+
+// versions of the matter, the latest first.
+func (this *MatterVersionDao) FindByMatterUuid(matterUuid string) []*MatterVersion {
+	var versions []*MatterVersion
+	db := core.CONTEXT.GetDB().Where("matter_uuid = ?", matterUuid).Order("version DESC").Find(&versions)
+	this.PanicError(db.Error)
+	return versions
+}
+
+// the number of the latest version of the matter. 0 if it has none.
+func (this *MatterVersionDao) MaxVersion(matterUuid string) int64 {
+	var version int64
+	row := core.CONTEXT.GetDB().Model(&MatterVersion{}).Where("matter_uuid = ?", matterUuid).Select("COALESCE(MAX(version), 0)").Row()
+	err := row.Scan(&version)
+	this.PanicError(err)
+	return version
+}
+
+// versions of the space. ordered by uuid, start after afterUuid.
+func (this *MatterVersionDao) FindBySpaceUuidAfter(spaceUuid string, afterUuid string, limit int) []*MatterVersion {
+	var versions []*MatterVersion
+	db := core.CONTEXT.GetDB().
+		Where("space_uuid = ? AND uuid > ?", spaceUuid, afterUuid).
+		Order("uuid ASC").
+		Limit(limit).
+		Find(&versions)
+	this.PanicError(db.Error)
+	return versions
+}
+
+// versions replaced before the time. ordered by uuid, start after afterUuid.
+func (this *MatterVersionDao) FindCreatedBefore(before time.Time, afterUuid string, limit int) []*MatterVersion {
+	var versions []*MatterVersion
+	db := core.CONTEXT.GetDB().
+		Where("create_time < ? AND uuid > ?", before, afterUuid).
+		Order("uuid ASC").
+		Limit(limit).
+		Find(&versions)
+	this.PanicError(db.Error)
+	return versions
+}
+
+// total size of the versions in the space.
+func (this *MatterVersionDao) SizeBySpaceUuid(spaceUuid string) int64 {
+	var size int64
+	row := core.CONTEXT.GetDB().Model(&MatterVersion{}).Where("space_uuid = ?", spaceUuid).Select("COALESCE(SUM(size), 0)").Row()
+	err := row.Scan(&size)
+	this.PanicError(err)
+	return size
+}
+
+func (this *MatterVersionDao) Create(version *MatterVersion) *MatterVersion {
+
+	timeUUID, _ := uuid.NewV4()
+	version.Uuid = string(timeUUID.String())
+	version.CreateTime = time.Now()
+	version.UpdateTime = time.Now()
+	version.Sort = time.Now().UnixNano() / 1e6
+	db := core.CONTEXT.GetDB().Create(version)
+	this.PanicError(db.Error)
+
+	return version
+}
+
+func (this *MatterVersionDao) UpdateHash(version *MatterVersion) {
+	db := core.CONTEXT.GetDB().Model(&MatterVersion{}).Where("uuid = ?", version.Uuid).Updates(map[string]any{"md5": version.Md5, "sha256": version.Sha256})
+	this.PanicError(db.Error)
+}
+
+// delete a version from db and storage.
+func (this *MatterVersionDao) Delete(version *MatterVersion) {
+
+	db := core.CONTEXT.GetDB().Delete(version)
+	this.PanicError(db.Error)
+
+	this.storageService.Remove(version.SpaceName, version.StorageKey())
+
+	//the blob is removed when no matter or version references it.
+	this.blobService.Release(version.Sha256)
+}
+
+// delete all the versions of the matter.
+func (this *MatterVersionDao) DeleteByMatter(matter *Matter) {
+	versions := this.FindByMatterUuid(matter.Uuid)
+	for _, version := range versions {
+		this.Delete(version)
+	}
+	if len(versions) > 0 {
+		this.storageService.RemoveDir(matter.SpaceName, matter.SpaceName+"/"+MATTER_VERSION+"/"+matter.Uuid)
+	}
+}
+
+// delete the versions of the space from db only. the files go with the space.
+func (this *MatterVersionDao) DeleteBySpaceUuid(spaceUuid string) {
+	db := core.CONTEXT.GetDB().Where("space_uuid = ?", spaceUuid).Delete(MatterVersion{})
+	this.PanicError(db.Error)
+}
+
+// System cleanup.
+func (this *MatterVersionDao) Cleanup() {
+	this.logger.Info("[MatterVersionDao] clean up. Delete all MatterVersion")
+	db := core.CONTEXT.GetDB().Where("uuid is not null").Delete(MatterVersion{})
+	this.PanicError(db.Error)
+}
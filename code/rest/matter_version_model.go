@@ -0,0 +1,50 @@
+package rest
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	//prior contents of the files are kept under the space, eg. {spaceName}/version/{matterUuid}/{versionUuid}
+	MATTER_VERSION = "version"
+)
+
+// MatterVersion is a prior content of a file, kept when the file is overwritten.
+// Versions are numbered from 1 for each matter, the current content of the matter comes after the last one.
+// Their sizes count against the total size of the space.
+type MatterVersion struct {
+	Uuid       string    `json:"uuid" gorm:"type:char(36);primary_key;unique"`
+	Sort       int64     `json:"sort" gorm:"type:bigint(20) not null"`
+	UpdateTime time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	CreateTime time.Time `json:"createTime" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"` //when the content was replaced.
+	MatterUuid string    `json:"matterUuid" gorm:"type:char(36) not null;index:idx_matter_version_mu"`
+	SpaceUuid  string    `json:"spaceUuid" gorm:"type:char(36) not null;index:idx_matter_version_su"`
+	SpaceName  string    `json:"spaceName" gorm:"type:varchar(100) not null"`
+	Version    int64     `json:"version" gorm:"type:bigint(20) not null;default:0"`
+	//the author of the content.
+	UserUuid  string    `json:"userUuid" gorm:"type:char(36)"`
+	Size      int64     `json:"size" gorm:"type:bigint(20) not null;default:0"`
+	Md5       string    `json:"-" gorm:"type:varchar(45)"`
+	Sha256    string    `json:"-" gorm:"type:char(64);index:idx_matter_version_sha256"`                 //the blob sharing its bytes. empty if not in the blob store. hashes are never shown, see Matter.ShowHash.
+	WriteTime time.Time `json:"writeTime" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"` //when the content was written.
+	User      *User     `json:"user" gorm:"-"`
+}
+
+// get the key in the storage of the space.
+func (this *MatterVersion) StorageKey() string {
+	return fmt.Sprintf("%s/%s/%s/%s", this.SpaceName, MATTER_VERSION, this.MatterUuid, this.Uuid)
+}
+
+// the difference of the metadata between two contents of a file.
+type MatterVersionDiff struct {
+	//the current content of the matter is taken as a version with empty uuid.
+	From *MatterVersion `json:"from"`
+	To   *MatterVersion `json:"to"`
+	//size of To minus size of From.
+	SizeDelta int64 `json:"sizeDelta"`
+	//whether the md5 of the two are the same. false if either is unknown.
+	SameContent bool `json:"sameContent"`
+	//seconds between the two are written.
+	Seconds int64 `json:"seconds"`
+}
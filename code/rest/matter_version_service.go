@@ -0,0 +1,240 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+// prior versions of the files. the content is archived before it is overwritten, see MatterService.put.
+// @Service
+type MatterVersionService struct {
+	BaseBean
+	matterVersionDao  *MatterVersionDao
+	matterDao         *MatterDao
+	userDao           *UserDao
+	spaceDao          *SpaceDao
+	matterService     *MatterService
+	preferenceService *PreferenceService
+	storageService    *StorageService
+	blobService       *BlobService
+}
+
+func (this *MatterVersionService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.matterVersionDao)
+	if b, ok := b.(*MatterVersionDao); ok {
+		this.matterVersionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userDao)
+	if b, ok := b.(*UserDao); ok {
+		this.userDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceDao)
+	if b, ok := b.(*SpaceDao); ok {
+		this.spaceDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterService)
+	if b, ok := b.(*MatterService); ok {
+		this.matterService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.preferenceService)
+	if b, ok := b.(*PreferenceService); ok {
+		this.preferenceService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.storageService)
+	if b, ok := b.(*StorageService); ok {
+		this.storageService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.blobService)
+	if b, ok := b.(*BlobService); ok {
+		this.blobService = b
+	}
+}
+
+// how much the versions of the space grow if the current content of the matter is archived,
+// with the versions beyond the count removed.
+func (this *MatterVersionService) ArchiveSize(matter *Matter) int64 {
+	keepCount := this.preferenceService.Fetch().FetchVersionConfig().KeepCount
+	if keepCount <= 0 {
+		return 0
+	}
+
+	size := matter.Size
+	versions := this.matterVersionDao.FindByMatterUuid(matter.Uuid)
+	for i := keepCount - 1; i < int64(len(versions)); i++ {
+		size -= versions[i].Size
+	}
+	return size
+}
+
+// keep the current content of the matter as a new version. nil if no version is kept.
+// the versions beyond the retention are not removed until Prune.
+func (this *MatterVersionService) Archive(matter *Matter) *MatterVersion {
+	if this.preferenceService.Fetch().FetchVersionConfig().KeepCount <= 0 {
+		return nil
+	}
+
+	author := matter.EditorUuid
+	if author == "" {
+		author = matter.UserUuid
+	}
+
+	version := this.matterVersionDao.Create(&MatterVersion{
+		MatterUuid: matter.Uuid,
+		SpaceUuid:  matter.SpaceUuid,
+		SpaceName:  matter.SpaceName,
+		Version:    this.matterVersionDao.MaxVersion(matter.Uuid) + 1,
+		UserUuid:   author,
+		Size:       matter.Size,
+		Md5:        matter.Md5,
+		WriteTime:  matter.UpdateTime,
+	})
+
+	archived := false
+	defer func() {
+		if !archived {
+			this.matterVersionDao.Delete(version)
+		}
+	}()
+
+	//the version shares the blob of the file, or it is copied in the storage.
+	blob := this.blobService.Link(version.SpaceName, version.StorageKey(), matter.Sha256, matter.Md5, matter.Size)
+	if blob != nil {
+		version.Sha256 = blob.Sha256
+		this.matterVersionDao.UpdateHash(version)
+	} else {
+		this.storageService.Copy(matter.SpaceName, matter.StorageKey(), version.SpaceName, version.StorageKey())
+	}
+	archived = true
+
+	this.logger.Info("archive %s as version %d", matter.Path, version.Version)
+	return version
+}
+
+// remove the version archived, eg. the content is not overwritten at last.
+func (this *MatterVersionService) Discard(version *MatterVersion) {
+	if version != nil {
+		this.matterVersionDao.Delete(version)
+	}
+}
+
+// remove the versions of the matter beyond the retention.
+func (this *MatterVersionService) Prune(matter *Matter) {
+	versionConfig := this.preferenceService.Fetch().FetchVersionConfig()
+
+	var before time.Time
+	if versionConfig.KeepDays > 0 {
+		before = time.Now().AddDate(0, 0, int(-versionConfig.KeepDays))
+	}
+	for i, version := range this.matterVersionDao.FindByMatterUuid(matter.Uuid) {
+		if int64(i) >= versionConfig.KeepCount || (versionConfig.KeepDays > 0 && version.CreateTime.Before(before)) {
+			this.logger.Info("remove version %d of %s", version.Version, matter.Path)
+			this.matterVersionDao.Delete(version)
+		}
+	}
+}
+
+// versions of the matter with their authors, the latest first.
+func (this *MatterVersionService) List(matter *Matter) []*MatterVersion {
+	versions := this.matterVersionDao.FindByMatterUuid(matter.Uuid)
+	users := make(map[string]*User)
+	for _, version := range versions {
+		if _, ok := users[version.UserUuid]; !ok {
+			users[version.UserUuid] = this.userDao.FindByUuid(version.UserUuid)
+		}
+		version.User = users[version.UserUuid]
+	}
+	return versions
+}
+
+// the current content of the matter as a version, whose uuid is empty.
+func (this *MatterVersionService) Current(matter *Matter) *MatterVersion {
+	author := matter.EditorUuid
+	if author == "" {
+		author = matter.UserUuid
+	}
+	return &MatterVersion{
+		MatterUuid: matter.Uuid,
+		SpaceUuid:  matter.SpaceUuid,
+		SpaceName:  matter.SpaceName,
+		Version:    this.matterVersionDao.MaxVersion(matter.Uuid) + 1,
+		UserUuid:   author,
+		Size:       matter.Size,
+		Md5:        matter.Md5,
+		Sha256:     matter.Sha256,
+		WriteTime:  matter.UpdateTime,
+		User:       this.userDao.FindByUuid(author),
+	}
+}
+
+// the version of the matter, or the current content if versionUuid is empty.
+func (this *MatterVersionService) Find(matter *Matter, versionUuid string) *MatterVersion {
+	if versionUuid == "" {
+		return this.Current(matter)
+	}
+	version := this.matterVersionDao.CheckByUuid(versionUuid)
+	if version.MatterUuid != matter.Uuid {
+		panic(result.BadRequest("version %s is not of %s", versionUuid, matter.Name))
+	}
+	version.User = this.userDao.FindByUuid(version.UserUuid)
+	return version
+}
+
+// compare the metadata of two contents of a file.
+func (this *MatterVersionService) Diff(from *MatterVersion, to *MatterVersion) *MatterVersionDiff {
+	return &MatterVersionDiff{
+		From:        from,
+		To:          to,
+		SizeDelta:   to.Size - from.Size,
+		SameContent: from.Md5 != "" && from.Md5 == to.Md5,
+		Seconds:     int64(to.WriteTime.Sub(from.WriteTime).Seconds()),
+	}
+}
+
+// remove the versions replaced more than the days ago, then count the sizes of the spaces again.
+func (this *MatterVersionService) CleanExpired() {
+	keepDays := this.preferenceService.Fetch().FetchVersionConfig().KeepDays
+	if keepDays <= 0 {
+		return
+	}
+	before := time.Now().AddDate(0, 0, int(-keepDays))
+
+	spaceUuids := make(map[string]bool)
+	var count int64 = 0
+	afterUuid := ""
+	for {
+		versions := this.matterVersionDao.FindCreatedBefore(before, afterUuid, 1000)
+		if len(versions) == 0 {
+			break
+		}
+		for _, version := range versions {
+			afterUuid = version.Uuid
+			this.matterVersionDao.Delete(version)
+			spaceUuids[version.SpaceUuid] = true
+			count++
+		}
+	}
+
+	for spaceUuid := range spaceUuids {
+		if space := this.spaceDao.FindByUuid(spaceUuid); space != nil {
+			this.matterService.ComputeRouteSize(MATTER_ROOT, nil, space)
+		}
+	}
+
+	this.logger.Info("clean %d versions replaced before %s", count, util.ConvertTimeToDateTimeString(before))
+}
@@ -8,6 +8,7 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type PreferenceController struct {
@@ -17,6 +18,8 @@ type PreferenceController struct {
 	matterService     *MatterService
 	preferenceService *PreferenceService
 	taskService       *TaskService
+	rankingService    *RankingService
+	auditService      *AuditService
 }
 
 func (this *PreferenceController) Init() {
@@ -45,6 +48,16 @@ func (this *PreferenceController) Init() {
 		this.taskService = b
 	}
 
+	b = core.CONTEXT.GetBean(this.rankingService)
+	if b, ok := b.(*RankingService); ok {
+		this.rankingService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.auditService)
+	if b, ok := b.(*AuditService); ok {
+		this.auditService = b
+	}
+
 }
 
 func (this *PreferenceController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
@@ -56,6 +69,12 @@ func (this *PreferenceController) RegisterRoutes() map[string]func(writer http.R
 	routeMap["/api/preference/edit"] = this.Wrap(this.Edit, USER_ROLE_ADMINISTRATOR)
 	routeMap["/api/preference/edit/preview/config"] = this.Wrap(this.EditPreviewConfig, USER_ROLE_ADMINISTRATOR)
 	routeMap["/api/preference/edit/scan/config"] = this.Wrap(this.EditScanConfig, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/preference/edit/window/config"] = this.Wrap(this.EditWindowConfig, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/preference/edit/ranking/config"] = this.Wrap(this.EditRankingConfig, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/preference/edit/appeal/config"] = this.Wrap(this.EditAppealConfig, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/preference/edit/anomaly/config"] = this.Wrap(this.EditAnomalyConfig, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/preference/edit/quota/config"] = this.Wrap(this.EditQuotaConfig, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/preference/edit/version/config"] = this.Wrap(this.EditVersionConfig, USER_ROLE_ADMINISTRATOR)
 	routeMap["/api/preference/scan/once"] = this.Wrap(this.ScanOnce, USER_ROLE_ADMINISTRATOR)
 	routeMap["/api/preference/system/cleanup"] = this.Wrap(this.SystemCleanup, USER_ROLE_ADMINISTRATOR)
 
@@ -219,6 +238,198 @@ func (this *PreferenceController) EditScanConfig(writer http.ResponseWriter, req
 	return this.Success(preference)
 }
 
+// edit submission window config.
+func (this *PreferenceController) EditWindowConfig(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	windowConfigStr := request.FormValue("windowConfig")
+	if windowConfigStr == "" {
+		panic(result.BadRequest("windowConfig cannot be null"))
+	}
+
+	windowConfig := &WindowConfig{}
+	err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(windowConfigStr), &windowConfig)
+	if err != nil {
+		panic(result.BadRequest("windowConfig format error"))
+	}
+
+	//validate the windows.
+	local, _ := time.LoadLocation("Local")
+	for _, window := range windowConfig.Windows {
+		if window.TrackId <= 0 {
+			panic(result.BadRequest("trackId cannot be null"))
+		}
+		var openTime, closeTime time.Time
+		if window.OpenTime != "" {
+			openTime, err = time.ParseInLocation("2006-01-02 15:04:05", window.OpenTime, local)
+			if err != nil {
+				panic(result.BadRequest("openTime %s format error", window.OpenTime))
+			}
+		}
+		if window.CloseTime != "" {
+			closeTime, err = time.ParseInLocation("2006-01-02 15:04:05", window.CloseTime, local)
+			if err != nil {
+				panic(result.BadRequest("closeTime %s format error", window.CloseTime))
+			}
+		}
+		if window.OpenTime != "" && window.CloseTime != "" && !closeTime.After(openTime) {
+			panic(result.BadRequest("closeTime must after openTime"))
+		}
+	}
+
+	preference := this.preferenceDao.Fetch()
+	before := preference.FetchWindowConfig()
+	preference.WindowConfig = windowConfigStr
+	preference = this.preferenceService.Save(preference)
+
+	this.auditService.Log(request, this.findUser(request), AUDIT_ACTION_WINDOW_CONFIG_EDIT, AUDIT_TARGET_PREFERENCE, "windowConfig", before, windowConfig)
+
+	return this.Success(preference)
+}
+
+// edit ranking config.
+func (this *PreferenceController) EditRankingConfig(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	rankingConfigStr := request.FormValue("rankingConfig")
+	if rankingConfigStr == "" {
+		panic(result.BadRequest("rankingConfig cannot be null"))
+	}
+
+	rankingConfig := &RankingConfig{}
+	err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(rankingConfigStr), &rankingConfig)
+	if err != nil {
+		panic(result.BadRequest("rankingConfig format error"))
+	}
+	if webResult := this.rankingService.ValidConfig(rankingConfig); webResult != nil {
+		panic(webResult)
+	}
+
+	preference := this.preferenceDao.Fetch()
+	preference.RankingConfig = rankingConfigStr
+	preference = this.preferenceService.Save(preference)
+
+	return this.Success(preference)
+}
+
+// edit appeal config.
+func (this *PreferenceController) EditAppealConfig(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	appealConfigStr := request.FormValue("appealConfig")
+	if appealConfigStr == "" {
+		panic(result.BadRequest("appealConfig cannot be null"))
+	}
+
+	appealConfig := &AppealConfig{}
+	err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(appealConfigStr), &appealConfig)
+	if err != nil {
+		panic(result.BadRequest("appealConfig format error"))
+	}
+	if appealConfig.FileDays < 0 || appealConfig.MaxPerSubmission < 0 {
+		panic(result.BadRequest("fileDays and maxPerSubmission cannot be negative"))
+	}
+	if appealConfig.HandleDays <= 0 || appealConfig.ReviewDays <= 0 {
+		panic(result.BadRequest("handleDays and reviewDays must be positive"))
+	}
+
+	preference := this.preferenceDao.Fetch()
+	preference.AppealConfig = appealConfigStr
+	preference = this.preferenceService.Save(preference)
+
+	return this.Success(preference)
+}
+
+// edit anomaly config.
+func (this *PreferenceController) EditAnomalyConfig(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	anomalyConfigStr := request.FormValue("anomalyConfig")
+	if anomalyConfigStr == "" {
+		panic(result.BadRequest("anomalyConfig cannot be null"))
+	}
+
+	anomalyConfig := &AnomalyConfig{}
+	err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(anomalyConfigStr), &anomalyConfig)
+	if err != nil {
+		panic(result.BadRequest("anomalyConfig format error"))
+	}
+	if anomalyConfig.IdenticalRun < 2 {
+		panic(result.BadRequest("identicalRun must be at least 2"))
+	}
+	if anomalyConfig.OutlierSd <= 0 || anomalyConfig.MinSd <= 0 || anomalyConfig.FastSeconds < 0 {
+		panic(result.BadRequest("outlierSd and minSd must be positive, fastSeconds cannot be negative"))
+	}
+
+	preference := this.preferenceDao.Fetch()
+	preference.AnomalyConfig = anomalyConfigStr
+	preference = this.preferenceService.Save(preference)
+
+	return this.Success(preference)
+}
+
+func (this *PreferenceController) EditQuotaConfig(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	quotaConfigStr := request.FormValue("quotaConfig")
+	if quotaConfigStr == "" {
+		panic(result.BadRequest("quotaConfig cannot be null"))
+	}
+
+	quotaConfig := &QuotaConfig{}
+	err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(quotaConfigStr), &quotaConfig)
+	if err != nil {
+		panic(result.BadRequest("quotaConfig format error"))
+	}
+
+	//validate the quotas.
+	for _, quota := range quotaConfig.Quotas {
+		if quota.TrackId <= 0 {
+			panic(result.BadRequest("trackId cannot be null"))
+		}
+		switch quota.Type {
+		case QUOTA_TYPE_ABSOLUTE:
+			if quota.Value < 0 || quota.Value != float64(int(quota.Value)) {
+				panic(result.BadRequest("value of an absolute quota must be a non-negative integer"))
+			}
+		case QUOTA_TYPE_PERCENT:
+			if quota.Value < 0 || quota.Value > 100 {
+				panic(result.BadRequest("value of a percent quota must be between 0 and 100"))
+			}
+		default:
+			panic(result.BadRequest("quota type %s not supported", quota.Type))
+		}
+	}
+
+	preference := this.preferenceDao.Fetch()
+	before := preference.FetchQuotaConfig()
+	preference.QuotaConfig = quotaConfigStr
+	preference = this.preferenceService.Save(preference)
+
+	this.auditService.Log(request, this.findUser(request), AUDIT_ACTION_QUOTA_CONFIG_EDIT, AUDIT_TARGET_PREFERENCE, "quotaConfig", before, quotaConfig)
+
+	return this.Success(preference)
+}
+
+// edit version config.
+func (this *PreferenceController) EditVersionConfig(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	versionConfigStr := request.FormValue("versionConfig")
+	if versionConfigStr == "" {
+		panic(result.BadRequest("versionConfig cannot be null"))
+	}
+
+	versionConfig := &VersionConfig{}
+	err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(versionConfigStr), &versionConfig)
+	if err != nil {
+		panic(result.BadRequest("versionConfig format error"))
+	}
+	if versionConfig.KeepCount < 0 || versionConfig.KeepDays < 0 {
+		panic(result.BadRequest("keepCount and keepDays cannot be negative"))
+	}
+
+	preference := this.preferenceDao.Fetch()
+	preference.VersionConfig = versionConfigStr
+	preference = this.preferenceService.Save(preference)
+
+	return this.Success(preference)
+}
+
 // scan immediately according the current config.
 func (this *PreferenceController) ScanOnce(writer http.ResponseWriter, request *http.Request) *result.WebResult {
 
@@ -24,6 +24,12 @@ type Preference struct {
 	DeletedKeepDays       int64     `json:"deletedKeepDays" gorm:"type:bigint(20) not null;default:7"`
 	CollegeConfig         string    `json:"collegeConfig" gorm:"type:text"`
 	TrackConfig           string    `json:"trackConfig" gorm:"type:text"`
+	WindowConfig          string    `json:"windowConfig" gorm:"type:text"`
+	RankingConfig         string    `json:"rankingConfig" gorm:"type:text"`
+	AppealConfig          string    `json:"appealConfig" gorm:"type:text"`
+	AnomalyConfig         string    `json:"anomalyConfig" gorm:"type:text"`
+	QuotaConfig           string    `json:"quotaConfig" gorm:"type:text"`
+	VersionConfig         string    `json:"versionConfig" gorm:"type:text"`
 	Version               string    `json:"version" gorm:"-"`
 }
 
@@ -109,3 +115,260 @@ func (this *Preference) FetchTrackConfig() *TrackConfig {
 		return m
 	}
 }
+
+// SubmissionWindow struct. time format is yyyy-MM-dd HH:mm:ss, empty means no limit.
+type SubmissionWindow struct {
+	TrackId int64 `json:"trackId"`
+	//0 means the window applies to every college of the track.
+	CollegeId int64  `json:"collegeId"`
+	OpenTime  string `json:"openTime"`
+	CloseTime string `json:"closeTime"`
+}
+
+// WindowConfig struct
+type WindowConfig struct {
+	Windows []*SubmissionWindow `json:"windows"`
+}
+
+// find the window of a track. the college specified window takes precedence.
+func (this *WindowConfig) FindWindow(trackId int64, collegeId int64) *SubmissionWindow {
+	var trackWindow *SubmissionWindow
+	for _, window := range this.Windows {
+		if window.TrackId != trackId {
+			continue
+		}
+		if collegeId != 0 && window.CollegeId == collegeId {
+			return window
+		}
+		if window.CollegeId == 0 {
+			trackWindow = window
+		}
+	}
+	return trackWindow
+}
+
+// fetch the submission window config
+func (this *Preference) FetchWindowConfig() *WindowConfig {
+	json := this.WindowConfig
+	if json == "" || json == EMPTY_JSON_MAP {
+		return &WindowConfig{
+			Windows: []*SubmissionWindow{},
+		}
+	} else {
+		m := &WindowConfig{}
+		err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(json), &m)
+		if err != nil {
+			panic(err)
+		}
+		return m
+	}
+}
+
+// AwardTier struct. Quota is the number of submissions of the tier.
+type AwardTier struct {
+	Name  string `json:"name"`
+	Quota int    `json:"quota"`
+}
+
+// TrackAward struct. tiers are ordered from the highest award.
+type TrackAward struct {
+	//0 means the default tiers of the tracks not configured.
+	TrackId int64        `json:"trackId"`
+	Tiers   []*AwardTier `json:"tiers"`
+}
+
+// RankingConfig struct
+type RankingConfig struct {
+	//normalize scores by z-score of each judge.
+	Normalize bool `json:"normalize"`
+	//drop the Trim highest and the Trim lowest scores of a submission.
+	Trim int `json:"trim"`
+	//tie breaking rules in order.
+	TieBreaks []string      `json:"tieBreaks"`
+	Awards    []*TrackAward `json:"awards"`
+}
+
+// find the award tiers of a track. the track specified tiers take precedence.
+func (this *RankingConfig) FindTiers(trackId int64) []*AwardTier {
+	var defaultTiers []*AwardTier
+	for _, award := range this.Awards {
+		if award.TrackId == trackId {
+			return award.Tiers
+		}
+		if award.TrackId == 0 {
+			defaultTiers = award.Tiers
+		}
+	}
+	return defaultTiers
+}
+
+// fetch the ranking config
+func (this *Preference) FetchRankingConfig() *RankingConfig {
+	json := this.RankingConfig
+	if json == "" || json == EMPTY_JSON_MAP {
+		return &RankingConfig{
+			TieBreaks: []string{},
+			Awards:    []*TrackAward{},
+		}
+	} else {
+		m := &RankingConfig{}
+		err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(json), &m)
+		if err != nil {
+			panic(err)
+		}
+		return m
+	}
+}
+
+// AppealConfig struct. days are counted in whole days.
+type AppealConfig struct {
+	//students can appeal within FileDays after the decision. 0 means no limit.
+	FileDays int `json:"fileDays"`
+	//the handler must reply within HandleDays.
+	HandleDays int `json:"handleDays"`
+	//the judges of a re-review must rate within ReviewDays.
+	ReviewDays int `json:"reviewDays"`
+	//appeals a submission can have at most. 0 means no limit.
+	MaxPerSubmission int `json:"maxPerSubmission"`
+}
+
+// fetch the appeal config
+func (this *Preference) FetchAppealConfig() *AppealConfig {
+	json := this.AppealConfig
+	if json == "" || json == EMPTY_JSON_MAP {
+		return &AppealConfig{
+			FileDays:         7,
+			HandleDays:       5,
+			ReviewDays:       7,
+			MaxPerSubmission: 1,
+		}
+	} else {
+		m := &AppealConfig{}
+		err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(json), &m)
+		if err != nil {
+			panic(err)
+		}
+		return m
+	}
+}
+
+// AnomalyConfig struct. thresholds of the rating anomaly report.
+type AnomalyConfig struct {
+	//a judge giving the same score to at least IdenticalRun submissions in a row.
+	IdenticalRun int `json:"identicalRun"`
+	//a rating more than OutlierSd standard deviations away from the other judges of the submission.
+	OutlierSd float64 `json:"outlierSd"`
+	//the standard deviation is taken as at least MinSd, so close scores of an unanimous panel are not outliers.
+	MinSd float64 `json:"minSd"`
+	//a judge rating again within FastSeconds after the previous rating.
+	FastSeconds int `json:"fastSeconds"`
+}
+
+// fetch the anomaly config
+func (this *Preference) FetchAnomalyConfig() *AnomalyConfig {
+	json := this.AnomalyConfig
+	if json == "" || json == EMPTY_JSON_MAP {
+		return &AnomalyConfig{
+			IdenticalRun: 5,
+			OutlierSd:    2,
+			MinSd:        5,
+			FastSeconds:  60,
+		}
+	} else {
+		m := &AnomalyConfig{}
+		err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(json), &m)
+		if err != nil {
+			panic(err)
+		}
+		return m
+	}
+}
+
+const (
+	//at most Value submissions.
+	QUOTA_TYPE_ABSOLUTE = "ABSOLUTE"
+	//at most Value percent of the submissions of the college in the track, rounded half up.
+	QUOTA_TYPE_PERCENT = "PERCENT"
+)
+
+// RecommendQuota struct. how many submissions of a college can be recommended in a track.
+type RecommendQuota struct {
+	TrackId int64 `json:"trackId"`
+	//0 means the quota applies to every college of the track.
+	CollegeId int64   `json:"collegeId"`
+	Type      string  `json:"type"`
+	Value     float64 `json:"value"`
+}
+
+// the number of submissions can be recommended, out of total submissions.
+func (this *RecommendQuota) Limit(total int) int {
+	if this.Type == QUOTA_TYPE_PERCENT {
+		return int(float64(total)*this.Value/100 + 0.5)
+	}
+	return int(this.Value)
+}
+
+// QuotaConfig struct
+type QuotaConfig struct {
+	Quotas []*RecommendQuota `json:"quotas"`
+}
+
+// find the quota of a college in a track. the college specified quota takes precedence.
+func (this *QuotaConfig) FindQuota(trackId int64, collegeId int64) *RecommendQuota {
+	var trackQuota *RecommendQuota
+	for _, quota := range this.Quotas {
+		if quota.TrackId != trackId {
+			continue
+		}
+		if collegeId != 0 && quota.CollegeId == collegeId {
+			return quota
+		}
+		if quota.CollegeId == 0 {
+			trackQuota = quota
+		}
+	}
+	return trackQuota
+}
+
+// fetch the recommendation quota config
+func (this *Preference) FetchQuotaConfig() *QuotaConfig {
+	json := this.QuotaConfig
+	if json == "" || json == EMPTY_JSON_MAP {
+		return &QuotaConfig{
+			Quotas: []*RecommendQuota{},
+		}
+	} else {
+		m := &QuotaConfig{}
+		err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(json), &m)
+		if err != nil {
+			panic(err)
+		}
+		return m
+	}
+}
+
+// VersionConfig struct. how the prior versions of the files are kept.
+type VersionConfig struct {
+	//keep the last KeepCount versions of a file. 0 means no version is kept.
+	KeepCount int64 `json:"keepCount"`
+	//remove the versions replaced more than KeepDays days ago. 0 means no limit of days.
+	KeepDays int64 `json:"keepDays"`
+}
+
+// fetch the version config
+func (this *Preference) FetchVersionConfig() *VersionConfig {
+	json := this.VersionConfig
+	if json == "" || json == EMPTY_JSON_MAP {
+		return &VersionConfig{
+			KeepCount: 5,
+			KeepDays:  30,
+		}
+	} else {
+		m := &VersionConfig{}
+		err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(json), &m)
+		if err != nil {
+			panic(err)
+		}
+		return m
+	}
+}
@@ -0,0 +1,92 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+type RankingController struct {
+	BaseController
+	rankingService *RankingService
+}
+
+func (this *RankingController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.rankingService)
+	if b, ok := b.(*RankingService); ok {
+		this.rankingService = b
+	}
+}
+
+func (this *RankingController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/ranking/leaderboard"] = this.Wrap(this.Leaderboard, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/ranking/audit"] = this.Wrap(this.Audit, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+// rank with the config in the preference. normalize and trim can be overridden by the request to compare the results.
+func (this *RankingController) rank(request *http.Request) *Leaderboard {
+	trackId, err := strconv.ParseInt(request.FormValue("trackId"), 10, 64)
+	if err != nil {
+		panic(result.BadRequest("trackId格式错误"))
+	}
+	var roundId int64
+	if request.FormValue("roundId") != "" {
+		roundId, err = strconv.ParseInt(request.FormValue("roundId"), 10, 64)
+		if err != nil {
+			panic(result.BadRequest("roundId格式错误"))
+		}
+	}
+
+	config := this.rankingService.FetchConfig()
+	if normalizeStr := request.FormValue("normalize"); normalizeStr != "" {
+		config.Normalize = normalizeStr == TRUE
+	}
+	if trimStr := request.FormValue("trim"); trimStr != "" {
+		config.Trim, err = strconv.Atoi(trimStr)
+		if err != nil {
+			panic(result.BadRequest("trim格式错误"))
+		}
+	}
+
+	leaderboard, webResult := this.rankingService.Rank(trackId, roundId, config)
+	if webResult != nil {
+		panic(webResult)
+	}
+	return leaderboard
+}
+
+func (this *RankingController) Leaderboard(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	leaderboard := this.rank(request)
+	for _, entry := range leaderboard.Entries {
+		entry.Scores = nil
+	}
+	return this.Success(leaderboard)
+}
+
+// the leaderboard with every score used in the calculation. submissionId narrows it to one submission.
+func (this *RankingController) Audit(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	leaderboard := this.rank(request)
+
+	if submissionIdStr := request.FormValue("submissionId"); submissionIdStr != "" {
+		submissionId, err := strconv.ParseInt(submissionIdStr, 10, 64)
+		if err != nil {
+			return result.BadRequest("submissionId格式错误")
+		}
+		var entries []*RankingEntry
+		for _, entry := range leaderboard.Entries {
+			if entry.Submission.Id == submissionId {
+				entries = append(entries, entry)
+			}
+		}
+		leaderboard.Entries = entries
+	}
+	return this.Success(leaderboard)
+}
@@ -0,0 +1,56 @@
+package rest
+
+const (
+	//higher mean of the raw scores.
+	TIE_BREAK_RAW_MEAN = "RAW_MEAN"
+	//higher highest score.
+	TIE_BREAK_MAX_SCORE = "MAX_SCORE"
+	//higher lowest score.
+	TIE_BREAK_MIN_SCORE = "MIN_SCORE"
+	//more ratings.
+	TIE_BREAK_RATING_COUNT = "RATING_COUNT"
+	//earlier submitted.
+	TIE_BREAK_CREATE_TIME = "CREATE_TIME"
+)
+
+var TIE_BREAKS = []string{TIE_BREAK_RAW_MEAN, TIE_BREAK_MAX_SCORE, TIE_BREAK_MIN_SCORE, TIE_BREAK_RATING_COUNT, TIE_BREAK_CREATE_TIME}
+
+// how a rating contributes to the final score.
+type RankingScore struct {
+	RatingId    int64   `json:"ratingId"`
+	JudgeUuid   string  `json:"judgeUuid"`
+	Raw         float64 `json:"raw"`
+	JudgeMean   float64 `json:"judgeMean"`
+	JudgeStdDev float64 `json:"judgeStdDev"`
+	ZScore      float64 `json:"zScore"`
+	Normalized  float64 `json:"normalized"`
+	Dropped     bool    `json:"dropped"`
+}
+
+// a line of the leaderboard.
+type RankingEntry struct {
+	Rank        int         `json:"rank"`
+	Submission  *Submission `json:"submission"`
+	FinalScore  float64     `json:"finalScore"`
+	RawMean     float64     `json:"rawMean"`
+	MaxScore    float64     `json:"maxScore"`
+	MinScore    float64     `json:"minScore"`
+	RatingCount int         `json:"ratingCount"`
+	Award       string      `json:"award"`
+	//the rule which puts this entry ahead of the next one when their final scores are equal.
+	TieBreak string          `json:"tieBreak"`
+	Scores   []*RankingScore `json:"scores,omitempty"`
+}
+
+// the leaderboard of a track in a round, with the parameters used.
+type Leaderboard struct {
+	TrackId   int64           `json:"trackId"`
+	RoundId   int64           `json:"roundId"`
+	Normalize bool            `json:"normalize"`
+	Trim      int             `json:"trim"`
+	TieBreaks []string        `json:"tieBreaks"`
+	Tiers     []*AwardTier    `json:"tiers"`
+	Mean      float64         `json:"mean"`
+	StdDev    float64         `json:"stdDev"`
+	Entries   []*RankingEntry `json:"entries"`
+}
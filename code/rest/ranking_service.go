@@ -0,0 +1,230 @@
+package rest
+
+import (
+	"math"
+	"sort"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+// scores closer than this are regarded as equal.
+const RANKING_EPSILON = 1e-9
+
+// @Service
+type RankingService struct {
+	BaseBean
+	ratingDao         *RatingDao
+	submissionDao     *SubmissionDao
+	trackDao          *TrackDao
+	preferenceService *PreferenceService
+}
+
+func (this *RankingService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.ratingDao)
+	if b, ok := b.(*RatingDao); ok {
+		this.ratingDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.trackDao)
+	if b, ok := b.(*TrackDao); ok {
+		this.trackDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.preferenceService)
+	if b, ok := b.(*PreferenceService); ok {
+		this.preferenceService = b
+	}
+}
+
+func (this *RankingService) ValidConfig(config *RankingConfig) *result.WebResult {
+	if config.Trim < 0 {
+		return result.BadRequest("去掉最高最低分的个数不能为负数")
+	}
+	for _, tieBreak := range config.TieBreaks {
+		valid := false
+		for _, t := range TIE_BREAKS {
+			if t == tieBreak {
+				valid = true
+			}
+		}
+		if !valid {
+			return result.BadRequest("不支持的同分规则 %s", tieBreak)
+		}
+	}
+	for _, award := range config.Awards {
+		for _, tier := range award.Tiers {
+			if tier.Name == "" {
+				return result.BadRequest("奖项名称不能为空")
+			}
+			if tier.Quota < 0 {
+				return result.BadRequest("奖项 %s 的名额不能为负数", tier.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// the ranking config in the preference.
+func (this *RankingService) FetchConfig() *RankingConfig {
+	return this.preferenceService.Fetch().FetchRankingConfig()
+}
+
+// compare two entries by a tie breaking rule. >0 means a ranks ahead of b.
+func (this *RankingService) compare(a *RankingEntry, b *RankingEntry, tieBreak string) float64 {
+	switch tieBreak {
+	case TIE_BREAK_RAW_MEAN:
+		return a.RawMean - b.RawMean
+	case TIE_BREAK_MAX_SCORE:
+		return a.MaxScore - b.MaxScore
+	case TIE_BREAK_MIN_SCORE:
+		return a.MinScore - b.MinScore
+	case TIE_BREAK_RATING_COUNT:
+		return float64(a.RatingCount - b.RatingCount)
+	case TIE_BREAK_CREATE_TIME:
+		return float64(b.Submission.CreateTime.UnixNano() - a.Submission.CreateTime.UnixNano())
+	}
+	return 0
+}
+
+// the rule which decides the order of two entries. empty means they are equal on every rule.
+func (this *RankingService) decide(a *RankingEntry, b *RankingEntry, tieBreaks []string) (string, float64) {
+	if diff := a.FinalScore - b.FinalScore; math.Abs(diff) > RANKING_EPSILON {
+		return "", diff
+	}
+	for _, tieBreak := range tieBreaks {
+		if diff := this.compare(a, b, tieBreak); math.Abs(diff) > RANKING_EPSILON {
+			return tieBreak, diff
+		}
+	}
+	return "", 0
+}
+
+// rank the rated submissions of a track in a round.
+// with normalization, every score is turned into the z-score among the ratings of its judge in the round,
+// then mapped back to the scale of all the ratings in the round, so judges with different habits weigh the same.
+func (this *RankingService) Rank(trackId int64, roundId int64, config *RankingConfig) (*Leaderboard, *result.WebResult) {
+	if this.trackDao.Find(trackId) == nil {
+		return nil, result.BadRequest("赛道不存在")
+	}
+	if webResult := this.ValidConfig(config); webResult != nil {
+		return nil, webResult
+	}
+
+	ratings := this.ratingDao.FindByRoundId(roundId)
+
+	//statistics of each judge and of the whole round.
+	var allScores []float64
+	judgeScores := make(map[string][]float64)
+	for _, rating := range ratings {
+		allScores = append(allScores, rating.TotalScore())
+		judgeScores[rating.JudgeUuid] = append(judgeScores[rating.JudgeUuid], rating.TotalScore())
+	}
+	mean := util.Mean(allScores)
+	stdDev := util.StdDev(allScores)
+
+	leaderboard := &Leaderboard{
+		TrackId:   trackId,
+		RoundId:   roundId,
+		Normalize: config.Normalize,
+		Trim:      config.Trim,
+		TieBreaks: config.TieBreaks,
+		Tiers:     config.FindTiers(trackId),
+		Mean:      mean,
+		StdDev:    stdDev,
+		Entries:   []*RankingEntry{},
+	}
+
+	entryMap := make(map[int64]*RankingEntry)
+	for _, rating := range ratings {
+		entry, ok := entryMap[rating.SubmissionId]
+		if !ok {
+			submission := this.submissionDao.FindById(rating.SubmissionId)
+			if submission == nil || submission.TrackId != trackId {
+				continue
+			}
+			entry = &RankingEntry{Submission: submission, Scores: []*RankingScore{}}
+			entryMap[rating.SubmissionId] = entry
+			leaderboard.Entries = append(leaderboard.Entries, entry)
+		}
+
+		scores := judgeScores[rating.JudgeUuid]
+		score := &RankingScore{
+			RatingId:    rating.Id,
+			JudgeUuid:   rating.JudgeUuid,
+			Raw:         rating.TotalScore(),
+			JudgeMean:   util.Mean(scores),
+			JudgeStdDev: util.StdDev(scores),
+		}
+		score.ZScore = util.ZScore(score.Raw, score.JudgeMean, score.JudgeStdDev)
+		if config.Normalize {
+			score.Normalized = mean + score.ZScore*stdDev
+		} else {
+			score.Normalized = score.Raw
+		}
+		entry.Scores = append(entry.Scores, score)
+	}
+
+	for _, entry := range leaderboard.Entries {
+		var raws, values []float64
+		for _, score := range entry.Scores {
+			raws = append(raws, score.Raw)
+			values = append(values, score.Normalized)
+		}
+
+		var dropped []int
+		entry.FinalScore, dropped = util.TrimmedMean(values, config.Trim)
+		for _, index := range dropped {
+			entry.Scores[index].Dropped = true
+		}
+
+		entry.RawMean = util.Mean(raws)
+		entry.RatingCount = len(raws)
+		entry.MaxScore, entry.MinScore = raws[0], raws[0]
+		for _, raw := range raws {
+			entry.MaxScore = math.Max(entry.MaxScore, raw)
+			entry.MinScore = math.Min(entry.MinScore, raw)
+		}
+	}
+
+	sort.SliceStable(leaderboard.Entries, func(i, j int) bool {
+		a, b := leaderboard.Entries[i], leaderboard.Entries[j]
+		if _, diff := this.decide(a, b, config.TieBreaks); diff != 0 {
+			return diff > 0
+		}
+		return a.Submission.Id < b.Submission.Id
+	})
+
+	//rank and award by position. the quota of a tier is never exceeded.
+	tierIndex, tierUsed := 0, 0
+	for i, entry := range leaderboard.Entries {
+		entry.Rank = i + 1
+		if i+1 < len(leaderboard.Entries) {
+			next := leaderboard.Entries[i+1]
+			if tieBreak, diff := this.decide(entry, next, config.TieBreaks); tieBreak != "" {
+				entry.TieBreak = tieBreak
+			} else if diff == 0 {
+				entry.TieBreak = "ID"
+			}
+		}
+
+		for tierIndex < len(leaderboard.Tiers) && tierUsed >= leaderboard.Tiers[tierIndex].Quota {
+			tierIndex++
+			tierUsed = 0
+		}
+		if tierIndex < len(leaderboard.Tiers) {
+			entry.Award = leaderboard.Tiers[tierIndex].Name
+			tierUsed++
+		}
+	}
+
+	return leaderboard, nil
+}
@@ -3,14 +3,23 @@ package rest
 import (
 	"github.com/eyebluecn/tank/code/core"
 	"github.com/eyebluecn/tank/code/tool/result"
+	jsoniter "github.com/json-iterator/go"
+	"math"
 	"net/http"
 	"strconv"
 )
 
 type RatingController struct {
 	BaseController
-	ratingDao     *RatingDao
-	submissionDao *SubmissionDao
+	ratingDao         *RatingDao
+	submissionDao     *SubmissionDao
+	roundService      *RoundService
+	rubricService     *RubricService
+	ratingItemDao     *RatingItemDao
+	assignmentService *AssignmentService
+	appealService     *AppealService
+	auditService      *AuditService
+	workbenchService  *WorkbenchService
 }
 
 func (this *RatingController) Init() {
@@ -25,24 +34,59 @@ func (this *RatingController) Init() {
 	if b, ok := b.(*SubmissionDao); ok {
 		this.submissionDao = b
 	}
+
+	b = core.CONTEXT.GetBean(this.roundService)
+	if b, ok := b.(*RoundService); ok {
+		this.roundService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.rubricService)
+	if b, ok := b.(*RubricService); ok {
+		this.rubricService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.ratingItemDao)
+	if b, ok := b.(*RatingItemDao); ok {
+		this.ratingItemDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.assignmentService)
+	if b, ok := b.(*AssignmentService); ok {
+		this.assignmentService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.appealService)
+	if b, ok := b.(*AppealService); ok {
+		this.appealService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.auditService)
+	if b, ok := b.(*AuditService); ok {
+		this.auditService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.workbenchService)
+	if b, ok := b.(*WorkbenchService); ok {
+		this.workbenchService = b
+	}
 }
 
 func (this *RatingController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
 	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
 	routeMap["/api/rating/submit"] = this.Wrap(this.SubmitRating, USER_ROLE_JUDGE)
 	routeMap["/api/rating/scored"] = this.Wrap(this.GetScoredSubmissions, USER_ROLE_JUDGE)
+	routeMap["/api/rating/my"] = this.Wrap(this.GetMyRating, USER_ROLE_JUDGE)
 	return routeMap
 }
 
-// 提交评分
+// 提交评分。赛道配置了评分标准时按评分项打分，否则打一个总分
 func (this *RatingController) SubmitRating(writer http.ResponseWriter, request *http.Request) *result.WebResult {
 	user := this.checkUser(request)
 	
 	submissionIdStr := request.FormValue("submissionId")
-	scoreStr := request.FormValue("score")
 	comment := request.FormValue("comment")
 	
-	if submissionIdStr == "" || scoreStr == "" {
+	if submissionIdStr == "" {
 		return result.BadRequest("参数不完整")
 	}
 	
@@ -51,36 +95,98 @@ func (this *RatingController) SubmitRating(writer http.ResponseWriter, request *
 		return result.BadRequest("submissionId格式错误")
 	}
 	
-	score, err := strconv.Atoi(scoreStr)
-	if err != nil || score < 0 || score > 100 {
-		return result.BadRequest("评分必须在0-100之间")
-	}
-	
 	// 检查提交是否存在
 	submission := this.submissionDao.FindById(submissionId)
 	if submission == nil {
 		return result.BadRequest("提交作品不存在")
 	}
 	
-	// 检查是否已经评分过
-	existingRating := this.ratingDao.FindBySubmissionAndJudge(submissionId, user.Uuid)
-	if existingRating != nil {
+	// 检查作品所在轮次是否允许该评委评分
+	if webResult := this.roundService.CheckRatable(submission, user); webResult != nil {
+		return webResult
+	}
+	
+	// 只能评审分配给自己的作品
+	if webResult := this.assignmentService.CheckAssigned(submission, user); webResult != nil {
+		return webResult
+	}
+	
+	var score int
+	var weightedScore float64
+	var rubricId int64
+	var items []*RatingItem
+	
+	rubric := this.rubricService.FindActive(submission.TrackId)
+	if rubric != nil {
+		// 按当前生效的评分标准计算加权总分
+		rubricId, err = strconv.ParseInt(request.FormValue("rubricId"), 10, 64)
+		if err != nil {
+			return result.BadRequest("rubricId格式错误")
+		}
+		err = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(request.FormValue("items")), &items)
+		if err != nil {
+			return result.BadRequest("评分项数据格式错误")
+		}
+		
+		var webResult *result.WebResult
+		weightedScore, webResult = this.rubricService.Evaluate(rubric, rubricId, items)
+		if webResult != nil {
+			return webResult
+		}
+		score = int(math.Round(weightedScore))
+	} else {
+		score, err = strconv.Atoi(request.FormValue("score"))
+		if err != nil || score < 0 || score > 100 {
+			return result.BadRequest("评分必须在0-100之间")
+		}
+		weightedScore = float64(score)
+	}
+	
+	// 检查是否已经在本轮评分过
+	rating := this.ratingDao.FindBySubmissionAndJudgeAndRound(submissionId, user.Uuid, submission.RoundId)
+	var before *Rating
+	if rating != nil {
+		// 留存修改前的评分用于审计
+		copied := *rating
+		copied.Items = this.ratingItemDao.FindByRatingId(rating.Id)
+		before = &copied
+		
 		// 更新现有评分
-		existingRating.Score = score
-		existingRating.Comment = comment
-		this.ratingDao.Save(existingRating)
-		return this.Success(existingRating)
+		rating.Score = score
+		rating.RubricId = rubricId
+		rating.WeightedScore = weightedScore
+		rating.Comment = comment
+		this.ratingDao.Save(rating)
+	} else {
+		// 创建新评分
+		rating = &Rating{
+			SubmissionId:  submissionId,
+			JudgeUuid:     user.Uuid,
+			RoundId:       submission.RoundId,
+			Score:         score,
+			RubricId:      rubricId,
+			WeightedScore: weightedScore,
+			Comment:       comment,
+		}
+		this.ratingDao.Create(rating)
+	}
+	
+	if rubric != nil {
+		this.rubricService.SaveItems(rating, items)
 	}
 	
-	// 创建新评分
-	rating := &Rating{
-		SubmissionId: submissionId,
-		JudgeUuid:    user.Uuid,
-		Score:        score,
-		Comment:      comment,
+	// 提交后草稿作废
+	this.workbenchService.DiscardDraft(submission, user)
+	
+	if before != nil {
+		this.auditService.Log(request, user, AUDIT_ACTION_RATING_EDIT, AUDIT_TARGET_RATING, rating.Id, before, rating)
+	} else {
+		this.auditService.Log(request, user, AUDIT_ACTION_RATING_CREATE, AUDIT_TARGET_RATING, rating.Id, nil, rating)
 	}
 	
-	this.ratingDao.Create(rating)
+	// 申诉复评的评委全部评分后结束复评
+	this.appealService.CheckReview(submission)
+	
 	return this.Success(rating)
 }
 
@@ -88,9 +194,38 @@ func (this *RatingController) SubmitRating(writer http.ResponseWriter, request *
 func (this *RatingController) GetScoredSubmissions(writer http.ResponseWriter, request *http.Request) *result.WebResult {
 	user := this.checkUser(request)
 	
+	// 指定赛事时只返回该赛事的作品
+	if competitionIdStr := request.FormValue("competitionId"); competitionIdStr != "" {
+		competitionId, err := strconv.ParseInt(competitionIdStr, 10, 64)
+		if err != nil {
+			return result.BadRequest("competitionId格式错误")
+		}
+		return this.Success(this.ratingDao.FindScoredSubmissionIdsByJudgeAndCompetition(user.Uuid, competitionId))
+	}
+
 	// 获取当前评委已经评分的所有提交ID
 	scoredSubmissionIds := this.ratingDao.FindScoredSubmissionIdsByJudge(user.Uuid)
 	
 	return this.Success(scoredSubmissionIds)
 }
 
+// 获取当前评委对某作品本轮的评分及评分项
+func (this *RatingController) GetMyRating(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	submissionId, err := strconv.ParseInt(request.FormValue("submissionId"), 10, 64)
+	if err != nil {
+		return result.BadRequest("submissionId格式错误")
+	}
+	submission := this.submissionDao.FindById(submissionId)
+	if submission == nil {
+		return result.BadRequest("提交作品不存在")
+	}
+
+	rating := this.ratingDao.FindBySubmissionAndJudgeAndRound(submissionId, user.Uuid, submission.RoundId)
+	if rating == nil {
+		return this.Success(nil)
+	}
+	rating.Items = this.ratingItemDao.FindByRatingId(rating.Id)
+	return this.Success(rating)
+}
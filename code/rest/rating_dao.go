@@ -2,6 +2,7 @@ package rest
 
 import (
 	"github.com/eyebluecn/tank/code/core"
+	"time"
 )
 
 // @Service
@@ -14,12 +15,15 @@ func (this *RatingDao) Init() {
 }
 
 func (this *RatingDao) Create(rating *Rating) *Rating {
+	rating.CreateTime = time.Now()
+	rating.UpdateTime = time.Now()
 	var db = core.CONTEXT.GetDB().Create(rating)
 	this.PanicError(db.Error)
 	return rating
 }
 
 func (this *RatingDao) Save(rating *Rating) *Rating {
+	rating.UpdateTime = time.Now()
 	var db = core.CONTEXT.GetDB().Save(rating)
 	this.PanicError(db.Error)
 	return rating
@@ -45,3 +49,50 @@ func (this *RatingDao) FindScoredSubmissionIdsByJudge(judgeUuid string) []int64
 	return submissionIds
 }
 
+// submissions of the competition scored by the judge.
+func (this *RatingDao) FindScoredSubmissionIdsByJudgeAndCompetition(judgeUuid string, competitionId int64) []int64 {
+	var submissionIds []int64
+	submissions := core.CONTEXT.GetDB().Model(&Submission{}).Select("id").Where("competition_id = ?", competitionId)
+	db := core.CONTEXT.GetDB().Model(&Rating{}).
+		Where("judge_uuid = ? AND submission_id IN (?)", judgeUuid, submissions).
+		Pluck("submission_id", &submissionIds)
+	this.PanicError(db.Error)
+	return submissionIds
+}
+
+func (this *RatingDao) FindBySubmissionAndJudgeAndRound(submissionId int64, judgeUuid string, roundId int64) *Rating {
+	var rating Rating
+	db := core.CONTEXT.GetDB().Where("submission_id = ? AND judge_uuid = ? AND round_id = ?", submissionId, judgeUuid, roundId).First(&rating)
+	if db.Error != nil {
+		return nil
+	}
+	return &rating
+}
+
+func (this *RatingDao) FindByRoundId(roundId int64) []*Rating {
+	var ratings []*Rating
+	db := core.CONTEXT.GetDB().Where("round_id = ? AND superseded = 0", roundId).Find(&ratings)
+	this.PanicError(db.Error)
+	return ratings
+}
+
+func (this *RatingDao) CountByRubricId(rubricId int64) int64 {
+	var count int64
+	db := core.CONTEXT.GetDB().Model(&Rating{}).Where("rubric_id = ?", rubricId).Count(&count)
+	this.PanicError(db.Error)
+	return count
+}
+
+
+func (this *RatingDao) FindBySubmissionId(submissionId int64) []*Rating {
+	var ratings []*Rating
+	db := core.CONTEXT.GetDB().Where("submission_id = ?", submissionId).Order("id ASC").Find(&ratings)
+	this.PanicError(db.Error)
+	return ratings
+}
+
+// supersede the ratings of the submission in the round when it is reviewed again after an appeal. superseded ratings no longer count.
+func (this *RatingDao) SupersedeBySubmissionAndRound(submissionId int64, roundId int64) {
+	db := core.CONTEXT.GetDB().Model(&Rating{}).Where("submission_id = ? AND round_id = ?", submissionId, roundId).Update("superseded", true)
+	this.PanicError(db.Error)
+}
@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+type RatingDraftDao struct {
+	BaseDao
+}
+
+func (this *RatingDraftDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *RatingDraftDao) Create(draft *RatingDraft) *RatingDraft {
+	draft.CreateTime = time.Now()
+	draft.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(draft)
+	this.PanicError(db.Error)
+	return draft
+}
+
+func (this *RatingDraftDao) Save(draft *RatingDraft) *RatingDraft {
+	draft.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Save(draft)
+	this.PanicError(db.Error)
+	return draft
+}
+
+func (this *RatingDraftDao) FindBySubmissionAndJudgeAndRound(submissionId int64, judgeUuid string, roundId int64) *RatingDraft {
+	var entity = &RatingDraft{}
+	db := core.CONTEXT.GetDB().Where("submission_id = ? AND judge_uuid = ? AND round_id = ?", submissionId, judgeUuid, roundId).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+func (this *RatingDraftDao) FindByJudge(judgeUuid string) []*RatingDraft {
+	var entities []*RatingDraft
+	db := core.CONTEXT.GetDB().Where("judge_uuid = ?", judgeUuid).Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *RatingDraftDao) FindByRoundId(roundId int64) []*RatingDraft {
+	var entities []*RatingDraft
+	db := core.CONTEXT.GetDB().Where("round_id = ?", roundId).Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *RatingDraftDao) Delete(draft *RatingDraft) {
+	db := core.CONTEXT.GetDB().Delete(draft)
+	this.PanicError(db.Error)
+}
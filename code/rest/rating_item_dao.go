@@ -0,0 +1,31 @@
+package rest
+
+import (
+	"github.com/eyebluecn/tank/code/core"
+)
+
+type RatingItemDao struct {
+	BaseDao
+}
+
+func (this *RatingItemDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *RatingItemDao) Create(ratingItem *RatingItem) *RatingItem {
+	db := core.CONTEXT.GetDB().Create(ratingItem)
+	this.PanicError(db.Error)
+	return ratingItem
+}
+
+func (this *RatingItemDao) FindByRatingId(ratingId int64) []*RatingItem {
+	var entities []*RatingItem
+	db := core.CONTEXT.GetDB().Where("rating_id = ?", ratingId).Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *RatingItemDao) DeleteByRatingId(ratingId int64) {
+	db := core.CONTEXT.GetDB().Where("rating_id = ?", ratingId).Delete(RatingItem{})
+	this.PanicError(db.Error)
+}
@@ -5,11 +5,24 @@ import (
 )
 
 type Rating struct {
-	Id           int64     `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
-	SubmissionId int64     `json:"submissionId" gorm:"type:bigint(20) not null"`
-	JudgeUuid    string    `json:"judgeUuid" gorm:"type:char(36) not null"`
-	Score        int       `json:"score" gorm:"type:int not null"`
-	Comment      string    `json:"comment" gorm:"type:text"`
-	CreateTime   time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
-	UpdateTime   time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
-}
\ No newline at end of file
+	Id            int64         `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	SubmissionId  int64         `json:"submissionId" gorm:"type:bigint(20) not null"`
+	JudgeUuid     string        `json:"judgeUuid" gorm:"type:char(36) not null"`
+	RoundId       int64         `json:"roundId" gorm:"type:bigint(20) not null;default:0"`
+	Score         int           `json:"score" gorm:"type:int not null"`
+	RubricId      int64         `json:"rubricId" gorm:"type:bigint(20) not null;default:0"`
+	WeightedScore float64       `json:"weightedScore" gorm:"type:double not null;default:0"`
+	Comment       string        `json:"comment" gorm:"type:text"`
+	Superseded    bool          `json:"superseded" gorm:"type:tinyint(1) not null;default:0"`
+	CreateTime    time.Time     `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime    time.Time     `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	Items         []*RatingItem `json:"items" gorm:"-"`
+}
+
+// total score of the rating. ratings scored with a rubric use the weighted total.
+func (this *Rating) TotalScore() float64 {
+	if this.RubricId > 0 {
+		return this.WeightedScore
+	}
+	return float64(this.Score)
+}
@@ -0,0 +1,370 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+type RoundController struct {
+	BaseController
+	roundDao          *RoundDao
+	roundService      *RoundService
+	submissionDao     *SubmissionDao
+	submissionService *SubmissionService
+	auditService      *AuditService
+}
+
+func (this *RoundController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.roundDao)
+	if b, ok := b.(*RoundDao); ok {
+		this.roundDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.roundService)
+	if b, ok := b.(*RoundService); ok {
+		this.roundService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionService)
+	if b, ok := b.(*SubmissionService); ok {
+		this.submissionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.auditService)
+	if b, ok := b.(*AuditService); ok {
+		this.auditService = b
+	}
+}
+
+func (this *RoundController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/round/list"] = this.Wrap(this.List, USER_ROLE_USER)
+	routeMap["/api/round/detail"] = this.Wrap(this.Detail, USER_ROLE_USER)
+	routeMap["/api/round/create"] = this.Wrap(this.Create, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/round/edit"] = this.Wrap(this.Edit, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/round/delete"] = this.Wrap(this.Delete, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/round/judge/add"] = this.Wrap(this.AddJudge, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/round/judge/remove"] = this.Wrap(this.RemoveJudge, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/round/judge/list"] = this.Wrap(this.ListJudges, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/round/submissions"] = this.Wrap(this.Submissions, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/round/enter"] = this.Wrap(this.Enter, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/round/promote"] = this.Wrap(this.Promote, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/round/eliminate"] = this.Wrap(this.Eliminate, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/round/apply"] = this.Wrap(this.Apply, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/round/history"] = this.Wrap(this.History, USER_ROLE_USER)
+
+	return routeMap
+}
+
+// parse a required int64 form value.
+func (this *RoundController) formInt64(request *http.Request, key string) (int64, *result.WebResult) {
+	str := request.FormValue(key)
+	if str == "" {
+		return 0, result.BadRequest("%s不能为空", key)
+	}
+	value, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return 0, result.BadRequest("%s格式错误", key)
+	}
+	return value, nil
+}
+
+// read the editable fields of a round from the request.
+func (this *RoundController) fillRound(request *http.Request, round *Round) *result.WebResult {
+	round.Name = request.FormValue("name")
+	round.Description = request.FormValue("description")
+
+	round.Blind = request.FormValue("blind") == TRUE
+	round.BlindRename = round.Blind && request.FormValue("blindRename") == TRUE
+
+	round.PromotionRule = request.FormValue("promotionRule")
+	if round.PromotionRule == "" {
+		round.PromotionRule = ROUND_RULE_MANUAL
+	}
+
+	if sortStr := request.FormValue("sort"); sortStr != "" {
+		sort, err := strconv.ParseInt(sortStr, 10, 64)
+		if err != nil {
+			return result.BadRequest("sort格式错误")
+		}
+		round.Sort = sort
+	}
+
+	if valueStr := request.FormValue("promotionValue"); valueStr != "" {
+		value, err := strconv.ParseFloat(valueStr, 64)
+		if err != nil {
+			return result.BadRequest("promotionValue格式错误")
+		}
+		round.PromotionValue = value
+	}
+
+	local, _ := time.LoadLocation("Local")
+	openTime, err := time.ParseInLocation("2006-01-02 15:04:05", request.FormValue("openTime"), local)
+	if err != nil {
+		return result.BadRequest("开放时间格式错误")
+	}
+	closeTime, err := time.ParseInLocation("2006-01-02 15:04:05", request.FormValue("closeTime"), local)
+	if err != nil {
+		return result.BadRequest("截止时间格式错误")
+	}
+	round.OpenTime = openTime
+	round.CloseTime = closeTime
+
+	return nil
+}
+
+// rounds of the competition, of every competition without competitionId.
+func (this *RoundController) List(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	if request.FormValue("competitionId") != "" {
+		competitionId, webResult := this.formInt64(request, "competitionId")
+		if webResult != nil {
+			return webResult
+		}
+		return this.Success(this.roundDao.FindByCompetitionId(competitionId))
+	}
+	return this.Success(this.roundDao.FindAll())
+}
+
+func (this *RoundController) Detail(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	id, webResult := this.formInt64(request, "id")
+	if webResult != nil {
+		return webResult
+	}
+
+	round := this.roundDao.Find(id)
+	if round == nil {
+		return result.BadRequest("轮次不存在")
+	}
+	return this.Success(round)
+}
+
+func (this *RoundController) Create(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	round := &Round{}
+	if request.FormValue("competitionId") != "" {
+		competitionId, webResult := this.formInt64(request, "competitionId")
+		if webResult != nil {
+			return webResult
+		}
+		round.CompetitionId = competitionId
+	}
+	if webResult := this.fillRound(request, round); webResult != nil {
+		return webResult
+	}
+
+	round, webResult := this.roundService.CreateRound(round)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(round)
+}
+
+func (this *RoundController) Edit(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	id, webResult := this.formInt64(request, "id")
+	if webResult != nil {
+		return webResult
+	}
+
+	round := this.roundDao.Find(id)
+	if round == nil {
+		return result.BadRequest("轮次不存在")
+	}
+	openTime, closeTime := round.OpenTime, round.CloseTime
+	if webResult := this.fillRound(request, round); webResult != nil {
+		return webResult
+	}
+
+	round, webResult = this.roundService.EditRound(round)
+	if webResult != nil {
+		return webResult
+	}
+	if !round.OpenTime.Equal(openTime) || !round.CloseTime.Equal(closeTime) {
+		this.auditService.Log(request, this.findUser(request), AUDIT_ACTION_ROUND_DEADLINE_EDIT, AUDIT_TARGET_ROUND, round.Id,
+			map[string]any{"openTime": openTime, "closeTime": closeTime},
+			map[string]any{"openTime": round.OpenTime, "closeTime": round.CloseTime})
+	}
+	return this.Success(round)
+}
+
+func (this *RoundController) Delete(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	id, webResult := this.formInt64(request, "id")
+	if webResult != nil {
+		return webResult
+	}
+
+	if webResult := this.roundService.DeleteRound(id); webResult != nil {
+		return webResult
+	}
+	return this.Success("删除成功")
+}
+
+func (this *RoundController) AddJudge(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	roundId, webResult := this.formInt64(request, "roundId")
+	if webResult != nil {
+		return webResult
+	}
+
+	roundJudge, webResult := this.roundService.AddJudge(roundId, request.FormValue("judgeUuid"))
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(roundJudge)
+}
+
+func (this *RoundController) RemoveJudge(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	roundId, webResult := this.formInt64(request, "roundId")
+	if webResult != nil {
+		return webResult
+	}
+
+	if webResult := this.roundService.RemoveJudge(roundId, request.FormValue("judgeUuid")); webResult != nil {
+		return webResult
+	}
+	return this.Success("移除成功")
+}
+
+func (this *RoundController) ListJudges(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	roundId, webResult := this.formInt64(request, "roundId")
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(this.roundService.ListJudges(roundId))
+}
+
+// submissions in a round, optionally filtered by round status.
+func (this *RoundController) Submissions(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	roundId, webResult := this.formInt64(request, "roundId")
+	if webResult != nil {
+		return webResult
+	}
+
+	roundStatus := request.FormValue("roundStatus")
+	if roundStatus != "" {
+		return this.Success(this.submissionDao.FindByRoundIdAndStatus(roundId, roundStatus))
+	}
+	return this.Success(this.submissionDao.FindByRoundId(roundId))
+}
+
+// put recommended submissions into the first round. Without submissionId, all recommended submissions not in any round enter,
+// only those of the competition if competitionId is given.
+func (this *RoundController) Enter(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	var submissions []*Submission
+	if request.FormValue("submissionId") != "" {
+		submissionId, webResult := this.formInt64(request, "submissionId")
+		if webResult != nil {
+			return webResult
+		}
+		submission := this.submissionDao.FindById(submissionId)
+		if submission == nil {
+			return result.BadRequest("提交作品不存在")
+		}
+		submissions = append(submissions, submission)
+	} else {
+		submissions = this.submissionDao.FindRecommendedWithoutRound()
+		if request.FormValue("competitionId") != "" {
+			competitionId, webResult := this.formInt64(request, "competitionId")
+			if webResult != nil {
+				return webResult
+			}
+			var competitionSubmissions []*Submission
+			for _, submission := range submissions {
+				if submission.CompetitionId == competitionId {
+					competitionSubmissions = append(competitionSubmissions, submission)
+				}
+			}
+			submissions = competitionSubmissions
+		}
+	}
+
+	for _, submission := range submissions {
+		if webResult := this.roundService.Enter(submission, user); webResult != nil {
+			return webResult
+		}
+	}
+	return this.Success(submissions)
+}
+
+func (this *RoundController) Promote(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	submissionId, webResult := this.formInt64(request, "submissionId")
+	if webResult != nil {
+		return webResult
+	}
+	submission := this.submissionDao.FindById(submissionId)
+	if submission == nil {
+		return result.BadRequest("提交作品不存在")
+	}
+
+	if webResult := this.roundService.Promote(submission, user, 0, request.FormValue("note")); webResult != nil {
+		return webResult
+	}
+	return this.Success(submission)
+}
+
+func (this *RoundController) Eliminate(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	submissionId, webResult := this.formInt64(request, "submissionId")
+	if webResult != nil {
+		return webResult
+	}
+	submission := this.submissionDao.FindById(submissionId)
+	if submission == nil {
+		return result.BadRequest("提交作品不存在")
+	}
+
+	if webResult := this.roundService.Eliminate(submission, user, 0, request.FormValue("note")); webResult != nil {
+		return webResult
+	}
+	return this.Success(submission)
+}
+
+// apply the promotion rule of a closed round.
+func (this *RoundController) Apply(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	roundId, webResult := this.formInt64(request, "roundId")
+	if webResult != nil {
+		return webResult
+	}
+
+	submissions, webResult := this.roundService.ApplyPromotionRule(roundId, user)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(submissions)
+}
+
+// stage history of a submission. Students can only see their own.
+func (this *RoundController) History(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	submissionId, webResult := this.formInt64(request, "submissionId")
+	if webResult != nil {
+		return webResult
+	}
+	submission := this.submissionDao.FindById(submissionId)
+	if submission == nil {
+		return result.BadRequest("提交作品不存在")
+	}
+
+	if user.Role == USER_ROLE_USER && !this.submissionService.IsAuthor(user, submission) {
+		panic(result.UNAUTHORIZED)
+	}
+
+	return this.Success(this.roundService.History(submissionId))
+}
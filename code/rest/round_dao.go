@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+type RoundDao struct {
+	BaseDao
+}
+
+func (this *RoundDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *RoundDao) Create(round *Round) *Round {
+	if round == nil {
+		panic(result.BadRequest("round cannot be nil"))
+	}
+
+	round.CreateTime = time.Now()
+	round.UpdateTime = time.Now()
+
+	db := core.CONTEXT.GetDB().Create(round)
+	this.PanicError(db.Error)
+
+	return round
+}
+
+func (this *RoundDao) Save(round *Round) *Round {
+	if round == nil {
+		panic(result.BadRequest("round cannot be nil"))
+	}
+
+	round.UpdateTime = time.Now()
+
+	db := core.CONTEXT.GetDB().Save(round)
+	this.PanicError(db.Error)
+
+	return round
+}
+
+func (this *RoundDao) Find(id int64) *Round {
+	var entity = &Round{}
+	db := core.CONTEXT.GetDB().Where("id = ?", id).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+// all rounds ordered by sort.
+func (this *RoundDao) FindAll() []*Round {
+	var entities []*Round
+	db := core.CONTEXT.GetDB().Order("sort ASC, id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+// rounds of the competition ordered by sort.
+func (this *RoundDao) FindByCompetitionId(competitionId int64) []*Round {
+	var entities []*Round
+	db := core.CONTEXT.GetDB().Where("competition_id = ?", competitionId).Order("sort ASC, id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+// the first round of the competition. if not found return nil.
+func (this *RoundDao) FindFirst(competitionId int64) *Round {
+	rounds := this.FindByCompetitionId(competitionId)
+	if len(rounds) == 0 {
+		return nil
+	}
+	return rounds[0]
+}
+
+// the round after the given one in its competition. if it is the last round return nil.
+func (this *RoundDao) FindNext(round *Round) *Round {
+	rounds := this.FindByCompetitionId(round.CompetitionId)
+	for i, r := range rounds {
+		if r.Id == round.Id && i+1 < len(rounds) {
+			return rounds[i+1]
+		}
+	}
+	return nil
+}
+
+func (this *RoundDao) Delete(round *Round) {
+	if round == nil {
+		panic(result.BadRequest("round cannot be nil"))
+	}
+
+	db := core.CONTEXT.GetDB().Delete(round)
+	this.PanicError(db.Error)
+}
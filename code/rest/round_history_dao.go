@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+type RoundHistoryDao struct {
+	BaseDao
+}
+
+func (this *RoundHistoryDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *RoundHistoryDao) Create(roundHistory *RoundHistory) *RoundHistory {
+	roundHistory.CreateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(roundHistory)
+	this.PanicError(db.Error)
+	return roundHistory
+}
+
+// full stage history of a submission, oldest first.
+func (this *RoundHistoryDao) FindBySubmissionId(submissionId int64) []*RoundHistory {
+	var entities []*RoundHistory
+	db := core.CONTEXT.GetDB().Where("submission_id = ?", submissionId).Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *RoundHistoryDao) CountByRoundId(roundId int64) int64 {
+	var count int64
+	db := core.CONTEXT.GetDB().Model(&RoundHistory{}).Where("round_id = ?", roundId).Count(&count)
+	this.PanicError(db.Error)
+	return count
+}
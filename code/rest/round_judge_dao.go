@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+type RoundJudgeDao struct {
+	BaseDao
+}
+
+func (this *RoundJudgeDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *RoundJudgeDao) Create(roundJudge *RoundJudge) *RoundJudge {
+	roundJudge.CreateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(roundJudge)
+	this.PanicError(db.Error)
+	return roundJudge
+}
+
+func (this *RoundJudgeDao) FindByRoundId(roundId int64) []*RoundJudge {
+	var entities []*RoundJudge
+	db := core.CONTEXT.GetDB().Where("round_id = ?", roundId).Order("id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *RoundJudgeDao) FindByRoundIdAndJudgeUuid(roundId int64, judgeUuid string) *RoundJudge {
+	var entity = &RoundJudge{}
+	db := core.CONTEXT.GetDB().Where("round_id = ? AND judge_uuid = ?", roundId, judgeUuid).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+func (this *RoundJudgeDao) CountByRoundId(roundId int64) int64 {
+	var count int64
+	db := core.CONTEXT.GetDB().Model(&RoundJudge{}).Where("round_id = ?", roundId).Count(&count)
+	this.PanicError(db.Error)
+	return count
+}
+
+func (this *RoundJudgeDao) Delete(roundJudge *RoundJudge) {
+	db := core.CONTEXT.GetDB().Delete(roundJudge)
+	this.PanicError(db.Error)
+}
+
+func (this *RoundJudgeDao) DeleteByRoundId(roundId int64) {
+	db := core.CONTEXT.GetDB().Where("round_id = ?", roundId).Delete(RoundJudge{})
+	this.PanicError(db.Error)
+}
@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"time"
+)
+
+const (
+	//promote by administrator manually.
+	ROUND_RULE_MANUAL = "MANUAL"
+	//promote the top N submissions of every track.
+	ROUND_RULE_TOP_N = "TOP_N"
+	//promote the top N percent submissions of every track.
+	ROUND_RULE_TOP_PERCENT = "TOP_PERCENT"
+	//promote the submissions whose average score is not less than N.
+	ROUND_RULE_MIN_SCORE = "MIN_SCORE"
+)
+
+const (
+	//submission is being reviewed in this round.
+	ROUND_STATUS_ACTIVE = "ACTIVE"
+	//submission has been promoted out of this round.
+	ROUND_STATUS_PROMOTED = "PROMOTED"
+	//submission has been eliminated in this round.
+	ROUND_STATUS_ELIMINATED = "ELIMINATED"
+)
+
+// Round is one stage of the competition, eg. college preliminary -> school semifinal -> final.
+// Rounds are ordered by Sort, every competition has its own sequence of rounds.
+type Round struct {
+	Id             int64     `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	CompetitionId  int64     `json:"competitionId" gorm:"type:bigint(20) not null;default:0;index:idx_round_ci"`
+	Name           string    `json:"name" gorm:"type:varchar(100) not null"`
+	Sort           int64     `json:"sort" gorm:"type:bigint(20) not null;default:0"`
+	OpenTime       time.Time `json:"openTime" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"`
+	CloseTime      time.Time `json:"closeTime" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"`
+	PromotionRule  string    `json:"promotionRule" gorm:"type:varchar(20) not null;default:'MANUAL'"`
+	PromotionValue float64   `json:"promotionValue" gorm:"type:double not null;default:0"`
+	Description    string    `json:"description" gorm:"type:text"`
+	//judges see the submissions of a blind round by their blind codes only.
+	Blind bool `json:"blind" gorm:"type:tinyint(1) not null;default:0"`
+	//in a blind round, files are renamed after the blind code as well.
+	BlindRename bool      `json:"blindRename" gorm:"type:tinyint(1) not null;default:0"`
+	CreateTime  time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime  time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
+
+// whether the round is open at the moment.
+func (this *Round) IsOpen(now time.Time) bool {
+	return !now.Before(this.OpenTime) && !now.After(this.CloseTime)
+}
+
+// whether the round has been closed at the moment.
+func (this *Round) IsClosed(now time.Time) bool {
+	return now.After(this.CloseTime)
+}
+
+// RoundJudge is a judge in the judge pool of a round.
+type RoundJudge struct {
+	Id         int64     `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	RoundId    int64     `json:"roundId" gorm:"type:bigint(20) not null;index:idx_round_judge_ri"`
+	JudgeUuid  string    `json:"judgeUuid" gorm:"type:char(36) not null"`
+	CreateTime time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	User       *User     `json:"user" gorm:"-"`
+}
+
+// RoundHistory records every change of a submission's stage.
+type RoundHistory struct {
+	Id           int64     `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	SubmissionId int64     `json:"submissionId" gorm:"type:bigint(20) not null;index:idx_round_history_si"`
+	RoundId      int64     `json:"roundId" gorm:"type:bigint(20) not null"`
+	Status       string    `json:"status" gorm:"type:varchar(20) not null"`
+	Score        float64   `json:"score" gorm:"type:double not null;default:0"`
+	OperatorUuid string    `json:"operatorUuid" gorm:"type:char(36)"`
+	Note         string    `json:"note" gorm:"type:varchar(1024)"`
+	CreateTime   time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
@@ -0,0 +1,435 @@
+package rest
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+// @Service
+type RoundService struct {
+	BaseBean
+	roundDao           *RoundDao
+	roundJudgeDao      *RoundJudgeDao
+	roundHistoryDao    *RoundHistoryDao
+	submissionDao      *SubmissionDao
+	ratingDao          *RatingDao
+	userDao            *UserDao
+	snapshotService    *SnapshotService
+	appealDao          *AppealDao
+	competitionService *CompetitionService
+}
+
+func (this *RoundService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.roundDao)
+	if b, ok := b.(*RoundDao); ok {
+		this.roundDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.roundJudgeDao)
+	if b, ok := b.(*RoundJudgeDao); ok {
+		this.roundJudgeDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.roundHistoryDao)
+	if b, ok := b.(*RoundHistoryDao); ok {
+		this.roundHistoryDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.ratingDao)
+	if b, ok := b.(*RatingDao); ok {
+		this.ratingDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userDao)
+	if b, ok := b.(*UserDao); ok {
+		this.userDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.snapshotService)
+	if b, ok := b.(*SnapshotService); ok {
+		this.snapshotService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.appealDao)
+	if b, ok := b.(*AppealDao); ok {
+		this.appealDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.competitionService)
+	if b, ok := b.(*CompetitionService); ok {
+		this.competitionService = b
+	}
+}
+
+// validate the editable fields of a round.
+func (this *RoundService) validRound(round *Round) *result.WebResult {
+	if strings.TrimSpace(round.Name) == "" {
+		return result.BadRequest("轮次名称不能为空")
+	}
+	if !round.CloseTime.After(round.OpenTime) {
+		return result.BadRequest("截止时间必须晚于开放时间")
+	}
+
+	switch round.PromotionRule {
+	case ROUND_RULE_MANUAL:
+	case ROUND_RULE_TOP_N:
+		if round.PromotionValue < 1 {
+			return result.BadRequest("晋级数量必须大于0")
+		}
+	case ROUND_RULE_TOP_PERCENT:
+		if round.PromotionValue <= 0 || round.PromotionValue > 100 {
+			return result.BadRequest("晋级比例必须在0-100之间")
+		}
+	case ROUND_RULE_MIN_SCORE:
+		if round.PromotionValue < 0 || round.PromotionValue > 100 {
+			return result.BadRequest("晋级分数线必须在0-100之间")
+		}
+	default:
+		return result.BadRequest("不支持的晋级规则 %s", round.PromotionRule)
+	}
+	return nil
+}
+
+func (this *RoundService) CreateRound(round *Round) (*Round, *result.WebResult) {
+	if webResult := this.competitionService.CheckEditable(round.CompetitionId); webResult != nil {
+		return nil, webResult
+	}
+	if webResult := this.validRound(round); webResult != nil {
+		return nil, webResult
+	}
+
+	round = this.roundDao.Create(round)
+	return round, nil
+}
+
+func (this *RoundService) EditRound(round *Round) (*Round, *result.WebResult) {
+	if webResult := this.competitionService.CheckEditable(round.CompetitionId); webResult != nil {
+		return nil, webResult
+	}
+	if webResult := this.validRound(round); webResult != nil {
+		return nil, webResult
+	}
+
+	round = this.roundDao.Save(round)
+	return round, nil
+}
+
+// a round can only be deleted before any submission entered it.
+func (this *RoundService) DeleteRound(id int64) *result.WebResult {
+	round := this.roundDao.Find(id)
+	if round == nil {
+		return result.BadRequest("轮次不存在")
+	}
+	if webResult := this.competitionService.CheckEditable(round.CompetitionId); webResult != nil {
+		return webResult
+	}
+
+	if this.submissionDao.CountByRoundId(id) > 0 || this.roundHistoryDao.CountByRoundId(id) > 0 {
+		return result.BadRequest("该轮次已有作品参与，不能删除")
+	}
+
+	this.roundJudgeDao.DeleteByRoundId(id)
+	this.roundDao.Delete(round)
+	return nil
+}
+
+func (this *RoundService) AddJudge(roundId int64, judgeUuid string) (*RoundJudge, *result.WebResult) {
+	round := this.roundDao.Find(roundId)
+	if round == nil {
+		return nil, result.BadRequest("轮次不存在")
+	}
+
+	judge := this.userDao.FindByUuid(judgeUuid)
+	if judge == nil {
+		return nil, result.BadRequest("用户不存在")
+	}
+	if judge.Role != USER_ROLE_JUDGE {
+		return nil, result.BadRequest("该用户不是评委")
+	}
+
+	if this.roundJudgeDao.FindByRoundIdAndJudgeUuid(roundId, judgeUuid) != nil {
+		return nil, result.BadRequest("该评委已在本轮评委库中")
+	}
+
+	roundJudge := this.roundJudgeDao.Create(&RoundJudge{
+		RoundId:   roundId,
+		JudgeUuid: judgeUuid,
+	})
+	roundJudge.User = judge
+	return roundJudge, nil
+}
+
+func (this *RoundService) RemoveJudge(roundId int64, judgeUuid string) *result.WebResult {
+	roundJudge := this.roundJudgeDao.FindByRoundIdAndJudgeUuid(roundId, judgeUuid)
+	if roundJudge == nil {
+		return result.BadRequest("该评委不在本轮评委库中")
+	}
+
+	this.roundJudgeDao.Delete(roundJudge)
+	return nil
+}
+
+// judge pool of a round with user info.
+func (this *RoundService) ListJudges(roundId int64) []*RoundJudge {
+	roundJudges := this.roundJudgeDao.FindByRoundId(roundId)
+	for _, roundJudge := range roundJudges {
+		roundJudge.User = this.userDao.FindByUuid(roundJudge.JudgeUuid)
+	}
+	return roundJudges
+}
+
+// whether the judge can rate the submission in its current round.
+// submissions not in any round keep the legacy behavior.
+func (this *RoundService) CheckRatable(submission *Submission, judge *User) *result.WebResult {
+	//an archived competition takes no rating, in a round or not.
+	if webResult := this.competitionService.CheckEditable(submission.CompetitionId); webResult != nil {
+		return webResult
+	}
+	if submission.RoundId == 0 {
+		return nil
+	}
+
+	round := this.roundDao.Find(submission.RoundId)
+	if round == nil {
+		return result.BadRequest("作品所在轮次不存在")
+	}
+	if submission.RoundStatus != ROUND_STATUS_ACTIVE {
+		return result.BadRequest("作品已结束本轮评审")
+	}
+	//the re-review of an accepted appeal goes on after the round closes, until its own deadline.
+	if !round.IsOpen(time.Now()) {
+		appeal := this.appealDao.FindReviewing(submission.Id, round.Id)
+		if appeal == nil || time.Now().After(appeal.ReviewDeadline) {
+			return result.BadRequest("%s 当前不在评审时间内", round.Name)
+		}
+	}
+
+	//an empty judge pool means every judge can rate.
+	if this.roundJudgeDao.CountByRoundId(round.Id) > 0 && this.roundJudgeDao.FindByRoundIdAndJudgeUuid(round.Id, judge.Uuid) == nil {
+		return result.BadRequest("您不在 %s 的评委库中", round.Name)
+	}
+	return nil
+}
+
+func (this *RoundService) history(submission *Submission, operator *User, score float64, note string) {
+	operatorUuid := ""
+	if operator != nil {
+		operatorUuid = operator.Uuid
+	}
+	this.roundHistoryDao.Create(&RoundHistory{
+		SubmissionId: submission.Id,
+		RoundId:      submission.RoundId,
+		Status:       submission.RoundStatus,
+		Score:        score,
+		OperatorUuid: operatorUuid,
+		Note:         note,
+	})
+}
+
+// whether any round has been configured in the competition.
+func (this *RoundService) HasRounds(competitionId int64) bool {
+	return this.roundDao.FindFirst(competitionId) != nil
+}
+
+// put a recommended submission into the first round of its competition.
+func (this *RoundService) Enter(submission *Submission, operator *User) *result.WebResult {
+	if webResult := this.competitionService.CheckEditable(submission.CompetitionId); webResult != nil {
+		return webResult
+	}
+	if submission.RoundId != 0 {
+		return result.BadRequest("作品已进入轮次评审")
+	}
+	if !submission.IsRecommended {
+		return result.BadRequest("作品尚未被推荐")
+	}
+
+	first := this.roundDao.FindFirst(submission.CompetitionId)
+	if first == nil {
+		return result.BadRequest("尚未配置评审轮次")
+	}
+
+	submission.RoundId = first.Id
+	submission.RoundStatus = ROUND_STATUS_ACTIVE
+	this.submissionDao.Save(submission)
+	this.history(submission, operator, 0, "")
+	return nil
+}
+
+// promote a submission out of its current round. If there is a next round, it enters the next round.
+func (this *RoundService) Promote(submission *Submission, operator *User, score float64, note string) *result.WebResult {
+	if webResult := this.competitionService.CheckEditable(submission.CompetitionId); webResult != nil {
+		return webResult
+	}
+	if submission.RoundId == 0 || submission.RoundStatus != ROUND_STATUS_ACTIVE {
+		return result.BadRequest("作品不在评审中")
+	}
+
+	round := this.roundDao.Find(submission.RoundId)
+	if round == nil {
+		return result.BadRequest("作品所在轮次不存在")
+	}
+
+	submission.RoundStatus = ROUND_STATUS_PROMOTED
+	this.history(submission, operator, score, note)
+
+	next := this.roundDao.FindNext(round)
+	if next != nil {
+		submission.RoundId = next.Id
+		submission.RoundStatus = ROUND_STATUS_ACTIVE
+		this.history(submission, operator, 0, "")
+	}
+
+	this.submissionDao.Save(submission)
+
+	//freeze what the judges of the next round will review.
+	if next != nil {
+		if _, webResult := this.snapshotService.Take(submission, SNAPSHOT_REASON_ROUND, operator); webResult != nil {
+			this.logger.Warn("cannot take snapshot of submission %d: %s", submission.Id, webResult.Msg)
+		}
+	}
+	return nil
+}
+
+// eliminate a submission in its current round.
+func (this *RoundService) Eliminate(submission *Submission, operator *User, score float64, note string) *result.WebResult {
+	if webResult := this.competitionService.CheckEditable(submission.CompetitionId); webResult != nil {
+		return webResult
+	}
+	if submission.RoundId == 0 || submission.RoundStatus != ROUND_STATUS_ACTIVE {
+		return result.BadRequest("作品不在评审中")
+	}
+
+	submission.RoundStatus = ROUND_STATUS_ELIMINATED
+	this.submissionDao.Save(submission)
+	this.history(submission, operator, score, note)
+	return nil
+}
+
+// put the submission back under review in the round, eg. for the re-review of an accepted appeal.
+func (this *RoundService) Reopen(submission *Submission, roundId int64, operator *User, note string) *result.WebResult {
+	if webResult := this.competitionService.CheckEditable(submission.CompetitionId); webResult != nil {
+		return webResult
+	}
+	if submission.RoundId != roundId {
+		return result.BadRequest("作品已不在该轮次")
+	}
+	if submission.RoundStatus == ROUND_STATUS_ACTIVE {
+		return nil
+	}
+	if submission.RoundStatus == ROUND_STATUS_PROMOTED {
+		return result.BadRequest("作品已晋级")
+	}
+
+	submission.RoundStatus = ROUND_STATUS_ACTIVE
+	this.submissionDao.Save(submission)
+	this.history(submission, operator, 0, note)
+	return nil
+}
+
+// average score of the submission in the round, and the number of ratings.
+func (this *RoundService) Score(submissionId int64, roundId int64) (float64, int) {
+	var sum float64
+	count := 0
+	for _, rating := range this.ratingDao.FindBySubmissionId(submissionId) {
+		if rating.RoundId == roundId && !rating.Superseded {
+			sum += rating.TotalScore()
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return sum / float64(count), count
+}
+
+// average score of every submission in the round.
+func (this *RoundService) averageScores(roundId int64) map[int64]float64 {
+	sums := make(map[int64]float64)
+	counts := make(map[int64]int)
+	for _, rating := range this.ratingDao.FindByRoundId(roundId) {
+		sums[rating.SubmissionId] += rating.TotalScore()
+		counts[rating.SubmissionId]++
+	}
+
+	averages := make(map[int64]float64)
+	for submissionId, sum := range sums {
+		averages[submissionId] = sum / float64(counts[submissionId])
+	}
+	return averages
+}
+
+// apply the promotion rule of a closed round to all its active submissions, track by track.
+func (this *RoundService) ApplyPromotionRule(roundId int64, operator *User) ([]*Submission, *result.WebResult) {
+	round := this.roundDao.Find(roundId)
+	if round == nil {
+		return nil, result.BadRequest("轮次不存在")
+	}
+	if webResult := this.competitionService.CheckEditable(round.CompetitionId); webResult != nil {
+		return nil, webResult
+	}
+	if round.PromotionRule == ROUND_RULE_MANUAL {
+		return nil, result.BadRequest("该轮次为手动晋级")
+	}
+	if !round.IsClosed(time.Now()) {
+		return nil, result.BadRequest("轮次尚未截止，不能执行晋级")
+	}
+
+	averages := this.averageScores(roundId)
+
+	trackSubmissions := make(map[int64][]*Submission)
+	var trackIds []int64
+	for _, submission := range this.submissionDao.FindByRoundIdAndStatus(roundId, ROUND_STATUS_ACTIVE) {
+		if _, ok := trackSubmissions[submission.TrackId]; !ok {
+			trackIds = append(trackIds, submission.TrackId)
+		}
+		trackSubmissions[submission.TrackId] = append(trackSubmissions[submission.TrackId], submission)
+	}
+
+	var submissions []*Submission
+	for _, trackId := range trackIds {
+		list := trackSubmissions[trackId]
+		sort.SliceStable(list, func(i, j int) bool {
+			return averages[list[i].Id] > averages[list[j].Id]
+		})
+
+		quota := len(list)
+		switch round.PromotionRule {
+		case ROUND_RULE_TOP_N:
+			quota = int(round.PromotionValue)
+		case ROUND_RULE_TOP_PERCENT:
+			quota = int(float64(len(list))*round.PromotionValue/100 + 0.5)
+		}
+
+		for i, submission := range list {
+			score := averages[submission.Id]
+			promoted := i < quota
+			if round.PromotionRule == ROUND_RULE_MIN_SCORE {
+				promoted = score >= round.PromotionValue
+			}
+
+			if promoted {
+				this.Promote(submission, operator, score, round.PromotionRule)
+			} else {
+				this.Eliminate(submission, operator, score, round.PromotionRule)
+			}
+			submissions = append(submissions, submission)
+		}
+	}
+
+	return submissions, nil
+}
+
+func (this *RoundService) History(submissionId int64) []*RoundHistory {
+	return this.roundHistoryDao.FindBySubmissionId(submissionId)
+}
@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	jsoniter "github.com/json-iterator/go"
+)
+
+type RubricController struct {
+	BaseController
+	rubricDao     *RubricDao
+	rubricService *RubricService
+}
+
+func (this *RubricController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.rubricDao)
+	if b, ok := b.(*RubricDao); ok {
+		this.rubricDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.rubricService)
+	if b, ok := b.(*RubricService); ok {
+		this.rubricService = b
+	}
+}
+
+func (this *RubricController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/rubric/active"] = this.Wrap(this.Active, USER_ROLE_USER)
+	routeMap["/api/rubric/list"] = this.Wrap(this.List, USER_ROLE_USER)
+	routeMap["/api/rubric/detail"] = this.Wrap(this.Detail, USER_ROLE_USER)
+	routeMap["/api/rubric/create"] = this.Wrap(this.Create, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/rubric/delete"] = this.Wrap(this.Delete, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+// the rubric in effect of a track. judges score with it.
+func (this *RubricController) Active(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	trackId, err := strconv.ParseInt(request.FormValue("trackId"), 10, 64)
+	if err != nil {
+		return result.BadRequest("trackId格式错误")
+	}
+	return this.Success(this.rubricService.FindActive(trackId))
+}
+
+// all versions of a track. without trackId, the rubrics in effect of all tracks.
+func (this *RubricController) List(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	trackIdStr := request.FormValue("trackId")
+	if trackIdStr == "" {
+		return this.Success(this.rubricDao.FindActive())
+	}
+
+	trackId, err := strconv.ParseInt(trackIdStr, 10, 64)
+	if err != nil {
+		return result.BadRequest("trackId格式错误")
+	}
+	return this.Success(this.rubricDao.FindByTrackId(trackId))
+}
+
+func (this *RubricController) Detail(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	id, err := strconv.ParseInt(request.FormValue("id"), 10, 64)
+	if err != nil {
+		return result.BadRequest("ID格式错误")
+	}
+
+	rubric := this.rubricService.Wrap(this.rubricDao.Find(id))
+	if rubric == nil {
+		return result.BadRequest("评分标准不存在")
+	}
+	return this.Success(rubric)
+}
+
+// create a new version of the track's rubric. criteria is a json array of RubricCriterion.
+func (this *RubricController) Create(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	trackId, err := strconv.ParseInt(request.FormValue("trackId"), 10, 64)
+	if err != nil {
+		return result.BadRequest("trackId格式错误")
+	}
+
+	var criteria []*RubricCriterion
+	err = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(request.FormValue("criteria")), &criteria)
+	if err != nil {
+		return result.BadRequest("评分项数据格式错误")
+	}
+
+	rubric, webResult := this.rubricService.CreateRubric(trackId, request.FormValue("name"), criteria)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(rubric)
+}
+
+func (this *RubricController) Delete(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	id, err := strconv.ParseInt(request.FormValue("id"), 10, 64)
+	if err != nil {
+		return result.BadRequest("ID格式错误")
+	}
+
+	if webResult := this.rubricService.DeleteRubric(id); webResult != nil {
+		return webResult
+	}
+	return this.Success("删除成功")
+}
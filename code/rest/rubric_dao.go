@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+type RubricDao struct {
+	BaseDao
+}
+
+func (this *RubricDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *RubricDao) Create(rubric *Rubric) *Rubric {
+	rubric.CreateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(rubric)
+	this.PanicError(db.Error)
+	return rubric
+}
+
+func (this *RubricDao) Save(rubric *Rubric) *Rubric {
+	db := core.CONTEXT.GetDB().Save(rubric)
+	this.PanicError(db.Error)
+	return rubric
+}
+
+func (this *RubricDao) Find(id int64) *Rubric {
+	var entity = &Rubric{}
+	db := core.CONTEXT.GetDB().Where("id = ?", id).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+// the rubric version in effect of a track. if not found return nil.
+func (this *RubricDao) FindActiveByTrackId(trackId int64) *Rubric {
+	var entity = &Rubric{}
+	db := core.CONTEXT.GetDB().Where("track_id = ? AND active = ?", trackId, true).Order("version DESC").First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+// all versions of a track, latest first.
+func (this *RubricDao) FindByTrackId(trackId int64) []*Rubric {
+	var entities []*Rubric
+	db := core.CONTEXT.GetDB().Where("track_id = ?", trackId).Order("version DESC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *RubricDao) FindActive() []*Rubric {
+	var entities []*Rubric
+	db := core.CONTEXT.GetDB().Where("active = ?", true).Order("track_id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *RubricDao) MaxVersionByTrackId(trackId int64) int64 {
+	var version int64
+	err := core.CONTEXT.GetDB().Model(&Rubric{}).Where("track_id = ?", trackId).Select("COALESCE(MAX(version), 0)").Row().Scan(&version)
+	this.PanicError(err)
+	return version
+}
+
+func (this *RubricDao) DeactivateByTrackId(trackId int64) {
+	db := core.CONTEXT.GetDB().Model(&Rubric{}).Where("track_id = ?", trackId).Update("active", false)
+	this.PanicError(db.Error)
+}
+
+func (this *RubricDao) Delete(rubric *Rubric) {
+	db := core.CONTEXT.GetDB().Where("rubric_id = ?", rubric.Id).Delete(RubricCriterion{})
+	this.PanicError(db.Error)
+
+	db = core.CONTEXT.GetDB().Delete(rubric)
+	this.PanicError(db.Error)
+}
+
+func (this *RubricDao) CreateCriterion(criterion *RubricCriterion) *RubricCriterion {
+	db := core.CONTEXT.GetDB().Create(criterion)
+	this.PanicError(db.Error)
+	return criterion
+}
+
+func (this *RubricDao) FindCriteriaByRubricId(rubricId int64) []*RubricCriterion {
+	var entities []*RubricCriterion
+	db := core.CONTEXT.GetDB().Where("rubric_id = ?", rubricId).Order("sort ASC, id ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
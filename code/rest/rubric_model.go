@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"time"
+)
+
+// Rubric is the scoring standard of a track. Every edit creates a new version,
+// so ratings always refer to the exact criteria they were scored with.
+type Rubric struct {
+	Id         int64              `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	TrackId    int64              `json:"trackId" gorm:"type:bigint(20) not null;index:idx_rubric_ti"`
+	Name       string             `json:"name" gorm:"type:varchar(100) not null"`
+	Version    int64              `json:"version" gorm:"type:bigint(20) not null;default:1"`
+	Active     bool               `json:"active" gorm:"type:tinyint(1) not null;default:0"`
+	CreateTime time.Time          `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	Criteria   []*RubricCriterion `json:"criteria" gorm:"-"`
+}
+
+// RubricCriterion is a named criterion of a rubric, eg. innovation 30%.
+type RubricCriterion struct {
+	Id              int64   `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	RubricId        int64   `json:"rubricId" gorm:"type:bigint(20) not null;index:idx_rubric_criterion_ri"`
+	Name            string  `json:"name" gorm:"type:varchar(100) not null"`
+	Description     string  `json:"description" gorm:"type:text"`
+	Weight          float64 `json:"weight" gorm:"type:double not null;default:0"`
+	MinScore        float64 `json:"minScore" gorm:"type:double not null;default:0"`
+	MaxScore        float64 `json:"maxScore" gorm:"type:double not null;default:100"`
+	CommentRequired bool    `json:"commentRequired" gorm:"type:tinyint(1) not null;default:0"`
+	Sort            int64   `json:"sort" gorm:"type:bigint(20) not null;default:0"`
+}
+
+// RatingItem is the score of a judge on one criterion.
+type RatingItem struct {
+	Id          int64   `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	RatingId    int64   `json:"ratingId" gorm:"type:bigint(20) not null;index:idx_rating_item_ri"`
+	CriterionId int64   `json:"criterionId" gorm:"type:bigint(20) not null"`
+	Score       float64 `json:"score" gorm:"type:double not null;default:0"`
+	Comment     string  `json:"comment" gorm:"type:text"`
+}
+
+// weighted total in 0-100. every criterion is scaled to 0-100 by its own min and max first.
+func (this *Rubric) WeightedTotal(items []*RatingItem) float64 {
+	scores := make(map[int64]float64)
+	for _, item := range items {
+		scores[item.CriterionId] = item.Score
+	}
+
+	var sum, weights float64
+	for _, criterion := range this.Criteria {
+		scaled := 0.0
+		if criterion.MaxScore > criterion.MinScore {
+			scaled = (scores[criterion.Id] - criterion.MinScore) / (criterion.MaxScore - criterion.MinScore) * 100
+		}
+		sum += criterion.Weight * scaled
+		weights += criterion.Weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
+}
@@ -0,0 +1,192 @@
+package rest
+
+import (
+	"math"
+	"strings"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+// @Service
+type RubricService struct {
+	BaseBean
+	rubricDao          *RubricDao
+	trackDao           *TrackDao
+	ratingDao          *RatingDao
+	ratingItemDao      *RatingItemDao
+	competitionService *CompetitionService
+}
+
+func (this *RubricService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.rubricDao)
+	if b, ok := b.(*RubricDao); ok {
+		this.rubricDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.trackDao)
+	if b, ok := b.(*TrackDao); ok {
+		this.trackDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.ratingDao)
+	if b, ok := b.(*RatingDao); ok {
+		this.ratingDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.ratingItemDao)
+	if b, ok := b.(*RatingItemDao); ok {
+		this.ratingItemDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.competitionService)
+	if b, ok := b.(*CompetitionService); ok {
+		this.competitionService = b
+	}
+}
+
+// fill the criteria of the rubric.
+func (this *RubricService) Wrap(rubric *Rubric) *Rubric {
+	if rubric != nil {
+		rubric.Criteria = this.rubricDao.FindCriteriaByRubricId(rubric.Id)
+	}
+	return rubric
+}
+
+// the rubric version in effect of a track with its criteria. nil means the track is scored by a single score.
+func (this *RubricService) FindActive(trackId int64) *Rubric {
+	return this.Wrap(this.rubricDao.FindActiveByTrackId(trackId))
+}
+
+// create a new rubric version for the track. the new version takes effect immediately.
+func (this *RubricService) CreateRubric(trackId int64, name string, criteria []*RubricCriterion) (*Rubric, *result.WebResult) {
+	track := this.trackDao.Find(trackId)
+	if track == nil {
+		return nil, result.BadRequest("赛道不存在")
+	}
+	if webResult := this.competitionService.CheckEditable(track.CompetitionId); webResult != nil {
+		return nil, webResult
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, result.BadRequest("评分标准名称不能为空")
+	}
+	if len(criteria) == 0 {
+		return nil, result.BadRequest("评分项不能为空")
+	}
+
+	var weights float64
+	names := make(map[string]bool)
+	for _, criterion := range criteria {
+		criterion.Name = strings.TrimSpace(criterion.Name)
+		if criterion.Name == "" {
+			return nil, result.BadRequest("评分项名称不能为空")
+		}
+		if names[criterion.Name] {
+			return nil, result.BadRequest("评分项 %s 重复", criterion.Name)
+		}
+		names[criterion.Name] = true
+		if criterion.Weight <= 0 {
+			return nil, result.BadRequest("评分项 %s 的权重必须大于0", criterion.Name)
+		}
+		if criterion.MaxScore <= criterion.MinScore {
+			return nil, result.BadRequest("评分项 %s 的最高分必须大于最低分", criterion.Name)
+		}
+		weights += criterion.Weight
+	}
+	//weights are percentages.
+	if math.Abs(weights-100) > 0.001 {
+		return nil, result.BadRequest("评分项权重之和必须为100，当前为 %v", weights)
+	}
+
+	this.rubricDao.DeactivateByTrackId(trackId)
+
+	rubric := this.rubricDao.Create(&Rubric{
+		TrackId: trackId,
+		Name:    name,
+		Version: this.rubricDao.MaxVersionByTrackId(trackId) + 1,
+		Active:  true,
+	})
+	for i, criterion := range criteria {
+		criterion.Id = 0
+		criterion.RubricId = rubric.Id
+		criterion.Sort = int64(i)
+		this.rubricDao.CreateCriterion(criterion)
+	}
+	rubric.Criteria = criteria
+
+	return rubric, nil
+}
+
+// a rubric can only be deleted when no rating used it. the previous version takes effect again.
+func (this *RubricService) DeleteRubric(id int64) *result.WebResult {
+	rubric := this.rubricDao.Find(id)
+	if rubric == nil {
+		return result.BadRequest("评分标准不存在")
+	}
+	if track := this.trackDao.Find(rubric.TrackId); track != nil {
+		if webResult := this.competitionService.CheckEditable(track.CompetitionId); webResult != nil {
+			return webResult
+		}
+	}
+	if this.ratingDao.CountByRubricId(id) > 0 {
+		return result.BadRequest("已有评委使用该评分标准评分，不能删除")
+	}
+
+	this.rubricDao.Delete(rubric)
+
+	if rubric.Active {
+		versions := this.rubricDao.FindByTrackId(rubric.TrackId)
+		if len(versions) > 0 {
+			versions[0].Active = true
+			this.rubricDao.Save(versions[0])
+		}
+	}
+	return nil
+}
+
+// validate the criterion scores against the rubric in effect, and compute the weighted total.
+// rubricId is the version the judge was scoring with, it must be the one in effect.
+func (this *RubricService) Evaluate(rubric *Rubric, rubricId int64, items []*RatingItem) (float64, *result.WebResult) {
+	if rubricId != rubric.Id {
+		return 0, result.BadRequest("评分标准已更新为第 %d 版，请刷新后重新评分", rubric.Version)
+	}
+
+	itemMap := make(map[int64]*RatingItem)
+	for _, item := range items {
+		if _, ok := itemMap[item.CriterionId]; ok {
+			return 0, result.BadRequest("评分项重复")
+		}
+		itemMap[item.CriterionId] = item
+	}
+	if len(itemMap) != len(rubric.Criteria) {
+		return 0, result.BadRequest("请为全部 %d 个评分项打分", len(rubric.Criteria))
+	}
+
+	for _, criterion := range rubric.Criteria {
+		item, ok := itemMap[criterion.Id]
+		if !ok {
+			return 0, result.BadRequest("评分项 %s 未打分", criterion.Name)
+		}
+		if item.Score < criterion.MinScore || item.Score > criterion.MaxScore {
+			return 0, result.BadRequest("评分项 %s 的分数必须在 %v-%v 之间", criterion.Name, criterion.MinScore, criterion.MaxScore)
+		}
+		if criterion.CommentRequired && strings.TrimSpace(item.Comment) == "" {
+			return 0, result.BadRequest("评分项 %s 必须填写评语", criterion.Name)
+		}
+	}
+
+	return rubric.WeightedTotal(items), nil
+}
+
+// replace the criterion scores of a rating.
+func (this *RubricService) SaveItems(rating *Rating, items []*RatingItem) {
+	this.ratingItemDao.DeleteByRatingId(rating.Id)
+	for _, item := range items {
+		item.Id = 0
+		item.RatingId = rating.Id
+		this.ratingItemDao.Create(item)
+	}
+	rating.Items = items
+}
@@ -291,6 +291,9 @@ func (this *ShareController) Browse(writer http.ResponseWriter, request *http.Re
 				},
 			}
 			matters = this.matterDao.FindByUuids(uuids, sortArray)
+			for _, matter := range matters {
+				matter.ShowHash(user)
+			}
 
 			share.Matters = matters
 		}
@@ -432,6 +435,12 @@ func (this *ShareController) MatterPage(writer http.ResponseWriter, request *htt
 		"",
 	)
 
+	if matters, ok := pager.Data.([]*Matter); ok {
+		for _, matter := range matters {
+			matter.ShowHash(user)
+		}
+	}
+
 	return this.Success(pager)
 }
 
@@ -0,0 +1,199 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+// judges review the snapshots instead of the live folders.
+type SnapshotController struct {
+	BaseController
+	snapshotDao       *SnapshotDao
+	snapshotService   *SnapshotService
+	submissionDao     *SubmissionDao
+	submissionService *SubmissionService
+	blindService      *BlindService
+}
+
+func (this *SnapshotController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.snapshotDao)
+	if b, ok := b.(*SnapshotDao); ok {
+		this.snapshotDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.snapshotService)
+	if b, ok := b.(*SnapshotService); ok {
+		this.snapshotService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionService)
+	if b, ok := b.(*SubmissionService); ok {
+		this.submissionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.blindService)
+	if b, ok := b.(*BlindService); ok {
+		this.blindService = b
+	}
+}
+
+func (this *SnapshotController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/snapshot/list"] = this.Wrap(this.List, USER_ROLE_USER)
+	routeMap["/api/snapshot/detail"] = this.Wrap(this.Detail, USER_ROLE_USER)
+	routeMap["/api/snapshot/preview"] = this.Wrap(this.Preview, USER_ROLE_USER)
+	routeMap["/api/snapshot/download"] = this.Wrap(this.Download, USER_ROLE_USER)
+	routeMap["/api/snapshot/zip"] = this.Wrap(this.Zip, USER_ROLE_USER)
+	routeMap["/api/snapshot/take"] = this.Wrap(this.Take, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+// find the submission and check whether the user can view it.
+func (this *SnapshotController) checkSubmission(request *http.Request, submissionId int64) *Submission {
+	user := this.checkUser(request)
+
+	submission := this.submissionDao.FindById(submissionId)
+	if submission == nil {
+		panic(result.BadRequest("提交作品不存在"))
+	}
+	if !this.submissionService.CanView(user, submission) {
+		panic(result.UNAUTHORIZED)
+	}
+	return submission
+}
+
+func (this *SnapshotController) checkSnapshot(request *http.Request, idStr string) (*Snapshot, *Submission) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		panic(result.BadRequest("snapshotId格式错误"))
+	}
+	snapshot := this.snapshotDao.Find(id)
+	if snapshot == nil {
+		panic(result.BadRequest("快照不存在"))
+	}
+	return snapshot, this.checkSubmission(request, snapshot.SubmissionId)
+}
+
+func (this *SnapshotController) checkEntry(request *http.Request) (*SnapshotEntry, *Submission) {
+	entryId, err := strconv.ParseInt(request.FormValue("entryId"), 10, 64)
+	if err != nil {
+		panic(result.BadRequest("entryId格式错误"))
+	}
+	entry := this.snapshotDao.FindEntry(entryId)
+	if entry == nil {
+		panic(result.BadRequest("快照文件不存在"))
+	}
+	snapshot := this.snapshotDao.Find(entry.SnapshotId)
+	if snapshot == nil {
+		panic(result.BadRequest("快照不存在"))
+	}
+	return entry, this.checkSubmission(request, snapshot.SubmissionId)
+}
+
+// judges of a blind round see neither who took the snapshot nor, if renamed, the original file names.
+func (this *SnapshotController) mask(request *http.Request, submission *Submission, snapshot *Snapshot, entries []*SnapshotEntry) (*Snapshot, []*SnapshotEntry) {
+	user := this.checkUser(request)
+	if !this.blindService.Applies(user, submission) {
+		return snapshot, entries
+	}
+	if snapshot != nil {
+		masked := *snapshot
+		masked.OperatorUuid = ""
+		snapshot = &masked
+	}
+	return snapshot, this.blindService.MaskEntries(user, submission, entries)
+}
+
+func (this *SnapshotController) List(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	submissionId, err := strconv.ParseInt(request.FormValue("submissionId"), 10, 64)
+	if err != nil {
+		return result.BadRequest("submissionId格式错误")
+	}
+	submission := this.checkSubmission(request, submissionId)
+
+	snapshots := this.snapshotDao.FindBySubmissionId(submissionId)
+	for i, snapshot := range snapshots {
+		snapshots[i], _ = this.mask(request, submission, snapshot, nil)
+	}
+	return this.Success(snapshots)
+}
+
+// snapshot with all its entries. without id, the current snapshot of the submission is returned.
+func (this *SnapshotController) Detail(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	idStr := request.FormValue("id")
+	if idStr == "" {
+		submissionId, err := strconv.ParseInt(request.FormValue("submissionId"), 10, 64)
+		if err != nil {
+			return result.BadRequest("submissionId格式错误")
+		}
+		submission := this.checkSubmission(request, submissionId)
+		if submission.SnapshotId == 0 {
+			return this.Success(nil)
+		}
+		idStr = strconv.FormatInt(submission.SnapshotId, 10)
+	}
+
+	snapshot, submission := this.checkSnapshot(request, idStr)
+	snapshot, entries := this.mask(request, submission, snapshot, this.snapshotDao.FindEntriesBySnapshotId(snapshot.Id))
+	return this.Success(map[string]any{
+		"snapshot": snapshot,
+		"entries":  entries,
+	})
+}
+
+func (this *SnapshotController) Preview(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	entry, submission := this.checkEntry(request)
+	_, entries := this.mask(request, submission, nil, []*SnapshotEntry{entry})
+	this.snapshotService.DownloadEntry(writer, request, entries[0], false)
+	return nil
+}
+
+func (this *SnapshotController) Download(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	entry, submission := this.checkEntry(request)
+	_, entries := this.mask(request, submission, nil, []*SnapshotEntry{entry})
+	this.snapshotService.DownloadEntry(writer, request, entries[0], true)
+	return nil
+}
+
+func (this *SnapshotController) Zip(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	snapshot, submission := this.checkSnapshot(request, request.FormValue("snapshotId"))
+	_, entries := this.mask(request, submission, nil, this.snapshotDao.FindEntriesBySnapshotId(snapshot.Id))
+	name := submission.Title
+	if this.blindService.Applies(this.checkUser(request), submission) {
+		name = this.blindService.Code(submission)
+	}
+	this.snapshotService.DownloadZip(writer, request, entries, name)
+	return nil
+}
+
+// take a snapshot manually.
+func (this *SnapshotController) Take(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	submissionId, err := strconv.ParseInt(request.FormValue("submissionId"), 10, 64)
+	if err != nil {
+		return result.BadRequest("submissionId格式错误")
+	}
+	submission := this.submissionDao.FindById(submissionId)
+	if submission == nil {
+		return result.BadRequest("提交作品不存在")
+	}
+
+	snapshot, webResult := this.snapshotService.Take(submission, SNAPSHOT_REASON_MANUAL, user)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(snapshot)
+}
@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+type SnapshotDao struct {
+	BaseDao
+}
+
+func (this *SnapshotDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *SnapshotDao) Create(snapshot *Snapshot) *Snapshot {
+	snapshot.CreateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(snapshot)
+	this.PanicError(db.Error)
+	return snapshot
+}
+
+func (this *SnapshotDao) Find(id int64) *Snapshot {
+	var entity = &Snapshot{}
+	db := core.CONTEXT.GetDB().Where("id = ?", id).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+// snapshots of a submission, latest first.
+func (this *SnapshotDao) FindBySubmissionId(submissionId int64) []*Snapshot {
+	var entities []*Snapshot
+	db := core.CONTEXT.GetDB().Where("submission_id = ?", submissionId).Order("id DESC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *SnapshotDao) CreateEntry(entry *SnapshotEntry) *SnapshotEntry {
+	db := core.CONTEXT.GetDB().Create(entry)
+	this.PanicError(db.Error)
+	return entry
+}
+
+func (this *SnapshotDao) FindEntry(id int64) *SnapshotEntry {
+	var entity = &SnapshotEntry{}
+	db := core.CONTEXT.GetDB().Where("id = ?", id).First(entity)
+	if db.Error != nil {
+		return nil
+	}
+	return entity
+}
+
+func (this *SnapshotDao) FindEntriesBySnapshotId(snapshotId int64) []*SnapshotEntry {
+	var entities []*SnapshotEntry
+	db := core.CONTEXT.GetDB().Where("snapshot_id = ?", snapshotId).Order("path ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
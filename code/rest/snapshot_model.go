@@ -0,0 +1,45 @@
+package rest
+
+import (
+	"regexp"
+	"time"
+)
+
+const (
+	//snapshot taken when the college admin recommends the submission.
+	SNAPSHOT_REASON_RECOMMEND = "RECOMMEND"
+	//snapshot taken when the submission enters a later round.
+	SNAPSHOT_REASON_ROUND = "ROUND"
+	//snapshot taken by administrator manually.
+	SNAPSHOT_REASON_MANUAL = "MANUAL"
+)
+
+// Snapshot is a read-only copy of the matter tree under Submission.MatterUuid.
+// Files are kept in the blob store by their sha256, so identical files are stored only once, see BlobService.Keep.
+type Snapshot struct {
+	Id           int64     `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	SubmissionId int64     `json:"submissionId" gorm:"type:bigint(20) not null;index:idx_snapshot_si"`
+	RoundId      int64     `json:"roundId" gorm:"type:bigint(20) not null;default:0"`
+	Reason       string    `json:"reason" gorm:"type:varchar(20) not null"`
+	MatterUuid   string    `json:"matterUuid" gorm:"type:char(36) not null"`
+	FileCount    int64     `json:"fileCount" gorm:"type:bigint(20) not null;default:0"`
+	Size         int64     `json:"size" gorm:"type:bigint(20) not null;default:0"`
+	Hash         string    `json:"hash" gorm:"type:char(64) not null"`
+	OperatorUuid string    `json:"operatorUuid" gorm:"type:char(36)"`
+	CreateTime   time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
+
+// SnapshotEntry is a file or directory in a snapshot. Path is relative to the submission folder.
+type SnapshotEntry struct {
+	Id         int64  `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	SnapshotId int64  `json:"snapshotId" gorm:"type:bigint(20) not null;index:idx_snapshot_entry_si"`
+	MatterUuid string `json:"matterUuid" gorm:"type:char(36)"`
+	Dir        bool   `json:"dir" gorm:"type:tinyint(1) not null;default:0"`
+	Name       string `json:"name" gorm:"type:varchar(255) not null"`
+	Path       string `json:"path" gorm:"type:varchar(1024)"`
+	Size       int64  `json:"size" gorm:"type:bigint(20) not null;default:0"`
+	Sha256     string `json:"sha256" gorm:"type:char(64)"`
+}
+
+// name of the blobs snapshots kept on their own under GetSnapshotRootDir, before they were kept in the blob store.
+var SNAPSHOT_BLOB_PATTERN = regexp.MustCompile(`^[0-9a-f]{64}$`)
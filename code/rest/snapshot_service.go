@@ -0,0 +1,251 @@
+package rest
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/download"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+// @Service
+type SnapshotService struct {
+	BaseBean
+	snapshotDao   *SnapshotDao
+	submissionDao *SubmissionDao
+	matterDao     *MatterDao
+	blobService   *BlobService
+}
+
+func (this *SnapshotService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.snapshotDao)
+	if b, ok := b.(*SnapshotDao); ok {
+		this.snapshotDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.blobService)
+	if b, ok := b.(*BlobService); ok {
+		this.blobService = b
+	}
+}
+
+// move the blobs the snapshots kept on their own before into the blob store, and count the references again.
+func (this *SnapshotService) Bootstrap() {
+	rootDir := GetSnapshotRootDir()
+	if !util.PathExists(rootDir) {
+		return
+	}
+
+	var count int64 = 0
+	var dirs []string
+	err := filepath.WalkDir(rootDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			dirs = append(dirs, path)
+			return nil
+		}
+		if !SNAPSHOT_BLOB_PATTERN.MatchString(entry.Name()) {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		blobHash := NewBlobHash()
+		size, err := io.Copy(blobHash, file)
+		_ = file.Close()
+		if err != nil {
+			return err
+		}
+		if blobHash.Sha256() != entry.Name() {
+			this.logger.Error("snapshot blob %s is broken, left as it is", path)
+			return nil
+		}
+		//snapshot blobs were read-only, while the blobs are shared with the files.
+		if err := os.Chmod(path, 0644); err != nil {
+			return err
+		}
+		if _, err := this.blobService.Adopt(path, entry.Name(), blobHash.Md5(), size); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		this.logger.Error("cannot move snapshot blobs into the blob store %s", err.Error())
+	}
+	if count == 0 {
+		return
+	}
+
+	//the references were counted as the blobs were moved, count them from the snapshot entries instead.
+	this.blobService.Recount()
+
+	//remove the directories left empty, the deepest first.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i])
+	}
+	this.logger.Info("move %d snapshot blobs into the blob store", count)
+}
+
+// freeze the matter tree of the submission into a new snapshot, and make it the current snapshot of the submission.
+func (this *SnapshotService) Take(submission *Submission, reason string, operator *User) (*Snapshot, *result.WebResult) {
+
+	root := this.matterDao.FindByUuid(submission.MatterUuid)
+	if root == nil || root.Deleted || !root.Dir {
+		return nil, result.BadRequest("作品文件夹不存在")
+	}
+
+	var entries []*SnapshotEntry
+
+	//DFS the tree. paths are relative to the submission folder.
+	var walkFunc func(dirMatter *Matter, relativePath string)
+	walkFunc = func(dirMatter *Matter, relativePath string) {
+		for _, matter := range this.matterDao.FindByPuuidAndSpaceUuid(dirMatter.Uuid, dirMatter.SpaceUuid) {
+			entry := &SnapshotEntry{
+				MatterUuid: matter.Uuid,
+				Dir:        matter.Dir,
+				Name:       matter.Name,
+				Path:       relativePath + "/" + matter.Name,
+			}
+			if matter.Dir {
+				entries = append(entries, entry)
+				walkFunc(matter, entry.Path)
+			} else {
+				blob := this.blobService.Keep(matter)
+				entry.Sha256, entry.Size = blob.Sha256, blob.Size
+				entries = append(entries, entry)
+			}
+		}
+	}
+	walkFunc(root, "")
+
+	//the hash of the whole tree.
+	treeHash := sha256.New()
+	var fileCount, size int64
+	for _, entry := range entries {
+		_, _ = fmt.Fprintf(treeHash, "%s\t%s\n", entry.Path, entry.Sha256)
+		if !entry.Dir {
+			fileCount++
+			size += entry.Size
+		}
+	}
+
+	operatorUuid := ""
+	if operator != nil {
+		operatorUuid = operator.Uuid
+	}
+
+	snapshot := this.snapshotDao.Create(&Snapshot{
+		SubmissionId: submission.Id,
+		RoundId:      submission.RoundId,
+		Reason:       reason,
+		MatterUuid:   submission.MatterUuid,
+		FileCount:    fileCount,
+		Size:         size,
+		Hash:         hex.EncodeToString(treeHash.Sum(nil)),
+		OperatorUuid: operatorUuid,
+	})
+	for _, entry := range entries {
+		entry.SnapshotId = snapshot.Id
+		this.snapshotDao.CreateEntry(entry)
+	}
+
+	submission.SnapshotId = snapshot.Id
+	this.submissionDao.Save(submission)
+
+	this.logger.Info("take snapshot %d of submission %d, %d files %s", snapshot.Id, submission.Id, fileCount, util.HumanFileSize(size))
+
+	return snapshot, nil
+}
+
+// download a file in the snapshot.
+func (this *SnapshotService) DownloadEntry(writer http.ResponseWriter, request *http.Request, entry *SnapshotEntry, withContentDisposition bool) {
+	if entry.Dir {
+		panic(result.BadRequest("directory cannot be downloaded"))
+	}
+
+	download.DownloadFile(writer, request, GetBlobPath(entry.Sha256), entry.Name, withContentDisposition)
+}
+
+// download the entries of a snapshot as a zip.
+func (this *SnapshotService) DownloadZip(writer http.ResponseWriter, request *http.Request, entries []*SnapshotEntry, name string) {
+
+	destZipDirPath := fmt.Sprintf("%s/%s/%d", GetSnapshotRootDir(), MATTER_ZIP, time.Now().UnixNano()/1e6)
+	util.MakeDirAll(destZipDirPath)
+
+	destZipName := fmt.Sprintf("%s.zip", name)
+	destZipPath := fmt.Sprintf("%s/%s", destZipDirPath, destZipName)
+
+	this.zipEntries(entries, destZipPath)
+
+	download.DownloadFile(writer, request, destZipPath, destZipName, true)
+
+	//delete the temp zip file.
+	err := os.Remove(destZipPath)
+	if err != nil {
+		this.logger.Error("error while deleting zip file %s", err.Error())
+	}
+	util.DeleteEmptyDir(destZipDirPath)
+}
+
+func (this *SnapshotService) zipEntries(entries []*SnapshotEntry, destPath string) {
+
+	fileWriter, err := os.Create(destPath)
+	this.PanicError(err)
+	defer func() {
+		err := fileWriter.Close()
+		this.PanicError(err)
+	}()
+
+	zipWriter := zip.NewWriter(fileWriter)
+	defer func() {
+		err := zipWriter.Close()
+		this.PanicError(err)
+	}()
+
+	for _, entry := range entries {
+		name := strings.TrimPrefix(entry.Path, "/")
+		if entry.Dir {
+			_, err := zipWriter.Create(name + "/")
+			this.PanicError(err)
+			continue
+		}
+
+		writer, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		this.PanicError(err)
+
+		blobFile, err := os.Open(GetBlobPath(entry.Sha256))
+		this.PanicError(err)
+		_, err = io.Copy(writer, blobFile)
+		closeErr := blobFile.Close()
+		this.PanicError(err)
+		this.PanicError(closeErr)
+	}
+}
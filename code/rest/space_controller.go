@@ -18,6 +18,7 @@ type SpaceController struct {
 	matterService      *MatterService
 	spaceService       *SpaceService
 	userService        *UserService
+	blindService       *BlindService
 }
 
 func (this *SpaceController) Init() {
@@ -58,6 +59,11 @@ func (this *SpaceController) Init() {
 		this.userService = b
 	}
 
+	b = core.CONTEXT.GetBean(this.blindService)
+	if b, ok := b.(*BlindService); ok {
+		this.blindService = b
+	}
+
 }
 
 func (this *SpaceController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
@@ -77,9 +83,11 @@ func (this *SpaceController) Create(writer http.ResponseWriter, request *http.Re
 	name := util.ExtractRequestString(request, "name")
 	sizeLimit := util.ExtractRequestInt64(request, "sizeLimit")
 	totalSizeLimit := util.ExtractRequestInt64(request, "totalSizeLimit")
+	//where the files are kept. empty for the default storage.
+	storageUuid := util.ExtractRequestOptionalString(request, "storageUuid", "")
 
 	//create related space.
-	space := this.spaceService.CreateSpace(request, name, nil, sizeLimit, totalSizeLimit, SPACE_TYPE_SHARED)
+	space := this.spaceService.CreateSpace(request, name, nil, sizeLimit, totalSizeLimit, SPACE_TYPE_SHARED, storageUuid)
 
 	return this.Success(space)
 }
@@ -128,7 +136,7 @@ func (this *SpaceController) Detail(writer http.ResponseWriter, request *http.Re
 		panic(result.BadRequestI18n(request, i18n.PermissionDenied))
 	}
 
-	return this.Success(space)
+	return this.Success(this.blindService.MaskSpace(user, space))
 
 }
 
@@ -42,8 +42,13 @@ func (this *SpaceDao) CheckByUuid(uuid string) *Space {
 }
 
 func (this *SpaceDao) CountByName(name string) int {
+	return this.CountByNameTx(core.CONTEXT.GetDB(), name)
+}
+
+// count in the transaction.
+func (this *SpaceDao) CountByNameTx(tx *gorm.DB, name string) int {
 	var count int64
-	db := core.CONTEXT.GetDB().
+	db := tx.
 		Model(&Space{}).
 		Where("name = ?", name).
 		Count(&count)
@@ -66,8 +71,13 @@ func (this *SpaceDao) FindByName(name string) *Space {
 }
 
 func (this *SpaceDao) CountByUserUuid(userUuid string) int {
+	return this.CountByUserUuidTx(core.CONTEXT.GetDB(), userUuid)
+}
+
+// count in the transaction.
+func (this *SpaceDao) CountByUserUuidTx(tx *gorm.DB, userUuid string) int {
 	var count int64
-	db := core.CONTEXT.GetDB().
+	db := tx.
 		Model(&Space{}).
 		Where("user_uuid = ?", userUuid).
 		Count(&count)
@@ -75,6 +85,23 @@ func (this *SpaceDao) CountByUserUuid(userUuid string) int {
 	return int(count)
 }
 
+func (this *SpaceDao) CountByStorageUuid(storageUuid string) int64 {
+	var count int64
+	db := core.CONTEXT.GetDB().
+		Model(&Space{}).
+		Where("storage_uuid = ?", storageUuid).
+		Count(&count)
+	this.PanicError(db.Error)
+	return count
+}
+
+func (this *SpaceDao) FindByUserUuid(userUuid string) []*Space {
+	var entities []*Space
+	db := core.CONTEXT.GetDB().Where("user_uuid = ?", userUuid).Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
 // TODO:
 func (this *SpaceDao) SelfPage(page int, pageSize int, userUuid string, spaceType string, sortArray []builder.OrderPair) *Pager {
 
@@ -136,13 +163,18 @@ func (this *SpaceDao) PlainPage(page int, pageSize int, spaceType string, name s
 }
 
 func (this *SpaceDao) Create(space *Space) *Space {
+	return this.CreateTx(core.CONTEXT.GetDB(), space)
+}
+
+// create in the transaction.
+func (this *SpaceDao) CreateTx(tx *gorm.DB, space *Space) *Space {
 
 	timeUUID, _ := uuid.NewV4()
 	space.Uuid = string(timeUUID.String())
 	space.CreateTime = time.Now()
 	space.UpdateTime = time.Now()
 	space.Sort = time.Now().UnixNano() / 1e6
-	db := core.CONTEXT.GetDB().Create(space)
+	db := tx.Create(space)
 	this.PanicError(db.Error)
 
 	return space
@@ -53,6 +53,14 @@ func (this *SpaceMemberDao) FindBySpaceUuidAndUserUuid(spaceUuid string, userUui
 	return entity
 }
 
+// memberships of the user.
+func (this *SpaceMemberDao) FindByUserUuid(userUuid string) []*SpaceMember {
+	var spaceMembers []*SpaceMember
+	db := core.CONTEXT.GetDB().Where("user_uuid = ?", userUuid).Find(&spaceMembers)
+	this.PanicError(db.Error)
+	return spaceMembers
+}
+
 func (this *SpaceMemberDao) Page(page int, pageSize int, spaceUuid string, sortArray []builder.OrderPair) *Pager {
 
 	count, spaceMembers := this.PlainPage(page, pageSize, spaceUuid, sortArray)
@@ -25,5 +25,6 @@ type Space struct {
 	TotalSizeLimit int64     `json:"totalSizeLimit" gorm:"type:bigint(20) not null;default:-1"`
 	TotalSize      int64     `json:"totalSize" gorm:"type:bigint(20) not null;default:0"`
 	Type           string    `json:"type" gorm:"type:varchar(45)"`
+	StorageUuid    string    `json:"storageUuid" gorm:"type:char(36)"` //empty for the default storage.
 	User           *User     `json:"user" gorm:"-"`
 }
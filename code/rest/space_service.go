@@ -4,6 +4,7 @@ import (
 	"github.com/eyebluecn/tank/code/core"
 	"github.com/eyebluecn/tank/code/tool/i18n"
 	"github.com/eyebluecn/tank/code/tool/result"
+	"gorm.io/gorm"
 	"net/http"
 	"regexp"
 )
@@ -16,6 +17,7 @@ type SpaceService struct {
 	matterDao          *MatterDao
 	bridgeDao          *BridgeDao
 	userDao            *UserDao
+	storageDao         *StorageDao
 }
 
 func (this *SpaceService) Init() {
@@ -46,6 +48,11 @@ func (this *SpaceService) Init() {
 		this.userDao = b
 	}
 
+	b = core.CONTEXT.GetBean(this.storageDao)
+	if b, ok := b.(*StorageDao); ok {
+		this.storageDao = b
+	}
+
 }
 
 func (this *SpaceService) Detail(uuid string) *Space {
@@ -62,7 +69,22 @@ func (this *SpaceService) CreateSpace(
 	user *User,
 	sizeLimit int64,
 	totalSizeLimit int64,
-	spaceType string) *Space {
+	spaceType string,
+	storageUuid string) *Space {
+
+	return this.CreateSpaceTx(core.CONTEXT.GetDB(), request, name, user, sizeLimit, totalSizeLimit, spaceType, storageUuid)
+}
+
+// create a space in the transaction.
+func (this *SpaceService) CreateSpaceTx(
+	tx *gorm.DB,
+	request *http.Request,
+	name string,
+	user *User,
+	sizeLimit int64,
+	totalSizeLimit int64,
+	spaceType string,
+	storageUuid string) *Space {
 
 	userUuid := ""
 	//validation work.
@@ -76,7 +98,7 @@ func (this *SpaceService) CreateSpace(
 		}
 
 		userUuid = user.Uuid
-		if this.spaceDao.CountByUserUuid(userUuid) > 0 {
+		if this.spaceDao.CountByUserUuidTx(tx, userUuid) > 0 {
 			panic(result.BadRequestI18n(request, i18n.SpaceExclusive, name))
 		}
 
@@ -86,10 +108,15 @@ func (this *SpaceService) CreateSpace(
 		panic("Not supported spaceType:" + spaceType)
 	}
 
-	if this.spaceDao.CountByName(name) > 0 {
+	if this.spaceDao.CountByNameTx(tx, name) > 0 {
 		panic(result.BadRequestI18n(request, i18n.SpaceNameExist, name))
 	}
 
+	//the files are in the default storage if not specified.
+	if storageUuid != "" {
+		this.storageDao.CheckByUuid(storageUuid)
+	}
+
 	space := &Space{
 		Name:           name,
 		UserUuid:       userUuid,
@@ -97,9 +124,10 @@ func (this *SpaceService) CreateSpace(
 		TotalSizeLimit: totalSizeLimit,
 		TotalSize:      0,
 		Type:           spaceType,
+		StorageUuid:    storageUuid,
 	}
 
-	space = this.spaceDao.Create(space)
+	space = this.spaceDao.CreateTx(tx, space)
 
 	return space
 
@@ -123,12 +151,7 @@ func (this *SpaceService) CheckAdminAbleByUuid(request *http.Request, user *User
 // checkout a writable space.
 func (this *SpaceService) CheckWritableByUuid(request *http.Request, user *User, spaceUuid string) *Space {
 	space := this.spaceDao.CheckByUuid(spaceUuid)
-	if space.Type == SPACE_TYPE_PRIVATE && user.Uuid == space.UserUuid {
-		return space
-	}
-
-	writable := this.spaceMemberService.canWrite(user, spaceUuid)
-	if !writable {
+	if !this.Writable(user, space) {
 		panic(result.BadRequestI18n(request, i18n.PermissionDenied))
 	}
 
@@ -138,18 +161,29 @@ func (this *SpaceService) CheckWritableByUuid(request *http.Request, user *User,
 // checkout a readable space.
 func (this *SpaceService) CheckReadableByUuid(request *http.Request, user *User, spaceUuid string) *Space {
 	space := this.spaceDao.CheckByUuid(spaceUuid)
-	if space.Type == SPACE_TYPE_PRIVATE && user.Uuid == space.UserUuid {
-		return space
-	}
-
-	manage := this.spaceMemberService.canRead(user, spaceUuid)
-	if !manage {
+	if !this.Readable(user, space) {
 		panic(result.BadRequestI18n(request, i18n.PermissionDenied))
 	}
 
 	return space
 }
 
+// whether the user can write the space.
+func (this *SpaceService) Writable(user *User, space *Space) bool {
+	if space.Type == SPACE_TYPE_PRIVATE && user.Uuid == space.UserUuid {
+		return true
+	}
+	return this.spaceMemberService.canWrite(user, space.Uuid)
+}
+
+// whether the user can read the space.
+func (this *SpaceService) Readable(user *User, space *Space) bool {
+	if space.Type == SPACE_TYPE_PRIVATE && user.Uuid == space.UserUuid {
+		return true
+	}
+	return this.spaceMemberService.canRead(user, space.Uuid)
+}
+
 // edit space's info
 func (this *SpaceService) Edit(request *http.Request, user *User, spaceUuid string, sizeLimit int64, totalSizeLimit int64) *Space {
 	space := this.CheckAdminAbleByUuid(request, user, spaceUuid)
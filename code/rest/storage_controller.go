@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+type StorageController struct {
+	BaseController
+	storageDao     *StorageDao
+	spaceDao       *SpaceDao
+	storageService *StorageService
+}
+
+func (this *StorageController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.storageDao)
+	if b, ok := b.(*StorageDao); ok {
+		this.storageDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceDao)
+	if b, ok := b.(*SpaceDao); ok {
+		this.spaceDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.storageService)
+	if b, ok := b.(*StorageService); ok {
+		this.storageService = b
+	}
+}
+
+func (this *StorageController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/storage/create"] = this.Wrap(this.Create, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/storage/edit"] = this.Wrap(this.Edit, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/storage/delete"] = this.Wrap(this.Delete, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/storage/list"] = this.Wrap(this.List, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/storage/migrate"] = this.Wrap(this.Migrate, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+// the settings of a storage in the request.
+func (this *StorageController) extract(request *http.Request) *Storage {
+	return &Storage{
+		Name:      util.ExtractRequestString(request, "name"),
+		Path:      util.ExtractRequestOptionalString(request, "path", ""),
+		Endpoint:  util.ExtractRequestOptionalString(request, "endpoint", ""),
+		Region:    util.ExtractRequestOptionalString(request, "region", ""),
+		Bucket:    util.ExtractRequestOptionalString(request, "bucket", ""),
+		Prefix:    util.ExtractRequestOptionalString(request, "prefix", ""),
+		PathStyle: util.ExtractRequestOptionalBool(request, "pathStyle", false),
+		AccessKey: util.ExtractRequestOptionalString(request, "accessKey", ""),
+		SecretKey: util.ExtractRequestOptionalString(request, "secretKey", ""),
+	}
+}
+
+func (this *StorageController) Create(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	storage := this.extract(request)
+	storage.Type = util.ExtractRequestString(request, "type")
+
+	return this.Success(this.storageService.Create(request, storage))
+}
+
+// the secret key is kept if not given.
+func (this *StorageController) Edit(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	uuid := util.ExtractRequestString(request, "uuid")
+	storage := this.storageDao.CheckByUuid(uuid)
+
+	edited := this.extract(request)
+	if edited.SecretKey == "" {
+		edited.SecretKey = storage.SecretKey
+	}
+
+	return this.Success(this.storageService.Edit(request, storage, edited))
+}
+
+func (this *StorageController) Delete(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	uuid := util.ExtractRequestString(request, "uuid")
+	storage := this.storageDao.CheckByUuid(uuid)
+
+	this.storageService.Delete(request, storage)
+
+	return this.Success(nil)
+}
+
+func (this *StorageController) List(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	return this.Success(this.storageService.List())
+}
+
+// move the files of a space to another storage. storageName empty for the default storage.
+func (this *StorageController) Migrate(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	spaceName := util.ExtractRequestString(request, "spaceName")
+	storageName := util.ExtractRequestOptionalString(request, "storageName", "")
+
+	space := this.spaceDao.FindByName(spaceName)
+	if space == nil {
+		panic(result.BadRequest("space %s not exist", spaceName))
+	}
+	storageUuid := ""
+	if storageName != "" {
+		storage := this.storageDao.FindByName(storageName)
+		if storage == nil {
+			panic(result.BadRequest("storage %s not exist", storageName))
+		}
+		storageUuid = storage.Uuid
+	}
+
+	return this.Success(this.storageService.Migrate(space, storageUuid))
+}
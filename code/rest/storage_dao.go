@@ -0,0 +1,92 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/uuid"
+)
+
+type StorageDao struct {
+	BaseDao
+}
+
+// find by uuid. if not found return nil.
+func (this *StorageDao) FindByUuid(uuid string) *Storage {
+	var entity = &Storage{}
+	db := core.CONTEXT.GetDB().Where("uuid = ?", uuid).First(entity)
+	if db.Error != nil {
+		if db.Error.Error() == result.DB_ERROR_NOT_FOUND {
+			return nil
+		} else {
+			panic(db.Error)
+		}
+	}
+	return entity
+}
+
+// find by uuid. if not found panic NotFound error
+func (this *StorageDao) CheckByUuid(uuid string) *Storage {
+	entity := this.FindByUuid(uuid)
+	if entity == nil {
+		panic(result.NotFound("not found record with uuid = %s", uuid))
+	}
+	return entity
+}
+
+func (this *StorageDao) FindByName(name string) *Storage {
+	var entity = &Storage{}
+	db := core.CONTEXT.GetDB().Where("name = ?", name).First(entity)
+	if db.Error != nil {
+		if db.Error.Error() == result.DB_ERROR_NOT_FOUND {
+			return nil
+		} else {
+			panic(db.Error)
+		}
+	}
+	return entity
+}
+
+func (this *StorageDao) FindAll() []*Storage {
+	var entities []*Storage
+	db := core.CONTEXT.GetDB().Order("sort ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *StorageDao) Create(storage *Storage) *Storage {
+
+	timeUUID, _ := uuid.NewV4()
+	storage.Uuid = string(timeUUID.String())
+	storage.CreateTime = time.Now()
+	storage.UpdateTime = time.Now()
+	storage.Sort = time.Now().UnixNano() / 1e6
+	db := core.CONTEXT.GetDB().Create(storage)
+	this.PanicError(db.Error)
+
+	return storage
+}
+
+func (this *StorageDao) Save(storage *Storage) *Storage {
+
+	storage.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Save(storage)
+	this.PanicError(db.Error)
+
+	return storage
+}
+
+func (this *StorageDao) Delete(storage *Storage) {
+
+	db := core.CONTEXT.GetDB().Delete(storage)
+	this.PanicError(db.Error)
+
+}
+
+// System cleanup.
+func (this *StorageDao) Cleanup() {
+	this.logger.Info("[StorageDao] clean up. Delete all Storage")
+	db := core.CONTEXT.GetDB().Where("uuid is not null").Delete(Storage{})
+	this.PanicError(db.Error)
+}
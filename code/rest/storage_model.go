@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+const (
+	//a directory of this machine.
+	STORAGE_TYPE_LOCAL = "LOCAL"
+	//a bucket of a s3 compatible service, eg. aws s3, minio, oss.
+	STORAGE_TYPE_S3 = "S3"
+
+	//files put into the storages are written here first. space name cannot start with dot, so it never conflicts with spaces.
+	MATTER_STAGING = ".staging"
+	//written and removed to check that a storage works.
+	STORAGE_CHECK_KEY = ".check"
+)
+
+// Storage is where the files of the spaces using it are kept. Spaces without storage use the matter path of the config,
+// which is the default storage. Files under a storage are laid out as under the matter path, ie. "space/root/path".
+type Storage struct {
+	Uuid       string    `json:"uuid" gorm:"type:char(36);primary_key;unique"`
+	Sort       int64     `json:"sort" gorm:"type:bigint(20) not null"`
+	UpdateTime time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	CreateTime time.Time `json:"createTime" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"`
+	Name       string    `json:"name" gorm:"type:varchar(45) not null;unique"`
+	Type       string    `json:"type" gorm:"type:varchar(45) not null"`
+	//LOCAL. absolute path of the directory.
+	Path string `json:"path" gorm:"type:varchar(1024)"`
+	//S3.
+	Endpoint  string `json:"endpoint" gorm:"type:varchar(255)"`
+	Region    string `json:"region" gorm:"type:varchar(45)"`
+	Bucket    string `json:"bucket" gorm:"type:varchar(255)"`
+	Prefix    string `json:"prefix" gorm:"type:varchar(255)"`
+	PathStyle bool   `json:"pathStyle" gorm:"type:tinyint(1) not null;default:0"`
+	AccessKey string `json:"accessKey" gorm:"type:varchar(255)"`
+	SecretKey string `json:"-" gorm:"type:varchar(255)"`
+	//number of the spaces using it.
+	SpaceCount int64 `json:"spaceCount" gorm:"-"`
+}
+
+// the result of moving the files of a space to another storage.
+type StorageMigration struct {
+	SpaceName string `json:"spaceName"`
+	//storage names, empty for the default storage.
+	From string `json:"from"`
+	To   string `json:"to"`
+	//directories, files and versions moved.
+	DirCount     int64 `json:"dirCount"`
+	FileCount    int64 `json:"fileCount"`
+	VersionCount int64 `json:"versionCount"`
+	Size         int64 `json:"size"`
+	//files in db but not in the storage, which are skipped.
+	MissingCount int64 `json:"missingCount"`
+}
+
+// get the staging absolute path.
+func GetStagingRootDir() (rootDirPath string) {
+
+	rootDirPath = fmt.Sprintf("%s/%s", core.CONFIG.MatterPath(), MATTER_STAGING)
+
+	return rootDirPath
+}
@@ -0,0 +1,604 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/download"
+	"github.com/eyebluecn/tank/code/tool/i18n"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/storage"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+// every file of the spaces is read and written through the driver of the storage of its space, see Storage.
+// @Service
+type StorageService struct {
+	BaseBean
+	storageDao       *StorageDao
+	spaceDao         *SpaceDao
+	matterDao        *MatterDao
+	matterVersionDao *MatterVersionDao
+	imageCacheDao    *ImageCacheDao
+	blobService      *BlobService
+	mutex            sync.Mutex
+	//drivers by storage uuid, made when first used.
+	drivers map[string]storage.Driver
+	//names of the spaces being moved to another storage. their files cannot be changed meanwhile.
+	migrating map[string]bool
+}
+
+func (this *StorageService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.storageDao)
+	if b, ok := b.(*StorageDao); ok {
+		this.storageDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceDao)
+	if b, ok := b.(*SpaceDao); ok {
+		this.spaceDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterVersionDao)
+	if b, ok := b.(*MatterVersionDao); ok {
+		this.matterVersionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.imageCacheDao)
+	if b, ok := b.(*ImageCacheDao); ok {
+		this.imageCacheDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.blobService)
+	if b, ok := b.(*BlobService); ok {
+		this.blobService = b
+	}
+
+	this.drivers = make(map[string]storage.Driver)
+	this.migrating = make(map[string]bool)
+}
+
+// make the driver of the storage.
+func (this *StorageService) newDriver(entity *Storage) (storage.Driver, error) {
+	switch entity.Type {
+	case STORAGE_TYPE_LOCAL:
+		return storage.NewLocalDriver(entity.Path), nil
+	case STORAGE_TYPE_S3:
+		return storage.NewS3Driver(storage.S3Config{
+			Endpoint:  entity.Endpoint,
+			Region:    entity.Region,
+			Bucket:    entity.Bucket,
+			AccessKey: entity.AccessKey,
+			SecretKey: entity.SecretKey,
+			Prefix:    entity.Prefix,
+			PathStyle: entity.PathStyle,
+		})
+	}
+	return nil, result.BadRequest("not supported storage type %s", entity.Type)
+}
+
+// the driver of the storage. the default storage if storageUuid is empty.
+func (this *StorageService) Driver(storageUuid string) storage.Driver {
+	if storageUuid == "" {
+		//the matter path may change when installing, so it is never kept.
+		return storage.NewLocalDriver(core.CONFIG.MatterPath())
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	driver := this.drivers[storageUuid]
+	if driver == nil {
+		var err error
+		driver, err = this.newDriver(this.storageDao.CheckByUuid(storageUuid))
+		this.PanicError(err)
+		this.drivers[storageUuid] = driver
+	}
+	return driver
+}
+
+// the storage uuid of the space. a space deleted already is taken as in the default storage.
+func (this *StorageService) storageUuidOf(spaceName string) string {
+	space := this.spaceDao.FindByName(spaceName)
+	if space == nil {
+		return ""
+	}
+	return space.StorageUuid
+}
+
+func (this *StorageService) SpaceDriver(spaceName string) storage.Driver {
+	return this.Driver(this.storageUuidOf(spaceName))
+}
+
+// whether the space is in the default storage, whose files can be linked to the blob store.
+func (this *StorageService) IsDefault(spaceName string) bool {
+	return this.storageUuidOf(spaceName) == ""
+}
+
+// the absolute path of the key in the default storage.
+func (this *StorageService) DefaultPath(key string) string {
+	return storage.NewLocalDriver(core.CONFIG.MatterPath()).Path(key)
+}
+
+// files of the space cannot be changed while it is moved to another storage.
+func (this *StorageService) CheckWritable(spaceName string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.migrating[spaceName] {
+		panic(result.BadRequestI18n(nil, i18n.StorageMigrating, spaceName))
+	}
+}
+
+// create a file to write what is put into a storage later.
+func (this *StorageService) CreateStagingFile() *os.File {
+	util.MakeDirAll(GetStagingRootDir())
+	file, err := os.CreateTemp(GetStagingRootDir(), "put-*")
+	this.PanicError(err)
+	return file
+}
+
+// write the content into a staging file, then put it at the key. return the size.
+func (this *StorageService) Write(spaceName string, key string, reader io.Reader) int64 {
+	stagingFile := this.CreateStagingFile()
+	size, err := io.Copy(stagingFile, reader)
+	closeErr := stagingFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(stagingFile.Name())
+		panic(err)
+	}
+	this.Put(spaceName, key, stagingFile.Name())
+	return size
+}
+
+// move the local file to the key in the storage of the space. the local file is gone even if it fails.
+func (this *StorageService) Put(spaceName string, key string, localPath string) {
+	defer func() {
+		_ = os.Remove(localPath)
+	}()
+	this.CheckWritable(spaceName)
+
+	err := this.SpaceDriver(spaceName).PutFile(key, localPath)
+	this.PanicError(err)
+}
+
+func (this *StorageService) Open(spaceName string, key string) (storage.File, error) {
+	return this.SpaceDriver(spaceName).Open(key)
+}
+
+func (this *StorageService) Stat(spaceName string, key string) (*storage.FileInfo, error) {
+	return this.SpaceDriver(spaceName).Stat(key)
+}
+
+func (this *StorageService) ListDir(spaceName string, key string) ([]*storage.FileInfo, error) {
+	return this.SpaceDriver(spaceName).List(key)
+}
+
+func (this *StorageService) MakeDir(spaceName string, key string) {
+	this.CheckWritable(spaceName)
+	err := this.SpaceDriver(spaceName).MakeDir(key)
+	this.PanicError(err)
+}
+
+func (this *StorageService) Rename(spaceName string, oldKey string, newKey string) {
+	this.CheckWritable(spaceName)
+	err := this.SpaceDriver(spaceName).Rename(oldKey, newKey)
+	this.PanicError(err)
+}
+
+// copy the file, through a staging file if the spaces are in different storages.
+func (this *StorageService) Copy(srcSpaceName string, srcKey string, destSpaceName string, destKey string) {
+	this.CheckWritable(destSpaceName)
+
+	srcStorageUuid := this.storageUuidOf(srcSpaceName)
+	destStorageUuid := this.storageUuidOf(destSpaceName)
+	if srcStorageUuid == destStorageUuid {
+		err := this.Driver(srcStorageUuid).Copy(srcKey, destKey)
+		this.PanicError(err)
+		return
+	}
+
+	file, err := this.Driver(srcStorageUuid).Open(srcKey)
+	this.PanicError(err)
+	defer func() {
+		_ = file.Close()
+	}()
+	this.Write(destSpaceName, destKey, file)
+}
+
+// remove the file. failures are only logged, as the file is no longer used.
+func (this *StorageService) Remove(spaceName string, key string) {
+	err := this.SpaceDriver(spaceName).Remove(key)
+	if err != nil {
+		this.logger.Error("occur error when removing %s. %v", key, err)
+	}
+}
+
+// remove the directory if empty. failures are only logged.
+func (this *StorageService) RemoveDir(spaceName string, key string) {
+	err := this.SpaceDriver(spaceName).RemoveDir(key)
+	if err != nil {
+		this.logger.Error("occur error when removing directory %s. %v", key, err)
+	}
+}
+
+// the directories of the files, caches and versions of the space.
+func (this *StorageService) spaceKeys(spaceName string) []string {
+	return []string{spaceName + "/" + MATTER_ROOT, spaceName + "/" + MATTER_CACHE, spaceName + "/" + MATTER_VERSION}
+}
+
+// remove the files, caches and versions of the space.
+func (this *StorageService) RemoveSpace(spaceName string) {
+	driver := this.SpaceDriver(spaceName)
+	for _, key := range this.spaceKeys(spaceName) {
+		err := driver.RemoveAll(key)
+		if err != nil {
+			this.logger.Error("occur error when removing %s. %v", key, err)
+		}
+	}
+}
+
+// download the file at key. Support chunk download.
+func (this *StorageService) Download(writer http.ResponseWriter, request *http.Request, spaceName string, key string, filename string, withContentDisposition bool) {
+	file, err := this.Open(spaceName, key)
+	this.PanicError(err)
+	defer func() {
+		_ = file.Close()
+	}()
+
+	download.DownloadContent(writer, request, file, file.Info().Size(), file.Info().ModTime(), filename, withContentDisposition)
+}
+
+// check the settings of the storage, and that it can be reached.
+func (this *StorageService) check(request *http.Request, entity *Storage) {
+
+	if m, _ := regexp.MatchString(USERNAME_PATTERN, entity.Name); !m {
+		panic(result.BadRequest("name can only be letters, numbers or _"))
+	}
+
+	switch entity.Type {
+	case STORAGE_TYPE_LOCAL:
+		entity.Path = strings.TrimSuffix(filepath.ToSlash(entity.Path), "/")
+		if entity.Path == "" || !filepath.IsAbs(entity.Path) {
+			panic(result.BadRequest("path must be absolute"))
+		}
+		matterPath := filepath.ToSlash(core.CONFIG.MatterPath())
+		if entity.Path == matterPath || strings.HasPrefix(entity.Path+"/", matterPath+"/") || strings.HasPrefix(matterPath+"/", entity.Path+"/") {
+			panic(result.BadRequest("path cannot overlap the matter path %s", matterPath))
+		}
+	case STORAGE_TYPE_S3:
+		if entity.Endpoint == "" || entity.Bucket == "" {
+			panic(result.BadRequest("endpoint and bucket are required"))
+		}
+	default:
+		panic(result.BadRequest("not supported storage type %s", entity.Type))
+	}
+
+	driver, err := this.newDriver(entity)
+	if err != nil {
+		panic(result.BadRequest(err.Error()))
+	}
+	if err := driver.MakeDir(STORAGE_CHECK_KEY); err != nil {
+		panic(result.BadRequest("cannot write the storage. %s", err.Error()))
+	}
+	if _, err := driver.Stat(STORAGE_CHECK_KEY); err != nil {
+		panic(result.BadRequest("cannot read the storage. %s", err.Error()))
+	}
+	_ = driver.RemoveAll(STORAGE_CHECK_KEY)
+}
+
+func (this *StorageService) Create(request *http.Request, entity *Storage) *Storage {
+
+	if this.storageDao.FindByName(entity.Name) != nil {
+		panic(result.BadRequest("storage %s exists", entity.Name))
+	}
+	this.check(request, entity)
+
+	return this.storageDao.Create(entity)
+}
+
+// edit the storage. where the files are cannot be changed once a space uses it, move the spaces away first.
+func (this *StorageService) Edit(request *http.Request, entity *Storage, edited *Storage) *Storage {
+
+	if entity.Name != edited.Name {
+		if this.storageDao.FindByName(edited.Name) != nil {
+			panic(result.BadRequest("storage %s exists", edited.Name))
+		}
+	}
+	if this.spaceDao.CountByStorageUuid(entity.Uuid) > 0 {
+		if entity.Path != edited.Path || entity.Endpoint != edited.Endpoint || entity.Bucket != edited.Bucket || entity.Prefix != edited.Prefix {
+			panic(result.BadRequest("storage %s is used by spaces, where the files are cannot be changed", entity.Name))
+		}
+	}
+
+	edited.Type = entity.Type
+	this.check(request, edited)
+
+	entity.Name = edited.Name
+	entity.Path = edited.Path
+	entity.Endpoint = edited.Endpoint
+	entity.Region = edited.Region
+	entity.Bucket = edited.Bucket
+	entity.Prefix = edited.Prefix
+	entity.PathStyle = edited.PathStyle
+	entity.AccessKey = edited.AccessKey
+	entity.SecretKey = edited.SecretKey
+	entity = this.storageDao.Save(entity)
+
+	this.mutex.Lock()
+	delete(this.drivers, entity.Uuid)
+	this.mutex.Unlock()
+
+	return entity
+}
+
+func (this *StorageService) Delete(request *http.Request, entity *Storage) {
+
+	if this.spaceDao.CountByStorageUuid(entity.Uuid) > 0 {
+		panic(result.BadRequest("storage %s is used by spaces, move them away first", entity.Name))
+	}
+	this.storageDao.Delete(entity)
+
+	this.mutex.Lock()
+	delete(this.drivers, entity.Uuid)
+	this.mutex.Unlock()
+}
+
+func (this *StorageService) List() []*Storage {
+	storages := this.storageDao.FindAll()
+	for _, entity := range storages {
+		entity.SpaceCount = this.spaceDao.CountByStorageUuid(entity.Uuid)
+	}
+	return storages
+}
+
+// the name of the storage, empty for the default storage.
+func (this *StorageService) nameOf(storageUuid string) string {
+	if storageUuid == "" {
+		return ""
+	}
+	return this.storageDao.CheckByUuid(storageUuid).Name
+}
+
+// the sha256 of the file at key of the driver.
+func (this *StorageService) hashOf(driver storage.Driver, key string) (string, error) {
+	file, err := driver.Open(key)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// move the files of the space to the storage, the default storage if storageUuid is empty. the files cannot be changed
+// meanwhile. the space uses the new storage only after every file is there and checked, and the old files are removed
+// at last.
+func (this *StorageService) Migrate(space *Space, storageUuid string) *StorageMigration {
+
+	if space.StorageUuid == storageUuid {
+		panic(result.BadRequest("space %s is in the storage already", space.Name))
+	}
+
+	this.mutex.Lock()
+	if this.migrating[space.Name] {
+		this.mutex.Unlock()
+		panic(result.BadRequestI18n(nil, i18n.StorageMigrating, space.Name))
+	}
+	this.migrating[space.Name] = true
+	this.mutex.Unlock()
+	defer func() {
+		this.mutex.Lock()
+		delete(this.migrating, space.Name)
+		this.mutex.Unlock()
+	}()
+
+	migration := &StorageMigration{
+		SpaceName: space.Name,
+		From:      this.nameOf(space.StorageUuid),
+		To:        this.nameOf(storageUuid),
+	}
+	this.logger.Info("migrate space %s from storage %s to %s", space.Name, migration.From, migration.To)
+
+	fromStorageUuid := space.StorageUuid
+	fromDriver := this.Driver(fromStorageUuid)
+	toDriver := this.Driver(storageUuid)
+
+	//what is copied is removed if it fails, the space stays where it was.
+	switched := false
+	defer func() {
+		if !switched {
+			this.logger.Error("fail to migrate space %s, remove what is copied", space.Name)
+			_ = toDriver.RemoveAll(space.Name + "/" + MATTER_ROOT)
+			_ = toDriver.RemoveAll(space.Name + "/" + MATTER_VERSION)
+		}
+	}()
+
+	//what is copied by key, with the file as it was when read from the old storage. nil for directories and missing files.
+	copied := make(map[string]*storage.FileInfo)
+
+	//copy the file at key, and check the copy by its hash. false if the file is missing.
+	copyFile := func(key string) bool {
+		file, err := fromDriver.Open(key)
+		if err != nil {
+			if os.IsNotExist(err) {
+				this.logger.Error("file %s not exist, skip it.", key)
+				copied[key] = nil
+				migration.MissingCount++
+				return false
+			}
+			panic(err)
+		}
+		info := file.Info()
+		stagingFile := this.CreateStagingFile()
+		hash := sha256.New()
+		size, err := io.Copy(io.MultiWriter(stagingFile, hash), file)
+		_ = file.Close()
+		closeErr := stagingFile.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = toDriver.PutFile(key, stagingFile.Name())
+		}
+		_ = os.Remove(stagingFile.Name())
+		this.PanicError(err)
+
+		copiedHash, err := this.hashOf(toDriver, key)
+		this.PanicError(err)
+		if copiedHash != hex.EncodeToString(hash.Sum(nil)) {
+			panic(result.BadRequest("the copy of %s does not match the %d bytes read", key, size))
+		}
+		copied[key] = info
+		migration.Size += size
+		return true
+	}
+
+	//copy the file again if it is changed in the old storage since copied. false if not.
+	copyChanged := func(key string) bool {
+		info, err := fromDriver.Stat(key)
+		if err != nil {
+			if os.IsNotExist(err) {
+				//still missing, or written into the new storage.
+				return false
+			}
+			panic(err)
+		}
+		if before := copied[key]; before != nil && info.Size() == before.Size() && info.ModTime().Equal(before.ModTime()) {
+			return false
+		}
+		return copyFile(key)
+	}
+
+	copyMatter := func(matter *Matter) {
+		key := matter.StorageKey()
+		if matter.Dir {
+			if _, ok := copied[key]; !ok {
+				err := toDriver.MakeDir(key)
+				this.PanicError(err)
+				copied[key] = nil
+				migration.DirCount++
+			}
+			return
+		}
+
+		if _, ok := copied[key]; !ok && copyFile(key) {
+			//the caches are made again in the new storage when asked.
+			this.imageCacheDao.DeleteByMatterUuid(matter.Uuid)
+			migration.FileCount++
+		} else if ok && copyChanged(key) {
+			this.imageCacheDao.DeleteByMatterUuid(matter.Uuid)
+		}
+	}
+	copyVersion := func(version *MatterVersion) {
+		key := version.StorageKey()
+		if _, ok := copied[key]; !ok && copyFile(key) {
+			migration.VersionCount++
+		} else if ok {
+			copyChanged(key)
+		}
+	}
+
+	this.eachMatter(space, copyMatter)
+	this.eachVersion(space, copyVersion)
+
+	space.StorageUuid = storageUuid
+	this.spaceDao.Save(space)
+	switched = true
+
+	//a change begun before the migration may still land in the old storage. walk the space again, and copy what is
+	//new or changed before the old files are removed.
+	this.eachMatter(space, copyMatter)
+	this.eachVersion(space, copyVersion)
+
+	//only files in the default storage are linked to the blob store.
+	if fromStorageUuid == "" || storageUuid == "" {
+		this.eachMatter(space, func(matter *Matter) {
+			if matter.Dir {
+				return
+			}
+			if fromStorageUuid == "" {
+				this.blobService.Unref(matter)
+				this.matterDao.UpdateHash(matter)
+			} else {
+				this.blobService.Store(matter, "", "")
+			}
+		})
+		//versions moved in are not linked, they are read seldom.
+		if fromStorageUuid == "" {
+			this.eachVersion(space, func(version *MatterVersion) {
+				if version.Sha256 != "" {
+					this.blobService.UnrefVersion(version)
+					this.matterVersionDao.UpdateHash(version)
+				}
+			})
+		}
+		this.blobService.Gc()
+	}
+
+	for _, key := range this.spaceKeys(space.Name) {
+		err := fromDriver.RemoveAll(key)
+		if err != nil {
+			this.logger.Error("occur error when removing %s from the old storage. %v", key, err)
+		}
+	}
+
+	this.logger.Info("migrate space %s. %d dirs, %d files, %d versions, %s, %d missing", space.Name,
+		migration.DirCount, migration.FileCount, migration.VersionCount, util.HumanFileSize(migration.Size), migration.MissingCount)
+
+	return migration
+}
+
+// every matter of the space, including those in the recycle bin.
+func (this *StorageService) eachMatter(space *Space, fun func(matter *Matter)) {
+	afterUuid := ""
+	for {
+		matters := this.matterDao.FindBySpaceUuidAfter(space.Uuid, afterUuid, 1000)
+		if len(matters) == 0 {
+			return
+		}
+		for _, matter := range matters {
+			afterUuid = matter.Uuid
+			fun(matter)
+		}
+	}
+}
+
+// every version of the space.
+func (this *StorageService) eachVersion(space *Space, fun func(version *MatterVersion)) {
+	afterUuid := ""
+	for {
+		versions := this.matterVersionDao.FindBySpaceUuidAfter(space.Uuid, afterUuid, 1000)
+		if len(versions) == 0 {
+			return
+		}
+		for _, version := range versions {
+			afterUuid = version.Uuid
+			fun(version)
+		}
+	}
+}
@@ -4,13 +4,17 @@ import (
 	"github.com/eyebluecn/tank/code/core"
 	"github.com/eyebluecn/tank/code/tool/result"
 	"net/http"
-	"time"
+	"strconv"
 )
 
 type SubmissionController struct {
 	BaseController
 	submissionDao *SubmissionDao
 	userProfileDao *UserProfileDao
+	teamService     *TeamService
+	submissionService *SubmissionService
+	blindService    *BlindService
+	auditService    *AuditService
 }
 
 func (this *SubmissionController) Init() {
@@ -25,28 +29,63 @@ func (this *SubmissionController) Init() {
 	if b, ok := b.(*UserProfileDao); ok {
 		this.userProfileDao = b
 	}
+
+	b = core.CONTEXT.GetBean(this.teamService)
+	if b, ok := b.(*TeamService); ok {
+		this.teamService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionService)
+	if b, ok := b.(*SubmissionService); ok {
+		this.submissionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.blindService)
+	if b, ok := b.(*BlindService); ok {
+		this.blindService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.auditService)
+	if b, ok := b.(*AuditService); ok {
+		this.auditService = b
+	}
 }
 
 func (this *SubmissionController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
 	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
 	routeMap["/api/submission/my"] = this.Wrap(this.MySubmission, USER_ROLE_USER)
+	routeMap["/api/submission/my/list"] = this.Wrap(this.MySubmissionList, USER_ROLE_USER)
 	routeMap["/api/submission/recommend"] = this.Wrap(this.RecommendSubmission, USER_ROLE_COLLEGE_ADMIN)
 	routeMap["/api/submission/by-matter"] = this.Wrap(this.GetSubmissionByMatter, USER_ROLE_USER)
+	routeMap["/api/submission/quota/usage"] = this.Wrap(this.QuotaUsage, USER_ROLE_COLLEGE_ADMIN)
 	return routeMap
 }
 
+// 本人提交的作品以及所在团队的作品，指定赛事时只返回该赛事的作品
+func (this *SubmissionController) mySubmissions(request *http.Request, user *User) []*Submission {
+	submissions := this.teamService.MySubmissions(user)
+	competitionIdStr := request.FormValue("competitionId")
+	if competitionIdStr == "" {
+		return submissions
+	}
+	competitionId, err := strconv.ParseInt(competitionIdStr, 10, 64)
+	if err != nil {
+		panic(result.BadRequest("competitionId格式错误"))
+	}
+	var competitionSubmissions []*Submission
+	for _, submission := range submissions {
+		if submission.CompetitionId == competitionId {
+			competitionSubmissions = append(competitionSubmissions, submission)
+		}
+	}
+	return competitionSubmissions
+}
+
 // 获取当前用户的提交
 func (this *SubmissionController) MySubmission(writer http.ResponseWriter, request *http.Request) *result.WebResult {
 	user := this.checkUser(request)
 	
-	// 获取用户档案信息
-	userProfile := this.userProfileDao.FindByUserUuid(user.Uuid)
-	if userProfile == nil {
-		return this.Success(nil)
-	}
-	
-	// 根据学号查找提交
-	submissions := this.submissionDao.FindByAuthorId(userProfile.StudentId)
+	submissions := this.mySubmissions(request, user)
 	if len(submissions) == 0 {
 		return this.Success(nil)
 	}
@@ -55,6 +94,12 @@ func (this *SubmissionController) MySubmission(writer http.ResponseWriter, reque
 	return this.Success(submissions[0])
 }
 
+// 获取当前用户的全部提交，包括所在团队的作品
+func (this *SubmissionController) MySubmissionList(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	return this.Success(this.mySubmissions(request, user))
+}
+
 // 推荐作品
 func (this *SubmissionController) RecommendSubmission(writer http.ResponseWriter, request *http.Request) *result.WebResult {
 	user := this.checkUser(request)
@@ -70,19 +115,46 @@ func (this *SubmissionController) RecommendSubmission(writer http.ResponseWriter
 	if submission == nil {
 		return result.BadRequest("未找到对应的作品提交")
 	}
+
+	// 学院管理员只能推荐本学院的作品，推荐名额计入作品所在学院
+	if user.Role != USER_ROLE_ADMINISTRATOR && !this.submissionService.IsCollegeAdminOf(user, submission) {
+		return result.BadRequest("您无权推荐该作品")
+	}
 	
-	// 更新推荐状态
-	submission.IsRecommended = true
-	submission.RecommendedBy = user.Uuid
-	submission.RecommendedAt = time.Now()
-	
-	this.submissionDao.Save(submission)
+	before := map[string]any{
+		"isRecommended": submission.IsRecommended,
+		"recommendedBy": submission.RecommendedBy,
+		"roundId":       submission.RoundId,
+		"snapshotId":    submission.SnapshotId,
+	}
+
+	// 推荐名额用完时管理员可以override超额推荐
+	override := request.FormValue("override") == TRUE
+
+	// 表单、文件夹完整后冻结快照、更新推荐状态，并进入第一轮
+	if webResult := this.submissionService.Recommend(submission, user, override); webResult != nil {
+		return webResult
+	}
+
+	if override && user.Role == USER_ROLE_ADMINISTRATOR {
+		if usage := this.submissionService.QuotaUsage(submission.TrackId, submission.CollegeId); usage.Limit >= 0 && usage.Used > usage.Limit {
+			this.auditService.Log(request, user, AUDIT_ACTION_QUOTA_OVERRIDE, AUDIT_TARGET_SUBMISSION, submission.Id, nil, usage)
+		}
+	}
+
+	this.auditService.Log(request, user, AUDIT_ACTION_RECOMMEND, AUDIT_TARGET_SUBMISSION, submission.Id, before, map[string]any{
+		"isRecommended": submission.IsRecommended,
+		"recommendedBy": submission.RecommendedBy,
+		"roundId":       submission.RoundId,
+		"snapshotId":    submission.SnapshotId,
+	})
 	
 	return this.Success("推荐成功")
 }
 
 // 根据作品UUID获取提交信息
 func (this *SubmissionController) GetSubmissionByMatter(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
 	matterUuid := request.FormValue("matterUuid")
 	if matterUuid == "" {
 		return result.BadRequest("matterUuid参数不能为空")
@@ -94,5 +166,40 @@ func (this *SubmissionController) GetSubmissionByMatter(writer http.ResponseWrit
 		return this.Success(nil)
 	}
 
-	return this.Success(submission)
-}
\ No newline at end of file
+	// 盲评轮次中评委只能看到作品编号
+	return this.Success(this.blindService.ForUser(user, submission))
+}
+
+// 学院在各赛道的推荐名额使用情况。学院管理员查看本学院，管理员可以指定collegeId
+func (this *SubmissionController) QuotaUsage(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	var collegeId int64
+	switch user.Role {
+	case USER_ROLE_ADMINISTRATOR:
+		id, err := strconv.ParseInt(request.FormValue("collegeId"), 10, 64)
+		if err != nil {
+			return result.BadRequest("collegeId格式错误")
+		}
+		collegeId = id
+	case USER_ROLE_COLLEGE_ADMIN:
+		college := this.submissionService.ManagedCollege(user)
+		if college == nil {
+			return result.BadRequest("未找到您所在的学院")
+		}
+		collegeId = college.Id
+	default:
+		panic(result.UNAUTHORIZED)
+	}
+
+	var competitionId int64
+	if competitionIdStr := request.FormValue("competitionId"); competitionIdStr != "" {
+		id, err := strconv.ParseInt(competitionIdStr, 10, 64)
+		if err != nil {
+			return result.BadRequest("competitionId格式错误")
+		}
+		competitionId = id
+	}
+
+	return this.Success(this.submissionService.CollegeQuotaUsages(collegeId, competitionId))
+}
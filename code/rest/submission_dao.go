@@ -1,6 +1,8 @@
 package rest
 
 import (
+	"fmt"
+
 	"github.com/eyebluecn/tank/code/core"
 )
 
@@ -34,6 +36,15 @@ func (this *SubmissionDao) FindByMatterUuid(matterUuid string) *Submission {
 	return &submission
 }
 
+func (this *SubmissionDao) FindByBlindCode(blindCode string) *Submission {
+	var submission Submission
+	db := core.CONTEXT.GetDB().Where("blind_code = ?", blindCode).First(&submission)
+	if db.Error != nil {
+		return nil
+	}
+	return &submission
+}
+
 func (this *SubmissionDao) FindById(id int64) *Submission {
 	var submission Submission
 	db := core.CONTEXT.GetDB().Where("id = ?", id).First(&submission)
@@ -57,6 +68,102 @@ func (this *SubmissionDao) Delete(submission *Submission) {
 		return
 	}
 	
-	db := core.CONTEXT.GetDB().Delete(submission)
+	db := core.CONTEXT.GetDB().Where("submission_id = ?", submission.Id).Delete(&FormValue{})
+	this.PanicError(db.Error)
+	db = core.CONTEXT.GetDB().Delete(submission)
+	this.PanicError(db.Error)
+}
+
+func (this *SubmissionDao) FindByRoundId(roundId int64) []*Submission {
+	var submissions []*Submission
+	db := core.CONTEXT.GetDB().Where("round_id = ?", roundId).Order("id ASC").Find(&submissions)
+	this.PanicError(db.Error)
+	return submissions
+}
+
+func (this *SubmissionDao) FindByRoundIdAndStatus(roundId int64, roundStatus string) []*Submission {
+	var submissions []*Submission
+	db := core.CONTEXT.GetDB().Where("round_id = ? AND round_status = ?", roundId, roundStatus).Order("id ASC").Find(&submissions)
+	this.PanicError(db.Error)
+	return submissions
+}
+
+// recommended submissions which have not entered any round yet.
+func (this *SubmissionDao) FindRecommendedWithoutRound() []*Submission {
+	var submissions []*Submission
+	db := core.CONTEXT.GetDB().Where("is_recommended = ? AND round_id = ?", true, 0).Order("id ASC").Find(&submissions)
 	this.PanicError(db.Error)
-}
\ No newline at end of file
+	return submissions
+}
+
+func (this *SubmissionDao) CountByRoundId(roundId int64) int64 {
+	var count int64
+	db := core.CONTEXT.GetDB().Model(&Submission{}).Where("round_id = ?", roundId).Count(&count)
+	this.PanicError(db.Error)
+	return count
+}
+
+// submissions of the college in the track, only the recommended ones if recommended is true.
+func (this *SubmissionDao) CountByTrackIdAndCollegeId(trackId int64, collegeId int64, recommended bool) int64 {
+	var count int64
+	db := core.CONTEXT.GetDB().Model(&Submission{}).Where("track_id = ? AND college_id = ?", trackId, collegeId)
+	if recommended {
+		db = db.Where("is_recommended = ?", true)
+	}
+	db = db.Count(&count)
+	this.PanicError(db.Error)
+	return count
+}
+
+func (this *SubmissionDao) FindRecommended() []*Submission {
+	var submissions []*Submission
+	db := core.CONTEXT.GetDB().Where("is_recommended = ?", true).Order("id ASC").Find(&submissions)
+	this.PanicError(db.Error)
+	return submissions
+}
+
+func (this *SubmissionDao) FindByFilter(filter *SubmissionFilter) []*Submission {
+	var submissions []*Submission
+	db := core.CONTEXT.GetDB()
+	if filter.CompetitionId != 0 {
+		db = db.Where("competition_id = ?", filter.CompetitionId)
+	}
+	if filter.TrackId != 0 {
+		db = db.Where("track_id = ?", filter.TrackId)
+	}
+	if filter.CollegeId != 0 {
+		//team submissions belong to the colleges of all the accepted members as well.
+		teamSql := fmt.Sprintf("SELECT m.team_id FROM `%steam_member` m JOIN `user_profile` p ON p.user_uuid = m.user_uuid "+
+			"JOIN `%scollege` c ON c.name = p.college WHERE m.status = ? AND c.id = ?", core.TABLE_PREFIX, core.TABLE_PREFIX)
+		db = db.Where("college_id = ? OR (team_id <> 0 AND team_id IN ("+teamSql+"))", filter.CollegeId, TEAM_MEMBER_STATUS_ACCEPTED, filter.CollegeId)
+	}
+	if filter.RoundId != 0 {
+		db = db.Where("round_id = ?", filter.RoundId)
+	}
+	if filter.Recommended == TRUE {
+		db = db.Where("is_recommended = ?", true)
+	} else if filter.Recommended == FALSE {
+		db = db.Where("is_recommended = ?", false)
+	}
+	if filter.Completeness != "" {
+		db = db.Where("completeness = ?", filter.Completeness)
+	}
+	valueSql := fmt.Sprintf("SELECT submission_id FROM `%sform_value` WHERE ", core.TABLE_PREFIX)
+	if filter.Keyword != "" {
+		keyword := "%" + filter.Keyword + "%"
+		db = db.Where("title LIKE ? OR id IN ("+valueSql+"value LIKE ?)", keyword, keyword)
+	}
+	for key, value := range filter.Fields {
+		db = db.Where("id IN ("+valueSql+"field_key = ? AND value LIKE ?)", key, "%"+value+"%")
+	}
+	db = db.Order("id ASC").Find(&submissions)
+	this.PanicError(db.Error)
+	return submissions
+}
+
+func (this *SubmissionDao) FindByTeamId(teamId int64) []*Submission {
+	var submissions []*Submission
+	db := core.CONTEXT.GetDB().Where("team_id = ?", teamId).Order("id DESC").Find(&submissions)
+	this.PanicError(db.Error)
+	return submissions
+}
@@ -7,6 +7,7 @@ import (
 type Submission struct {
 	Id             int64     `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
 	MatterUuid     string    `json:"matterUuid" gorm:"type:char(36) not null"`
+	CompetitionId  int64     `json:"competitionId" gorm:"type:bigint(20) not null;default:0"`
 	TrackId        int64     `json:"trackId" gorm:"type:bigint(20) not null"`
 	CollegeId      int64     `json:"collegeId" gorm:"type:bigint(20) not null"`
 	Title          string    `json:"title" gorm:"type:varchar(200) not null"`
@@ -15,10 +16,49 @@ type Submission struct {
 	IsRecommended  bool      `json:"isRecommended" gorm:"type:tinyint(1) not null;default:0"`
 	RecommendedBy  string    `json:"recommendedBy" gorm:"type:char(36)"`
 	RecommendedAt  time.Time `json:"recommendedAt" gorm:"type:timestamp"`
+	RoundId        int64     `json:"roundId" gorm:"type:bigint(20) not null;default:0"`
+	RoundStatus    string    `json:"roundStatus" gorm:"type:varchar(20)"`
+	SnapshotId     int64     `json:"snapshotId" gorm:"type:bigint(20) not null;default:0"`
+	TeamId         int64     `json:"teamId" gorm:"type:bigint(20) not null;default:0"`
+	Completeness   string    `json:"completeness" gorm:"type:varchar(20)"`
+	BlindCode      string    `json:"blindCode" gorm:"type:varchar(20)"`
 	CreateTime     time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
 	UpdateTime     time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	FormValues     map[string]string `json:"formValues,omitempty" gorm:"-"`
 }
 
 func (Submission) TableName() string {
 	return "submission"
-}
\ No newline at end of file
+}
+// SubmissionFilter filters submissions. zero values mean no restriction.
+type SubmissionFilter struct {
+	CompetitionId int64
+	TrackId       int64
+	CollegeId     int64
+	RoundId       int64
+	//"true", "false" or empty.
+	Recommended string
+	//matches the title or any form value.
+	Keyword string
+	//form values by field key, each matched partially.
+	Fields map[string]string
+	//COMPLETE, INCOMPLETE or empty. SubmissionService checks it again, SubmissionDao takes what is kept.
+	Completeness string
+}
+
+// QuotaUsage is how much of its recommendation quota a college has used in a track.
+type QuotaUsage struct {
+	TrackId     int64           `json:"trackId"`
+	TrackName   string          `json:"trackName"`
+	CollegeId   int64           `json:"collegeId"`
+	CollegeName string          `json:"collegeName"`
+	Quota       *RecommendQuota `json:"quota"`
+	//-1 means no limit.
+	Limit int `json:"limit"`
+	//recommended submissions.
+	Used int `json:"used"`
+	//all submissions.
+	Total int `json:"total"`
+	//-1 means no limit.
+	Remaining int `json:"remaining"`
+}
@@ -0,0 +1,253 @@
+package rest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+// @Service
+type SubmissionService struct {
+	BaseBean
+	submissionDao     *SubmissionDao
+	userProfileDao    *UserProfileDao
+	collegeDao        *CollegeDao
+	teamService       *TeamService
+	formService       *FormService
+	fileRuleService   *FileRuleService
+	snapshotService   *SnapshotService
+	roundService      *RoundService
+	trackDao          *TrackDao
+	preferenceService *PreferenceService
+	//recommendations of a college in a track are counted against its quota one at a time.
+	quotaMutex sync.Mutex
+	quotaLocks map[string]*sync.Mutex
+}
+
+func (this *SubmissionService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userProfileDao)
+	if b, ok := b.(*UserProfileDao); ok {
+		this.userProfileDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.collegeDao)
+	if b, ok := b.(*CollegeDao); ok {
+		this.collegeDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.teamService)
+	if b, ok := b.(*TeamService); ok {
+		this.teamService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.formService)
+	if b, ok := b.(*FormService); ok {
+		this.formService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.fileRuleService)
+	if b, ok := b.(*FileRuleService); ok {
+		this.fileRuleService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.snapshotService)
+	if b, ok := b.(*SnapshotService); ok {
+		this.snapshotService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.roundService)
+	if b, ok := b.(*RoundService); ok {
+		this.roundService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.trackDao)
+	if b, ok := b.(*TrackDao); ok {
+		this.trackDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.preferenceService)
+	if b, ok := b.(*PreferenceService); ok {
+		this.preferenceService = b
+	}
+
+	this.quotaLocks = make(map[string]*sync.Mutex)
+}
+
+// the submissions of the filter. the files of a submission change after its completeness is kept, so the completeness
+// is checked again when filtered by.
+func (this *SubmissionService) FindByFilter(filter *SubmissionFilter) []*Submission {
+	if filter.Completeness == "" {
+		return this.submissionDao.FindByFilter(filter)
+	}
+
+	unfiltered := *filter
+	unfiltered.Completeness = ""
+	submissions := []*Submission{}
+	for _, submission := range this.submissionDao.FindByFilter(&unfiltered) {
+		if this.fileRuleService.Check(submission).Completeness == filter.Completeness {
+			submissions = append(submissions, submission)
+		}
+	}
+	return submissions
+}
+
+// whether the user is an author of the submission. every accepted member of a team submission is an author.
+func (this *SubmissionService) IsAuthor(user *User, submission *Submission) bool {
+	if submission.TeamId != 0 && this.teamService.IsMember(submission.TeamId, user) {
+		return true
+	}
+	userProfile := this.userProfileDao.FindByUserUuid(user.Uuid)
+	return userProfile != nil && userProfile.StudentId != "" && userProfile.StudentId == submission.AuthorId
+}
+
+// the college a college admin manages, nil if not found.
+func (this *SubmissionService) ManagedCollege(user *User) *College {
+	userProfile := this.userProfileDao.FindByUserUuid(user.Uuid)
+	if userProfile == nil || userProfile.College == "" {
+		return nil
+	}
+	return this.collegeDao.FindByName(userProfile.College)
+}
+
+// whether the college admin manages the college of the submission.
+func (this *SubmissionService) IsCollegeAdminOf(user *User, submission *Submission) bool {
+	if user.Role != USER_ROLE_COLLEGE_ADMIN {
+		return false
+	}
+	userProfile := this.userProfileDao.FindByUserUuid(user.Uuid)
+	if userProfile == nil {
+		return false
+	}
+	college := this.collegeDao.Find(submission.CollegeId)
+	if college != nil && college.Name == userProfile.College {
+		return true
+	}
+	//a team may gather students of several colleges.
+	if submission.TeamId != 0 {
+		for _, memberProfile := range this.teamService.Profiles(submission) {
+			if memberProfile.College != "" && memberProfile.College == userProfile.College {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// whether the user can view the submission.
+// administrators see all, judges see recommended ones, college admins see their college's, students see their own.
+func (this *SubmissionService) CanView(user *User, submission *Submission) bool {
+	switch user.Role {
+	case USER_ROLE_ADMINISTRATOR:
+		return true
+	case USER_ROLE_JUDGE:
+		return submission.IsRecommended
+	case USER_ROLE_COLLEGE_ADMIN:
+		return this.IsCollegeAdminOf(user, submission)
+	default:
+		return this.IsAuthor(user, submission)
+	}
+}
+
+// lock the quota of the college in the track, returns the unlock function.
+func (this *SubmissionService) lockQuota(trackId int64, collegeId int64) func() {
+	key := fmt.Sprintf("%d-%d", trackId, collegeId)
+	this.quotaMutex.Lock()
+	lock, ok := this.quotaLocks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		this.quotaLocks[key] = lock
+	}
+	this.quotaMutex.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// how much of its recommendation quota the college has used in the track.
+func (this *SubmissionService) QuotaUsage(trackId int64, collegeId int64) *QuotaUsage {
+	usage := &QuotaUsage{
+		TrackId:   trackId,
+		CollegeId: collegeId,
+		Quota:     this.preferenceService.Fetch().FetchQuotaConfig().FindQuota(trackId, collegeId),
+		Limit:     -1,
+		Used:      int(this.submissionDao.CountByTrackIdAndCollegeId(trackId, collegeId, true)),
+		Total:     int(this.submissionDao.CountByTrackIdAndCollegeId(trackId, collegeId, false)),
+		Remaining: -1,
+	}
+	if track := this.trackDao.Find(trackId); track != nil {
+		usage.TrackName = track.Name
+	}
+	if college := this.collegeDao.Find(collegeId); college != nil {
+		usage.CollegeName = college.Name
+	}
+	if usage.Quota != nil {
+		usage.Limit = usage.Quota.Limit(usage.Total)
+		usage.Remaining = usage.Limit - usage.Used
+		if usage.Remaining < 0 {
+			usage.Remaining = 0
+		}
+	}
+	return usage
+}
+
+// quota usage of the college in every track of the competition, of every competition if competitionId is 0.
+func (this *SubmissionService) CollegeQuotaUsages(collegeId int64, competitionId int64) []*QuotaUsage {
+	tracks := this.trackDao.FindAll()
+	if competitionId != 0 {
+		tracks = this.trackDao.FindByCompetitionId(competitionId)
+	}
+	usages := []*QuotaUsage{}
+	for _, track := range tracks {
+		usages = append(usages, this.QuotaUsage(track.Id, collegeId))
+	}
+	return usages
+}
+
+// recommend the submission. the form must be filled and the folder complete, and the college must have quota left in the track.
+// administrators can recommend beyond the quota with override.
+// the folder is frozen, and the submission enters the first round if rounds are configured.
+func (this *SubmissionService) Recommend(submission *Submission, operator *User, override bool) *result.WebResult {
+	unlock := this.lockQuota(submission.TrackId, submission.CollegeId)
+	defer unlock()
+
+	if !submission.IsRecommended {
+		usage := this.QuotaUsage(submission.TrackId, submission.CollegeId)
+		if usage.Limit >= 0 && usage.Used >= usage.Limit && !(override && operator.Role == USER_ROLE_ADMINISTRATOR) {
+			return result.BadRequest("%s 在 %s 的推荐名额已用完（%d/%d）", usage.CollegeName, usage.TrackName, usage.Used, usage.Limit)
+		}
+	}
+
+	if missing := this.formService.Missing(submission); len(missing) > 0 {
+		return result.BadRequest("作品信息未填写完整：%s", strings.Join(missing, "、"))
+	}
+
+	if report := this.fileRuleService.Check(submission); report.Completeness != SUBMISSION_COMPLETENESS_COMPLETE {
+		return result.BadRequest("作品文件夹不完整：%s", this.fileRuleService.Summary(report))
+	}
+
+	//judges review the snapshot taken at the recommendation.
+	if _, webResult := this.snapshotService.Take(submission, SNAPSHOT_REASON_RECOMMEND, operator); webResult != nil {
+		return webResult
+	}
+
+	submission.IsRecommended = true
+	submission.RecommendedBy = operator.Uuid
+	submission.RecommendedAt = time.Now()
+	this.submissionDao.Save(submission)
+
+	if submission.RoundId == 0 && this.roundService.HasRounds(submission.CompetitionId) {
+		return this.roundService.Enter(submission, operator)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/i18n"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+// check the submission windows configured in preference.
+// @Service
+type SubmissionWindowService struct {
+	BaseBean
+	matterDao          *MatterDao
+	submissionDao      *SubmissionDao
+	lateGrantDao       *LateGrantDao
+	preferenceService  *PreferenceService
+	competitionService *CompetitionService
+}
+
+func (this *SubmissionWindowService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.lateGrantDao)
+	if b, ok := b.(*LateGrantDao); ok {
+		this.lateGrantDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.preferenceService)
+	if b, ok := b.(*PreferenceService); ok {
+		this.preferenceService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.competitionService)
+	if b, ok := b.(*CompetitionService); ok {
+		this.competitionService = b
+	}
+}
+
+// submissions bound to the matter or any of its ancestors.
+func (this *SubmissionWindowService) boundSubmissions(matter *Matter) []*Submission {
+	var submissions []*Submission
+	for matter != nil && matter.Uuid != MATTER_ROOT {
+		if matter.Dir {
+			submission := this.submissionDao.FindByMatterUuid(matter.Uuid)
+			if submission != nil {
+				submissions = append(submissions, submission)
+			}
+		}
+		if matter.Puuid == MATTER_ROOT {
+			break
+		}
+		matter = this.matterDao.FindByUuid(matter.Puuid)
+	}
+	return submissions
+}
+
+// panic if the submission is out of its window and has no valid late grant, or its competition is archived.
+func (this *SubmissionWindowService) CheckSubmission(request *http.Request, submission *Submission) {
+	if submission.TrackId == 0 {
+		return
+	}
+	if webResult := this.competitionService.CheckEditable(submission.CompetitionId); webResult != nil {
+		panic(webResult)
+	}
+
+	window := this.preferenceService.Fetch().FetchWindowConfig().FindWindow(submission.TrackId, submission.CollegeId)
+	if window == nil {
+		return
+	}
+
+	now := time.Now()
+	local, _ := time.LoadLocation("Local")
+	if window.OpenTime != "" {
+		openTime, err := time.ParseInLocation("2006-01-02 15:04:05", window.OpenTime, local)
+		this.PanicError(err)
+		if now.Before(openTime) {
+			panic(result.BadRequestI18n(request, i18n.SubmissionWindowNotOpen, submission.Title, window.OpenTime))
+		}
+	}
+	if window.CloseTime != "" {
+		closeTime, err := time.ParseInLocation("2006-01-02 15:04:05", window.CloseTime, local)
+		this.PanicError(err)
+		if now.After(closeTime) && !this.lateGrantDao.ExistValid(submission.Id, now) {
+			panic(result.BadRequestI18n(request, i18n.SubmissionWindowClosed, submission.Title, window.CloseTime))
+		}
+	}
+}
+
+// close time of the submission's window, nil if the window never closes.
+func (this *SubmissionWindowService) CloseTime(submission *Submission) *time.Time {
+	window := this.preferenceService.Fetch().FetchWindowConfig().FindWindow(submission.TrackId, submission.CollegeId)
+	if window == nil || window.CloseTime == "" {
+		return nil
+	}
+	local, _ := time.LoadLocation("Local")
+	closeTime, err := time.ParseInLocation("2006-01-02 15:04:05", window.CloseTime, local)
+	this.PanicError(err)
+	return &closeTime
+}
+
+// panic if the matter belongs to a submission folder whose window is closed. administrators are not limited.
+func (this *SubmissionWindowService) CheckWritable(request *http.Request, user *User, matter *Matter) {
+	if matter == nil || (user != nil && user.Role == USER_ROLE_ADMINISTRATOR) {
+		return
+	}
+
+	for _, submission := range this.boundSubmissions(matter) {
+		this.CheckSubmission(request, submission)
+	}
+}
+
+// grant a submission to be changed until expireTime.
+func (this *SubmissionWindowService) Grant(submissionId int64, expireTime time.Time, reason string, operator *User) (*LateGrant, *result.WebResult) {
+	submission := this.submissionDao.FindById(submissionId)
+	if submission == nil {
+		return nil, result.BadRequest("提交作品不存在")
+	}
+	if !expireTime.After(time.Now()) {
+		return nil, result.BadRequest("延期截止时间必须晚于当前时间")
+	}
+
+	lateGrant := this.lateGrantDao.Create(&LateGrant{
+		SubmissionId: submission.Id,
+		ExpireTime:   expireTime,
+		Reason:       reason,
+		OperatorUuid: operator.Uuid,
+	})
+	return lateGrant, nil
+}
@@ -11,12 +11,15 @@ import (
 // @Service
 type TaskService struct {
 	BaseBean
-	footprintService  *FootprintService
-	dashboardService  *DashboardService
-	preferenceService *PreferenceService
-	matterService     *MatterService
-	userDao           *UserDao
-	spaceDao          *SpaceDao
+	footprintService     *FootprintService
+	dashboardService     *DashboardService
+	preferenceService    *PreferenceService
+	matterService        *MatterService
+	appealService        *AppealService
+	uploadSessionService *UploadSessionService
+	matterVersionService *MatterVersionService
+	userDao              *UserDao
+	spaceDao             *SpaceDao
 
 	//whether scan task is running
 	scanTaskRunning bool
@@ -45,6 +48,18 @@ func (this *TaskService) Init() {
 	if b, ok := b.(*MatterService); ok {
 		this.matterService = b
 	}
+	b = core.CONTEXT.GetBean(this.appealService)
+	if b, ok := b.(*AppealService); ok {
+		this.appealService = b
+	}
+	b = core.CONTEXT.GetBean(this.uploadSessionService)
+	if b, ok := b.(*UploadSessionService); ok {
+		this.uploadSessionService = b
+	}
+	b = core.CONTEXT.GetBean(this.matterVersionService)
+	if b, ok := b.(*MatterVersionService); ok {
+		this.matterVersionService = b
+	}
 	b = core.CONTEXT.GetBean(this.userDao)
 	if b, ok := b.(*UserDao); ok {
 		this.userDao = b
@@ -94,6 +109,42 @@ func (this *TaskService) InitCleanDeletedMattersTask() {
 	this.logger.Info("[cron job] Everyday 01:00 Clean deleted matters.")
 }
 
+// init the appeal deadline task.
+func (this *TaskService) InitAppealDeadlineTask() {
+
+	expression := "0 * * * *"
+	cronJob := cron.New()
+	_, err := cronJob.AddFunc(expression, this.appealService.CheckDeadlines)
+	core.PanicError(err)
+	cronJob.Start()
+
+	this.logger.Info("[cron job] Every hour check appeal deadlines.")
+}
+
+// init the clean expired upload sessions task.
+func (this *TaskService) InitCleanUploadSessionsTask() {
+
+	expression := "30 * * * *"
+	cronJob := cron.New()
+	_, err := cronJob.AddFunc(expression, this.uploadSessionService.CleanExpired)
+	core.PanicError(err)
+	cronJob.Start()
+
+	this.logger.Info("[cron job] Every hour clean expired upload sessions.")
+}
+
+// init the clean expired versions task.
+func (this *TaskService) InitCleanMatterVersionsTask() {
+
+	expression := "20 1 * * *"
+	cronJob := cron.New()
+	_, err := cronJob.AddFunc(expression, this.matterVersionService.CleanExpired)
+	core.PanicError(err)
+	cronJob.Start()
+
+	this.logger.Info("[cron job] Everyday 01:20 clean expired versions.")
+}
+
 // scan task.
 func (this *TaskService) doScanTask() {
 
@@ -210,6 +261,15 @@ func (this *TaskService) Bootstrap() {
 	//load the clean deleted matters task.
 	this.InitCleanDeletedMattersTask()
 
+	//load the appeal deadline task.
+	this.InitAppealDeadlineTask()
+
+	//load the clean upload sessions task.
+	this.InitCleanUploadSessionsTask()
+
+	//load the clean expired versions task.
+	this.InitCleanMatterVersionsTask()
+
 	//load the scan task.
 	this.InitScanTask()
 
@@ -0,0 +1,221 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+type TeamController struct {
+	BaseController
+	teamDao        *TeamDao
+	teamService    *TeamService
+	userDao        *UserDao
+	userProfileDao *UserProfileDao
+	submissionDao  *SubmissionDao
+}
+
+func (this *TeamController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.teamDao)
+	if b, ok := b.(*TeamDao); ok {
+		this.teamDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.teamService)
+	if b, ok := b.(*TeamService); ok {
+		this.teamService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userDao)
+	if b, ok := b.(*UserDao); ok {
+		this.userDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userProfileDao)
+	if b, ok := b.(*UserProfileDao); ok {
+		this.userProfileDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+}
+
+func (this *TeamController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/team/create"] = this.Wrap(this.Create, USER_ROLE_USER)
+	routeMap["/api/team/detail"] = this.Wrap(this.Detail, USER_ROLE_USER)
+	routeMap["/api/team/my"] = this.Wrap(this.My, USER_ROLE_USER)
+	routeMap["/api/team/invite"] = this.Wrap(this.Invite, USER_ROLE_USER)
+	routeMap["/api/team/accept"] = this.Wrap(this.Accept, USER_ROLE_USER)
+	routeMap["/api/team/decline"] = this.Wrap(this.Decline, USER_ROLE_USER)
+	routeMap["/api/team/remove"] = this.Wrap(this.Remove, USER_ROLE_USER)
+	routeMap["/api/team/transfer"] = this.Wrap(this.Transfer, USER_ROLE_USER)
+	routeMap["/api/team/dissolve"] = this.Wrap(this.Dissolve, USER_ROLE_USER)
+	routeMap["/api/team/submissions"] = this.Wrap(this.Submissions, USER_ROLE_USER)
+
+	return routeMap
+}
+
+func (this *TeamController) checkTeam(request *http.Request) *Team {
+	id, err := strconv.ParseInt(request.FormValue("teamId"), 10, 64)
+	if err != nil {
+		panic(result.BadRequest("teamId格式错误"))
+	}
+	team := this.teamDao.Find(id)
+	if team == nil {
+		panic(result.BadRequest("团队不存在"))
+	}
+	return team
+}
+
+func (this *TeamController) checkMember(request *http.Request) *TeamMember {
+	id, err := strconv.ParseInt(request.FormValue("memberId"), 10, 64)
+	if err != nil {
+		panic(result.BadRequest("memberId格式错误"))
+	}
+	member := this.teamDao.FindMember(id)
+	if member == nil {
+		panic(result.BadRequest("团队成员不存在"))
+	}
+	return member
+}
+
+// only members of the team and administrators can look into a team.
+func (this *TeamController) checkVisible(team *Team, user *User) {
+	if user.Role != USER_ROLE_ADMINISTRATOR && !this.teamService.IsMember(team.Id, user) {
+		panic(result.UNAUTHORIZED)
+	}
+}
+
+func (this *TeamController) Create(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	team, webResult := this.teamService.CreateTeam(request, user, request.FormValue("name"))
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(team)
+}
+
+func (this *TeamController) Detail(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	team := this.checkTeam(request)
+	this.checkVisible(team, user)
+
+	return this.Success(this.teamService.Wrap(team))
+}
+
+// the teams of the current user, and the invitations waiting for an answer.
+func (this *TeamController) My(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+
+	teams, invitations := this.teamService.MyTeams(user)
+	for _, invitation := range invitations {
+		invitation.User = this.userDao.FindByUuid(invitation.InviterUuid)
+	}
+	return this.Success(map[string]any{
+		"teams":       teams,
+		"invitations": invitations,
+	})
+}
+
+// invite a user by username or student id. role is MEMBER (default) or ADVISOR.
+func (this *TeamController) Invite(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	team := this.checkTeam(request)
+
+	var invitee *User
+	if username := request.FormValue("username"); username != "" {
+		invitee = this.userDao.FindByUsername(username)
+	} else if studentId := request.FormValue("studentId"); studentId != "" {
+		if userProfile := this.userProfileDao.FindByStudentId(studentId); userProfile != nil {
+			invitee = this.userDao.FindByUuid(userProfile.UserUuid)
+		}
+	} else {
+		return result.BadRequest("username和studentId不能同时为空")
+	}
+	if invitee == nil {
+		return result.BadRequest("被邀请的用户不存在")
+	}
+
+	role := request.FormValue("role")
+	if role == "" {
+		role = TEAM_ROLE_MEMBER
+	}
+
+	member, webResult := this.teamService.Invite(team, user, invitee, role)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(member)
+}
+
+func (this *TeamController) Accept(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	member := this.checkMember(request)
+
+	member, webResult := this.teamService.Respond(member, user, true)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(member)
+}
+
+func (this *TeamController) Decline(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	member := this.checkMember(request)
+
+	member, webResult := this.teamService.Respond(member, user, false)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(member)
+}
+
+// the leader removes a member or cancels an invitation. a member removes their own membership to leave the team.
+func (this *TeamController) Remove(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	team := this.checkTeam(request)
+	member := this.checkMember(request)
+
+	if webResult := this.teamService.RemoveMember(team, user, member); webResult != nil {
+		return webResult
+	}
+	return this.Success("OK")
+}
+
+func (this *TeamController) Transfer(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	team := this.checkTeam(request)
+	member := this.checkMember(request)
+
+	if webResult := this.teamService.Transfer(team, user, member); webResult != nil {
+		return webResult
+	}
+	return this.Success(this.teamService.Wrap(team))
+}
+
+func (this *TeamController) Dissolve(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	team := this.checkTeam(request)
+
+	if webResult := this.teamService.Dissolve(team, user); webResult != nil {
+		return webResult
+	}
+	return this.Success("OK")
+}
+
+func (this *TeamController) Submissions(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	team := this.checkTeam(request)
+	this.checkVisible(team, user)
+
+	return this.Success(this.submissionDao.FindByTeamId(team.Id))
+}
@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+// @Service
+type TeamDao struct {
+	BaseDao
+}
+
+func (this *TeamDao) Init() {
+	this.BaseDao.Init()
+}
+
+func (this *TeamDao) Create(team *Team) *Team {
+	team.CreateTime = time.Now()
+	team.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(team)
+	this.PanicError(db.Error)
+	return team
+}
+
+func (this *TeamDao) Save(team *Team) *Team {
+	team.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Save(team)
+	this.PanicError(db.Error)
+	return team
+}
+
+func (this *TeamDao) Find(id int64) *Team {
+	var team Team
+	db := core.CONTEXT.GetDB().Where("id = ?", id).First(&team)
+	if db.Error != nil {
+		return nil
+	}
+	return &team
+}
+
+func (this *TeamDao) FindBySpaceUuid(spaceUuid string) *Team {
+	var team Team
+	db := core.CONTEXT.GetDB().Where("space_uuid = ?", spaceUuid).First(&team)
+	if db.Error != nil {
+		return nil
+	}
+	return &team
+}
+
+func (this *TeamDao) Delete(team *Team) {
+	db := core.CONTEXT.GetDB().Where("team_id = ?", team.Id).Delete(&TeamMember{})
+	this.PanicError(db.Error)
+	db = core.CONTEXT.GetDB().Delete(team)
+	this.PanicError(db.Error)
+}
+
+func (this *TeamDao) CreateMember(member *TeamMember) *TeamMember {
+	member.CreateTime = time.Now()
+	member.RespondTime = time.Now()
+	db := core.CONTEXT.GetDB().Create(member)
+	this.PanicError(db.Error)
+	return member
+}
+
+func (this *TeamDao) SaveMember(member *TeamMember) *TeamMember {
+	db := core.CONTEXT.GetDB().Save(member)
+	this.PanicError(db.Error)
+	return member
+}
+
+func (this *TeamDao) FindMember(id int64) *TeamMember {
+	var member TeamMember
+	db := core.CONTEXT.GetDB().Where("id = ?", id).First(&member)
+	if db.Error != nil {
+		return nil
+	}
+	return &member
+}
+
+func (this *TeamDao) FindMemberByTeamIdAndUserUuid(teamId int64, userUuid string) *TeamMember {
+	var member TeamMember
+	db := core.CONTEXT.GetDB().Where("team_id = ? AND user_uuid = ?", teamId, userUuid).First(&member)
+	if db.Error != nil {
+		return nil
+	}
+	return &member
+}
+
+func (this *TeamDao) FindMembersByTeamId(teamId int64) []*TeamMember {
+	var members []*TeamMember
+	db := core.CONTEXT.GetDB().Where("team_id = ?", teamId).Order("id ASC").Find(&members)
+	this.PanicError(db.Error)
+	return members
+}
+
+func (this *TeamDao) FindMembersByUserUuid(userUuid string) []*TeamMember {
+	var members []*TeamMember
+	db := core.CONTEXT.GetDB().Where("user_uuid = ?", userUuid).Order("id DESC").Find(&members)
+	this.PanicError(db.Error)
+	return members
+}
+
+func (this *TeamDao) DeleteMember(member *TeamMember) {
+	db := core.CONTEXT.GetDB().Delete(member)
+	this.PanicError(db.Error)
+}
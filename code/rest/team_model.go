@@ -0,0 +1,44 @@
+package rest
+
+import (
+	"time"
+)
+
+const (
+	TEAM_ROLE_LEADER  = "LEADER"
+	TEAM_ROLE_MEMBER  = "MEMBER"
+	TEAM_ROLE_ADVISOR = "ADVISOR"
+
+	TEAM_MEMBER_STATUS_INVITED  = "INVITED"
+	TEAM_MEMBER_STATUS_ACCEPTED = "ACCEPTED"
+	TEAM_MEMBER_STATUS_DECLINED = "DECLINED"
+
+	//students of a team, leader included.
+	TEAM_MAX_MEMBERS = 5
+	//advisor teachers of a team.
+	TEAM_MAX_ADVISORS = 2
+)
+
+// Team works on a submission together. The team folder lives in a shared space, of which every accepted member is a space member.
+type Team struct {
+	Id         int64         `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	Name       string        `json:"name" gorm:"type:varchar(100) not null"`
+	LeaderUuid string        `json:"leaderUuid" gorm:"type:char(36) not null"`
+	SpaceUuid  string        `json:"spaceUuid" gorm:"type:char(36);index:idx_team_su"`
+	CreateTime time.Time     `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime time.Time     `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	Members    []*TeamMember `json:"members" gorm:"-"`
+}
+
+type TeamMember struct {
+	Id          int64        `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	TeamId      int64        `json:"teamId" gorm:"type:bigint(20) not null;index:idx_team_member_ti"`
+	UserUuid    string       `json:"userUuid" gorm:"type:char(36) not null;index:idx_team_member_uu"`
+	Role        string       `json:"role" gorm:"type:varchar(20) not null"`
+	Status      string       `json:"status" gorm:"type:varchar(20) not null"`
+	InviterUuid string       `json:"inviterUuid" gorm:"type:char(36)"`
+	CreateTime  time.Time    `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	RespondTime time.Time    `json:"respondTime" gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
+	User        *User        `json:"user" gorm:"-"`
+	UserProfile *UserProfile `json:"userProfile" gorm:"-"`
+}
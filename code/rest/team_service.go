@@ -0,0 +1,402 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+)
+
+// @Service
+type TeamService struct {
+	BaseBean
+	teamDao            *TeamDao
+	userDao            *UserDao
+	userProfileDao     *UserProfileDao
+	submissionDao      *SubmissionDao
+	spaceDao           *SpaceDao
+	spaceService       *SpaceService
+	spaceMemberDao     *SpaceMemberDao
+	spaceMemberService *SpaceMemberService
+	collegeDao         *CollegeDao
+	preferenceService  *PreferenceService
+}
+
+func (this *TeamService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.teamDao)
+	if b, ok := b.(*TeamDao); ok {
+		this.teamDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userDao)
+	if b, ok := b.(*UserDao); ok {
+		this.userDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userProfileDao)
+	if b, ok := b.(*UserProfileDao); ok {
+		this.userProfileDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceDao)
+	if b, ok := b.(*SpaceDao); ok {
+		this.spaceDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceService)
+	if b, ok := b.(*SpaceService); ok {
+		this.spaceService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceMemberDao)
+	if b, ok := b.(*SpaceMemberDao); ok {
+		this.spaceMemberDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceMemberService)
+	if b, ok := b.(*SpaceMemberService); ok {
+		this.spaceMemberService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.collegeDao)
+	if b, ok := b.(*CollegeDao); ok {
+		this.collegeDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.preferenceService)
+	if b, ok := b.(*PreferenceService); ok {
+		this.preferenceService = b
+	}
+}
+
+// fill the members of the team with their users and profiles.
+func (this *TeamService) Wrap(team *Team) *Team {
+	if team != nil {
+		team.Members = this.teamDao.FindMembersByTeamId(team.Id)
+		for _, member := range team.Members {
+			member.User = this.userDao.FindByUuid(member.UserUuid)
+			member.UserProfile = this.userProfileDao.FindByUserUuid(member.UserUuid)
+		}
+	}
+	return team
+}
+
+// space role of a team role. advisors can read the team folder but not change it.
+func (this *TeamService) spaceRole(teamRole string) string {
+	switch teamRole {
+	case TEAM_ROLE_LEADER:
+		return SPACE_MEMBER_ROLE_ADMIN
+	case TEAM_ROLE_ADVISOR:
+		return SPACE_MEMBER_ROLE_READ_ONLY
+	default:
+		return SPACE_MEMBER_ROLE_READ_WRITE
+	}
+}
+
+// grant or revoke the team space for the member.
+func (this *TeamService) syncSpaceMember(team *Team, member *TeamMember) {
+	space := this.spaceDao.FindByUuid(team.SpaceUuid)
+	if space == nil {
+		return
+	}
+	spaceMember := this.spaceMemberDao.FindBySpaceUuidAndUserUuid(space.Uuid, member.UserUuid)
+	if member.Status != TEAM_MEMBER_STATUS_ACCEPTED {
+		if spaceMember != nil {
+			this.spaceMemberDao.Delete(spaceMember)
+		}
+		return
+	}
+
+	if spaceMember == nil {
+		user := this.userDao.FindByUuid(member.UserUuid)
+		if user != nil {
+			this.spaceMemberService.CreateMember(space, user, this.spaceRole(member.Role))
+		}
+	} else if spaceMember.Role != this.spaceRole(member.Role) {
+		spaceMember.Role = this.spaceRole(member.Role)
+		this.spaceMemberDao.Save(spaceMember)
+	}
+}
+
+// create a team led by the user, with a shared space as the team folder.
+func (this *TeamService) CreateTeam(request *http.Request, user *User, name string) (*Team, *result.WebResult) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, result.BadRequest("团队名称不能为空")
+	}
+	if user.Role != USER_ROLE_USER {
+		return nil, result.BadRequest("只有学生可以创建团队")
+	}
+
+	team := this.teamDao.Create(&Team{Name: name, LeaderUuid: user.Uuid})
+
+	preference := this.preferenceService.Fetch()
+	space := this.spaceService.CreateSpace(request, fmt.Sprintf("team_%d", team.Id), nil, -1, preference.DefaultTotalSizeLimit, SPACE_TYPE_SHARED, "")
+	team.SpaceUuid = space.Uuid
+	this.teamDao.Save(team)
+
+	leader := this.teamDao.CreateMember(&TeamMember{
+		TeamId:      team.Id,
+		UserUuid:    user.Uuid,
+		Role:        TEAM_ROLE_LEADER,
+		Status:      TEAM_MEMBER_STATUS_ACCEPTED,
+		InviterUuid: user.Uuid,
+	})
+	this.syncSpaceMember(team, leader)
+
+	return this.Wrap(team), nil
+}
+
+func (this *TeamService) checkLeader(team *Team, user *User) *result.WebResult {
+	if team.LeaderUuid != user.Uuid && user.Role != USER_ROLE_ADMINISTRATOR {
+		return result.BadRequest("只有队长可以进行此操作")
+	}
+	return nil
+}
+
+// invite a user to the team as a member or an advisor.
+func (this *TeamService) Invite(team *Team, operator *User, invitee *User, role string) (*TeamMember, *result.WebResult) {
+	if webResult := this.checkLeader(team, operator); webResult != nil {
+		return nil, webResult
+	}
+	if role != TEAM_ROLE_MEMBER && role != TEAM_ROLE_ADVISOR {
+		return nil, result.BadRequest("团队角色只能是成员或指导教师")
+	}
+	if role == TEAM_ROLE_MEMBER && invitee.Role != USER_ROLE_USER {
+		return nil, result.BadRequest("只有学生可以作为团队成员")
+	}
+	if role == TEAM_ROLE_ADVISOR {
+		userProfile := this.userProfileDao.FindByUserUuid(invitee.Uuid)
+		if userProfile != nil && userProfile.UserType == "STUDENT" {
+			return nil, result.BadRequest("学生不能作为指导教师")
+		}
+	}
+
+	existing := this.teamDao.FindMemberByTeamIdAndUserUuid(team.Id, invitee.Uuid)
+	if existing != nil && existing.Status != TEAM_MEMBER_STATUS_DECLINED {
+		return nil, result.BadRequest("%s 已在团队中或已被邀请", invitee.Username)
+	}
+
+	//pending invitations take places too.
+	var count int
+	for _, member := range this.teamDao.FindMembersByTeamId(team.Id) {
+		if member.Status == TEAM_MEMBER_STATUS_DECLINED {
+			continue
+		}
+		if (role == TEAM_ROLE_ADVISOR) == (member.Role == TEAM_ROLE_ADVISOR) {
+			count++
+		}
+	}
+	if role == TEAM_ROLE_ADVISOR && count >= TEAM_MAX_ADVISORS {
+		return nil, result.BadRequest("每个团队最多 %d 名指导教师", TEAM_MAX_ADVISORS)
+	}
+	if role != TEAM_ROLE_ADVISOR && count >= TEAM_MAX_MEMBERS {
+		return nil, result.BadRequest("每个团队最多 %d 名成员", TEAM_MAX_MEMBERS)
+	}
+
+	if existing != nil {
+		existing.Role = role
+		existing.Status = TEAM_MEMBER_STATUS_INVITED
+		existing.InviterUuid = operator.Uuid
+		return this.teamDao.SaveMember(existing), nil
+	}
+	return this.teamDao.CreateMember(&TeamMember{
+		TeamId:      team.Id,
+		UserUuid:    invitee.Uuid,
+		Role:        role,
+		Status:      TEAM_MEMBER_STATUS_INVITED,
+		InviterUuid: operator.Uuid,
+	}), nil
+}
+
+// accept or decline an invitation.
+func (this *TeamService) Respond(member *TeamMember, user *User, accept bool) (*TeamMember, *result.WebResult) {
+	if member.UserUuid != user.Uuid {
+		return nil, result.BadRequest("这不是您的邀请")
+	}
+	if member.Status != TEAM_MEMBER_STATUS_INVITED {
+		return nil, result.BadRequest("该邀请已处理")
+	}
+	team := this.teamDao.Find(member.TeamId)
+	if team == nil {
+		return nil, result.BadRequest("团队不存在")
+	}
+
+	if accept {
+		member.Status = TEAM_MEMBER_STATUS_ACCEPTED
+	} else {
+		member.Status = TEAM_MEMBER_STATUS_DECLINED
+	}
+	member.RespondTime = time.Now()
+	this.teamDao.SaveMember(member)
+	this.syncSpaceMember(team, member)
+
+	return member, nil
+}
+
+// the leader removes a member, or a member leaves the team. the leader cannot leave.
+func (this *TeamService) RemoveMember(team *Team, operator *User, member *TeamMember) *result.WebResult {
+	if member.TeamId != team.Id {
+		return result.BadRequest("该成员不在团队中")
+	}
+	if member.Role == TEAM_ROLE_LEADER {
+		return result.BadRequest("队长不能离开团队，请先转让队长")
+	}
+	if member.UserUuid != operator.Uuid {
+		if webResult := this.checkLeader(team, operator); webResult != nil {
+			return webResult
+		}
+	}
+
+	member.Status = TEAM_MEMBER_STATUS_DECLINED
+	this.syncSpaceMember(team, member)
+	this.teamDao.DeleteMember(member)
+	return nil
+}
+
+// hand the leadership to another student member. submissions of the team follow the new leader.
+func (this *TeamService) Transfer(team *Team, operator *User, member *TeamMember) *result.WebResult {
+	if webResult := this.checkLeader(team, operator); webResult != nil {
+		return webResult
+	}
+	if member.TeamId != team.Id || member.Status != TEAM_MEMBER_STATUS_ACCEPTED || member.Role != TEAM_ROLE_MEMBER {
+		return result.BadRequest("只能转让给已加入的学生成员")
+	}
+
+	oldLeader := this.teamDao.FindMemberByTeamIdAndUserUuid(team.Id, team.LeaderUuid)
+	if oldLeader != nil {
+		oldLeader.Role = TEAM_ROLE_MEMBER
+		this.teamDao.SaveMember(oldLeader)
+		this.syncSpaceMember(team, oldLeader)
+	}
+	member.Role = TEAM_ROLE_LEADER
+	this.teamDao.SaveMember(member)
+	this.syncSpaceMember(team, member)
+
+	team.LeaderUuid = member.UserUuid
+	this.teamDao.Save(team)
+
+	for _, submission := range this.submissionDao.FindByTeamId(team.Id) {
+		this.Bind(submission, team)
+		this.submissionDao.Save(submission)
+	}
+	return nil
+}
+
+// teams the user belongs to, and the pending invitations.
+func (this *TeamService) MyTeams(user *User) ([]*Team, []*TeamMember) {
+	var teams []*Team
+	var invitations []*TeamMember
+	for _, member := range this.teamDao.FindMembersByUserUuid(user.Uuid) {
+		switch member.Status {
+		case TEAM_MEMBER_STATUS_ACCEPTED:
+			if team := this.teamDao.Find(member.TeamId); team != nil {
+				teams = append(teams, this.Wrap(team))
+			}
+		case TEAM_MEMBER_STATUS_INVITED:
+			invitations = append(invitations, member)
+		}
+	}
+	return teams, invitations
+}
+
+// whether the user is an accepted member of the team, advisors included.
+func (this *TeamService) IsMember(teamId int64, user *User) bool {
+	member := this.teamDao.FindMemberByTeamIdAndUserUuid(teamId, user.Uuid)
+	return member != nil && member.Status == TEAM_MEMBER_STATUS_ACCEPTED
+}
+
+// make the submission a team submission. the leader is regarded as the author.
+func (this *TeamService) Bind(submission *Submission, team *Team) {
+	submission.TeamId = team.Id
+	userProfile := this.userProfileDao.FindByUserUuid(team.LeaderUuid)
+	if userProfile == nil {
+		return
+	}
+	submission.AuthorName = userProfile.RealName
+	submission.AuthorId = userProfile.StudentId
+	if college := this.collegeDao.FindByName(userProfile.College); college != nil {
+		submission.CollegeId = college.Id
+	}
+}
+
+// the team owning the space, nil if the space is not a team folder.
+func (this *TeamService) FindBySpaceUuid(spaceUuid string) *Team {
+	return this.teamDao.FindBySpaceUuid(spaceUuid)
+}
+
+// profiles of everyone the submission belongs to: the accepted team members, or the author.
+func (this *TeamService) Profiles(submission *Submission) []*UserProfile {
+	var profiles []*UserProfile
+	if submission.TeamId != 0 {
+		for _, member := range this.teamDao.FindMembersByTeamId(submission.TeamId) {
+			if member.Status != TEAM_MEMBER_STATUS_ACCEPTED {
+				continue
+			}
+			if userProfile := this.userProfileDao.FindByUserUuid(member.UserUuid); userProfile != nil {
+				profiles = append(profiles, userProfile)
+			}
+		}
+	} else if submission.AuthorId != "" {
+		if userProfile := this.userProfileDao.FindByStudentId(submission.AuthorId); userProfile != nil {
+			profiles = append(profiles, userProfile)
+		}
+	}
+	return profiles
+}
+
+// submissions the user authored or the user's teams own, latest first.
+func (this *TeamService) MySubmissions(user *User) []*Submission {
+	submissionMap := make(map[int64]*Submission)
+	userProfile := this.userProfileDao.FindByUserUuid(user.Uuid)
+	if userProfile != nil && userProfile.StudentId != "" {
+		for _, submission := range this.submissionDao.FindByAuthorId(userProfile.StudentId) {
+			submissionMap[submission.Id] = submission
+		}
+	}
+	for _, member := range this.teamDao.FindMembersByUserUuid(user.Uuid) {
+		if member.Status != TEAM_MEMBER_STATUS_ACCEPTED {
+			continue
+		}
+		for _, submission := range this.submissionDao.FindByTeamId(member.TeamId) {
+			submissionMap[submission.Id] = submission
+		}
+	}
+
+	submissions := []*Submission{}
+	for _, submission := range submissionMap {
+		submissions = append(submissions, submission)
+	}
+	sort.Slice(submissions, func(i, j int) bool {
+		return submissions[i].Id > submissions[j].Id
+	})
+	return submissions
+}
+
+// dissolve the team. only possible before the team submits, the team space is kept for the administrator.
+func (this *TeamService) Dissolve(team *Team, operator *User) *result.WebResult {
+	if webResult := this.checkLeader(team, operator); webResult != nil {
+		return webResult
+	}
+	if len(this.submissionDao.FindByTeamId(team.Id)) > 0 {
+		return result.BadRequest("团队已有提交作品，不能解散")
+	}
+
+	for _, member := range this.teamDao.FindMembersByTeamId(team.Id) {
+		member.Status = TEAM_MEMBER_STATUS_DECLINED
+		this.syncSpaceMember(team, member)
+	}
+	this.teamDao.Delete(team)
+	return nil
+}
@@ -34,17 +34,42 @@ func (this *TrackController) RegisterRoutes() map[string]func(writer http.Respon
 	return routeMap
 }
 
+// tracks of the competition, of every competition without competitionId.
 func (this *TrackController) List(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	if request.FormValue("competitionId") != "" {
+		competitionId, webResult := this.competitionId(request)
+		if webResult != nil {
+			return webResult
+		}
+		return this.Success(this.trackService.GetTracksByCompetitionId(competitionId))
+	}
 	tracks := this.trackService.GetAllTracks()
 	return this.Success(tracks)
 }
 
+// competitionId of the request, 0 if absent.
+func (this *TrackController) competitionId(request *http.Request) (int64, *result.WebResult) {
+	competitionIdStr := request.FormValue("competitionId")
+	if competitionIdStr == "" {
+		return 0, nil
+	}
+	competitionId, err := strconv.ParseInt(competitionIdStr, 10, 64)
+	if err != nil {
+		return 0, result.BadRequest("competitionId格式错误")
+	}
+	return competitionId, nil
+}
+
 func (this *TrackController) Create(writer http.ResponseWriter, request *http.Request) *result.WebResult {
 	name := request.FormValue("name")
 	targetUserType := request.FormValue("targetUserType")
 	description := request.FormValue("description")
+	competitionId, webResult := this.competitionId(request)
+	if webResult != nil {
+		return webResult
+	}
 
-	track, webResult := this.trackService.CreateTrack(name, targetUserType, description)
+	track, webResult := this.trackService.CreateTrack(competitionId, name, targetUserType, description)
 	if webResult != nil {
 		return webResult
 	}
@@ -64,7 +89,12 @@ func (this *TrackController) BulkCreate(writer http.ResponseWriter, request *htt
 		return result.BadRequest("赛道数据格式错误")
 	}
 
-	tracks, webResult := this.trackService.BulkCreateTracks(tracksData)
+	competitionId, webResult := this.competitionId(request)
+	if webResult != nil {
+		return webResult
+	}
+
+	tracks, webResult := this.trackService.BulkCreateTracks(competitionId, tracksData)
 	if webResult != nil {
 		return webResult
 	}
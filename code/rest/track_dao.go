@@ -48,9 +48,9 @@ func (this *TrackDao) Find(id int64) *Track {
 	return entity
 }
 
-func (this *TrackDao) FindByName(name string) *Track {
+func (this *TrackDao) FindByCompetitionIdAndName(competitionId int64, name string) *Track {
 	var entity = &Track{}
-	db := core.CONTEXT.GetDB().Where("name = ?", name).First(entity)
+	db := core.CONTEXT.GetDB().Where("competition_id = ? AND name = ?", competitionId, name).First(entity)
 	if db.Error != nil {
 		return nil
 	}
@@ -64,6 +64,13 @@ func (this *TrackDao) FindAll() []Track {
 	return entities
 }
 
+func (this *TrackDao) FindByCompetitionId(competitionId int64) []Track {
+	var entities []Track
+	db := core.CONTEXT.GetDB().Where("competition_id = ?", competitionId).Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
 func (this *TrackDao) FindByUserType(userType string) []Track {
 	var entities []Track
 	db := core.CONTEXT.GetDB().Where("target_user_type = ? OR target_user_type = 'BOTH'", userType).Find(&entities)
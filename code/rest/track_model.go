@@ -4,10 +4,12 @@ import (
 	"time"
 )
 
+// Track belongs to a competition, its name is unique in the competition.
 type Track struct {
 	Id             int64     `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
-	Name           string    `json:"name" gorm:"type:varchar(100) not null;unique"`
+	CompetitionId  int64     `json:"competitionId" gorm:"type:bigint(20) not null;default:0;index:idx_track_ci"`
+	Name           string    `json:"name" gorm:"type:varchar(100) not null"`
 	TargetUserType string    `json:"targetUserType" gorm:"type:varchar(20) not null;default:'BOTH'"`
 	Description    string    `json:"description" gorm:"type:text"`
 	CreateTime     time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
-}
\ No newline at end of file
+}
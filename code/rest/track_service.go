@@ -9,7 +9,8 @@ import (
 
 type TrackService struct {
 	BaseBean
-	trackDao *TrackDao
+	trackDao           *TrackDao
+	competitionService *CompetitionService
 }
 
 func (this *TrackService) Init() {
@@ -19,9 +20,18 @@ func (this *TrackService) Init() {
 	if b, ok := b.(*TrackDao); ok {
 		this.trackDao = b
 	}
+
+	b = core.CONTEXT.GetBean(this.competitionService)
+	if b, ok := b.(*CompetitionService); ok {
+		this.competitionService = b
+	}
 }
 
-func (this *TrackService) CreateTrack(name, targetUserType, description string) (*Track, *result.WebResult) {
+func (this *TrackService) CreateTrack(competitionId int64, name, targetUserType, description string) (*Track, *result.WebResult) {
+	if webResult := this.competitionService.CheckEditable(competitionId); webResult != nil {
+		return nil, webResult
+	}
+
 	if strings.TrimSpace(name) == "" {
 		return nil, result.BadRequest("赛道名称不能为空")
 	}
@@ -30,12 +40,13 @@ func (this *TrackService) CreateTrack(name, targetUserType, description string)
 		return nil, result.BadRequest("目标用户类型必须是 STUDENT, TEACHER 或 BOTH")
 	}
 
-	existing := this.trackDao.FindByName(name)
+	existing := this.trackDao.FindByCompetitionIdAndName(competitionId, name)
 	if existing != nil {
 		return nil, result.BadRequest("赛道名称已存在")
 	}
 
 	track := &Track{
+		CompetitionId:  competitionId,
 		Name:           name,
 		TargetUserType: targetUserType,
 		Description:    description,
@@ -45,7 +56,11 @@ func (this *TrackService) CreateTrack(name, targetUserType, description string)
 	return track, nil
 }
 
-func (this *TrackService) BulkCreateTracks(tracks []map[string]string) ([]Track, *result.WebResult) {
+func (this *TrackService) BulkCreateTracks(competitionId int64, tracks []map[string]string) ([]Track, *result.WebResult) {
+	if webResult := this.competitionService.CheckEditable(competitionId); webResult != nil {
+		return nil, webResult
+	}
+
 	var createdTracks []Track
 
 	for _, trackData := range tracks {
@@ -61,12 +76,13 @@ func (this *TrackService) BulkCreateTracks(tracks []map[string]string) ([]Track,
 
 		description := trackData["description"]
 
-		existing := this.trackDao.FindByName(name)
+		existing := this.trackDao.FindByCompetitionIdAndName(competitionId, name)
 		if existing != nil {
 			continue
 		}
 
 		track := &Track{
+			CompetitionId:  competitionId,
 			Name:           name,
 			TargetUserType: targetUserType,
 			Description:    description,
@@ -82,6 +98,10 @@ func (this *TrackService) GetAllTracks() []Track {
 	return this.trackDao.FindAll()
 }
 
+func (this *TrackService) GetTracksByCompetitionId(competitionId int64) []Track {
+	return this.trackDao.FindByCompetitionId(competitionId)
+}
+
 func (this *TrackService) GetTracksByUserType(userType string) []Track {
 	return this.trackDao.FindByUserType(userType)
 }
@@ -91,6 +111,9 @@ func (this *TrackService) DeleteTrack(id int64) *result.WebResult {
 	if track == nil {
 		return result.BadRequest("赛道不存在")
 	}
+	if webResult := this.competitionService.CheckEditable(track.CompetitionId); webResult != nil {
+		return webResult
+	}
 
 	this.trackDao.Delete(track)
 	return nil
@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/uuid"
+)
+
+type UploadChunkDao struct {
+	BaseDao
+}
+
+// find by the session and the index. if not found return nil.
+func (this *UploadChunkDao) FindByUploadSessionUuidAndChunkIndex(uploadSessionUuid string, chunkIndex int64) *UploadChunk {
+	var entity = &UploadChunk{}
+	db := core.CONTEXT.GetDB().Where("upload_session_uuid = ? AND chunk_index = ?", uploadSessionUuid, chunkIndex).First(entity)
+	if db.Error != nil {
+		if db.Error.Error() == result.DB_ERROR_NOT_FOUND {
+			return nil
+		} else {
+			panic(db.Error)
+		}
+	}
+	return entity
+}
+
+// the received chunks of the session, ordered by index.
+func (this *UploadChunkDao) FindByUploadSessionUuid(uploadSessionUuid string) []*UploadChunk {
+	var entities []*UploadChunk
+	db := core.CONTEXT.GetDB().Where("upload_session_uuid = ?", uploadSessionUuid).Order("chunk_index ASC").Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *UploadChunkDao) Create(uploadChunk *UploadChunk) *UploadChunk {
+
+	timeUUID, _ := uuid.NewV4()
+	uploadChunk.Uuid = string(timeUUID.String())
+
+	uploadChunk.CreateTime = time.Now()
+	uploadChunk.UpdateTime = time.Now()
+	uploadChunk.Sort = time.Now().UnixNano() / 1e6
+	db := core.CONTEXT.GetDB().Create(uploadChunk)
+	this.PanicError(db.Error)
+
+	return uploadChunk
+}
+
+func (this *UploadChunkDao) Save(uploadChunk *UploadChunk) *UploadChunk {
+
+	uploadChunk.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Save(uploadChunk)
+	this.PanicError(db.Error)
+
+	return uploadChunk
+}
+
+func (this *UploadChunkDao) DeleteByUploadSessionUuid(uploadSessionUuid string) {
+
+	db := core.CONTEXT.GetDB().Where("upload_session_uuid = ?", uploadSessionUuid).Delete(UploadChunk{})
+	this.PanicError(db.Error)
+
+}
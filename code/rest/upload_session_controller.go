@@ -0,0 +1,152 @@
+package rest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+// resumable uploads in chunks:
+// 1. /api/upload/session/create with the size of the file. it returns the session, or the unfinished one of the same file with the chunks received.
+// 2. /api/upload/session/chunk for every chunk, with the index and the md5 of the chunk. the chunk is the field "file" of a multipart form,
+// or the raw body with Content-Type application/octet-stream. chunks can be sent in parallel.
+// 3. /api/upload/session/complete to assemble the chunks into the file.
+type UploadSessionController struct {
+	BaseController
+	uploadSessionDao     *UploadSessionDao
+	uploadSessionService *UploadSessionService
+	matterDao            *MatterDao
+	matterService        *MatterService
+	spaceService         *SpaceService
+}
+
+func (this *UploadSessionController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.uploadSessionDao)
+	if b, ok := b.(*UploadSessionDao); ok {
+		this.uploadSessionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.uploadSessionService)
+	if b, ok := b.(*UploadSessionService); ok {
+		this.uploadSessionService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterService)
+	if b, ok := b.(*MatterService); ok {
+		this.matterService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceService)
+	if b, ok := b.(*SpaceService); ok {
+		this.spaceService = b
+	}
+}
+
+func (this *UploadSessionController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/upload/session/create"] = this.Wrap(this.Create, USER_ROLE_USER)
+	routeMap["/api/upload/session/detail"] = this.Wrap(this.Detail, USER_ROLE_USER)
+	routeMap["/api/upload/session/chunk"] = this.Wrap(this.Chunk, USER_ROLE_USER)
+	routeMap["/api/upload/session/complete"] = this.Wrap(this.Complete, USER_ROLE_USER)
+	routeMap["/api/upload/session/delete"] = this.Wrap(this.Delete, USER_ROLE_USER)
+
+	return routeMap
+}
+
+// the session of the current user. sessions are not shared, even in a shared space.
+func (this *UploadSessionController) checkUploadSession(request *http.Request, user *User) *UploadSession {
+	uuid := util.ExtractRequestString(request, "uuid")
+	uploadSession := this.uploadSessionDao.CheckByUuid(uuid)
+	if uploadSession.UserUuid != user.Uuid {
+		panic(result.UNAUTHORIZED)
+	}
+	return uploadSession
+}
+
+func (this *UploadSessionController) Create(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	puuid := util.ExtractRequestString(request, "puuid")
+	filename := util.ExtractRequestString(request, "filename")
+	size := util.ExtractRequestInt64(request, "size")
+	chunkSize := util.ExtractRequestOptionalInt64(request, "chunkSize", UPLOAD_CHUNK_DEFAULT_SIZE)
+	fileMd5 := util.ExtractRequestOptionalString(request, "md5", "")
+	privacy := util.ExtractRequestOptionalBool(request, "privacy", true)
+	trackId := util.ExtractRequestOptionalInt64(request, "trackId", 0)
+	workName := util.ExtractRequestOptionalString(request, "workName", "")
+
+	user := this.checkUser(request)
+	spaceUuid := util.ExtractRequestOptionalString(request, "spaceUuid", user.SpaceUuid)
+	space := this.spaceService.CheckWritableByUuid(request, user, spaceUuid)
+
+	//named the same as the plain upload.
+	filename = this.matterService.SubmissionFilename(user, trackId, workName, filename)
+
+	dirMatter := this.matterDao.CheckWithRootByUuid(puuid, space)
+
+	uploadSession := this.uploadSessionService.Create(request, user, space, dirMatter, filename, size, chunkSize, fileMd5, privacy)
+
+	return this.Success(uploadSession)
+}
+
+// the session with the indexes of the received chunks, so that an interrupted upload sends only the rest.
+func (this *UploadSessionController) Detail(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	uploadSession := this.checkUploadSession(request, user)
+
+	return this.Success(this.uploadSessionService.Detail(uploadSession))
+}
+
+func (this *UploadSessionController) Chunk(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	index := util.ExtractRequestInt64(request, "index")
+	chunkMd5 := util.ExtractRequestString(request, "md5")
+
+	user := this.checkUser(request)
+	uploadSession := this.checkUploadSession(request, user)
+	this.spaceService.CheckWritableByUuid(request, user, uploadSession.SpaceUuid)
+
+	var reader io.Reader = request.Body
+	if strings.HasPrefix(request.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := request.FormFile("file")
+		this.PanicError(err)
+		defer func() {
+			err := file.Close()
+			this.PanicError(err)
+		}()
+		reader = file
+	}
+
+	chunk := this.uploadSessionService.UploadChunk(request, uploadSession, index, chunkMd5, reader)
+
+	return this.Success(chunk)
+}
+
+func (this *UploadSessionController) Complete(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	uploadSession := this.checkUploadSession(request, user)
+	space := this.spaceService.CheckWritableByUuid(request, user, uploadSession.SpaceUuid)
+
+	matter := this.uploadSessionService.Complete(request, user, space, uploadSession)
+
+	return this.Success(matter.ShowHash(user))
+}
+
+func (this *UploadSessionController) Delete(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkUser(request)
+	uploadSession := this.checkUploadSession(request, user)
+
+	this.uploadSessionService.Delete(uploadSession)
+
+	return this.Success("OK")
+}
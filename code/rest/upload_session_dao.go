@@ -0,0 +1,130 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/uuid"
+)
+
+type UploadSessionDao struct {
+	BaseDao
+}
+
+// find by uuid. if not found return nil.
+func (this *UploadSessionDao) FindByUuid(uuid string) *UploadSession {
+	var entity = &UploadSession{}
+	db := core.CONTEXT.GetDB().Where("uuid = ?", uuid).First(entity)
+	if db.Error != nil {
+		if db.Error.Error() == result.DB_ERROR_NOT_FOUND {
+			return nil
+		} else {
+			panic(db.Error)
+		}
+	}
+	return entity
+}
+
+// find by uuid. if not found panic NotFound error
+func (this *UploadSessionDao) CheckByUuid(uuid string) *UploadSession {
+	entity := this.FindByUuid(uuid)
+	if entity == nil {
+		panic(result.NotFound("not found upload session with uuid = %s", uuid))
+	}
+	return entity
+}
+
+// the unfinished session of the same file, so that an upload started again resumes.
+func (this *UploadSessionDao) FindResumable(userUuid string, spaceUuid string, puuid string, filename string, size int64, chunkSize int64) *UploadSession {
+	var entity = &UploadSession{}
+	db := core.CONTEXT.GetDB().
+		Where("user_uuid = ? AND space_uuid = ? AND puuid = ? AND filename = ? AND size = ? AND chunk_size = ? AND status = ? AND expire_time > ?",
+			userUuid, spaceUuid, puuid, filename, size, chunkSize, UPLOAD_SESSION_STATUS_UPLOADING, time.Now()).
+		Order("create_time DESC").
+		First(entity)
+	if db.Error != nil {
+		if db.Error.Error() == result.DB_ERROR_NOT_FOUND {
+			return nil
+		} else {
+			panic(db.Error)
+		}
+	}
+	return entity
+}
+
+// the number of the sessions of the user not expired yet.
+func (this *UploadSessionDao) CountOpenByUserUuid(userUuid string, now time.Time) int64 {
+	var count int64
+	db := core.CONTEXT.GetDB().Model(&UploadSession{}).Where("user_uuid = ? AND expire_time > ?", userUuid, now).Count(&count)
+	this.PanicError(db.Error)
+	return count
+}
+
+// the total size of the files being uploaded into the space by the sessions not expired yet.
+func (this *UploadSessionDao) SumOpenSizeBySpaceUuid(spaceUuid string, now time.Time) int64 {
+	var size int64
+	db := core.CONTEXT.GetDB().Model(&UploadSession{}).Where("space_uuid = ? AND expire_time > ?", spaceUuid, now).
+		Select("COALESCE(SUM(size), 0)").Scan(&size)
+	this.PanicError(db.Error)
+	return size
+}
+
+func (this *UploadSessionDao) FindExpired(now time.Time) []*UploadSession {
+	var entities []*UploadSession
+	db := core.CONTEXT.GetDB().Where("expire_time < ?", now).Find(&entities)
+	this.PanicError(db.Error)
+	return entities
+}
+
+func (this *UploadSessionDao) Create(uploadSession *UploadSession) *UploadSession {
+
+	timeUUID, _ := uuid.NewV4()
+	uploadSession.Uuid = string(timeUUID.String())
+
+	uploadSession.CreateTime = time.Now()
+	uploadSession.UpdateTime = time.Now()
+	uploadSession.Sort = time.Now().UnixNano() / 1e6
+	db := core.CONTEXT.GetDB().Create(uploadSession)
+	this.PanicError(db.Error)
+
+	return uploadSession
+}
+
+func (this *UploadSessionDao) Save(uploadSession *UploadSession) *UploadSession {
+
+	uploadSession.UpdateTime = time.Now()
+	db := core.CONTEXT.GetDB().Save(uploadSession)
+	this.PanicError(db.Error)
+
+	return uploadSession
+}
+
+// change the status only if it is still from. false if another request changed it first.
+func (this *UploadSessionDao) SwitchStatus(uploadSession *UploadSession, from string, to string) bool {
+	db := core.CONTEXT.GetDB().Model(&UploadSession{}).
+		Where("uuid = ? AND status = ?", uploadSession.Uuid, from).
+		Updates(map[string]any{"status": to, "update_time": time.Now()})
+	this.PanicError(db.Error)
+	if db.RowsAffected == 0 {
+		return false
+	}
+	uploadSession.Status = to
+	return true
+}
+
+// put off the expiration. only the expire time is written, so a status changed meanwhile is kept.
+func (this *UploadSessionDao) Touch(uploadSession *UploadSession) {
+	uploadSession.ExpireTime = time.Now().Add(UPLOAD_SESSION_EXPIRE)
+	db := core.CONTEXT.GetDB().Model(&UploadSession{}).
+		Where("uuid = ?", uploadSession.Uuid).
+		Updates(map[string]any{"expire_time": uploadSession.ExpireTime, "update_time": time.Now()})
+	this.PanicError(db.Error)
+}
+
+func (this *UploadSessionDao) Delete(uploadSession *UploadSession) {
+
+	db := core.CONTEXT.GetDB().Delete(uploadSession)
+	this.PanicError(db.Error)
+
+}
@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+)
+
+const (
+	UPLOAD_SESSION_STATUS_UPLOADING = "UPLOADING"
+	//the chunks are being assembled. no chunk is accepted any more.
+	UPLOAD_SESSION_STATUS_COMPLETING = "COMPLETING"
+
+	//chunks are staged under the space, eg. matter/{spaceName}/upload/{uploadSessionUuid}/{index}
+	MATTER_UPLOAD = "upload"
+
+	UPLOAD_CHUNK_DEFAULT_SIZE = 5 * 1024 * 1024
+	UPLOAD_CHUNK_MIN_SIZE     = 64 * 1024
+	UPLOAD_CHUNK_MAX_SIZE     = 100 * 1024 * 1024
+	UPLOAD_CHUNK_MAX_COUNT    = 10000
+	//an upload session which receives nothing for so long is cleaned with its chunks.
+	UPLOAD_SESSION_EXPIRE = 24 * time.Hour
+	//unfinished uploads a user can have at the same time.
+	UPLOAD_SESSION_MAX_OPEN = 20
+)
+
+// UploadSession is a resumable upload of one file. The client sends the chunks in any order and in parallel,
+// asks which chunks the server has after a disconnect, and completes the session to get the matter.
+type UploadSession struct {
+	Uuid       string    `json:"uuid" gorm:"type:char(36);primary_key;unique"`
+	Sort       int64     `json:"sort" gorm:"type:bigint(20) not null"`
+	UpdateTime time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	CreateTime time.Time `json:"createTime" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"`
+	UserUuid   string    `json:"userUuid" gorm:"type:char(36) not null;index:idx_upload_session_uu"`
+	SpaceUuid  string    `json:"spaceUuid" gorm:"type:char(36) not null"`
+	SpaceName  string    `json:"spaceName" gorm:"type:varchar(100) not null"`
+	Puuid      string    `json:"puuid" gorm:"type:char(36) not null"`
+	Filename   string    `json:"filename" gorm:"type:varchar(255) not null"`
+	Privacy    bool      `json:"privacy" gorm:"type:tinyint(1) not null;default:0"`
+	Size       int64     `json:"size" gorm:"type:bigint(20) not null;default:0"`
+	ChunkSize  int64     `json:"chunkSize" gorm:"type:bigint(20) not null;default:0"`
+	ChunkCount int64     `json:"chunkCount" gorm:"type:bigint(20) not null;default:0"`
+	//md5 of the whole file given by the client. checked after assembly if not empty.
+	Md5        string    `json:"md5" gorm:"type:varchar(45)"`
+	Status     string    `json:"status" gorm:"type:varchar(20) not null;default:'UPLOADING'"`
+	ExpireTime time.Time `json:"expireTime" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"`
+	//indexes of the received chunks.
+	Chunks []int64 `json:"chunks" gorm:"-"`
+}
+
+// size of the chunk at index. the last one takes the rest.
+func (this *UploadSession) ChunkSizeOf(index int64) int64 {
+	if index == this.ChunkCount-1 {
+		return this.Size - this.ChunkSize*index
+	}
+	return this.ChunkSize
+}
+
+// the directory the chunks are staged in.
+func (this *UploadSession) StagingPath() string {
+	return GetSpaceUploadRootDir(this.SpaceName) + "/" + this.Uuid
+}
+
+// UploadChunk is a received chunk of an UploadSession, kept with its checksum.
+type UploadChunk struct {
+	Uuid              string    `json:"uuid" gorm:"type:char(36);primary_key;unique"`
+	Sort              int64     `json:"sort" gorm:"type:bigint(20) not null"`
+	UpdateTime        time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	CreateTime        time.Time `json:"createTime" gorm:"type:timestamp not null;default:'2018-01-01 00:00:00'"`
+	UploadSessionUuid string    `json:"uploadSessionUuid" gorm:"type:char(36) not null;uniqueIndex:idx_upload_chunk_si"`
+	ChunkIndex        int64     `json:"chunkIndex" gorm:"type:bigint(20) not null;uniqueIndex:idx_upload_chunk_si"`
+	Size              int64     `json:"size" gorm:"type:bigint(20) not null;default:0"`
+	Md5               string    `json:"md5" gorm:"type:varchar(45) not null"`
+}
+
+// get the upload staging absolute path of the space.
+func GetSpaceUploadRootDir(spaceName string) (rootDirPath string) {
+
+	rootDirPath = fmt.Sprintf("%s/%s/%s", core.CONFIG.MatterPath(), spaceName, MATTER_UPLOAD)
+
+	return rootDirPath
+}
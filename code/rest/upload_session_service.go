@@ -0,0 +1,273 @@
+package rest
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/i18n"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+var md5Pattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// resumable uploads of large files. the chunks are staged on disk,
+// and the assembled file is uploaded by MatterService.Upload like any other one.
+// @Service
+type UploadSessionService struct {
+	BaseBean
+	uploadSessionDao *UploadSessionDao
+	uploadChunkDao   *UploadChunkDao
+	matterDao        *MatterDao
+	matterService    *MatterService
+	//sessions are created one at a time, so that the limits are checked against all the others.
+	mutex sync.Mutex
+}
+
+func (this *UploadSessionService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.uploadSessionDao)
+	if b, ok := b.(*UploadSessionDao); ok {
+		this.uploadSessionDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.uploadChunkDao)
+	if b, ok := b.(*UploadChunkDao); ok {
+		this.uploadChunkDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterDao)
+	if b, ok := b.(*MatterDao); ok {
+		this.matterDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterService)
+	if b, ok := b.(*MatterService); ok {
+		this.matterService = b
+	}
+}
+
+// start an upload of filename under dirMatter. an unfinished upload of the same file is resumed instead.
+// the unfinished uploads of a user are limited in number, and their sizes count against the total size limit of the space.
+func (this *UploadSessionService) Create(request *http.Request, user *User, space *Space, dirMatter *Matter, filename string, size int64, chunkSize int64, fileMd5 string, privacy bool) *UploadSession {
+
+	if size <= 0 {
+		panic(result.BadRequest("size must be positive. upload empty files directly."))
+	}
+	if chunkSize < UPLOAD_CHUNK_MIN_SIZE || chunkSize > UPLOAD_CHUNK_MAX_SIZE {
+		panic(result.BadRequest("chunkSize must be between %s and %s", util.HumanFileSize(UPLOAD_CHUNK_MIN_SIZE), util.HumanFileSize(UPLOAD_CHUNK_MAX_SIZE)))
+	}
+	chunkCount := (size + chunkSize - 1) / chunkSize
+	if chunkCount > UPLOAD_CHUNK_MAX_COUNT {
+		panic(result.BadRequest("too many chunks %d > %d, use larger chunks", chunkCount, UPLOAD_CHUNK_MAX_COUNT))
+	}
+	fileMd5 = strings.ToLower(fileMd5)
+	if fileMd5 != "" && !md5Pattern.MatchString(fileMd5) {
+		panic(result.BadRequest("md5 must be 32 hex chars"))
+	}
+
+	//refuse in advance, so that nothing is uploaded in vain.
+	filename = this.matterService.CheckUploadable(request, user, space, dirMatter, filename, size)
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	uploadSession := this.uploadSessionDao.FindResumable(user.Uuid, space.Uuid, dirMatter.Uuid, filename, size, chunkSize)
+	if uploadSession != nil {
+		this.logger.Info("resume upload %s %s", uploadSession.Uuid, filename)
+		this.uploadSessionDao.Touch(uploadSession)
+		return this.Detail(uploadSession)
+	}
+
+	//staged chunks take the disk before they become files, so the unfinished uploads are limited too.
+	now := time.Now()
+	if this.uploadSessionDao.CountOpenByUserUuid(user.Uuid, now) >= UPLOAD_SESSION_MAX_OPEN {
+		panic(result.BadRequest("too many unfinished uploads, at most %d. complete or cancel some first.", UPLOAD_SESSION_MAX_OPEN))
+	}
+	if space.TotalSizeLimit >= 0 {
+		totalSize := space.TotalSize + this.uploadSessionDao.SumOpenSizeBySpaceUuid(space.Uuid, now)
+		if totalSize+size > space.TotalSizeLimit {
+			panic(result.BadRequestI18n(request, i18n.MatterSizeExceedTotalLimit, util.HumanFileSize(totalSize), util.HumanFileSize(space.TotalSizeLimit)))
+		}
+	}
+
+	uploadSession = this.uploadSessionDao.Create(&UploadSession{
+		UserUuid:   user.Uuid,
+		SpaceUuid:  space.Uuid,
+		SpaceName:  space.Name,
+		Puuid:      dirMatter.Uuid,
+		Filename:   filename,
+		Privacy:    privacy,
+		Size:       size,
+		ChunkSize:  chunkSize,
+		ChunkCount: chunkCount,
+		Md5:        fileMd5,
+		Status:     UPLOAD_SESSION_STATUS_UPLOADING,
+		ExpireTime: time.Now().Add(UPLOAD_SESSION_EXPIRE),
+	})
+	util.MakeDirAll(uploadSession.StagingPath())
+
+	return this.Detail(uploadSession)
+}
+
+// fill the indexes of the received chunks.
+func (this *UploadSessionService) Detail(uploadSession *UploadSession) *UploadSession {
+	uploadSession.Chunks = []int64{}
+	for _, chunk := range this.uploadChunkDao.FindByUploadSessionUuid(uploadSession.Uuid) {
+		uploadSession.Chunks = append(uploadSession.Chunks, chunk.ChunkIndex)
+	}
+	return uploadSession
+}
+
+// receive the chunk at index. chunks can be sent in parallel, and sending one again replaces it.
+func (this *UploadSessionService) UploadChunk(request *http.Request, uploadSession *UploadSession, index int64, chunkMd5 string, reader io.Reader) *UploadChunk {
+
+	if uploadSession.Status != UPLOAD_SESSION_STATUS_UPLOADING {
+		panic(result.BadRequest("upload %s is being completed", uploadSession.Uuid))
+	}
+	if index < 0 || index >= uploadSession.ChunkCount {
+		panic(result.BadRequest("index must be between 0 and %d", uploadSession.ChunkCount-1))
+	}
+	chunkMd5 = strings.ToLower(chunkMd5)
+	if !md5Pattern.MatchString(chunkMd5) {
+		panic(result.BadRequest("md5 must be 32 hex chars"))
+	}
+
+	//written aside, so that parallel or repeated sending of a chunk never leaves half of it in place.
+	chunkPath := fmt.Sprintf("%s/%d", uploadSession.StagingPath(), index)
+	tempPath := fmt.Sprintf("%s.%d.part", chunkPath, time.Now().UnixNano())
+	util.MakeDirAll(uploadSession.StagingPath())
+	tempFile, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0777)
+	this.PanicError(err)
+
+	expectedSize := uploadSession.ChunkSizeOf(index)
+	hash := md5.New()
+	//one more byte to tell a chunk which is too large.
+	size, err := io.Copy(tempFile, io.TeeReader(io.LimitReader(reader, expectedSize+1), hash))
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		panic(err)
+	}
+
+	if size != expectedSize {
+		_ = os.Remove(tempPath)
+		panic(result.BadRequest("chunk %d should be %d bytes, but got %d", index, expectedSize, size))
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != chunkMd5 {
+		_ = os.Remove(tempPath)
+		panic(result.BadRequestI18n(request, i18n.UploadChunkChecksumError, index))
+	}
+
+	err = os.Rename(tempPath, chunkPath)
+	this.PanicError(err)
+
+	chunk := this.uploadChunkDao.FindByUploadSessionUuidAndChunkIndex(uploadSession.Uuid, index)
+	if chunk == nil {
+		chunk = this.uploadChunkDao.Create(&UploadChunk{
+			UploadSessionUuid: uploadSession.Uuid,
+			ChunkIndex:        index,
+			Size:              size,
+			Md5:               chunkMd5,
+		})
+	} else {
+		chunk.Size = size
+		chunk.Md5 = chunkMd5
+		chunk = this.uploadChunkDao.Save(chunk)
+	}
+
+	this.uploadSessionDao.Touch(uploadSession)
+
+	return chunk
+}
+
+// assemble the chunks into the file. it goes through MatterService.Upload, so the limits of the space apply as usual.
+func (this *UploadSessionService) Complete(request *http.Request, user *User, space *Space, uploadSession *UploadSession) (matter *Matter) {
+
+	if !this.uploadSessionDao.SwitchStatus(uploadSession, UPLOAD_SESSION_STATUS_UPLOADING, UPLOAD_SESSION_STATUS_COMPLETING) {
+		panic(result.BadRequest("upload %s is being completed", uploadSession.Uuid))
+	}
+	//the chunks stay for another try if the assembly fails.
+	defer func() {
+		if matter == nil {
+			this.uploadSessionDao.SwitchStatus(uploadSession, UPLOAD_SESSION_STATUS_COMPLETING, UPLOAD_SESSION_STATUS_UPLOADING)
+		}
+	}()
+	this.uploadSessionDao.Touch(uploadSession)
+
+	chunks := this.uploadChunkDao.FindByUploadSessionUuid(uploadSession.Uuid)
+	if int64(len(chunks)) != uploadSession.ChunkCount {
+		panic(result.BadRequestI18n(request, i18n.UploadChunkMissing, uploadSession.ChunkCount-int64(len(chunks))))
+	}
+
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+	var readers []io.Reader
+	for index, chunk := range chunks {
+		if chunk.ChunkIndex != int64(index) || chunk.Size != uploadSession.ChunkSizeOf(chunk.ChunkIndex) {
+			panic(result.BadRequestI18n(request, i18n.UploadChunkChecksumError, index))
+		}
+		file, err := os.Open(fmt.Sprintf("%s/%d", uploadSession.StagingPath(), chunk.ChunkIndex))
+		this.PanicError(err)
+		files = append(files, file)
+		readers = append(readers, file)
+	}
+
+	dirMatter := this.matterDao.CheckWithRootByUuid(uploadSession.Puuid, space)
+
+	newMatter := this.matterService.Upload(request, io.MultiReader(readers...), nil, user, space, dirMatter, uploadSession.Filename, uploadSession.Privacy)
+
+	if uploadSession.Md5 != "" && uploadSession.Md5 != newMatter.Md5 {
+		this.matterService.Delete(request, newMatter, user, space)
+		panic(result.BadRequestI18n(request, i18n.UploadChecksumError, uploadSession.Filename))
+	}
+	matter = newMatter
+
+	this.logger.Info("complete upload %s %s in %d chunks", uploadSession.Uuid, uploadSession.Filename, uploadSession.ChunkCount)
+
+	this.delete(uploadSession)
+
+	return matter
+}
+
+// give up the upload and remove its chunks.
+func (this *UploadSessionService) Delete(uploadSession *UploadSession) {
+	if uploadSession.Status == UPLOAD_SESSION_STATUS_COMPLETING {
+		panic(result.BadRequest("upload %s is being completed", uploadSession.Uuid))
+	}
+	this.delete(uploadSession)
+}
+
+func (this *UploadSessionService) delete(uploadSession *UploadSession) {
+	err := os.RemoveAll(uploadSession.StagingPath())
+	if err != nil {
+		this.logger.Error("error while removing %s %s", uploadSession.StagingPath(), err.Error())
+	}
+	this.uploadChunkDao.DeleteByUploadSessionUuid(uploadSession.Uuid)
+	this.uploadSessionDao.Delete(uploadSession)
+}
+
+// remove the uploads which received nothing for a long time.
+func (this *UploadSessionService) CleanExpired() {
+	for _, uploadSession := range this.uploadSessionDao.FindExpired(time.Now()) {
+		this.logger.Info("clean expired upload %s %s", uploadSession.Uuid, uploadSession.Filename)
+		this.delete(uploadSession)
+	}
+}
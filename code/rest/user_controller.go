@@ -10,6 +10,7 @@ import (
 	"github.com/eyebluecn/tank/code/tool/builder"
 	"github.com/eyebluecn/tank/code/tool/i18n"
 	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/sheet"
 	"github.com/eyebluecn/tank/code/tool/util"
 )
 
@@ -20,6 +21,8 @@ type UserController struct {
 	spaceDao          *SpaceDao
 	spaceService      *SpaceService
 	matterService     *MatterService
+	userImportService *UserImportService
+	auditService      *AuditService
 }
 
 func (this *UserController) Init() {
@@ -47,6 +50,15 @@ func (this *UserController) Init() {
 	if b, ok := b.(*MatterService); ok {
 		this.matterService = b
 	}
+	b = core.CONTEXT.GetBean(this.userImportService)
+	if b, ok := b.(*UserImportService); ok {
+		this.userImportService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.auditService)
+	if b, ok := b.(*AuditService); ok {
+		this.auditService = b
+	}
 
 }
 
@@ -59,6 +71,7 @@ func (this *UserController) RegisterRoutes() map[string]func(writer http.Respons
 	routeMap["/api/user/authentication/login"] = this.Wrap(this.AuthenticationLogin, USER_ROLE_GUEST)
 	routeMap["/api/user/register"] = this.Wrap(this.Register, USER_ROLE_GUEST)
 	routeMap["/api/user/create"] = this.Wrap(this.Create, USER_ROLE_ADMINISTRATOR)
+	routeMap["/api/user/import"] = this.Wrap(this.Import, USER_ROLE_ADMINISTRATOR)
 	routeMap["/api/user/edit"] = this.Wrap(this.Edit, USER_ROLE_USER)
 	routeMap["/api/user/detail"] = this.Wrap(this.Detail, USER_ROLE_USER)
 	routeMap["/api/user/logout"] = this.Wrap(this.Logout, USER_ROLE_GUEST)
@@ -268,6 +281,37 @@ func (this *UserController) Create(writer http.ResponseWriter, request *http.Req
 	return this.Success(user)
 }
 
+// import users from a csv/xlsx roster. dryRun only validates and reports.
+func (this *UserController) Import(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+
+	file, handler, err := request.FormFile("file")
+	if err != nil {
+		panic(result.BadRequest("file cannot be null"))
+	}
+	defer func() {
+		err := file.Close()
+		this.PanicError(err)
+	}()
+
+	format := sheet.FormatOf(handler.Filename)
+	if format == "" {
+		panic(result.BadRequest("only csv and xlsx are supported"))
+	}
+	rows, err := sheet.Read(format, file, handler.Size)
+	if err != nil {
+		panic(result.BadRequest("cannot read %s: %s", handler.Filename, err.Error()))
+	}
+
+	dryRun := request.FormValue("dryRun") == TRUE
+	createCollege := request.FormValue("createCollege") == TRUE
+
+	report, webResult := this.userImportService.Import(request, this.checkUser(request), rows, dryRun, createCollege)
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(report)
+}
+
 func (this *UserController) Edit(writer http.ResponseWriter, request *http.Request) *result.WebResult {
 
 	uuid := request.FormValue("uuid")
@@ -281,6 +325,7 @@ func (this *UserController) Edit(writer http.ResponseWriter, request *http.Reque
 	currentUser := this.userDao.CheckByUuid(uuid)
 
 	currentUser.AvatarUrl = avatarUrl
+	oldRole := currentUser.Role
 
 	if operator.Role == USER_ROLE_ADMINISTRATOR {
 		//only admin can edit user's role and sizeLimit
@@ -307,6 +352,9 @@ func (this *UserController) Edit(writer http.ResponseWriter, request *http.Reque
 
 	//edit user's info
 	currentUser = this.userDao.Save(currentUser)
+	if currentUser.Role != oldRole {
+		this.auditService.Log(request, operator, AUDIT_ACTION_USER_ROLE, AUDIT_TARGET_USER, currentUser.Uuid, map[string]any{"role": oldRole}, map[string]any{"role": currentUser.Role})
+	}
 
 	//edit user's private space info.
 	space := this.spaceService.Edit(request, operator, currentUser.SpaceUuid, sizeLimit, totalSizeLimit)
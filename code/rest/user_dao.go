@@ -8,6 +8,7 @@ import (
 	"github.com/eyebluecn/tank/code/tool/builder"
 	"github.com/eyebluecn/tank/code/tool/result"
 	"github.com/eyebluecn/tank/code/tool/uuid"
+	"gorm.io/gorm"
 )
 
 type UserDao struct {
@@ -26,6 +27,11 @@ func (this *UserDao) Init() {
 }
 
 func (this *UserDao) Create(user *User) *User {
+	return this.CreateTx(core.CONTEXT.GetDB(), user)
+}
+
+// create in the transaction.
+func (this *UserDao) CreateTx(tx *gorm.DB, user *User) *User {
 
 	if user == nil {
 		panic(result.BadRequest("user cannot be nil"))
@@ -38,7 +44,7 @@ func (this *UserDao) Create(user *User) *User {
 	user.LastTime = time.Now()
 	user.Sort = time.Now().UnixNano() / 1e6
 
-	db := core.CONTEXT.GetDB().Create(user)
+	db := tx.Create(user)
 	this.PanicError(db.Error)
 
 	return user
@@ -127,6 +133,14 @@ func (this *UserDao) FindAnAdmin() *User {
 	return user
 }
 
+// find all the active users of a role.
+func (this *UserDao) FindByRole(role string) []*User {
+	var users []*User
+	db := core.CONTEXT.GetDB().Where("role = ? AND status = ?", role, USER_STATUS_OK).Order("username ASC").Find(&users)
+	this.PanicError(db.Error)
+	return users
+}
+
 func (this *UserDao) Page(page int, pageSize int, username string, status string, sortArray []builder.OrderPair) *Pager {
 
 	count, users := this.PlainPage(page, pageSize, username, status, sortArray)
@@ -201,10 +215,14 @@ func (this *UserDao) CountByUsername(username string) int {
 }
 
 func (this *UserDao) Save(user *User) *User {
+	return this.SaveTx(core.CONTEXT.GetDB(), user)
+}
+
+// save in the transaction.
+func (this *UserDao) SaveTx(tx *gorm.DB, user *User) *User {
 
 	user.UpdateTime = time.Now()
-	db := core.CONTEXT.GetDB().
-		Save(user)
+	db := tx.Save(user)
 	this.PanicError(db.Error)
 	return user
 }
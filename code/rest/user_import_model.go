@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"fmt"
+)
+
+const (
+	//the user will be created.
+	IMPORT_ACTION_CREATE = "CREATE"
+	//the role or the profile of the existing user will be updated.
+	IMPORT_ACTION_UPDATE = "UPDATE"
+	//the existing user is already up to date.
+	IMPORT_ACTION_SKIP = "SKIP"
+	//the row is invalid and ignored.
+	IMPORT_ACTION_ERROR = "ERROR"
+)
+
+// a roster row and what importing it does.
+type ImportRow struct {
+	Line           int      `json:"line"`
+	Username       string   `json:"username"`
+	Password       string   `json:"-"`
+	Role           string   `json:"role"`
+	RealName       string   `json:"realName"`
+	StudentId      string   `json:"studentId"`
+	College        string   `json:"college"`
+	PhoneNumber    string   `json:"phoneNumber"`
+	UserType       string   `json:"userType"`
+	SizeLimit      string   `json:"sizeLimit"`
+	TotalSizeLimit string   `json:"totalSizeLimit"`
+	Action         string   `json:"action"`
+	Errors         []string `json:"errors"`
+}
+
+func (this *ImportRow) fail(format string, v ...any) {
+	this.Errors = append(this.Errors, fmt.Sprintf(format, v...))
+}
+
+type ImportReport struct {
+	DryRun  bool         `json:"dryRun"`
+	Total   int          `json:"total"`
+	Created int          `json:"created"`
+	Updated int          `json:"updated"`
+	Skipped int          `json:"skipped"`
+	Failed  int          `json:"failed"`
+	Rows    []*ImportRow `json:"rows"`
+}
@@ -0,0 +1,450 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"gorm.io/gorm"
+)
+
+const IMPORT_PHONE_PATTERN = `^[0-9+\- ]{5,20}$`
+
+// roster header -> field of ImportRow. headers are matched case insensitively.
+var IMPORT_HEADERS = map[string]string{
+	"username":       "username",
+	"用户名":            "username",
+	"password":       "password",
+	"密码":             "password",
+	"role":           "role",
+	"角色":             "role",
+	"realname":       "realName",
+	"姓名":             "realName",
+	"真实姓名":           "realName",
+	"studentid":      "studentId",
+	"学号":             "studentId",
+	"工号":             "studentId",
+	"college":        "college",
+	"学院":             "college",
+	"phonenumber":    "phoneNumber",
+	"phone":          "phoneNumber",
+	"手机号":            "phoneNumber",
+	"联系电话":           "phoneNumber",
+	"usertype":       "userType",
+	"用户类型":           "userType",
+	"sizelimit":      "sizeLimit",
+	"totalsizelimit": "totalSizeLimit",
+}
+
+// role names accepted in the roster.
+var IMPORT_ROLES = map[string]string{
+	USER_ROLE_USER:          USER_ROLE_USER,
+	USER_ROLE_ADMINISTRATOR: USER_ROLE_ADMINISTRATOR,
+	USER_ROLE_COLLEGE_ADMIN: USER_ROLE_COLLEGE_ADMIN,
+	USER_ROLE_JUDGE:         USER_ROLE_JUDGE,
+	"学生":                    USER_ROLE_USER,
+	"用户":                    USER_ROLE_USER,
+	"管理员":                   USER_ROLE_ADMINISTRATOR,
+	"学院管理员":                 USER_ROLE_COLLEGE_ADMIN,
+	"评委":                    USER_ROLE_JUDGE,
+}
+
+// user types accepted in the roster.
+var IMPORT_USER_TYPES = map[string]string{
+	"STUDENT": "STUDENT",
+	"TEACHER": "TEACHER",
+	"学生":      "STUDENT",
+	"教师":      "TEACHER",
+}
+
+// @Service
+type UserImportService struct {
+	BaseBean
+	userDao           *UserDao
+	userService       *UserService
+	userProfileDao    *UserProfileDao
+	spaceDao          *SpaceDao
+	collegeDao        *CollegeDao
+	preferenceService *PreferenceService
+	auditService      *AuditService
+}
+
+func (this *UserImportService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.userDao)
+	if b, ok := b.(*UserDao); ok {
+		this.userDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userService)
+	if b, ok := b.(*UserService); ok {
+		this.userService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userProfileDao)
+	if b, ok := b.(*UserProfileDao); ok {
+		this.userProfileDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.spaceDao)
+	if b, ok := b.(*SpaceDao); ok {
+		this.spaceDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.collegeDao)
+	if b, ok := b.(*CollegeDao); ok {
+		this.collegeDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.preferenceService)
+	if b, ok := b.(*PreferenceService); ok {
+		this.preferenceService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.auditService)
+	if b, ok := b.(*AuditService); ok {
+		this.auditService = b
+	}
+}
+
+// turn the sheet rows into import rows. the first row is the header.
+func (this *UserImportService) parse(rows [][]string) ([]*ImportRow, *result.WebResult) {
+	if len(rows) == 0 {
+		return nil, result.BadRequest("名单为空")
+	}
+
+	columns := make(map[int]string)
+	hasUsername := false
+	for i, header := range rows[0] {
+		field, ok := IMPORT_HEADERS[strings.ToLower(strings.TrimSpace(header))]
+		if ok {
+			columns[i] = field
+			hasUsername = hasUsername || field == "username"
+		}
+	}
+	if !hasUsername {
+		return nil, result.BadRequest("名单缺少用户名列")
+	}
+
+	var importRows []*ImportRow
+	for i, row := range rows[1:] {
+		importRow := &ImportRow{Line: i + 2, Errors: []string{}}
+		empty := true
+		for j, value := range row {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			empty = false
+			switch columns[j] {
+			case "username":
+				importRow.Username = value
+			case "password":
+				importRow.Password = value
+			case "role":
+				importRow.Role = value
+			case "realName":
+				importRow.RealName = value
+			case "studentId":
+				importRow.StudentId = value
+			case "college":
+				importRow.College = value
+			case "phoneNumber":
+				importRow.PhoneNumber = value
+			case "userType":
+				importRow.UserType = value
+			case "sizeLimit":
+				importRow.SizeLimit = value
+			case "totalSizeLimit":
+				importRow.TotalSizeLimit = value
+			}
+		}
+		if !empty {
+			importRows = append(importRows, importRow)
+		}
+	}
+	return importRows, nil
+}
+
+// validate a row and decide its action.
+func (this *UserImportService) validate(row *ImportRow, usernames map[string]int, studentIds map[string]int, createCollege bool) {
+
+	if row.Username == "" {
+		row.fail("用户名不能为空")
+	} else if m, _ := regexp.MatchString(USERNAME_PATTERN, row.Username); !m {
+		row.fail("用户名 %s 只能包含字母、数字、汉字和下划线", row.Username)
+	} else if line, ok := usernames[row.Username]; ok {
+		row.fail("用户名 %s 与第 %d 行重复", row.Username, line)
+	} else {
+		usernames[row.Username] = row.Line
+	}
+
+	if row.Role != "" {
+		role, ok := IMPORT_ROLES[strings.ToUpper(row.Role)]
+		if !ok {
+			role, ok = IMPORT_ROLES[row.Role]
+		}
+		if ok {
+			row.Role = role
+		} else {
+			row.fail("角色 %s 不存在", row.Role)
+		}
+	}
+	if row.Role == USER_ROLE_COLLEGE_ADMIN && row.College == "" {
+		row.fail("学院管理员必须填写学院")
+	}
+
+	if row.UserType != "" {
+		userType, ok := IMPORT_USER_TYPES[strings.ToUpper(row.UserType)]
+		if ok {
+			row.UserType = userType
+		} else {
+			row.fail("用户类型 %s 不存在", row.UserType)
+		}
+	}
+
+	if row.College != "" && !createCollege && this.collegeDao.FindByName(row.College) == nil {
+		row.fail("学院 %s 不存在", row.College)
+	}
+
+	if row.PhoneNumber != "" {
+		if m, _ := regexp.MatchString(IMPORT_PHONE_PATTERN, row.PhoneNumber); !m {
+			row.fail("联系电话 %s 格式错误", row.PhoneNumber)
+		}
+	}
+
+	for _, limit := range []string{row.SizeLimit, row.TotalSizeLimit} {
+		if limit != "" {
+			if _, err := strconv.ParseInt(limit, 10, 64); err != nil {
+				row.fail("空间限制 %s 格式错误", limit)
+			}
+		}
+	}
+
+	var user *User
+	if row.Username != "" {
+		user = this.userDao.FindByUsername(row.Username)
+	}
+
+	if row.StudentId != "" {
+		if line, ok := studentIds[row.StudentId]; ok {
+			row.fail("学号 %s 与第 %d 行重复", row.StudentId, line)
+		} else {
+			studentIds[row.StudentId] = row.Line
+		}
+		owner := this.userProfileDao.FindByStudentId(row.StudentId)
+		if owner != nil && (user == nil || owner.UserUuid != user.Uuid) {
+			row.fail("学号 %s 已被其他用户使用", row.StudentId)
+		}
+	}
+
+	if user == nil {
+		if len(row.Password) < 6 {
+			row.fail("新用户的密码至少6位")
+		}
+		if row.Username != "" && this.spaceDao.CountByName(row.Username) > 0 {
+			row.fail("空间 %s 已存在", row.Username)
+		}
+	}
+
+	if len(row.Errors) > 0 {
+		row.Action = IMPORT_ACTION_ERROR
+	} else if user == nil {
+		row.Action = IMPORT_ACTION_CREATE
+	} else if this.changed(row, user) {
+		row.Action = IMPORT_ACTION_UPDATE
+	} else {
+		row.Action = IMPORT_ACTION_SKIP
+	}
+}
+
+// whether the row changes the existing user. empty cells keep the current values, and passwords are never overwritten.
+func (this *UserImportService) changed(row *ImportRow, user *User) bool {
+	if row.Role != "" && row.Role != user.Role {
+		return true
+	}
+	userProfile := this.userProfileDao.FindByUserUuid(user.Uuid)
+	if userProfile == nil {
+		return row.RealName != "" || row.StudentId != "" || row.College != "" || row.PhoneNumber != "" || row.UserType != ""
+	}
+	return (row.RealName != "" && row.RealName != userProfile.RealName) ||
+		(row.StudentId != "" && row.StudentId != userProfile.StudentId) ||
+		(row.College != "" && row.College != userProfile.College) ||
+		(row.PhoneNumber != "" && row.PhoneNumber != userProfile.PhoneNumber) ||
+		(row.UserType != "" && row.UserType != userProfile.UserType)
+}
+
+// turn a panic of the checks or the daos into the error of the row, which rolls back the whole import.
+// the checks refuse with a web result, the database fails with an error. anything else keeps panicking.
+func (this *UserImportService) recoverError(err *error) {
+	if e := recover(); e != nil {
+		if recovered, ok := e.(error); ok {
+			*err = recovered
+		} else {
+			panic(e)
+		}
+	}
+}
+
+// the user, space and profile of a new user, made by UserService.CreateUserTx.
+// what it refuses aborts the whole import, so no row is imported.
+func (this *UserImportService) create(tx *gorm.DB, request *http.Request, row *ImportRow, preference *Preference) (err error) {
+	defer this.recoverError(&err)
+
+	var sizeLimit int64 = -1
+	totalSizeLimit := preference.DefaultTotalSizeLimit
+	if row.SizeLimit != "" {
+		sizeLimit, _ = strconv.ParseInt(row.SizeLimit, 10, 64)
+	}
+	if row.TotalSizeLimit != "" {
+		totalSizeLimit, _ = strconv.ParseInt(row.TotalSizeLimit, 10, 64)
+	}
+	role := row.Role
+	if role == "" {
+		role = USER_ROLE_USER
+	}
+
+	this.userService.CreateUserTx(tx, request, row.Username, sizeLimit, totalSizeLimit, row.Password, role, row.College, row.RealName, row.PhoneNumber, row.UserType, row.StudentId)
+	return nil
+}
+
+// the role and profile of an existing user, saved by the daos in the transaction.
+func (this *UserImportService) update(tx *gorm.DB, row *ImportRow, user *User, userProfile *UserProfile) (err error) {
+	defer this.recoverError(&err)
+
+	if row.Role != "" && row.Role != user.Role {
+		user.Role = row.Role
+		this.userDao.SaveTx(tx, user)
+	}
+
+	exist := userProfile != nil
+	if !exist {
+		userProfile = &UserProfile{UserUuid: user.Uuid, CreateTime: time.Now(), UpdateTime: time.Now()}
+	}
+	if row.RealName != "" {
+		userProfile.RealName = row.RealName
+	}
+	if row.StudentId != "" {
+		userProfile.StudentId = row.StudentId
+	}
+	if row.College != "" {
+		userProfile.College = row.College
+	}
+	if row.PhoneNumber != "" {
+		userProfile.PhoneNumber = row.PhoneNumber
+	}
+	if row.UserType != "" {
+		userProfile.UserType = row.UserType
+	}
+
+	if exist {
+		this.userProfileDao.SaveTx(tx, userProfile)
+	} else {
+		this.userProfileDao.CreateTx(tx, userProfile)
+	}
+	return nil
+}
+
+// import a roster. every row is validated first; invalid rows are reported and skipped.
+// existing users are matched by username, so importing the same roster again changes nothing.
+// with dryRun, only the report is returned. role changes are audited as made by the operator.
+func (this *UserImportService) Import(request *http.Request, operator *User, rows [][]string, dryRun bool, createCollege bool) (*ImportReport, *result.WebResult) {
+	importRows, webResult := this.parse(rows)
+	if webResult != nil {
+		return nil, webResult
+	}
+
+	report := &ImportReport{DryRun: dryRun, Total: len(importRows), Rows: importRows}
+	usernames := make(map[string]int)
+	studentIds := make(map[string]int)
+	for _, row := range importRows {
+		this.validate(row, usernames, studentIds, createCollege)
+	}
+
+	for _, row := range importRows {
+		switch row.Action {
+		case IMPORT_ACTION_CREATE:
+			report.Created++
+		case IMPORT_ACTION_UPDATE:
+			report.Updated++
+		case IMPORT_ACTION_SKIP:
+			report.Skipped++
+		case IMPORT_ACTION_ERROR:
+			report.Failed++
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	//the rows are written in one transaction, so a failed row leaves nothing half imported.
+	//everything is read before it starts, as sqlite has only one connection.
+	preference := this.preferenceService.Fetch()
+	var colleges []*College
+	users := make(map[int]*User)
+	oldRoles := make(map[int]string)
+	userProfiles := make(map[int]*UserProfile)
+	for _, row := range importRows {
+		if row.Action != IMPORT_ACTION_CREATE && row.Action != IMPORT_ACTION_UPDATE {
+			continue
+		}
+		if createCollege && row.College != "" && this.collegeDao.FindByName(row.College) == nil {
+			missing := true
+			for _, college := range colleges {
+				missing = missing && college.Name != row.College
+			}
+			if missing {
+				colleges = append(colleges, &College{Name: row.College, CreateTime: time.Now()})
+			}
+		}
+		if row.Action == IMPORT_ACTION_UPDATE {
+			users[row.Line] = this.userDao.FindByUsername(row.Username)
+			oldRoles[row.Line] = users[row.Line].Role
+			userProfiles[row.Line] = this.userProfileDao.FindByUserUuid(users[row.Line].Uuid)
+		}
+	}
+
+	err := core.CONTEXT.GetDB().Transaction(func(tx *gorm.DB) error {
+		for _, college := range colleges {
+			if err := tx.Create(college).Error; err != nil {
+				return err
+			}
+		}
+		for _, row := range importRows {
+			var err error
+			switch row.Action {
+			case IMPORT_ACTION_CREATE:
+				err = this.create(tx, request, row, preference)
+			case IMPORT_ACTION_UPDATE:
+				err = this.update(tx, row, users[row.Line], userProfiles[row.Line])
+			}
+			if err != nil {
+				return fmt.Errorf("第 %d 行 %s：%s", row.Line, row.Username, err.Error())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, result.BadRequest("导入失败，所有修改已撤销。%s", err.Error())
+	}
+
+	for _, college := range colleges {
+		this.logger.Info("import created college %s", college.Name)
+	}
+	for line, user := range users {
+		this.userService.RemoveCacheUserByUuid(user.Uuid)
+		if user.Role != oldRoles[line] {
+			this.auditService.Log(request, operator, AUDIT_ACTION_USER_ROLE, AUDIT_TARGET_USER, user.Uuid, map[string]any{"role": oldRoles[line]}, map[string]any{"role": user.Role})
+		}
+	}
+
+	this.logger.Info("import users: %d created, %d updated, %d skipped, %d failed", report.Created, report.Updated, report.Skipped, report.Failed)
+
+	return report, nil
+}
@@ -2,6 +2,8 @@ package rest
 
 import (
 	"github.com/eyebluecn/tank/code/core"
+	"gorm.io/gorm"
+	"time"
 )
 
 // @Service
@@ -14,13 +16,24 @@ func (this *UserProfileDao) Init() {
 }
 
 func (this *UserProfileDao) Create(userProfile *UserProfile) *UserProfile {
-	var db = core.CONTEXT.GetDB().Create(userProfile)
+	return this.CreateTx(core.CONTEXT.GetDB(), userProfile)
+}
+
+// create in the transaction.
+func (this *UserProfileDao) CreateTx(tx *gorm.DB, userProfile *UserProfile) *UserProfile {
+	var db = tx.Create(userProfile)
 	this.PanicError(db.Error)
 	return userProfile
 }
 
 func (this *UserProfileDao) Save(userProfile *UserProfile) *UserProfile {
-	var db = core.CONTEXT.GetDB().Save(userProfile)
+	return this.SaveTx(core.CONTEXT.GetDB(), userProfile)
+}
+
+// save in the transaction.
+func (this *UserProfileDao) SaveTx(tx *gorm.DB, userProfile *UserProfile) *UserProfile {
+	userProfile.UpdateTime = time.Now()
+	var db = tx.Save(userProfile)
 	this.PanicError(db.Error)
 	return userProfile
 }
@@ -32,4 +45,13 @@ func (this *UserProfileDao) FindByUserUuid(userUuid string) *UserProfile {
 		return nil
 	}
 	return &userProfile
-}
\ No newline at end of file
+}
+
+func (this *UserProfileDao) FindByStudentId(studentId string) *UserProfile {
+	var userProfile UserProfile
+	db := core.CONTEXT.GetDB().Where("student_id = ?", studentId).First(&userProfile)
+	if db.Error != nil {
+		return nil
+	}
+	return &userProfile
+}
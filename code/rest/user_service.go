@@ -10,6 +10,7 @@ import (
 	"github.com/eyebluecn/tank/code/tool/result"
 	"github.com/eyebluecn/tank/code/tool/util"
 	"github.com/eyebluecn/tank/code/tool/uuid"
+	"gorm.io/gorm"
 )
 
 // @Service
@@ -34,6 +35,9 @@ type UserService struct {
 	downloadTokenDao *DownloadTokenDao
 	uploadTokenDao   *UploadTokenDao
 	footprintDao     *FootprintDao
+	blobService      *BlobService
+	storageService   *StorageService
+	matterVersionDao *MatterVersionDao
 }
 
 func (this *UserService) Init() {
@@ -103,6 +107,21 @@ func (this *UserService) Init() {
 		this.footprintDao = b
 	}
 
+	b = core.CONTEXT.GetBean(this.blobService)
+	if b, ok := b.(*BlobService); ok {
+		this.blobService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.storageService)
+	if b, ok := b.(*StorageService); ok {
+		this.storageService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.matterVersionDao)
+	if b, ok := b.(*MatterVersionDao); ok {
+		this.matterVersionDao = b
+	}
+
 	//create a lock cache.
 	this.locker = cache.NewTable()
 }
@@ -260,6 +279,11 @@ func (this *UserService) RemoveCacheUserByUuid(userUuid string) {
 
 // create user
 func (this *UserService) CreateUser(request *http.Request, username string, sizeLimit int64, totalSizeLimit int64, password string, role string, college string, realName string, phoneNumber string, userType string, studentId string) *User {
+	return this.CreateUserTx(core.CONTEXT.GetDB(), request, username, sizeLimit, totalSizeLimit, password, role, college, realName, phoneNumber, userType, studentId)
+}
+
+// create a user with the private space and the profile in the transaction, eg. of a roster import.
+func (this *UserService) CreateUserTx(tx *gorm.DB, request *http.Request, username string, sizeLimit int64, totalSizeLimit int64, password string, role string, college string, realName string, phoneNumber string, userType string, studentId string) *User {
 
 	user := &User{
 		Username: username,
@@ -268,14 +292,14 @@ func (this *UserService) CreateUser(request *http.Request, username string, size
 		Status:   USER_STATUS_OK,
 	}
 
-	user = this.userDao.Create(user)
+	user = this.userDao.CreateTx(tx, user)
 
 	//create space.
-	space := this.spaceService.CreateSpace(request, username, user, sizeLimit, totalSizeLimit, SPACE_TYPE_PRIVATE)
+	space := this.spaceService.CreateSpaceTx(tx, request, username, user, sizeLimit, totalSizeLimit, SPACE_TYPE_PRIVATE, "")
 
 	//update user's space.
 	user.SpaceUuid = space.Uuid
-	this.userDao.Save(user)
+	this.userDao.SaveTx(tx, user)
 
 	user.Space = space
 
@@ -291,7 +315,7 @@ func (this *UserService) CreateUser(request *http.Request, username string, size
 			CreateTime:  time.Now(),
 			UpdateTime:  time.Now(),
 		}
-		this.userProfileDao.Create(userProfile)
+		this.userProfileDao.CreateTx(tx, userProfile)
 	}
 
 	return user
@@ -347,6 +371,13 @@ func (this *UserService) DeleteUser(request *http.Request, currentUser *User) {
 	this.logger.Info("delete space members")
 	this.spaceMemberDao.DeleteBySpaceUuid(space.Uuid)
 
+	//delete files from the storages, while the spaces still tell where they are.
+	for _, userSpace := range this.spaceDao.FindByUserUuid(currentUser.Uuid) {
+		this.logger.Info("delete files of space %s", userSpace.Name)
+		this.storageService.RemoveSpace(userSpace.Name)
+		this.matterVersionDao.DeleteBySpaceUuid(userSpace.Uuid)
+	}
+
 	//delete spaces
 	this.logger.Info("delete spaces")
 	this.spaceDao.DeleteByUserUuid(currentUser.Uuid)
@@ -355,9 +386,13 @@ func (this *UserService) DeleteUser(request *http.Request, currentUser *User) {
 	this.logger.Info("delete this user.")
 	this.userDao.Delete(currentUser)
 
-	//delete files from disk.
+	//delete what is left on disk.
 	this.logger.Info("delete files from disk. %s", GetUserSpaceRootDir(currentUser.Username))
 	err := os.RemoveAll(GetUserSpaceRootDir(currentUser.Username))
 	this.PanicError(err)
 
+	//the matters were deleted at once, so the references of the blobs are counted again.
+	this.logger.Info("collect blobs")
+	this.blobService.Collect()
+
 }
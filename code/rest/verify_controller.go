@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+const (
+	//verifications allowed for an ip in every window, so that codes cannot be guessed by trying.
+	VERIFY_RATE_LIMIT  = 20
+	VERIFY_RATE_WINDOW = time.Minute
+)
+
+type VerifyController struct {
+	BaseController
+	certificateDao *CertificateDao
+	limiter        *util.RateLimiter
+}
+
+func (this *VerifyController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.certificateDao)
+	if b, ok := b.(*CertificateDao); ok {
+		this.certificateDao = b
+	}
+
+	this.limiter = util.NewRateLimiter(VERIFY_RATE_LIMIT, VERIFY_RATE_WINDOW)
+}
+
+func (this *VerifyController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/verify"] = this.Wrap(this.Verify, USER_ROLE_GUEST)
+
+	return routeMap
+}
+
+// anyone can check the code printed on a certificate, without login.
+func (this *VerifyController) Verify(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	//the forwarded headers are made up easily, so they are trusted only from the proxies.
+	if !this.limiter.Allow(util.GetClientIp(request, core.CONFIG.TrustedProxies()), time.Now()) {
+		return result.CustomWebResult(result.TOO_MANY_REQUESTS, "查询过于频繁，请稍后再试")
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(request.FormValue("code")))
+	if code == "" {
+		return result.BadRequest("验证码不能为空")
+	}
+	certificate := this.certificateDao.FindByCode(code)
+	if certificate == nil {
+		return result.CustomWebResult(result.NOT_FOUND, "证书不存在")
+	}
+	return this.Success(&CertificateVerification{
+		Name:  certificate.Name,
+		Award: certificate.Award,
+		Track: certificate.Track,
+		Year:  certificate.Year,
+	})
+}
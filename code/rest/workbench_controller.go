@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// the review queue of judges.
+type WorkbenchController struct {
+	BaseController
+	workbenchService *WorkbenchService
+	submissionDao    *SubmissionDao
+}
+
+func (this *WorkbenchController) Init() {
+	this.BaseController.Init()
+
+	b := core.CONTEXT.GetBean(this.workbenchService)
+	if b, ok := b.(*WorkbenchService); ok {
+		this.workbenchService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.submissionDao)
+	if b, ok := b.(*SubmissionDao); ok {
+		this.submissionDao = b
+	}
+}
+
+func (this *WorkbenchController) RegisterRoutes() map[string]func(writer http.ResponseWriter, request *http.Request) {
+	routeMap := make(map[string]func(writer http.ResponseWriter, request *http.Request))
+
+	routeMap["/api/workbench/queue"] = this.Wrap(this.Queue, USER_ROLE_JUDGE)
+	routeMap["/api/workbench/next"] = this.Wrap(this.Next, USER_ROLE_JUDGE)
+	routeMap["/api/workbench/draft/save"] = this.Wrap(this.SaveDraft, USER_ROLE_JUDGE)
+	routeMap["/api/workbench/draft/discard"] = this.Wrap(this.DiscardDraft, USER_ROLE_JUDGE)
+	routeMap["/api/workbench/progress"] = this.Wrap(this.Progress, USER_ROLE_ADMINISTRATOR)
+
+	return routeMap
+}
+
+func (this *WorkbenchController) formInt64(request *http.Request, key string) int64 {
+	str := request.FormValue(key)
+	if str == "" {
+		return 0
+	}
+	value, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		panic(result.BadRequest("%s格式错误", key))
+	}
+	return value
+}
+
+func (this *WorkbenchController) checkJudge(request *http.Request) *User {
+	user := this.checkUser(request)
+	if user.Role != USER_ROLE_JUDGE {
+		panic(result.UNAUTHORIZED)
+	}
+	return user
+}
+
+func (this *WorkbenchController) filter(request *http.Request) *WorkbenchFilter {
+	return &WorkbenchFilter{
+		Status:        request.FormValue("status"),
+		CompetitionId: this.formInt64(request, "competitionId"),
+		TrackId:       this.formInt64(request, "trackId"),
+		CollegeId:     this.formInt64(request, "collegeId"),
+	}
+}
+
+// the submissions assigned to the current judge. status is UNSCORED, DRAFT, SCORED or empty for all.
+func (this *WorkbenchController) Queue(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkJudge(request)
+	return this.Success(this.workbenchService.Queue(user, this.filter(request)))
+}
+
+// the next unscored submission after submissionId, in the same track and college filters.
+func (this *WorkbenchController) Next(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkJudge(request)
+	return this.Success(this.workbenchService.Next(user, this.filter(request), this.formInt64(request, "submissionId")))
+}
+
+// save a rating without submitting it. takes the same parameters as /api/rating/submit.
+func (this *WorkbenchController) SaveDraft(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkJudge(request)
+
+	submission := this.submissionDao.FindById(this.formInt64(request, "submissionId"))
+	if submission == nil {
+		return result.BadRequest("提交作品不存在")
+	}
+
+	score := 0
+	if str := request.FormValue("score"); str != "" {
+		var err error
+		score, err = strconv.Atoi(str)
+		if err != nil {
+			return result.BadRequest("score格式错误")
+		}
+	}
+
+	var items []*RatingItem
+	if str := request.FormValue("items"); str != "" {
+		err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(str), &items)
+		if err != nil {
+			return result.BadRequest("评分项数据格式错误")
+		}
+	}
+
+	draft, webResult := this.workbenchService.SaveDraft(user, submission, score, this.formInt64(request, "rubricId"), items, request.FormValue("comment"))
+	if webResult != nil {
+		return webResult
+	}
+	return this.Success(draft)
+}
+
+func (this *WorkbenchController) DiscardDraft(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	user := this.checkJudge(request)
+
+	submission := this.submissionDao.FindById(this.formInt64(request, "submissionId"))
+	if submission == nil {
+		return result.BadRequest("提交作品不存在")
+	}
+	this.workbenchService.DiscardDraft(submission, user)
+	return this.Success("OK")
+}
+
+// review progress of every judge in the round.
+func (this *WorkbenchController) Progress(writer http.ResponseWriter, request *http.Request) *result.WebResult {
+	return this.Success(this.workbenchService.Progress(this.formInt64(request, "roundId")))
+}
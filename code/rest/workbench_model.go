@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"time"
+)
+
+const (
+	//not rated yet, nothing saved.
+	WORKBENCH_STATUS_UNSCORED = "UNSCORED"
+	//not rated yet, a draft is saved.
+	WORKBENCH_STATUS_DRAFT = "DRAFT"
+	//rated in the current round.
+	WORKBENCH_STATUS_SCORED = "SCORED"
+)
+
+// RatingDraft is a rating saved by a judge without submitting. It does not count in any score and is discarded once the rating is submitted.
+type RatingDraft struct {
+	Id           int64  `json:"id" gorm:"type:bigint(20);primary_key;auto_increment"`
+	SubmissionId int64  `json:"submissionId" gorm:"type:bigint(20) not null;index:idx_rating_draft_si"`
+	JudgeUuid    string `json:"judgeUuid" gorm:"type:char(36) not null;index:idx_rating_draft_ju"`
+	RoundId      int64  `json:"roundId" gorm:"type:bigint(20) not null;default:0"`
+	Score        int    `json:"score" gorm:"type:int not null;default:0"`
+	RubricId     int64  `json:"rubricId" gorm:"type:bigint(20) not null;default:0"`
+	//json of the criterion scores, possibly incomplete.
+	Items      string    `json:"items" gorm:"type:text"`
+	Comment    string    `json:"comment" gorm:"type:text"`
+	CreateTime time.Time `json:"createTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime time.Time `json:"updateTime" gorm:"type:timestamp not null;default:CURRENT_TIMESTAMP"`
+}
+
+// WorkbenchItem is a submission in the queue of a judge.
+type WorkbenchItem struct {
+	AssignmentId int64 `json:"assignmentId"`
+	RoundId      int64 `json:"roundId"`
+	//masked in blind rounds.
+	Submission *Submission  `json:"submission"`
+	Status     string       `json:"status"`
+	Rating     *Rating      `json:"rating"`
+	Draft      *RatingDraft `json:"draft"`
+}
+
+// WorkbenchFilter filters the queue of a judge. zero values mean no restriction.
+type WorkbenchFilter struct {
+	//UNSCORED (drafts included), DRAFT, SCORED or empty for every assigned submission.
+	Status        string
+	CompetitionId int64
+	TrackId       int64
+	CollegeId     int64
+}
+
+// JudgeProgress is the review progress of a judge in a round.
+type JudgeProgress struct {
+	Judge    *User   `json:"judge"`
+	Assigned int     `json:"assigned"`
+	Scored   int     `json:"scored"`
+	Drafts   int     `json:"drafts"`
+	Unscored int     `json:"unscored"`
+	Percent  float64 `json:"percent"`
+	//time of the latest rating, nil if none.
+	LastRateTime *time.Time `json:"lastRateTime"`
+}
@@ -0,0 +1,216 @@
+package rest
+
+import (
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/tool/result"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// @Service
+type WorkbenchService struct {
+	BaseBean
+	ratingDraftDao    *RatingDraftDao
+	ratingDao         *RatingDao
+	assignmentDao     *AssignmentDao
+	assignmentService *AssignmentService
+	roundService      *RoundService
+	userDao           *UserDao
+}
+
+func (this *WorkbenchService) Init() {
+	this.BaseBean.Init()
+
+	b := core.CONTEXT.GetBean(this.ratingDraftDao)
+	if b, ok := b.(*RatingDraftDao); ok {
+		this.ratingDraftDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.ratingDao)
+	if b, ok := b.(*RatingDao); ok {
+		this.ratingDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.assignmentDao)
+	if b, ok := b.(*AssignmentDao); ok {
+		this.assignmentDao = b
+	}
+
+	b = core.CONTEXT.GetBean(this.assignmentService)
+	if b, ok := b.(*AssignmentService); ok {
+		this.assignmentService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.roundService)
+	if b, ok := b.(*RoundService); ok {
+		this.roundService = b
+	}
+
+	b = core.CONTEXT.GetBean(this.userDao)
+	if b, ok := b.(*UserDao); ok {
+		this.userDao = b
+	}
+}
+
+// the submissions assigned to the judge in their current rounds, in the order they were assigned.
+func (this *WorkbenchService) Queue(judge *User, filter *WorkbenchFilter) []*WorkbenchItem {
+	var items []*WorkbenchItem
+	for _, assignment := range this.assignmentService.MyAssignments(judge) {
+		//filter on what the judge sees, so blind submissions never match a college.
+		submission := assignment.Submission
+		if filter.CompetitionId != 0 && submission.CompetitionId != filter.CompetitionId {
+			continue
+		}
+		if filter.TrackId != 0 && submission.TrackId != filter.TrackId {
+			continue
+		}
+		if filter.CollegeId != 0 && submission.CollegeId != filter.CollegeId {
+			continue
+		}
+
+		item := &WorkbenchItem{
+			AssignmentId: assignment.Id,
+			RoundId:      assignment.RoundId,
+			Submission:   submission,
+			Status:       WORKBENCH_STATUS_UNSCORED,
+		}
+		rating := this.ratingDao.FindBySubmissionAndJudgeAndRound(submission.Id, judge.Uuid, assignment.RoundId)
+		if rating != nil && !rating.Superseded {
+			item.Rating = rating
+			item.Status = WORKBENCH_STATUS_SCORED
+		}
+		//a scored submission may have a draft too, when the judge is revising the rating.
+		item.Draft = this.ratingDraftDao.FindBySubmissionAndJudgeAndRound(submission.Id, judge.Uuid, assignment.RoundId)
+		if item.Draft != nil && item.Rating == nil {
+			item.Status = WORKBENCH_STATUS_DRAFT
+		}
+
+		switch filter.Status {
+		case WORKBENCH_STATUS_UNSCORED:
+			if item.Rating != nil {
+				continue
+			}
+		case WORKBENCH_STATUS_DRAFT:
+			if item.Draft == nil {
+				continue
+			}
+		case WORKBENCH_STATUS_SCORED:
+			if item.Rating == nil {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// the first unscored submission after the current one in the queue, starting over from the beginning. nil if all the others are scored.
+func (this *WorkbenchService) Next(judge *User, filter *WorkbenchFilter, currentSubmissionId int64) *WorkbenchItem {
+	items := this.Queue(judge, &WorkbenchFilter{CompetitionId: filter.CompetitionId, TrackId: filter.TrackId, CollegeId: filter.CollegeId})
+
+	current := -1
+	for i, item := range items {
+		if item.Submission.Id == currentSubmissionId {
+			current = i
+			break
+		}
+	}
+	for step := 1; step <= len(items); step++ {
+		item := items[(current+step+len(items))%len(items)]
+		if item.Rating == nil && item.Submission.Id != currentSubmissionId {
+			return item
+		}
+	}
+	return nil
+}
+
+// save the rating in progress without submitting it. the scores are not validated until the rating is submitted.
+func (this *WorkbenchService) SaveDraft(judge *User, submission *Submission, score int, rubricId int64, items []*RatingItem, comment string) (*RatingDraft, *result.WebResult) {
+	if webResult := this.roundService.CheckRatable(submission, judge); webResult != nil {
+		return nil, webResult
+	}
+	if webResult := this.assignmentService.CheckAssigned(submission, judge); webResult != nil {
+		return nil, webResult
+	}
+	if score < 0 || score > 100 {
+		return nil, result.BadRequest("评分必须在0-100之间")
+	}
+
+	itemsJson := ""
+	if len(items) > 0 {
+		bytes, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(items)
+		this.PanicError(err)
+		itemsJson = string(bytes)
+	}
+
+	draft := this.ratingDraftDao.FindBySubmissionAndJudgeAndRound(submission.Id, judge.Uuid, submission.RoundId)
+	if draft == nil {
+		draft = &RatingDraft{SubmissionId: submission.Id, JudgeUuid: judge.Uuid, RoundId: submission.RoundId}
+	}
+	draft.Score = score
+	draft.RubricId = rubricId
+	draft.Items = itemsJson
+	draft.Comment = comment
+	if draft.Id == 0 {
+		return this.ratingDraftDao.Create(draft), nil
+	}
+	return this.ratingDraftDao.Save(draft), nil
+}
+
+// discard the draft of the judge on the submission in its current round, eg. after the rating is submitted.
+func (this *WorkbenchService) DiscardDraft(submission *Submission, judge *User) {
+	draft := this.ratingDraftDao.FindBySubmissionAndJudgeAndRound(submission.Id, judge.Uuid, submission.RoundId)
+	if draft != nil {
+		this.ratingDraftDao.Delete(draft)
+	}
+}
+
+// progress of every judge with assignments in the round.
+func (this *WorkbenchService) Progress(roundId int64) []*JudgeProgress {
+	type key struct {
+		submissionId int64
+		judgeUuid    string
+	}
+	ratingMap := make(map[key]*Rating)
+	for _, rating := range this.ratingDao.FindByRoundId(roundId) {
+		ratingMap[key{rating.SubmissionId, rating.JudgeUuid}] = rating
+	}
+	draftMap := make(map[key]bool)
+	for _, draft := range this.ratingDraftDao.FindByRoundId(roundId) {
+		draftMap[key{draft.SubmissionId, draft.JudgeUuid}] = true
+	}
+
+	progressMap := make(map[string]*JudgeProgress)
+	var progresses []*JudgeProgress
+	for _, assignment := range this.assignmentDao.FindByRoundId(roundId) {
+		progress, ok := progressMap[assignment.JudgeUuid]
+		if !ok {
+			judge := this.userDao.FindByUuid(assignment.JudgeUuid)
+			if judge == nil {
+				continue
+			}
+			progress = &JudgeProgress{Judge: judge}
+			progressMap[assignment.JudgeUuid] = progress
+			progresses = append(progresses, progress)
+		}
+
+		progress.Assigned++
+		k := key{assignment.SubmissionId, assignment.JudgeUuid}
+		if rating, ok := ratingMap[k]; ok {
+			progress.Scored++
+			if progress.LastRateTime == nil || rating.UpdateTime.After(*progress.LastRateTime) {
+				rateTime := rating.UpdateTime
+				progress.LastRateTime = &rateTime
+			}
+		} else {
+			progress.Unscored++
+			if draftMap[k] {
+				progress.Drafts++
+			}
+		}
+	}
+
+	for _, progress := range progresses {
+		progress.Percent = float64(progress.Scored) * 100 / float64(progress.Assigned)
+	}
+	return progresses
+}
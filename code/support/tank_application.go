@@ -1,6 +1,7 @@
 package support
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"github.com/eyebluecn/tank/code/core"
@@ -8,10 +9,14 @@ import (
 	"github.com/eyebluecn/tank/code/tool/util"
 	jsoniter "github.com/json-iterator/go"
 	"golang.org/x/crypto/ssh/terminal"
+	"io"
 	"io/ioutil"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"syscall"
 )
@@ -23,6 +28,12 @@ const (
 	MODE_MIRROR = "mirror"
 	//crawl remote file to EyeblueTank
 	MODE_CRAWL = "crawl"
+	//import users from a csv/xlsx roster.
+	MODE_IMPORT = "import"
+	//move the files uploaded before into the blob store, so that files of the same content share their bytes.
+	MODE_DEDUP = "dedup"
+	//move the files of a space(-src) to another storage(-dest), the default storage if -dest is empty.
+	MODE_MIGRATE = "migrate"
 	//Current version.
 	MODE_VERSION = "version"
 )
@@ -45,6 +56,10 @@ type TankApplication struct {
 	//true: overwrite, false:skip
 	overwrite bool
 	filename  string
+	//true: only validate the roster when import.
+	dryRun bool
+	//true: create the colleges not exist when import.
+	createCollege bool
 }
 
 // Start the application.
@@ -56,7 +71,7 @@ func (this *TankApplication) Start() {
 		}
 	}()
 
-	modePtr := flag.String("mode", this.mode, "cli mode web/mirror/crawl")
+	modePtr := flag.String("mode", this.mode, "cli mode web/mirror/crawl/import/dedup/migrate")
 	hostPtr := flag.String("host", this.username, "tank host")
 	usernamePtr := flag.String("username", this.username, "username")
 	passwordPtr := flag.String("password", this.password, "password")
@@ -64,6 +79,8 @@ func (this *TankApplication) Start() {
 	destPtr := flag.String("dest", this.dest, "destination path in tank.")
 	overwritePtr := flag.Bool("overwrite", this.overwrite, "whether same file overwrite")
 	filenamePtr := flag.String("filename", this.filename, "filename when crawl")
+	dryRunPtr := flag.Bool("dryrun", this.dryRun, "only validate the roster when import")
+	createCollegePtr := flag.Bool("createcollege", this.createCollege, "create the colleges not exist when import")
 
 	//flag.Parse() must invoke before use.
 	flag.Parse()
@@ -76,6 +93,8 @@ func (this *TankApplication) Start() {
 	this.dest = *destPtr
 	this.overwrite = *overwritePtr
 	this.filename = *filenamePtr
+	this.dryRun = *dryRunPtr
+	this.createCollege = *createCollegePtr
 
 	//default start as web.
 	if this.mode == "" || strings.ToLower(this.mode) == MODE_WEB {
@@ -122,6 +141,18 @@ func (this *TankApplication) Start() {
 
 			this.HandleCrawl()
 
+		} else if strings.ToLower(this.mode) == MODE_IMPORT {
+
+			this.HandleImport()
+
+		} else if strings.ToLower(this.mode) == MODE_DEDUP {
+
+			this.HandleDedup()
+
+		} else if strings.ToLower(this.mode) == MODE_MIGRATE {
+
+			this.HandleMigrate()
+
 		} else {
 			panic(result.BadRequest("cannot handle mode %s \r\n", this.mode))
 		}
@@ -248,6 +279,153 @@ func (this *TankApplication) HandleCrawl() {
 
 }
 
+func (this *TankApplication) HandleImport() {
+
+	if this.src == "" {
+		panic("src is required")
+	}
+
+	fmt.Printf("import users from %s to EyeblueTank\r\n", this.src)
+
+	file, err := os.Open(this.src)
+	core.PanicError(err)
+	defer func() {
+		err := file.Close()
+		core.PanicError(err)
+	}()
+
+	body := &bytes.Buffer{}
+	multipartWriter := multipart.NewWriter(body)
+	fields := map[string]string{
+		"dryRun":          fmt.Sprintf("%v", this.dryRun),
+		"createCollege":   fmt.Sprintf("%v", this.createCollege),
+		core.USERNAME_KEY: this.username,
+		core.PASSWORD_KEY: this.password,
+	}
+	for key, value := range fields {
+		err = multipartWriter.WriteField(key, value)
+		core.PanicError(err)
+	}
+	fileWriter, err := multipartWriter.CreateFormFile("file", filepath.Base(this.src))
+	core.PanicError(err)
+	_, err = io.Copy(fileWriter, file)
+	core.PanicError(err)
+	err = multipartWriter.Close()
+	core.PanicError(err)
+
+	urlString := fmt.Sprintf("%s/api/user/import", this.host)
+	response, err := http.Post(urlString, multipartWriter.FormDataContentType(), body)
+	core.PanicError(err)
+
+	bodyBytes, err := ioutil.ReadAll(response.Body)
+
+	webResult := &result.WebResult{}
+
+	err = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(bodyBytes, webResult)
+	if err != nil {
+		fmt.Printf("error response format %s \r\n", err.Error())
+		return
+	}
+
+	if webResult.Code != result.OK.Code {
+		fmt.Printf("error %s\r\n", webResult.Msg)
+		return
+	}
+
+	//print the rows failed and the summary.
+	data, _ := webResult.Data.(map[string]interface{})
+	if rows, ok := data["rows"].([]interface{}); ok {
+		for _, row := range rows {
+			if row, ok := row.(map[string]interface{}); ok && row["action"] == "ERROR" {
+				fmt.Printf("line %v %v: %v\r\n", row["line"], row["username"], row["errors"])
+			}
+		}
+	}
+	fmt.Printf("dryRun=%v total=%v created=%v updated=%v skipped=%v failed=%v\r\n",
+		data["dryRun"], data["total"], data["created"], data["updated"], data["skipped"], data["failed"])
+
+}
+
+func (this *TankApplication) HandleDedup() {
+
+	fmt.Println("move files into the blob store of EyeblueTank")
+
+	urlString := fmt.Sprintf("%s/api/blob/migrate", this.host)
+
+	params := url.Values{
+		core.USERNAME_KEY: {this.username},
+		core.PASSWORD_KEY: {this.password},
+	}
+
+	response, err := http.PostForm(urlString, params)
+	core.PanicError(err)
+
+	bodyBytes, err := ioutil.ReadAll(response.Body)
+
+	webResult := &result.WebResult{}
+
+	err = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(bodyBytes, webResult)
+	if err != nil {
+		fmt.Printf("error response format %s \r\n", err.Error())
+		return
+	}
+
+	if webResult.Code != result.OK.Code {
+		fmt.Printf("error %s\r\n", webResult.Msg)
+		return
+	}
+
+	data, _ := webResult.Data.(map[string]interface{})
+	fmt.Printf("scanned=%v stored=%v shared=%v savedSize=%v failed=%v collected=%v\r\n",
+		data["scanned"], data["stored"], data["shared"], data["savedSize"], data["failed"], data["collected"])
+
+}
+
+func (this *TankApplication) HandleMigrate() {
+
+	if this.src == "" {
+		panic("src is required")
+	}
+
+	storageName := this.dest
+	if storageName == "" {
+		storageName = "the default storage"
+	}
+	fmt.Printf("move the files of space %s to %s\r\n", this.src, storageName)
+
+	urlString := fmt.Sprintf("%s/api/storage/migrate", this.host)
+
+	params := url.Values{
+		core.USERNAME_KEY: {this.username},
+		core.PASSWORD_KEY: {this.password},
+		"spaceName":       {this.src},
+		"storageName":     {this.dest},
+	}
+
+	response, err := http.PostForm(urlString, params)
+	core.PanicError(err)
+
+	bodyBytes, err := ioutil.ReadAll(response.Body)
+
+	webResult := &result.WebResult{}
+
+	err = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(bodyBytes, webResult)
+	if err != nil {
+		fmt.Printf("error response format %s \r\n", err.Error())
+		return
+	}
+
+	if webResult.Code != result.OK.Code {
+		fmt.Printf("error %s\r\n", webResult.Msg)
+		return
+	}
+
+	data, _ := webResult.Data.(map[string]interface{})
+	fmt.Printf("from=%v to=%v dirs=%v files=%v versions=%v size=%v missing=%v\r\n",
+		data["from"], data["to"], data["dirCount"], data["fileCount"], data["versionCount"], data["size"], data["missingCount"])
+
+}
+
 // fetch the application version
 func (this *TankApplication) HandleVersion() {
 
@@ -50,6 +50,9 @@ type ConfigItem struct {
 	//********sqlite configurations..********
 	//default value is matter/
 	SqliteFolder string
+	//ips or cidrs of the reverse proxies, eg. ["127.0.0.1"]. the ip of the client is taken from X-Forwarded-For
+	//and X-Real-Ip only for the requests from them.
+	TrustedProxies []string
 }
 
 // validate whether the config file is ok
@@ -212,6 +215,14 @@ func (this *TankConfig) MatterPath() string {
 	return this.matterPath
 }
 
+// reverse proxies
+func (this *TankConfig) TrustedProxies() []string {
+	if this.item == nil {
+		return nil
+	}
+	return this.item.TrustedProxies
+}
+
 // matter path
 func (this *TankConfig) NamingStrategy() schema.NamingStrategy {
 	return schema.NamingStrategy{
@@ -235,6 +246,8 @@ func (this *TankConfig) FinishInstall(dbType string, mysqlPort int, mysqlHost st
 		MysqlUsername: mysqlUsername,
 		MysqlPassword: mysqlPassword,
 		MysqlCharset:  mysqlCharset,
+		//kept if configured before.
+		TrustedProxies: core.CONFIG.TrustedProxies(),
 	}
 
 	//pretty json.
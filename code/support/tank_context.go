@@ -171,6 +171,11 @@ func (this *TankContext) registerBeans() {
 	this.registerBean(new(rest.CollegeDao))
 	this.registerBean(new(rest.CollegeService))
 
+	//competition
+	this.registerBean(new(rest.CompetitionController))
+	this.registerBean(new(rest.CompetitionDao))
+	this.registerBean(new(rest.CompetitionService))
+
 	//track
 	this.registerBean(new(rest.TrackController))
 	this.registerBean(new(rest.TrackDao))
@@ -189,6 +194,104 @@ func (this *TankContext) registerBeans() {
 	this.registerBean(new(rest.MatterDao))
 	this.registerBean(new(rest.MatterService))
 
+	//blob
+	this.registerBean(new(rest.BlobController))
+	this.registerBean(new(rest.BlobDao))
+	this.registerBean(new(rest.BlobService))
+
+	//storage
+	this.registerBean(new(rest.StorageController))
+	this.registerBean(new(rest.StorageDao))
+	this.registerBean(new(rest.StorageService))
+
+	//matter version
+	this.registerBean(new(rest.MatterVersionController))
+	this.registerBean(new(rest.MatterVersionDao))
+	this.registerBean(new(rest.MatterVersionService))
+
+	//round
+	this.registerBean(new(rest.RoundController))
+	this.registerBean(new(rest.RoundDao))
+	this.registerBean(new(rest.RoundJudgeDao))
+	this.registerBean(new(rest.RoundHistoryDao))
+	this.registerBean(new(rest.RoundService))
+
+	//submission window
+	this.registerBean(new(rest.LateGrantController))
+	this.registerBean(new(rest.LateGrantDao))
+	this.registerBean(new(rest.SubmissionWindowService))
+
+	//snapshot
+	this.registerBean(new(rest.SnapshotController))
+	this.registerBean(new(rest.SnapshotDao))
+	this.registerBean(new(rest.SnapshotService))
+
+	//rubric
+	this.registerBean(new(rest.RubricController))
+	this.registerBean(new(rest.RubricDao))
+	this.registerBean(new(rest.RubricService))
+
+	//assignment
+	this.registerBean(new(rest.AssignmentController))
+	this.registerBean(new(rest.AssignmentDao))
+	this.registerBean(new(rest.AssignmentService))
+
+	//ranking
+	this.registerBean(new(rest.RankingController))
+	this.registerBean(new(rest.RankingService))
+
+	//export
+	this.registerBean(new(rest.ExportController))
+	this.registerBean(new(rest.ExportService))
+
+	//team
+	this.registerBean(new(rest.TeamController))
+	this.registerBean(new(rest.TeamDao))
+	this.registerBean(new(rest.TeamService))
+
+	//form
+	this.registerBean(new(rest.FormController))
+	this.registerBean(new(rest.FormDao))
+	this.registerBean(new(rest.FormService))
+
+	//file rule
+	this.registerBean(new(rest.FileRuleController))
+	this.registerBean(new(rest.FileRuleDao))
+	this.registerBean(new(rest.FileRuleService))
+
+	//blind
+	this.registerBean(new(rest.BlindController))
+	this.registerBean(new(rest.BlindService))
+
+	//appeal
+	this.registerBean(new(rest.AppealController))
+	this.registerBean(new(rest.AppealDao))
+	this.registerBean(new(rest.AppealService))
+
+	//audit
+	this.registerBean(new(rest.AuditController))
+	this.registerBean(new(rest.AuditDao))
+	this.registerBean(new(rest.AuditService))
+
+	//workbench
+	this.registerBean(new(rest.WorkbenchController))
+	this.registerBean(new(rest.RatingDraftDao))
+	this.registerBean(new(rest.WorkbenchService))
+
+	//anomaly
+	this.registerBean(new(rest.AnomalyController))
+	this.registerBean(new(rest.AnomalyService))
+
+	//certificate
+	this.registerBean(new(rest.CertificateController))
+	this.registerBean(new(rest.CertificateDao))
+	this.registerBean(new(rest.CertificateService))
+	this.registerBean(new(rest.VerifyController))
+
+	//dav
+	this.registerBean(new(rest.DavController))
+	this.registerBean(new(rest.DavService))
+
 	//preference
 	this.registerBean(new(rest.PreferenceController))
 	this.registerBean(new(rest.PreferenceDao))
@@ -221,6 +324,12 @@ func (this *TankContext) registerBeans() {
 	//uploadToken
 	this.registerBean(new(rest.UploadTokenDao))
 
+	//upload session
+	this.registerBean(new(rest.UploadSessionController))
+	this.registerBean(new(rest.UploadSessionDao))
+	this.registerBean(new(rest.UploadChunkDao))
+	this.registerBean(new(rest.UploadSessionService))
+
 	//task
 	this.registerBean(new(rest.TaskService))
 
@@ -229,9 +338,12 @@ func (this *TankContext) registerBeans() {
 	this.registerBean(new(rest.UserDao))
 	this.registerBean(new(rest.UserProfileDao))
 	this.registerBean(new(rest.UserService))
+	this.registerBean(new(rest.UserImportService))
 	this.registerBean(new(rest.SubmissionDao))
+	this.registerBean(new(rest.SubmissionService))
 	this.registerBean(new(rest.SubmissionController))
 	this.registerBean(new(rest.RatingDao))
+	this.registerBean(new(rest.RatingItemDao))
 	this.registerBean(new(rest.RatingController))
 }
 
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/rest"
+)
+
+// minSd has to be positive. a panel kept from before, agreeing exactly, still flags any other score.
+func TestAnomalyUnanimousPanel(t *testing.T) {
+	startTank(t)
+	admin := tankAdmin()
+
+	if r := admin.post(t, "/api/preference/edit/anomaly/config", url.Values{"anomalyConfig": {`{"identicalRun":5,"outlierSd":2,"minSd":0,"fastSeconds":0}`}}); r.Code == "OK" {
+		t.Fatal("minSd 0 should be rejected")
+	}
+
+	preferenceService := core.CONTEXT.GetBean(&rest.PreferenceService{}).(*rest.PreferenceService)
+	preference := preferenceService.Fetch()
+	preference.AnomalyConfig = `{"identicalRun":5,"outlierSd":2,"minSd":0,"fastSeconds":0}`
+	preferenceService.Save(preference)
+	defer func() {
+		preference.AnomalyConfig = ""
+		preferenceService.Save(preference)
+	}()
+
+	tankImport(t, "username,password,role,studentId,college\nanomalystu,123456,USER,2024070,AnomalyCollege")
+	student := &tankClient{username: "anomalystu", password: TANK_PASSWORD}
+	_, submission := student.submit(t, tankTrack(t, "AnomalyTrack"), "AnomalyWork")
+
+	var roundId int64 = 9070
+	ratingDao := core.CONTEXT.GetBean(&rest.RatingDao{}).(*rest.RatingDao)
+	for i, score := range []int{80, 80, 80, 81} {
+		ratingDao.Create(&rest.Rating{SubmissionId: submission.Id, JudgeUuid: "anomaly-judge-" + strconv.Itoa(i), RoundId: roundId, Score: score})
+	}
+
+	r := admin.post(t, "/api/anomaly/report", url.Values{"roundId": {strconv.FormatInt(roundId, 10)}})
+	if r.Code != "OK" {
+		t.Fatalf("report: %s", r.Msg)
+	}
+	report := &rest.AnomalyReport{}
+	r.decode(t, report)
+	var outliers []*rest.RatingAnomaly
+	for _, anomaly := range report.Anomalies {
+		if anomaly.Type == rest.ANOMALY_TYPE_OUTLIER {
+			outliers = append(outliers, anomaly)
+		}
+	}
+	if len(outliers) != 1 || outliers[0].JudgeUuid != "anomaly-judge-3" {
+		t.Fatalf("expect the 81 of anomaly-judge-3 as the only outlier, got %d outliers", len(outliers))
+	}
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/eyebluecn/tank/code/rest"
+)
+
+// a judge is assigned by hand only to a submission under review, and only from the judges of its round.
+func TestAssignOne(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role,studentId,college\n"+
+		"asgstu,123456,USER,2024050,AssignCollege\n"+
+		"asgjudge1,123456,JUDGE,,\n"+
+		"asgjudge2,123456,JUDGE,,")
+	admin := tankAdmin()
+	student := &tankClient{username: "asgstu", password: TANK_PASSWORD}
+	judge1, _ := tankUser(t, "asgjudge1")
+	judge2, _ := tankUser(t, "asgjudge2")
+
+	track := tankTrack(t, "AssignTrack")
+	dirMatter, submission := student.submit(t, track, "AssignWork")
+	submissionId := strconv.FormatInt(submission.Id, 10)
+
+	assign := func(judge *rest.User) *tankResult {
+		return admin.post(t, "/api/assignment/create", url.Values{"submissionId": {submissionId}, "judgeUuid": {judge.Uuid}})
+	}
+
+	if r := assign(judge1); r.Code == "OK" {
+		t.Fatal("a submission neither recommended nor in a round should not be assigned")
+	}
+
+	if r := admin.post(t, "/api/submission/recommend", url.Values{"matterUuid": {dirMatter.Uuid}, "override": {"true"}}); r.Code != "OK" {
+		t.Fatalf("recommend: %s", r.Msg)
+	}
+	if r := assign(judge1); r.Code != "OK" {
+		t.Fatalf("assign recommended submission: %s", r.Msg)
+	}
+
+	now := time.Now()
+	r := admin.post(t, "/api/round/create", url.Values{
+		"name":      {"AssignRound"},
+		"openTime":  {now.Add(-time.Hour).Format("2006-01-02 15:04:05")},
+		"closeTime": {now.Add(time.Hour).Format("2006-01-02 15:04:05")},
+	})
+	if r.Code != "OK" {
+		t.Fatalf("create round: %s", r.Msg)
+	}
+	round := &rest.Round{}
+	r.decode(t, round)
+	roundId := strconv.FormatInt(round.Id, 10)
+	if r = admin.post(t, "/api/round/judge/add", url.Values{"roundId": {roundId}, "judgeUuid": {judge2.Uuid}}); r.Code != "OK" {
+		t.Fatalf("add round judge: %s", r.Msg)
+	}
+	if r = admin.post(t, "/api/round/enter", url.Values{"submissionId": {submissionId}}); r.Code != "OK" {
+		t.Fatalf("enter round: %s", r.Msg)
+	}
+
+	if r = assign(judge1); r.Code == "OK" {
+		t.Fatal("a judge out of the round should not be assigned")
+	}
+	if r = assign(judge2); r.Code != "OK" {
+		t.Fatalf("assign round judge: %s", r.Msg)
+	}
+}
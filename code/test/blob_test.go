@@ -0,0 +1,131 @@
+package test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/rest"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+// the hashes of a file are shown to its owner only, and they link the content only for whoever can read a file of it.
+func TestInstantUploadNeedsReadableContent(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role\ninstanta,123456,USER\ninstantb,123456,USER")
+	owner := &tankClient{username: "instanta", password: TANK_PASSWORD}
+	other := &tankClient{username: "instantb", password: TANK_PASSWORD}
+
+	content := []byte("content only instanta has")
+	sha256Sum := sha256.Sum256(content)
+	md5Sum := md5.Sum(content)
+	hashes := url.Values{
+		"puuid":  {"root"},
+		"size":   {strconv.Itoa(len(content))},
+		"sha256": {hex.EncodeToString(sha256Sum[:])},
+		"md5":    {hex.EncodeToString(md5Sum[:])},
+	}
+
+	r := owner.upload(t, "/api/matter/upload", url.Values{"puuid": {"root"}}, "secret.txt", content)
+	matter := &rest.Matter{}
+	r.decode(t, matter)
+	if matter.OwnerSha256 != hashes.Get("sha256") || matter.OwnerMd5 != hashes.Get("md5") {
+		t.Fatalf("the owner should see the hashes, got %s %s", matter.OwnerSha256, matter.OwnerMd5)
+	}
+
+	r = owner.post(t, "/api/matter/rename", url.Values{"uuid": {matter.Uuid}, "name": {"renamed.txt"}})
+	renamed := &rest.Matter{}
+	r.decode(t, renamed)
+	if renamed.OwnerMd5 != hashes.Get("md5") {
+		t.Fatalf("the owner should see the hashes after a rename, got %q", renamed.OwnerMd5)
+	}
+
+	hashes.Set("filename", "stolen.txt")
+	r = other.post(t, "/api/matter/instant/upload", hashes)
+	if r.Code != "OK" || (len(r.Data) != 0 && string(r.Data) != "null") {
+		t.Fatalf("instantb cannot read the content but links it: %s %s", r.Code, string(r.Data))
+	}
+
+	hashes.Set("filename", "again.txt")
+	r = owner.post(t, "/api/matter/instant/upload", hashes)
+	again := &rest.Matter{}
+	r.decode(t, again)
+	if again.Name != "again.txt" || again.Size != int64(len(content)) {
+		t.Fatalf("instanta should instant upload the content, got %s", string(r.Data))
+	}
+}
+
+// deleting the last file of a content removes its blob at once, while a blob still shared is kept.
+func TestDeleteReleasesBlob(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role\nreleasea,123456,USER")
+	owner := &tankClient{username: "releasea", password: TANK_PASSWORD}
+
+	content := []byte("content released on delete")
+	sha256Sum := sha256.Sum256(content)
+	sha := hex.EncodeToString(sha256Sum[:])
+
+	root := &rest.Matter{Uuid: "root"}
+	first := owner.put(t, root, "first.txt", content)
+	second := owner.put(t, root, "second.txt", content)
+
+	refCount := func() int64 {
+		blob := &rest.Blob{}
+		if db := core.CONTEXT.GetDB().Where("sha256 = ?", sha).Find(blob); db.RowsAffected == 0 {
+			return -1
+		}
+		return blob.RefCount
+	}
+	if n := refCount(); n != 2 {
+		t.Fatalf("expect 2 references, got %d", n)
+	}
+
+	if r := owner.post(t, "/api/matter/delete", url.Values{"uuid": {first.Uuid}}); r.Code != "OK" {
+		t.Fatalf("delete first: %s", r.Msg)
+	}
+	if n := refCount(); n != 1 || !util.PathExists(rest.GetBlobPath(sha)) {
+		t.Fatalf("the blob is still shared, got %d references", n)
+	}
+
+	if r := owner.post(t, "/api/matter/delete", url.Values{"uuid": {second.Uuid}}); r.Code != "OK" {
+		t.Fatalf("delete second: %s", r.Msg)
+	}
+	if n := refCount(); n != -1 || util.PathExists(rest.GetBlobPath(sha)) {
+		t.Fatalf("the blob should be removed, got %d references", n)
+	}
+}
+
+// a blob a snapshot entry references is kept even if its count left the snapshot out, eg. counted again while the
+// snapshot was being taken.
+func TestReleaseKeepsSnapshotBlob(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role,studentId,college\nkeepstu,123456,USER,2024031,KeepCollege")
+	admin := tankAdmin()
+	student := &tankClient{username: "keepstu", password: TANK_PASSWORD}
+
+	folder, submission := student.submit(t, tankTrack(t, "KeepTrack"), "KeepWork")
+	content := []byte("content frozen by the snapshot")
+	demo := student.put(t, folder, "demo.txt", content)
+	sha256Sum := sha256.Sum256(content)
+	sha := hex.EncodeToString(sha256Sum[:])
+
+	if r := admin.post(t, "/api/snapshot/take", url.Values{"submissionId": {strconv.FormatInt(submission.Id, 10)}}); r.Code != "OK" {
+		t.Fatalf("take snapshot: %s", r.Msg)
+	}
+	core.CONTEXT.GetDB().Model(&rest.Blob{}).Where("sha256 = ?", sha).Update("ref_count", 1)
+
+	if r := student.post(t, "/api/matter/delete", url.Values{"uuid": {demo.Uuid}}); r.Code != "OK" {
+		t.Fatalf("delete demo: %s", r.Msg)
+	}
+	blob := &rest.Blob{}
+	if db := core.CONTEXT.GetDB().Where("sha256 = ?", sha).Find(blob); db.RowsAffected == 0 || !util.PathExists(rest.GetBlobPath(sha)) {
+		t.Fatalf("the blob of the snapshot is removed")
+	}
+	if blob.RefCount != 1 {
+		t.Fatalf("the snapshot entry should be counted, got %d references", blob.RefCount)
+	}
+}
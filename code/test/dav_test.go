@@ -2,10 +2,18 @@ package test
 
 import (
 	"bytes"
-	"github.com/eyebluecn/tank/code/tool/dav"
-	"github.com/eyebluecn/tank/code/tool/dav/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/rest"
+	"github.com/eyebluecn/tank/code/tool/dav"
+	"github.com/eyebluecn/tank/code/tool/dav/xml"
+	jsoniter "github.com/json-iterator/go"
 )
 
 func TestXmlDecoder(t *testing.T) {
@@ -101,3 +109,173 @@ func TestXmlEncoder(t *testing.T) {
 	t.Logf("[%v] pass!", time.Now())
 
 }
+
+// send a webdav request to /api/dav, and get the status and the body.
+func (this *tankClient) dav(t *testing.T, method string, path string, body string, header map[string]string) (int, string) {
+	t.Helper()
+	request, err := http.NewRequest(method, tankServer.URL+rest.DAV_PREFIX+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.SetBasicAuth(this.username, this.password)
+	for k, v := range header {
+		request.Header.Set(k, v)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode, string(content)
+}
+
+func (this *tankClient) expectDav(t *testing.T, status int, method string, path string, body string, header map[string]string) string {
+	t.Helper()
+	got, content := this.dav(t, method, path, body, header)
+	if got != status {
+		t.Fatalf("%s %s should be %d, got %d %s", method, path, status, got, content)
+	}
+	return content
+}
+
+func tankUser(t *testing.T, username string) (*rest.User, *rest.Space) {
+	t.Helper()
+	user := &rest.User{}
+	if db := core.CONTEXT.GetDB().Where("username = ?", username).First(user); db.Error != nil {
+		t.Fatalf("no user %s: %s", username, db.Error.Error())
+	}
+	space := &rest.Space{}
+	if db := core.CONTEXT.GetDB().Where("uuid = ?", user.SpaceUuid).First(space); db.Error != nil {
+		t.Fatalf("no space of %s: %s", username, db.Error.Error())
+	}
+	return user, space
+}
+
+// the space is served over webdav the same as on the web page.
+func TestDavFileSystem(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role\ndavstu,123456,USER")
+	student := &tankClient{username: "davstu", password: TANK_PASSWORD}
+	_, space := tankUser(t, "davstu")
+	root := "/" + space.Name
+
+	if status, _ := (&tankClient{username: "davstu", password: "wrong"}).dav(t, "PROPFIND", "/", "", nil); status != http.StatusUnauthorized {
+		t.Fatalf("a wrong password should be asked again, got %d", status)
+	}
+	if content := student.expectDav(t, http.StatusMultiStatus, "PROPFIND", "/", "", map[string]string{"Depth": "1"}); !strings.Contains(content, space.Name) {
+		t.Fatalf("the root should list the space %s: %s", space.Name, content)
+	}
+
+	student.expectDav(t, http.StatusCreated, "MKCOL", root+"/docs", "", nil)
+	student.expectDav(t, http.StatusCreated, "PUT", root+"/docs/a.txt", "hello dav", nil)
+	if content := student.expectDav(t, http.StatusOK, "GET", root+"/docs/a.txt", "", nil); content != "hello dav" {
+		t.Fatalf("GET a.txt got %s", content)
+	}
+	if content := student.expectDav(t, http.StatusMultiStatus, "PROPFIND", root+"/docs", "", map[string]string{"Depth": "1"}); !strings.Contains(content, "a.txt") {
+		t.Fatalf("docs should list a.txt: %s", content)
+	}
+
+	student.expectDav(t, http.StatusCreated, "COPY", root+"/docs/a.txt", "", map[string]string{"Destination": rest.DAV_PREFIX + root + "/docs/b.txt"})
+	student.expectDav(t, http.StatusCreated, "MOVE", root+"/docs/b.txt", "", map[string]string{"Destination": rest.DAV_PREFIX + root + "/c.txt"})
+	student.expectDav(t, http.StatusNotFound, "GET", root+"/docs/b.txt", "", nil)
+	if content := student.expectDav(t, http.StatusOK, "GET", root+"/c.txt", "", nil); content != "hello dav" {
+		t.Fatalf("GET c.txt got %s", content)
+	}
+
+	student.expectDav(t, http.StatusNoContent, "DELETE", root+"/c.txt", "", nil)
+	student.expectDav(t, http.StatusNotFound, "GET", root+"/c.txt", "", nil)
+	//the file in the recycle bin is recovered and overwritten, its content kept as a version.
+	student.expectDav(t, http.StatusCreated, "PUT", root+"/c.txt", "again", nil)
+	if content := student.expectDav(t, http.StatusOK, "GET", root+"/c.txt", "", nil); content != "again" {
+		t.Fatalf("GET c.txt got %s", content)
+	}
+	recovered := &rest.Matter{}
+	core.CONTEXT.GetDB().Where("space_uuid = ? AND name = ?", space.Uuid, "c.txt").First(recovered)
+	var versions int64
+	core.CONTEXT.GetDB().Model(&rest.MatterVersion{}).Where("matter_uuid = ?", recovered.Uuid).Count(&versions)
+	if recovered.Deleted || versions != 1 {
+		t.Fatalf("c.txt should be recovered with 1 version, got deleted %v and %d versions", recovered.Deleted, versions)
+	}
+}
+
+// a read only member reads the space but changes nothing.
+func TestDavReadOnlyMember(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role\ndavowner,123456,USER\ndavreader,123456,USER")
+	owner := &tankClient{username: "davowner", password: TANK_PASSWORD}
+	reader := &tankClient{username: "davreader", password: TANK_PASSWORD}
+	_, space := tankUser(t, "davowner")
+	readerUser, _ := tankUser(t, "davreader")
+	root := "/" + space.Name
+
+	r := tankAdmin().post(t, "/api/space/member/create", url.Values{"spaceUuid": {space.Uuid}, "userUuids": {readerUser.Uuid}, "role": {rest.SPACE_MEMBER_ROLE_READ_ONLY}})
+	if r.Code != "OK" {
+		t.Fatalf("add the member: %s", r.Msg)
+	}
+	owner.expectDav(t, http.StatusCreated, "PUT", root+"/shared.txt", "shared", nil)
+
+	if content := reader.expectDav(t, http.StatusOK, "GET", root+"/shared.txt", "", nil); content != "shared" {
+		t.Fatalf("GET shared.txt got %s", content)
+	}
+	reader.expectDav(t, http.StatusMultiStatus, "PROPFIND", root, "", map[string]string{"Depth": "1"})
+	reader.expectDav(t, http.StatusForbidden, "PUT", root+"/mine.txt", "mine", nil)
+	reader.expectDav(t, http.StatusForbidden, "MKCOL", root+"/mine", "", nil)
+	reader.expectDav(t, http.StatusForbidden, "DELETE", root+"/shared.txt", "", nil)
+	reader.expectDav(t, http.StatusForbidden, "MOVE", root+"/shared.txt", "", map[string]string{"Destination": rest.DAV_PREFIX + root + "/moved.txt"})
+	owner.expectDav(t, http.StatusOK, "GET", root+"/shared.txt", "", nil)
+	owner.expectDav(t, http.StatusNotFound, "GET", root+"/mine.txt", "", nil)
+}
+
+// what goes beyond the size limits, or into a submission folder after its deadline, is refused.
+func TestDavLimits(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role,studentId,college\ndavlimit,123456,USER,2024030,DavCollege")
+	student := &tankClient{username: "davlimit", password: TANK_PASSWORD}
+	_, space := tankUser(t, "davlimit")
+	root := "/" + space.Name
+
+	core.CONTEXT.GetDB().Model(&rest.Space{}).Where("uuid = ?", space.Uuid).Update("size_limit", 4)
+	student.expectDav(t, http.StatusRequestEntityTooLarge, "PUT", root+"/large.txt", "too large", nil)
+	core.CONTEXT.GetDB().Model(&rest.Space{}).Where("uuid = ?", space.Uuid).Updates(map[string]any{"size_limit": -1, "total_size_limit": 4})
+	student.expectDav(t, http.StatusInsufficientStorage, "PUT", root+"/large.txt", "too large", nil)
+	core.CONTEXT.GetDB().Model(&rest.Space{}).Where("uuid = ?", space.Uuid).Update("total_size_limit", -1)
+	student.expectDav(t, http.StatusNotFound, "GET", root+"/large.txt", "", nil)
+
+	track := tankTrack(t, "DavTrack")
+	dirMatter, _ := student.submit(t, track, "DavWork")
+	student.expectDav(t, http.StatusCreated, "PUT", root+"/"+dirMatter.Name+"/before.txt", "before", nil)
+
+	local, _ := time.LoadLocation("Local")
+	windowConfig, err := jsoniter.ConfigCompatibleWithStandardLibrary.MarshalToString(&rest.WindowConfig{Windows: []*rest.SubmissionWindow{{
+		TrackId:   track.Id,
+		OpenTime:  time.Now().Add(-2 * time.Hour).In(local).Format("2006-01-02 15:04:05"),
+		CloseTime: time.Now().Add(-time.Hour).In(local).Format("2006-01-02 15:04:05"),
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := tankAdmin().post(t, "/api/preference/edit/window/config", url.Values{"windowConfig": {windowConfig}}); r.Code != "OK" {
+		t.Fatalf("close the window: %s", r.Msg)
+	}
+	defer tankAdmin().post(t, "/api/preference/edit/window/config", url.Values{"windowConfig": {`{"windows":[]}`}})
+
+	folder := root + "/" + dirMatter.Name
+	for _, request := range []struct{ method, path, body string }{
+		{"PUT", folder + "/after.txt", "after"},
+		{"PUT", folder + "/before.txt", "changed"},
+		{"MKCOL", folder + "/sub", ""},
+		{"DELETE", folder + "/before.txt", ""},
+	} {
+		if status, _ := student.dav(t, request.method, request.path, request.body, nil); status < 400 {
+			t.Fatalf("%s %s after the deadline should be refused, got %d", request.method, request.path, status)
+		}
+	}
+	if content := student.expectDav(t, http.StatusOK, "GET", folder+"/before.txt", "", nil); content != "before" {
+		t.Fatalf("before.txt is changed after the deadline: %s", content)
+	}
+	student.expectDav(t, http.StatusNotFound, "GET", folder+"/after.txt", "", nil)
+}
@@ -0,0 +1,33 @@
+package test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/rest"
+)
+
+// a college admin exports the team submissions with a student of the college, as the ranking export has them.
+func TestExportTeamOfCollege(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role,studentId,college\n"+
+		"expleader,123456,USER,2024100,ExpCollegeA\n"+
+		"expmember,123456,USER,2024101,ExpCollegeB\n"+
+		"expadminb,123456,COLLEGE_ADMIN,,ExpCollegeB")
+	leader := &tankClient{username: "expleader", password: TANK_PASSWORD}
+	adminB := &tankClient{username: "expadminb", password: TANK_PASSWORD}
+	member, _ := tankUser(t, "expmember")
+
+	_, submission := leader.submit(t, tankTrack(t, "ExpTrack"), "ExpTeamWork")
+	team := &rest.Team{Name: "ExpTeam", LeaderUuid: member.Uuid}
+	core.CONTEXT.GetDB().Create(team)
+	core.CONTEXT.GetDB().Create(&rest.TeamMember{TeamId: team.Id, UserUuid: member.Uuid, Role: rest.TEAM_ROLE_MEMBER, Status: rest.TEAM_MEMBER_STATUS_ACCEPTED})
+	core.CONTEXT.GetDB().Model(&rest.Submission{}).Where("id = ?", submission.Id).Update("team_id", team.Id)
+
+	r := adminB.post(t, "/api/export/submissions", url.Values{"format": {"csv"}})
+	if !strings.Contains(string(r.Data), "ExpTeamWork") {
+		t.Fatalf("the team submission with a student of ExpCollegeB is not exported: %s", string(r.Data))
+	}
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/rest"
+)
+
+// the completeness filter follows the files of the folder, not what was kept at the last check.
+func TestCompletenessFilterFollowsFiles(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role,studentId,college\nrulestu,123456,USER,2024040,RuleCollege")
+	admin := tankAdmin()
+	student := &tankClient{username: "rulestu", password: TANK_PASSWORD}
+
+	track := tankTrack(t, "RuleTrack")
+	trackId := strconv.FormatInt(track.Id, 10)
+	dirMatter, submission := student.submit(t, track, "RuleWork")
+
+	rules := `[{"name":"Paper","extensions":"pdf","minCount":1}]`
+	if r := admin.post(t, "/api/file/rule/save", url.Values{"trackId": {trackId}, "rules": {rules}}); r.Code != "OK" {
+		t.Fatalf("save rules: %s", r.Msg)
+	}
+
+	search := func(completeness string) []*rest.Submission {
+		t.Helper()
+		r := admin.post(t, "/api/form/search", url.Values{"trackId": {trackId}, "completeness": {completeness}})
+		if r.Code != "OK" {
+			t.Fatalf("search %s: %s", completeness, r.Msg)
+		}
+		var submissions []*rest.Submission
+		r.decode(t, &submissions)
+		return submissions
+	}
+	expect := func(completeness string, count int) {
+		t.Helper()
+		submissions := search(completeness)
+		if len(submissions) != count || (count == 1 && submissions[0].Id != submission.Id) {
+			t.Fatalf("expect %d %s submissions, got %d", count, completeness, len(submissions))
+		}
+	}
+
+	expect(rest.SUBMISSION_COMPLETENESS_INCOMPLETE, 1)
+
+	paper := student.put(t, dirMatter, "paper.pdf", []byte("%PDF-1.4"))
+	expect(rest.SUBMISSION_COMPLETENESS_COMPLETE, 1)
+	expect(rest.SUBMISSION_COMPLETENESS_INCOMPLETE, 0)
+
+	if r := student.post(t, "/api/matter/delete", url.Values{"uuid": {paper.Uuid}}); r.Code != "OK" {
+		t.Fatalf("delete paper: %s", r.Msg)
+	}
+	expect(rest.SUBMISSION_COMPLETENESS_COMPLETE, 0)
+	expect(rest.SUBMISSION_COMPLETENESS_INCOMPLETE, 1)
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/rest"
+)
+
+// a submission folder whose form is rejected leaves nothing behind, so it can be created again.
+func TestCreateDirectoryRejectedForm(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role,studentId,college\nformstu,123456,USER,2024010,FormCollege")
+	admin := tankAdmin()
+	student := &tankClient{username: "formstu", password: TANK_PASSWORD}
+
+	track := tankTrack(t, "FormTrack")
+	trackId := strconv.FormatInt(track.Id, 10)
+
+	fields := `[{"key":"advisor","label":"Advisor","type":"TEXT","required":true,"maxLength":4}]`
+	if r := admin.post(t, "/api/form/save", url.Values{"trackId": {trackId}, "fields": {fields}}); r.Code != "OK" {
+		t.Fatalf("save form: %s", r.Msg)
+	}
+
+	create := func(formValues string) *tankResult {
+		return student.post(t, "/api/matter/create/directory", url.Values{
+			"puuid":           {"root"},
+			"name":            {"work"},
+			"trackId":         {trackId},
+			"workName":        {"FormWork"},
+			"isRootDirectory": {"true"},
+			"formValues":      {formValues},
+		})
+	}
+	count := func() int64 {
+		var n int64
+		core.CONTEXT.GetDB().Model(&rest.Submission{}).Where("title = ?", "FormWork").Count(&n)
+		return n
+	}
+
+	for _, formValues := range []string{`{"advisor":`, `{"advisor":"too long"}`, `{"unknown":"x"}`} {
+		r := create(formValues)
+		if r.Code != "BAD_REQUEST" {
+			t.Fatalf("form %s should be rejected, got %s", formValues, r.Code)
+		}
+		if n := count(); n != 0 {
+			t.Fatalf("form %s is rejected but %d submissions are left", formValues, n)
+		}
+	}
+
+	r := create(`{"advisor":"Wang"}`)
+	if r.Code != "OK" {
+		t.Fatalf("create after rejected forms: %s", r.Msg)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("expect 1 submission, got %d", n)
+	}
+}
@@ -30,3 +30,34 @@ func TestDayAgo(t *testing.T) {
 	fmt.Printf("%s\n", util.ConvertTimeToDateTimeString(thenDay))
 
 }
+
+func TestFillPlaceholders(t *testing.T) {
+
+	text := util.FillPlaceholders("{name}同学在{track}中荣获{award}，{unknown}", map[string]string{
+		"name":  "张三",
+		"track": "创新赛道",
+		"award": "一等奖",
+	})
+	if text != "张三同学在创新赛道中荣获一等奖，{unknown}" {
+		t.Errorf("text %s", text)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+
+	limiter := util.NewRateLimiter(2, time.Minute)
+	now := time.Now()
+
+	if !limiter.Allow("1.1.1.1", now) || !limiter.Allow("1.1.1.1", now.Add(time.Second)) {
+		t.Error("first two requests should be allowed")
+	}
+	if limiter.Allow("1.1.1.1", now.Add(2*time.Second)) {
+		t.Error("third request in the window should be refused")
+	}
+	if !limiter.Allow("2.2.2.2", now.Add(2*time.Second)) {
+		t.Error("other keys have their own window")
+	}
+	if !limiter.Allow("1.1.1.1", now.Add(time.Minute)) {
+		t.Error("a new window should be allowed again")
+	}
+}
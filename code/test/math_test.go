@@ -0,0 +1,71 @@
+package test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+func TestMeanAndStdDev(t *testing.T) {
+
+	values := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+
+	if mean := util.Mean(values); mean != 5 {
+		t.Errorf("mean %v != 5", mean)
+	}
+	if stdDev := util.StdDev(values); stdDev != 2 {
+		t.Errorf("stdDev %v != 2", stdDev)
+	}
+	if util.Mean(nil) != 0 || util.StdDev(nil) != 0 {
+		t.Error("empty values should be 0")
+	}
+}
+
+func TestZScore(t *testing.T) {
+
+	if z := util.ZScore(9, 5, 2); z != 2 {
+		t.Errorf("z %v != 2", z)
+	}
+	if z := util.ZScore(80, 80, 0); z != 0 {
+		t.Errorf("z %v != 0 when stdDev is 0", z)
+	}
+}
+
+func TestTrimmedMean(t *testing.T) {
+
+	mean, dropped := util.TrimmedMean([]float64{90, 10, 70, 80, 75}, 1)
+	if math.Abs(mean-75) > 1e-9 {
+		t.Errorf("mean %v != 75", mean)
+	}
+	if !reflect.DeepEqual(dropped, []int{0, 1}) {
+		t.Errorf("dropped %v != [0 1]", dropped)
+	}
+
+	//too few values, nothing dropped.
+	mean, dropped = util.TrimmedMean([]float64{60, 80}, 1)
+	if mean != 70 || len(dropped) != 0 {
+		t.Errorf("mean %v dropped %v, expect 70 []", mean, dropped)
+	}
+
+	mean, dropped = util.TrimmedMean([]float64{60, 80, 100}, 0)
+	if mean != 80 || len(dropped) != 0 {
+		t.Errorf("mean %v dropped %v, expect 80 []", mean, dropped)
+	}
+}
+
+func TestEqualRuns(t *testing.T) {
+
+	runs := util.EqualRuns([]float64{80, 80, 80, 75, 90, 90, 90, 90}, 3)
+	if !reflect.DeepEqual(runs, [][2]int{{0, 3}, {4, 8}}) {
+		t.Errorf("runs %v != [[0 3] [4 8]]", runs)
+	}
+
+	if runs := util.EqualRuns([]float64{80, 80, 75}, 3); len(runs) != 0 {
+		t.Errorf("runs %v, expect none", runs)
+	}
+	if runs := util.EqualRuns(nil, 2); len(runs) != 0 {
+		t.Errorf("runs %v, expect none for empty values", runs)
+	}
+}
@@ -0,0 +1,44 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+// the forwarded headers are taken only from the trusted proxies.
+func TestGetClientIp(t *testing.T) {
+
+	proxies := []string{"10.0.0.1", "192.168.0.0/16"}
+	cases := []struct {
+		remoteAddr string
+		forwarded  string
+		realIp     string
+		expect     string
+	}{
+		//a client cannot make up its ip.
+		{"203.0.113.7:5000", "1.2.3.4", "", "203.0.113.7"},
+		{"203.0.113.7:5000", "", "1.2.3.4", "203.0.113.7"},
+		//the proxy tells the client.
+		{"10.0.0.1:5000", "203.0.113.7", "", "203.0.113.7"},
+		{"192.168.3.4:5000", "", "203.0.113.7", "203.0.113.7"},
+		//what the client puts before the proxies is skipped.
+		{"10.0.0.1:5000", "1.2.3.4, 203.0.113.7, 192.168.1.1", "", "203.0.113.7"},
+		{"10.0.0.1:5000", "", "", "10.0.0.1"},
+		{"[2001:db8::1]:5000", "1.2.3.4", "", "2001:db8::1"},
+	}
+	for _, c := range cases {
+		request, _ := http.NewRequest(http.MethodGet, "http://tank/api/verify", nil)
+		request.RemoteAddr = c.remoteAddr
+		if c.forwarded != "" {
+			request.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+		if c.realIp != "" {
+			request.Header.Set("X-Real-Ip", c.realIp)
+		}
+		if ip := util.GetClientIp(request, proxies); ip != c.expect {
+			t.Errorf("%s forwarded %q real %q = %s, expect %s", c.remoteAddr, c.forwarded, c.realIp, ip, c.expect)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/tool/pdf"
+)
+
+func TestPdfWriteImage(t *testing.T) {
+
+	img := image.NewGray(image.Rect(0, 0, 300, 150))
+	img.SetGray(10, 10, color.Gray{Y: 128})
+
+	buffer := &bytes.Buffer{}
+	if err := pdf.WriteImage(buffer, img, 150); err != nil {
+		t.Fatal(err)
+	}
+	content := buffer.String()
+
+	if !strings.HasPrefix(content, "%PDF-1.4") || !strings.HasSuffix(content, "%%EOF\n") {
+		t.Error("pdf should start with the header and end with the eof marker")
+	}
+	//300px at 150dpi is 144pt.
+	if !strings.Contains(content, "/MediaBox [0 0 144.00 72.00]") {
+		t.Error("page should be sized by the dpi")
+	}
+	if !strings.Contains(content, "/Width 300 /Height 150 /ColorSpace /DeviceRGB") {
+		t.Error("gray image should be embedded as rgb")
+	}
+
+	//every offset in the xref table points at its object.
+	xref := regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(content)
+	if xref == nil {
+		t.Fatal("startxref not found")
+	}
+	xrefOffset, _ := strconv.Atoi(xref[1])
+	if !strings.HasPrefix(content[xrefOffset:], "xref\n0 6\n") {
+		t.Fatalf("startxref %d does not point at the xref table", xrefOffset)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(content[xrefOffset:], -1)
+	if len(entries) != 5 {
+		t.Fatalf("%d xref entries, expect 5", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		if !strings.HasPrefix(content[offset:], strconv.Itoa(i+1)+" 0 obj") {
+			t.Errorf("xref entry %d does not point at object %d", i+1, i+1)
+		}
+	}
+
+	if err := pdf.WriteImage(&bytes.Buffer{}, img, 0); err == nil {
+		t.Error("dpi 0 should be rejected")
+	}
+}
@@ -0,0 +1,39 @@
+package test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/rest"
+)
+
+// a college admin recommends the submissions of the own college only, so the quota of another college is never used up.
+func TestRecommendOwnCollege(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role,studentId,college\n"+
+		"recstu,123456,USER,2024080,RecCollegeA\n"+
+		"recadmina,123456,COLLEGE_ADMIN,,RecCollegeA\n"+
+		"recadminb,123456,COLLEGE_ADMIN,,RecCollegeB")
+	student := &tankClient{username: "recstu", password: TANK_PASSWORD}
+	adminA := &tankClient{username: "recadmina", password: TANK_PASSWORD}
+	adminB := &tankClient{username: "recadminb", password: TANK_PASSWORD}
+
+	dirMatter, submission := student.submit(t, tankTrack(t, "RecTrack"), "RecWork")
+	recommend := url.Values{"matterUuid": {dirMatter.Uuid}}
+
+	if r := adminB.post(t, "/api/submission/recommend", recommend); r.Code == "OK" {
+		t.Fatal("recadminb should not recommend a submission of RecCollegeA")
+	}
+	if r := student.post(t, "/api/submission/recommend", recommend); r.Code == "OK" {
+		t.Fatal("a student should not recommend")
+	}
+	if r := adminA.post(t, "/api/submission/recommend", recommend); r.Code != "OK" {
+		t.Fatalf("recadmina recommends: %s", r.Msg)
+	}
+	recommended := &rest.Submission{}
+	core.CONTEXT.GetDB().Where("id = ?", submission.Id).First(recommended)
+	if !recommended.IsRecommended {
+		t.Fatal("the submission should be recommended")
+	}
+}
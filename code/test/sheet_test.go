@@ -0,0 +1,183 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/tool/sheet"
+)
+
+func TestColumnName(t *testing.T) {
+
+	testMap := map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"}
+	for index, name := range testMap {
+		if sheet.ColumnName(index) != name {
+			t.Errorf("column %d = %s, expect %s", index, sheet.ColumnName(index), name)
+		}
+	}
+}
+
+func TestCsvWriter(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+	writer, err := sheet.NewWriter(sheet.FORMAT_CSV, buffer, "作品")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = writer.WriteRow("标题", "分数")
+	_ = writer.WriteRow("a,b", 85.5)
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(buffer.Bytes(), sheet.UTF8_BOM) {
+		t.Error("csv should start with utf-8 bom")
+	}
+	content := string(buffer.Bytes()[len(sheet.UTF8_BOM):])
+	if content != "标题,分数\n\"a,b\",85.5\n" {
+		t.Errorf("unexpected csv %q", content)
+	}
+}
+
+func TestXlsxWriter(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+	writer, err := sheet.NewWriter(sheet.FORMAT_XLSX, buffer, "作品/列表")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = writer.WriteRow("标题", "分数", "推荐")
+	_ = writer.WriteRow("<a&b>", 85.5, true)
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, file := range zipReader.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(reader)
+		_ = reader.Close()
+		files[file.Name] = string(content)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `name="作品_列表"`) {
+		t.Errorf("sheet name not sanitized: %s", files["xl/workbook.xml"])
+	}
+	sheetXml := files["xl/worksheets/sheet1.xml"]
+	for _, expect := range []string{`<c r="A2" t="inlineStr"><is><t xml:space="preserve">&lt;a&amp;b&gt;</t></is></c>`, `<c r="B2"><v>85.5</v></c>`, `<c r="C2" t="b"><v>1</v></c>`} {
+		if !strings.Contains(sheetXml, expect) {
+			t.Errorf("sheet missing %s", expect)
+		}
+	}
+}
+
+// text filled in by users must not run as a formula, while negative numbers stay numbers.
+func TestSheetEscapeFormula(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+	writer, _ := sheet.NewWriter(sheet.FORMAT_CSV, buffer, "作品")
+	_ = writer.WriteRow("=HYPERLINK(\"http://evil\")", "+1", "-2", "@SUM(A1)", "\tx", "\rx", "a=b", -3)
+	_ = writer.Close()
+	content := string(buffer.Bytes()[len(sheet.UTF8_BOM):])
+	if content != "\"'=HYPERLINK(\"\"http://evil\"\")\",'+1,'-2,'@SUM(A1),'\tx,\"'\rx\",a=b,-3\n" {
+		t.Errorf("unexpected csv %q", content)
+	}
+
+	buffer = &bytes.Buffer{}
+	xlsxWriter, _ := sheet.NewXlsxWriter(buffer, "作品")
+	_ = xlsxWriter.WriteRow("=1+1", -3)
+	_ = xlsxWriter.Close()
+	rows, err := sheet.Read(sheet.FORMAT_XLSX, bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	//inline strings never run as a formula, so they are kept as they are.
+	expect := [][]string{{"=1+1", "-3"}}
+	if !reflect.DeepEqual(rows, expect) {
+		t.Errorf("rows %v != %v", rows, expect)
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+
+	testMap := map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "ba3": 52, "12": -1}
+	for ref, index := range testMap {
+		if sheet.ColumnIndex(ref) != index {
+			t.Errorf("column of %s = %d, expect %d", ref, sheet.ColumnIndex(ref), index)
+		}
+	}
+}
+
+func TestReadCsv(t *testing.T) {
+
+	content := append(append([]byte{}, sheet.UTF8_BOM...), []byte("用户名,学号\nzhangsan,2021001\nlisi\n")...)
+	rows, err := sheet.Read(sheet.FORMAT_CSV, bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]string{{"用户名", "学号"}, {"zhangsan", "2021001"}, {"lisi"}}
+	if !reflect.DeepEqual(rows, expect) {
+		t.Errorf("rows %v != %v", rows, expect)
+	}
+}
+
+func TestReadXlsxWritten(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+	writer, _ := sheet.NewXlsxWriter(buffer, "roster")
+	_ = writer.WriteRow("用户名", "学号", "分数")
+	_ = writer.WriteRow("zhangsan", "", 85.5)
+	_ = writer.Close()
+
+	rows, err := sheet.Read(sheet.FORMAT_XLSX, bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]string{{"用户名", "学号", "分数"}, {"zhangsan", "", "85.5"}}
+	if !reflect.DeepEqual(rows, expect) {
+		t.Errorf("rows %v != %v", rows, expect)
+	}
+}
+
+// xlsx saved by office uses shared strings, and skips empty rows and cells.
+func TestReadXlsxSharedStrings(t *testing.T) {
+
+	parts := map[string]string{
+		"xl/workbook.xml":            `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="名单" sheetId="1" r:id="rId3"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId3" Type="worksheet" Target="worksheets/roster.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>用户名</t></si><si><r><t>张</t></r><r><t>三</t></r></si></sst>`,
+		"xl/worksheets/roster.xml":   `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row r="1"><c r="A1" t="s"><v>0</v></c></row><row r="3"><c r="A3" t="s"><v>1</v></c><c r="C3"><v>13800138000</v></c></row></sheetData></worksheet>`,
+	}
+	buffer := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buffer)
+	for name, content := range parts {
+		writer, _ := zipWriter.Create(name)
+		_, _ = writer.Write([]byte(content))
+	}
+	_ = zipWriter.Close()
+
+	rows, err := sheet.ReadXlsx(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]string{{"用户名"}, {}, {"张三", "", "13800138000"}}
+	if !reflect.DeepEqual(rows, expect) {
+		t.Errorf("rows %v != %v", rows, expect)
+	}
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/rest"
+)
+
+// snapshots taken at the same time keep the files in the blob store, each entry counted as a reference.
+func TestSnapshotKeepsBlobs(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role,studentId,college\nsnapstu,123456,USER,2024020,SnapCollege")
+	admin := tankAdmin()
+	student := &tankClient{username: "snapstu", password: TANK_PASSWORD}
+
+	folder, submission := student.submit(t, tankTrack(t, "SnapTrack"), "SnapWork")
+	content := []byte("the demo of the snapshot")
+	student.put(t, folder, "demo.txt", content)
+
+	var wg sync.WaitGroup
+	results := make([]*tankResult, 2)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = admin.post(t, "/api/snapshot/take", url.Values{"submissionId": {strconv.FormatInt(submission.Id, 10)}})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		if r.Code != "OK" {
+			t.Fatalf("take snapshot: %s", r.Msg)
+		}
+		snapshot := &rest.Snapshot{}
+		r.decode(t, snapshot)
+
+		var entry rest.SnapshotEntry
+		core.CONTEXT.GetDB().Where("snapshot_id = ? AND name = ?", snapshot.Id, "demo.txt").First(&entry)
+		download := admin.post(t, "/api/snapshot/download", url.Values{"entryId": {strconv.FormatInt(entry.Id, 10)}})
+		if string(download.Data) != string(content) {
+			t.Fatalf("snapshot %d downloads %q", snapshot.Id, string(download.Data))
+		}
+
+		blob := &rest.Blob{}
+		core.CONTEXT.GetDB().Where("sha256 = ?", entry.Sha256).First(blob)
+		//the file and the entries of the two snapshots.
+		if blob.RefCount != 3 {
+			t.Fatalf("blob %s should be referenced 3 times, got %d", entry.Sha256, blob.RefCount)
+		}
+		if _, err := os.Stat(rest.GetBlobPath(entry.Sha256)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//no snapshot keeps its own copy any more.
+	_ = filepath.WalkDir(rest.GetSnapshotRootDir(), func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			t.Fatalf("snapshot file %s out of the blob store", path)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,254 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/rest"
+	"github.com/eyebluecn/tank/code/support"
+	jsoniter "github.com/json-iterator/go"
+	"gorm.io/gorm"
+)
+
+// the tank shared by the tests. it is installed with sqlite under the home of the test binary.
+var (
+	tankOnce   sync.Once
+	tankServer *httptest.Server
+)
+
+const (
+	TANK_ADMIN_USERNAME = "admin"
+	TANK_PASSWORD       = "123456"
+)
+
+// the response of the tank api.
+type tankResult struct {
+	Status int
+	Code   string              `json:"code"`
+	Msg    string              `json:"msg"`
+	Data   jsoniter.RawMessage `json:"data"`
+}
+
+// decode the data of the result into v.
+func (this *tankResult) decode(t *testing.T, v any) {
+	t.Helper()
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(this.Data, v); err != nil {
+		t.Fatalf("cannot decode %s: %s", string(this.Data), err.Error())
+	}
+}
+
+// a user calling the tank api with basic auth.
+type tankClient struct {
+	username string
+	password string
+}
+
+// start the tank once and install it with an administrator.
+func startTank(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	tankOnce.Do(func() {
+		tankLogger := &support.TankLogger{}
+		core.LOGGER = tankLogger
+		tankLogger.Init()
+
+		tankConfig := &support.TankConfig{}
+		core.CONFIG = tankConfig
+		tankConfig.Init()
+
+		tankContext := &support.TankContext{}
+		core.CONTEXT = tankContext
+		tankContext.Init()
+
+		tankServer = httptest.NewServer(core.CONTEXT)
+
+		if !core.CONFIG.Installed() {
+			guest := &tankClient{}
+			install := url.Values{"dbType": {"sqlite"}}
+			for _, path := range []string{"/api/install/create/table", "/api/install/create/admin", "/api/install/finish"} {
+				form := url.Values{"adminUsername": {TANK_ADMIN_USERNAME}, "adminPassword": {TANK_PASSWORD}}
+				for k, v := range install {
+					form[k] = v
+				}
+				if r := guest.post(t, path, form); r.Code != "OK" {
+					panic(fmt.Sprintf("cannot install tank at %s: %s", path, r.Msg))
+				}
+			}
+		}
+
+		//sqlite only generates the rowid for "integer primary key", the bigint(20) ids are left null. give them as mysql does.
+		err := core.CONTEXT.GetDB().Callback().Create().Before("gorm:create").Register("test:id", assignId)
+		core.PanicError(err)
+	})
+
+	if tankServer == nil {
+		t.Fatal("tank is not started")
+	}
+	return tankServer
+}
+
+// give the next id to the records with a zero int64 primary key "Id".
+func assignId(db *gorm.DB) {
+	if db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field == nil || field.Name != "Id" || field.FieldType.Kind() != reflect.Int64 {
+		return
+	}
+
+	assign := func(value reflect.Value) {
+		if _, zero := field.ValueOf(db.Statement.Context, value); !zero {
+			return
+		}
+		var max int64
+		row := db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Table).Select("COALESCE(MAX(id), 0)").Row()
+		core.PanicError(row.Scan(&max))
+		core.PanicError(field.Set(db.Statement.Context, value, max+1))
+	}
+
+	value := reflect.Indirect(db.Statement.ReflectValue)
+	if value.Kind() == reflect.Slice {
+		for i := 0; i < value.Len(); i++ {
+			assign(reflect.Indirect(value.Index(i)))
+		}
+	} else if value.Kind() == reflect.Struct {
+		assign(value)
+	}
+}
+
+func tankAdmin() *tankClient {
+	return &tankClient{username: TANK_ADMIN_USERNAME, password: TANK_PASSWORD}
+}
+
+// import the users of a csv roster, eg. "username,role,studentId,college\nalice,USER,2024001,Art".
+func tankImport(t *testing.T, roster string) {
+	t.Helper()
+	r := tankAdmin().upload(t, "/api/user/import", url.Values{"createCollege": {"true"}}, "roster.csv", []byte(roster))
+	if r.Code != "OK" {
+		t.Fatalf("cannot import users: %s", r.Msg)
+	}
+}
+
+// create a track open to everyone.
+func tankTrack(t *testing.T, name string) *rest.Track {
+	t.Helper()
+	r := tankAdmin().post(t, "/api/track/create", url.Values{"name": {name}, "targetUserType": {"BOTH"}})
+	if r.Code != "OK" {
+		t.Fatalf("cannot create track %s: %s", name, r.Msg)
+	}
+	track := &rest.Track{}
+	r.decode(t, track)
+	return track
+}
+
+// the student creates a submission folder of the track in the root of the space.
+func (this *tankClient) submit(t *testing.T, track *rest.Track, workName string) (*rest.Matter, *rest.Submission) {
+	t.Helper()
+	r := this.post(t, "/api/matter/create/directory", url.Values{
+		"puuid":           {"root"},
+		"name":            {workName},
+		"trackId":         {strconv.FormatInt(track.Id, 10)},
+		"workName":        {workName},
+		"isRootDirectory": {"true"},
+	})
+	if r.Code != "OK" {
+		t.Fatalf("cannot submit %s: %s", workName, r.Msg)
+	}
+	matter := &rest.Matter{}
+	r.decode(t, matter)
+
+	submission := &rest.Submission{}
+	db := core.CONTEXT.GetDB().Where("matter_uuid = ?", matter.Uuid).First(submission)
+	if db.Error != nil {
+		t.Fatalf("no submission of %s: %s", workName, db.Error.Error())
+	}
+	return matter, submission
+}
+
+// upload a file into the directory.
+func (this *tankClient) put(t *testing.T, dirMatter *rest.Matter, filename string, content []byte) *rest.Matter {
+	t.Helper()
+	r := this.upload(t, "/api/matter/upload", url.Values{"puuid": {dirMatter.Uuid}, "spaceUuid": {dirMatter.SpaceUuid}}, filename, content)
+	if r.Code != "OK" {
+		t.Fatalf("cannot upload %s: %s", filename, r.Msg)
+	}
+	matter := &rest.Matter{}
+	r.decode(t, matter)
+	return matter
+}
+
+func (this *tankClient) do(t *testing.T, request *http.Request) *tankResult {
+	t.Helper()
+	if this.username != "" {
+		request.SetBasicAuth(this.username, this.password)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &tankResult{Status: response.StatusCode}
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(body, r); err != nil {
+		r.Data = body
+	}
+	return r
+}
+
+// post a form to the api.
+func (this *tankClient) post(t *testing.T, path string, form url.Values) *tankResult {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodPost, tankServer.URL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return this.do(t, request)
+}
+
+// post a multipart form with a file to the api.
+func (this *tankClient) upload(t *testing.T, path string, form url.Values, filename string, content []byte) *tankResult {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for k, values := range form {
+		for _, v := range values {
+			if err := writer.WriteField(k, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, tankServer.URL+path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return this.do(t, request)
+}
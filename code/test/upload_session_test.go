@@ -0,0 +1,207 @@
+package test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/rest"
+	"github.com/eyebluecn/tank/code/tool/util"
+)
+
+const testChunkSize = rest.UPLOAD_CHUNK_MIN_SIZE
+
+func md5Of(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// a file of three chunks, the last one shorter.
+func chunkedContent() []byte {
+	content := make([]byte, 2*testChunkSize+10)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+func (this *tankClient) createUploadSession(t *testing.T, filename string, size int) *tankResult {
+	t.Helper()
+	return this.post(t, "/api/upload/session/create", url.Values{
+		"puuid":     {"root"},
+		"filename":  {filename},
+		"size":      {strconv.Itoa(size)},
+		"chunkSize": {strconv.Itoa(testChunkSize)},
+	})
+}
+
+// send the chunk as the raw body.
+func (this *tankClient) sendChunk(t *testing.T, uploadSession *rest.UploadSession, index int64, content []byte, chunkMd5 string) *tankResult {
+	t.Helper()
+	query := url.Values{"uuid": {uploadSession.Uuid}, "index": {strconv.FormatInt(index, 10)}, "md5": {chunkMd5}}
+	request, err := http.NewRequest(http.MethodPost, tankServer.URL+"/api/upload/session/chunk?"+query.Encode(), bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/octet-stream")
+	return this.do(t, request)
+}
+
+func (this *tankClient) uploadSessionDetail(t *testing.T, uploadSession *rest.UploadSession) *rest.UploadSession {
+	t.Helper()
+	r := this.post(t, "/api/upload/session/detail", url.Values{"uuid": {uploadSession.Uuid}})
+	if r.Code != "OK" {
+		t.Fatalf("detail of %s: %s", uploadSession.Uuid, r.Msg)
+	}
+	detail := &rest.UploadSession{}
+	r.decode(t, detail)
+	return detail
+}
+
+func chunkOf(content []byte, index int64) []byte {
+	end := (index + 1) * testChunkSize
+	if end > int64(len(content)) {
+		end = int64(len(content))
+	}
+	return content[index*testChunkSize : end]
+}
+
+// an upload started again resumes with the chunks received, and only the rest is sent.
+func TestUploadSessionResume(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role\nresumestu,123456,USER")
+	student := &tankClient{username: "resumestu", password: TANK_PASSWORD}
+	content := chunkedContent()
+
+	r := student.createUploadSession(t, "resume.bin", len(content))
+	if r.Code != "OK" {
+		t.Fatalf("create: %s", r.Msg)
+	}
+	uploadSession := &rest.UploadSession{}
+	r.decode(t, uploadSession)
+	if uploadSession.ChunkCount != 3 {
+		t.Fatalf("expect 3 chunks, got %d", uploadSession.ChunkCount)
+	}
+	if r := student.sendChunk(t, uploadSession, 1, chunkOf(content, 1), md5Of(chunkOf(content, 1))); r.Code != "OK" {
+		t.Fatalf("chunk 1: %s", r.Msg)
+	}
+
+	r = student.createUploadSession(t, "resume.bin", len(content))
+	resumed := &rest.UploadSession{}
+	r.decode(t, resumed)
+	if resumed.Uuid != uploadSession.Uuid || fmt.Sprint(resumed.Chunks) != "[1]" {
+		t.Fatalf("expect %s resumed with chunk 1, got %s with %v", uploadSession.Uuid, resumed.Uuid, resumed.Chunks)
+	}
+
+	for _, index := range []int64{0, 2} {
+		if r := student.sendChunk(t, resumed, index, chunkOf(content, index), md5Of(chunkOf(content, index))); r.Code != "OK" {
+			t.Fatalf("chunk %d: %s", index, r.Msg)
+		}
+	}
+	r = student.post(t, "/api/upload/session/complete", url.Values{"uuid": {resumed.Uuid}})
+	if r.Code != "OK" {
+		t.Fatalf("complete: %s", r.Msg)
+	}
+	matter := &rest.Matter{}
+	r.decode(t, matter)
+	if matter.Size != int64(len(content)) || matter.OwnerMd5 != md5Of(content) {
+		t.Fatalf("the assembled file is %d bytes of md5 %s", matter.Size, matter.OwnerMd5)
+	}
+	if util.PathExists(resumed.StagingPath()) {
+		t.Fatalf("the chunks of %s are left", resumed.Uuid)
+	}
+}
+
+// chunks of a wrong checksum or out of range are refused and never recorded.
+func TestUploadSessionRejectsChunk(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role\nchunkstu,123456,USER")
+	student := &tankClient{username: "chunkstu", password: TANK_PASSWORD}
+	content := chunkedContent()
+
+	r := student.createUploadSession(t, "reject.bin", len(content))
+	uploadSession := &rest.UploadSession{}
+	r.decode(t, uploadSession)
+
+	first := chunkOf(content, 0)
+	if r := student.sendChunk(t, uploadSession, 0, first, md5Of([]byte("other"))); r.Code != "BAD_REQUEST" {
+		t.Fatalf("a chunk of a wrong md5 should be refused, got %s", r.Code)
+	}
+	for _, index := range []int64{-1, 3} {
+		if r := student.sendChunk(t, uploadSession, index, first, md5Of(first)); r.Code != "BAD_REQUEST" {
+			t.Fatalf("chunk %d should be out of range, got %s", index, r.Code)
+		}
+	}
+	if chunks := student.uploadSessionDetail(t, uploadSession).Chunks; len(chunks) != 0 {
+		t.Fatalf("no chunk should be received, got %v", chunks)
+	}
+	if r := student.post(t, "/api/upload/session/complete", url.Values{"uuid": {uploadSession.Uuid}}); r.Code != "BAD_REQUEST" {
+		t.Fatalf("an upload missing chunks should not complete, got %s", r.Code)
+	}
+}
+
+// the expired uploads are removed with their chunks.
+func TestUploadSessionCleanExpired(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role\nexpirestu,123456,USER")
+	student := &tankClient{username: "expirestu", password: TANK_PASSWORD}
+	content := chunkedContent()
+
+	r := student.createUploadSession(t, "expire.bin", len(content))
+	uploadSession := &rest.UploadSession{}
+	r.decode(t, uploadSession)
+	if r := student.sendChunk(t, uploadSession, 0, chunkOf(content, 0), md5Of(chunkOf(content, 0))); r.Code != "OK" {
+		t.Fatalf("chunk 0: %s", r.Msg)
+	}
+
+	core.CONTEXT.GetDB().Model(&rest.UploadSession{}).Where("uuid = ?", uploadSession.Uuid).Update("expire_time", time.Now().Add(-time.Minute))
+	core.CONTEXT.GetBean(&rest.UploadSessionService{}).(*rest.UploadSessionService).CleanExpired()
+
+	var count int64
+	core.CONTEXT.GetDB().Model(&rest.UploadChunk{}).Where("upload_session_uuid = ?", uploadSession.Uuid).Count(&count)
+	if count != 0 || util.PathExists(uploadSession.StagingPath()) {
+		t.Fatalf("the chunks of the expired upload are left")
+	}
+	if r := student.post(t, "/api/upload/session/detail", url.Values{"uuid": {uploadSession.Uuid}}); r.Code == "OK" {
+		t.Fatalf("the expired upload is still there")
+	}
+}
+
+// the unfinished uploads count against the total size limit, and their number is limited.
+func TestUploadSessionLimits(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role\nlimitstu,123456,USER")
+	student := &tankClient{username: "limitstu", password: TANK_PASSWORD}
+	content := chunkedContent()
+
+	user := &rest.User{}
+	core.CONTEXT.GetDB().Where("username = ?", "limitstu").First(user)
+	core.CONTEXT.GetDB().Model(&rest.Space{}).Where("uuid = ?", user.SpaceUuid).Update("total_size_limit", 3*len(content)/2)
+
+	if r := student.createUploadSession(t, "quota1.bin", len(content)); r.Code != "OK" {
+		t.Fatalf("the first upload fits: %s", r.Msg)
+	}
+	if r := student.createUploadSession(t, "quota2.bin", len(content)); r.Code != "BAD_REQUEST" {
+		t.Fatalf("the second upload goes beyond the limit with the first, got %s", r.Code)
+	}
+	if r := student.createUploadSession(t, "quota1.bin", len(content)); r.Code != "OK" {
+		t.Fatalf("the first upload resumes: %s", r.Msg)
+	}
+
+	core.CONTEXT.GetDB().Model(&rest.Space{}).Where("uuid = ?", user.SpaceUuid).Update("total_size_limit", -1)
+	for i := 2; i <= rest.UPLOAD_SESSION_MAX_OPEN; i++ {
+		if r := student.createUploadSession(t, fmt.Sprintf("open%d.bin", i), len(content)); r.Code != "OK" {
+			t.Fatalf("upload %d: %s", i, r.Msg)
+		}
+	}
+	if r := student.createUploadSession(t, "onemore.bin", len(content)); r.Code != "BAD_REQUEST" {
+		t.Fatalf("the uploads beyond %d should be refused, got %s", rest.UPLOAD_SESSION_MAX_OPEN, r.Code)
+	}
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/rest"
+	"gorm.io/gorm"
+)
+
+// a row failing to be written undoes the rows and colleges written before it.
+func TestUserImportRollback(t *testing.T) {
+	startTank(t)
+
+	callbacks := core.CONTEXT.GetDB().Callback().Create()
+	err := callbacks.Before("gorm:create").Register("test:fail_import", func(db *gorm.DB) {
+		if userProfile, ok := db.Statement.Dest.(*rest.UserProfile); ok && userProfile.StudentId == "2024069" {
+			_ = db.AddError(errors.New("disk full"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = callbacks.Remove("test:fail_import") }()
+
+	roster := "username,password,role,studentId,college\n" +
+		"importok,123456,USER,2024068,RollbackCollege\n" +
+		"importfail,123456,USER,2024069,RollbackCollege"
+	r := tankAdmin().upload(t, "/api/user/import", url.Values{"createCollege": {"true"}}, "roster.csv", []byte(roster))
+	if r.Code == "OK" {
+		t.Fatal("import should fail")
+	}
+
+	userDao := core.CONTEXT.GetBean(&rest.UserDao{}).(*rest.UserDao)
+	for _, username := range []string{"importok", "importfail"} {
+		if userDao.FindByUsername(username) != nil {
+			t.Errorf("%s should be rolled back", username)
+		}
+	}
+	spaceDao := core.CONTEXT.GetBean(&rest.SpaceDao{}).(*rest.SpaceDao)
+	if spaceDao.CountByName("importok") > 0 {
+		t.Error("space importok should be rolled back")
+	}
+	collegeDao := core.CONTEXT.GetBean(&rest.CollegeDao{}).(*rest.CollegeDao)
+	if collegeDao.FindByName("RollbackCollege") != nil {
+		t.Error("college RollbackCollege should be rolled back")
+	}
+}
+
+// a role changed by importing a roster again is audited.
+func TestUserImportAuditsRole(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role\nimportrole,123456,USER")
+	tankImport(t, "username,password,role\nimportrole,123456,JUDGE")
+
+	user := rest.User{}
+	core.CONTEXT.GetDB().Where("username = ?", "importrole").First(&user)
+	var auditLog rest.AuditLog
+	db := core.CONTEXT.GetDB().Where("action = ? AND target_id = ?", rest.AUDIT_ACTION_USER_ROLE, user.Uuid).First(&auditLog)
+	if db.Error != nil {
+		t.Fatalf("the role change is not audited: %s", db.Error.Error())
+	}
+	if auditLog.Before != `{"role":"USER"}` || auditLog.After != `{"role":"JUDGE"}` || auditLog.ActorName != TANK_ADMIN_USERNAME {
+		t.Fatalf("unexpected audit log %s -> %s by %s", auditLog.Before, auditLog.After, auditLog.ActorName)
+	}
+}
@@ -0,0 +1,48 @@
+package test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/eyebluecn/tank/code/core"
+	"github.com/eyebluecn/tank/code/rest"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// a file cannot come back from the recycle bin into a submission folder after its deadline.
+func TestRecoveryAfterDeadline(t *testing.T) {
+	startTank(t)
+	tankImport(t, "username,password,role,studentId,college\nwinstu,123456,USER,2024090,WinCollege")
+	student := &tankClient{username: "winstu", password: TANK_PASSWORD}
+
+	track := tankTrack(t, "WinTrack")
+	folder, _ := student.submit(t, track, "WinWork")
+	demo := student.put(t, folder, "demo.txt", []byte("the demo"))
+	if r := student.post(t, "/api/matter/soft/delete", url.Values{"uuid": {demo.Uuid}}); r.Code != "OK" {
+		t.Fatalf("soft delete: %s", r.Msg)
+	}
+
+	local, _ := time.LoadLocation("Local")
+	windowConfig, err := jsoniter.ConfigCompatibleWithStandardLibrary.MarshalToString(&rest.WindowConfig{Windows: []*rest.SubmissionWindow{{
+		TrackId:   track.Id,
+		OpenTime:  time.Now().Add(-2 * time.Hour).In(local).Format("2006-01-02 15:04:05"),
+		CloseTime: time.Now().Add(-time.Hour).In(local).Format("2006-01-02 15:04:05"),
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := tankAdmin().post(t, "/api/preference/edit/window/config", url.Values{"windowConfig": {windowConfig}}); r.Code != "OK" {
+		t.Fatalf("close the window: %s", r.Msg)
+	}
+	defer tankAdmin().post(t, "/api/preference/edit/window/config", url.Values{"windowConfig": {`{"windows":[]}`}})
+
+	if r := student.post(t, "/api/matter/recovery", url.Values{"uuid": {demo.Uuid}}); r.Code == "OK" {
+		t.Fatal("recovery after the deadline should be refused")
+	}
+	matter := &rest.Matter{}
+	core.CONTEXT.GetDB().Where("uuid = ?", demo.Uuid).First(matter)
+	if !matter.Deleted {
+		t.Fatal("demo.txt is recovered after the deadline")
+	}
+}
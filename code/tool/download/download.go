@@ -228,18 +228,29 @@ func DownloadFile(
 		PanicError(e)
 	}()
 
-	//content-disposition tell browser to download rather than preview.
-	if withContentDisposition {
-		fileName := url.QueryEscape(filename)
-		writer.Header().Set("content-disposition", "attachment; filename=\""+fileName+"\"")
-	}
-
 	fileInfo, err := diskFile.Stat()
 	if err != nil {
 		panic("cannot load fileInfo from disk." + filePath)
 	}
 
-	modifyTime := fileInfo.ModTime()
+	DownloadContent(writer, request, diskFile, fileInfo.Size(), fileInfo.ModTime(), filename, withContentDisposition)
+}
+
+// download the content of size, eg. a file of a storage. the ranges are served by seeking the content.
+func DownloadContent(
+	writer http.ResponseWriter,
+	request *http.Request,
+	diskFile io.ReadSeeker,
+	size int64,
+	modifyTime time.Time,
+	filename string,
+	withContentDisposition bool) {
+
+	//content-disposition tell browser to download rather than preview.
+	if withContentDisposition {
+		fileName := url.QueryEscape(filename)
+		writer.Header().Set("content-disposition", "attachment; filename=\""+fileName+"\"")
+	}
 
 	if CheckLastModified(writer, request, modifyTime) {
 		return
@@ -277,8 +288,6 @@ func DownloadFile(
 		ctype = ctypes[0]
 	}
 
-	size := fileInfo.Size()
-
 	// handle Content-Range header.
 	sendSize := size
 	var sendContent io.Reader = diskFile
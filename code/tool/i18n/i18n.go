@@ -53,6 +53,12 @@ var (
 	SpaceExclusive                 = &Item{English: `user can only own ONE space`, Chinese: `一个用户只能拥有一个私有空间`}
 	SpaceMemberExist               = &Item{English: `space member %s exists`, Chinese: `用户 %s 已经是空间的成员`}
 	PermissionDenied               = &Item{English: `permission denied.`, Chinese: `没有操作权限`}
+	SubmissionWindowNotOpen        = &Item{English: `submission "%s" cannot be changed before %s`, Chinese: `作品"%s"的提交时间从 %s 开始，当前不能修改`}
+	SubmissionWindowClosed         = &Item{English: `submission "%s" was closed at %s, changes are not allowed`, Chinese: `作品"%s"已于 %s 截止提交，不能再修改`}
+	UploadChunkChecksumError       = &Item{English: `checksum of chunk %d mismatched, upload it again`, Chinese: `分片 %d 校验失败，请重新上传该分片`}
+	UploadChunkMissing             = &Item{English: `%d chunks are not uploaded yet`, Chinese: `还有 %d 个分片没有上传`}
+	UploadChecksumError            = &Item{English: `checksum of "%s" mismatched after assembly`, Chinese: `"%s" 合并后校验失败`}
+	StorageMigrating               = &Item{English: `space %s is being moved to another storage, try later`, Chinese: `空间 %s 正在迁移存储，请稍后再试`}
 )
 
 func (this *Item) Message(request *http.Request) string {
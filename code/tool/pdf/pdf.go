@@ -0,0 +1,71 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+)
+
+const CONTENT_TYPE = "application/pdf"
+
+// WriteImage writes a single page pdf showing the image on the whole page.
+// the image is embedded as jpeg, and the page is sized by the image at the dpi, eg. a 2480x3508 image at 300 dpi is an A4 page.
+func WriteImage(writer io.Writer, img image.Image, dpi float64) error {
+	if dpi <= 0 {
+		return fmt.Errorf("dpi must be positive")
+	}
+
+	//jpeg of a gray image has one component only, always embed rgb.
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+
+	jpegBuffer := &bytes.Buffer{}
+	if err := jpeg.Encode(jpegBuffer, rgba, &jpeg.Options{Quality: 92}); err != nil {
+		return err
+	}
+
+	width := float64(bounds.Dx()) * 72 / dpi
+	height := float64(bounds.Dy()) * 72 / dpi
+	content := fmt.Sprintf("q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q", width, height)
+
+	objects := [][]byte{
+		[]byte("<< /Type /Catalog /Pages 2 0 R >>"),
+		[]byte("<< /Type /Pages /Kids [3 0 R] /Count 1 >>"),
+		[]byte(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /XObject << /Im0 5 0 R >> >> /Contents 4 0 R >>", width, height)),
+		stream("", []byte(content)),
+		stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode ", bounds.Dx(), bounds.Dy()), jpegBuffer.Bytes()),
+	}
+
+	buffer := &bytes.Buffer{}
+	buffer.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buffer.Len()
+		fmt.Fprintf(buffer, "%d 0 obj\n", i+1)
+		buffer.Write(object)
+		buffer.WriteString("\nendobj\n")
+	}
+
+	xref := buffer.Len()
+	fmt.Fprintf(buffer, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buffer, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buffer, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+// a stream object with the extra dictionary entries.
+func stream(dictionary string, data []byte) []byte {
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "<< %s/Length %d >>\nstream\n", dictionary, len(data))
+	buffer.Write(data)
+	buffer.WriteString("\nendstream")
+	return buffer.Bytes()
+}
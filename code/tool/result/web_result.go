@@ -38,6 +38,7 @@ var (
 	PRECONDITION_FAILED    = &CodeWrapper{Code: "PRECONDITION_FAILED", HttpStatus: http.StatusPreconditionFailed, Description: "412 precondition failed"}
 	UNSUPPORTED_MEDIA_TYPE = &CodeWrapper{Code: "UNSUPPORTED_MEDIA_TYPE", HttpStatus: http.StatusUnsupportedMediaType, Description: "415 conflict"}
 	RANGE_NOT_SATISFIABLE  = &CodeWrapper{Code: "RANGE_NOT_SATISFIABLE", HttpStatus: http.StatusRequestedRangeNotSatisfiable, Description: "range not satisfiable"}
+	TOO_MANY_REQUESTS      = &CodeWrapper{Code: "TOO_MANY_REQUESTS", HttpStatus: http.StatusTooManyRequests, Description: "429 too many requests"}
 	NOT_INSTALLED          = &CodeWrapper{Code: "NOT_INSTALLED", HttpStatus: http.StatusInternalServerError, Description: "application not installed"}
 	SERVER                 = &CodeWrapper{Code: "SERVER", HttpStatus: http.StatusInternalServerError, Description: "server error"}
 	UNKNOWN                = &CodeWrapper{Code: "UNKNOWN", HttpStatus: http.StatusInternalServerError, Description: "server unknow error"}
@@ -70,6 +71,8 @@ func FetchHttpStatus(code string) int {
 		return UNSUPPORTED_MEDIA_TYPE.HttpStatus
 	} else if code == RANGE_NOT_SATISFIABLE.Code {
 		return RANGE_NOT_SATISFIABLE.HttpStatus
+	} else if code == TOO_MANY_REQUESTS.Code {
+		return TOO_MANY_REQUESTS.HttpStatus
 	} else if code == NOT_INSTALLED.Code {
 		return NOT_INSTALLED.HttpStatus
 	} else if code == SERVER.Code {
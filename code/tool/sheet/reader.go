@@ -0,0 +1,226 @@
+package sheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// format of a file by its extension. empty for unsupported files.
+func FormatOf(filename string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".xlsx":
+		return FORMAT_XLSX
+	case ".csv":
+		return FORMAT_CSV
+	}
+	return ""
+}
+
+// read all the rows of a csv, or of the first sheet of a xlsx. rows[i] is the line i+1.
+func Read(format string, reader io.ReaderAt, size int64) ([][]string, error) {
+	switch format {
+	case FORMAT_XLSX:
+		return ReadXlsx(reader, size)
+	case FORMAT_CSV:
+		return ReadCsv(io.NewSectionReader(reader, 0, size))
+	}
+	return nil, errors.New("unsupported format " + format)
+}
+
+// read a csv. the utf-8 bom is skipped, and rows may have different numbers of fields.
+func ReadCsv(reader io.Reader) ([][]string, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimPrefix(content, UTF8_BOM)
+
+	csvReader := csv.NewReader(bytes.NewReader(content))
+	csvReader.FieldsPerRecord = -1
+	return csvReader.ReadAll()
+}
+
+type xlsxText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+// plain text of a shared or inline string, rich text runs joined.
+func (this *xlsxText) String() string {
+	if len(this.R) == 0 {
+		return this.T
+	}
+	var builder strings.Builder
+	for _, r := range this.R {
+		builder.WriteString(r.T)
+	}
+	return builder.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []*xlsxText `xml:"si"`
+}
+
+type xlsxCell struct {
+	R  string    `xml:"r,attr"`
+	T  string    `xml:"t,attr"`
+	V  string    `xml:"v"`
+	Is *xlsxText `xml:"is"`
+}
+
+type xlsxRow struct {
+	R     int         `xml:"r,attr"`
+	Cells []*xlsxCell `xml:"c"`
+}
+
+type xlsxSheet struct {
+	Rows []*xlsxRow `xml:"sheetData>row"`
+}
+
+type xlsxWorkbookXml struct {
+	Sheets []struct {
+		Id string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		Id     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// 0-based column index of a cell reference like "AB12". -1 if there is no column.
+func ColumnIndex(ref string) int {
+	index := 0
+	letters := 0
+	for _, c := range strings.ToUpper(ref) {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		index = index*26 + int(c-'A') + 1
+		letters++
+	}
+	if letters == 0 {
+		return -1
+	}
+	return index - 1
+}
+
+func unmarshalPart(files map[string]*zip.File, name string, v any) (bool, error) {
+	file, ok := files[name]
+	if !ok {
+		return false, nil
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return true, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	return true, xml.NewDecoder(reader).Decode(v)
+}
+
+// path of the first sheet in the zip.
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	workbook := &xlsxWorkbookXml{}
+	rels := &xlsxRelationships{}
+	if ok, err := unmarshalPart(files, "xl/workbook.xml", workbook); err != nil || !ok {
+		return "", errors.New("not a xlsx file")
+	}
+	if ok, err := unmarshalPart(files, "xl/_rels/workbook.xml.rels", rels); err != nil || !ok || len(workbook.Sheets) == 0 {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	for _, relationship := range rels.Relationships {
+		if relationship.Id == workbook.Sheets[0].Id {
+			if strings.HasPrefix(relationship.Target, "/") {
+				return strings.TrimPrefix(relationship.Target, "/"), nil
+			}
+			return path.Join("xl", relationship.Target), nil
+		}
+	}
+	return "xl/worksheets/sheet1.xml", nil
+}
+
+// read the first sheet of a xlsx as text. missing rows and cells are empty.
+func ReadXlsx(reader io.ReaderAt, size int64) ([][]string, error) {
+	zipReader, err := zip.NewReader(reader, size)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File)
+	for _, file := range zipReader.File {
+		files[file.Name] = file
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedStrings := &xlsxSharedStrings{}
+	if _, err = unmarshalPart(files, "xl/sharedStrings.xml", sharedStrings); err != nil {
+		return nil, err
+	}
+
+	sheet := &xlsxSheet{}
+	if ok, err := unmarshalPart(files, sheetPath, sheet); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.New("sheet not found")
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		rowIndex := len(rows)
+		if row.R > 0 {
+			rowIndex = row.R - 1
+		}
+		for len(rows) <= rowIndex {
+			rows = append(rows, []string{})
+		}
+
+		var values []string
+		for _, cell := range row.Cells {
+			columnIndex := ColumnIndex(cell.R)
+			if columnIndex < 0 {
+				columnIndex = len(values)
+			}
+			for len(values) <= columnIndex {
+				values = append(values, "")
+			}
+
+			switch cell.T {
+			case "s":
+				index, err := strconv.Atoi(cell.V)
+				if err == nil && index >= 0 && index < len(sharedStrings.Items) {
+					values[columnIndex] = sharedStrings.Items[index].String()
+				}
+			case "inlineStr":
+				if cell.Is != nil {
+					values[columnIndex] = cell.Is.String()
+				}
+			case "b":
+				if cell.V == "1" {
+					values[columnIndex] = "true"
+				} else {
+					values[columnIndex] = "false"
+				}
+			default:
+				values[columnIndex] = cell.V
+			}
+		}
+		rows[rowIndex] = values
+	}
+	return rows, nil
+}
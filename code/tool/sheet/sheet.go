@@ -0,0 +1,110 @@
+package sheet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FORMAT_CSV  = "csv"
+	FORMAT_XLSX = "xlsx"
+
+	CSV_CONTENT_TYPE  = "text/csv; charset=utf-8"
+	XLSX_CONTENT_TYPE = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// excel needs the bom to recognize utf-8 csv.
+var UTF8_BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Writer writes a sheet row by row, so large sheets can be streamed.
+// cells can be string, bool, integers, floats or time.Time.
+type Writer interface {
+	WriteRow(cells ...any) error
+	Close() error
+}
+
+// create a writer of the format. csv is used for unknown formats.
+func NewWriter(format string, writer io.Writer, sheetName string) (Writer, error) {
+	if format == FORMAT_XLSX {
+		return NewXlsxWriter(writer, sheetName)
+	}
+	return NewCsvWriter(writer)
+}
+
+func ContentType(format string) string {
+	if format == FORMAT_XLSX {
+		return XLSX_CONTENT_TYPE
+	}
+	return CSV_CONTENT_TYPE
+}
+
+// text of a cell.
+func FormatCell(cell any) string {
+	switch value := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case bool:
+		if value {
+			return "true"
+		}
+		return "false"
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(value), 'f', -1, 32)
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// spreadsheets run text starting with these as a formula.
+const FORMULA_PREFIXES = "=+-@\t\r"
+
+// text filled in by users is kept as text, so it cannot run as a formula when the sheet is opened.
+func EscapeFormula(text string) string {
+	if text != "" && strings.ContainsRune(FORMULA_PREFIXES, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+// csv writer with utf-8 bom.
+func NewCsvWriter(writer io.Writer) (Writer, error) {
+	if _, err := writer.Write(UTF8_BOM); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: csv.NewWriter(writer)}, nil
+}
+
+func (this *csvWriter) WriteRow(cells ...any) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		//xlsx keeps text as an inline string, while csv has no types and text can run as a formula.
+		if text, ok := cell.(string); ok {
+			record[i] = EscapeFormula(text)
+		} else {
+			record[i] = FormatCell(cell)
+		}
+	}
+	return this.writer.Write(record)
+}
+
+func (this *csvWriter) Close() error {
+	this.writer.Flush()
+	return this.writer.Error()
+}
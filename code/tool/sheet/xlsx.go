@@ -0,0 +1,133 @@
+package sheet
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxSheetHead = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const xlsxSheetTail = `</sheetData></worksheet>`
+
+// characters not allowed in sheet names.
+var sheetNameReplacer = strings.NewReplacer(":", "_", "\\", "_", "/", "_", "?", "_", "*", "_", "[", "_", "]", "_")
+
+// column name of a 0-based index. 0 -> A, 25 -> Z, 26 -> AA.
+func ColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func escapeXml(text string) string {
+	var builder strings.Builder
+	_ = xml.EscapeText(&builder, []byte(text))
+	return builder.String()
+}
+
+// a minimal xlsx writer with a single sheet. strings are written inline, so rows are streamed without buffering.
+type xlsxWriter struct {
+	zipWriter *zip.Writer
+	sheet     *bufio.Writer
+	row       int
+}
+
+func NewXlsxWriter(writer io.Writer, sheetName string) (Writer, error) {
+	sheetName = sheetNameReplacer.Replace(sheetName)
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	}
+	if len([]rune(sheetName)) > 31 {
+		sheetName = string([]rune(sheetName)[:31])
+	}
+
+	zipWriter := zip.NewWriter(writer)
+	parts := [][2]string{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, escapeXml(sheetName))},
+	}
+	for _, part := range parts {
+		partWriter, err := zipWriter.Create(part[0])
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(partWriter, part[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	sheetWriter, err := zipWriter.CreateHeader(&zip.FileHeader{Name: "xl/worksheets/sheet1.xml", Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	this := &xlsxWriter{zipWriter: zipWriter, sheet: bufio.NewWriter(sheetWriter)}
+	if _, err = this.sheet.WriteString(xlsxSheetHead); err != nil {
+		return nil, err
+	}
+	return this, nil
+}
+
+func (this *xlsxWriter) WriteRow(cells ...any) error {
+	this.row++
+	rowStr := strconv.Itoa(this.row)
+
+	var builder strings.Builder
+	builder.WriteString(`<row r="` + rowStr + `">`)
+	for i, cell := range cells {
+		ref := ColumnName(i) + rowStr
+		switch value := cell.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			builder.WriteString(`<c r="` + ref + `"><v>` + FormatCell(value) + `</v></c>`)
+		case bool:
+			v := "0"
+			if value {
+				v = "1"
+			}
+			builder.WriteString(`<c r="` + ref + `" t="b"><v>` + v + `</v></c>`)
+		default:
+			text := FormatCell(value)
+			if text == "" {
+				continue
+			}
+			builder.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">` + escapeXml(text) + `</t></is></c>`)
+		}
+	}
+	builder.WriteString(`</row>`)
+
+	_, err := this.sheet.WriteString(builder.String())
+	return err
+}
+
+func (this *xlsxWriter) Close() error {
+	if _, err := this.sheet.WriteString(xlsxSheetTail); err != nil {
+		return err
+	}
+	if err := this.sheet.Flush(); err != nil {
+		return err
+	}
+	return this.zipWriter.Close()
+}
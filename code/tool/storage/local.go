@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LocalDriver keeps the files in a directory of the local disk, the key being the path under it.
+type LocalDriver struct {
+	root string
+}
+
+func NewLocalDriver(root string) *LocalDriver {
+	return &LocalDriver{root: root}
+}
+
+// the absolute path of the key.
+func (this *LocalDriver) Path(key string) string {
+	return this.root + "/" + key
+}
+
+func (this *LocalDriver) Stat(key string) (*FileInfo, error) {
+	fileInfo, err := os.Stat(this.Path(key))
+	if err != nil {
+		return nil, err
+	}
+	return NewFileInfo(baseName(key), fileInfo.Size(), fileInfo.ModTime(), fileInfo.IsDir()), nil
+}
+
+func (this *LocalDriver) List(key string) ([]*FileInfo, error) {
+	entries, err := os.ReadDir(this.Path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var fileInfos []*FileInfo
+	for _, entry := range entries {
+		fileInfo, err := entry.Info()
+		if err != nil {
+			//removed in the meantime.
+			continue
+		}
+		fileInfos = append(fileInfos, NewFileInfo(entry.Name(), fileInfo.Size(), fileInfo.ModTime(), fileInfo.IsDir()))
+	}
+	return fileInfos, nil
+}
+
+func (this *LocalDriver) Open(key string) (File, error) {
+	file, err := os.Open(this.Path(key))
+	if err != nil {
+		return nil, err
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if fileInfo.IsDir() {
+		_ = file.Close()
+		return nil, notExist("open", key)
+	}
+	return &localFile{File: file, info: NewFileInfo(baseName(key), fileInfo.Size(), fileInfo.ModTime(), false)}, nil
+}
+
+// rename when the local file is on the same disk, otherwise copy it.
+func (this *LocalDriver) PutFile(key string, localPath string) error {
+	destPath := this.Path(key)
+	if err := os.MkdirAll(filepath.Dir(destPath), 0777); err != nil {
+		return err
+	}
+	if err := os.Rename(localPath, destPath); err == nil {
+		return nil
+	}
+	if err := copyFile(localPath, destPath); err != nil {
+		return err
+	}
+	return os.Remove(localPath)
+}
+
+func (this *LocalDriver) Copy(srcKey string, destKey string) error {
+	destPath := this.Path(destKey)
+	if err := os.MkdirAll(filepath.Dir(destPath), 0777); err != nil {
+		return err
+	}
+	return copyFile(this.Path(srcKey), destPath)
+}
+
+func (this *LocalDriver) Rename(oldKey string, newKey string) error {
+	newPath := this.Path(newKey)
+	if err := os.MkdirAll(filepath.Dir(newPath), 0777); err != nil {
+		return err
+	}
+	return os.Rename(this.Path(oldKey), newPath)
+}
+
+func (this *LocalDriver) Remove(key string) error {
+	err := os.Remove(this.Path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (this *LocalDriver) RemoveDir(key string) error {
+	entries, err := os.ReadDir(this.Path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if len(entries) > 0 {
+		return nil
+	}
+	return this.Remove(key)
+}
+
+func (this *LocalDriver) RemoveAll(key string) error {
+	return os.RemoveAll(this.Path(key))
+}
+
+func (this *LocalDriver) MakeDir(key string) error {
+	return os.MkdirAll(this.Path(key), 0777)
+}
+
+type localFile struct {
+	*os.File
+	info *FileInfo
+}
+
+func (this *localFile) Info() *FileInfo {
+	return this.info
+}
+
+// copy through a temp file, so that destPath is never half written.
+func copyFile(srcPath string, destPath string) error {
+	srcFile, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = srcFile.Close()
+	}()
+
+	tempFile, err := os.CreateTemp(filepath.Dir(destPath), ".copy-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tempFile, srcFile)
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempFile.Name(), 0777)
+	}
+	if err == nil {
+		err = os.Rename(tempFile.Name(), destPath)
+	}
+	if err != nil {
+		_ = os.Remove(tempFile.Name())
+	}
+	return err
+}
@@ -0,0 +1,519 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// a single PUT cannot be larger.
+	S3_PUT_MAX_SIZE = 5 * 1024 * 1024 * 1024
+	// a request is given up if no byte is sent or received for so long.
+	S3_IDLE_TIMEOUT = time.Minute
+)
+
+type S3Config struct {
+	//eg. https://s3.amazonaws.com or http://127.0.0.1:9000
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	//keys are stored under the prefix, so that a bucket can be shared.
+	Prefix string
+	//address the bucket by path rather than by host, as minio and most self hosted services do.
+	PathStyle bool
+	//S3_IDLE_TIMEOUT if zero.
+	IdleTimeout time.Duration
+}
+
+// S3Driver keeps the files in a bucket of an s3 compatible service. the requests are signed by SignV4.
+// directories are marked by empty objects named "key/", as the consoles of s3 do, so that empty ones exist too.
+type S3Driver struct {
+	config   S3Config
+	scheme   string
+	host     string
+	basePath string
+	client   *http.Client
+}
+
+func NewS3Driver(config S3Config) (*S3Driver, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %s", config.Endpoint)
+	}
+	if config.Bucket == "" {
+		return nil, errors.New("bucket is required")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = S3_IDLE_TIMEOUT
+	}
+	config.Prefix = strings.Trim(config.Prefix, "/")
+
+	driver := &S3Driver{config: config, scheme: endpoint.Scheme, host: endpoint.Host, client: &http.Client{}}
+	if config.PathStyle {
+		driver.basePath = "/" + UriEncode(config.Bucket, false)
+	} else {
+		driver.host = config.Bucket + "." + endpoint.Host
+	}
+	return driver, nil
+}
+
+// the object name of the key.
+func (this *S3Driver) objectName(key string) string {
+	if this.config.Prefix == "" {
+		return key
+	}
+	return this.config.Prefix + "/" + key
+}
+
+// the key of the object name.
+func (this *S3Driver) keyOf(objectName string) string {
+	if this.config.Prefix == "" {
+		return objectName
+	}
+	return strings.TrimPrefix(objectName, this.config.Prefix+"/")
+}
+
+// send a signed request. objectName is empty for the requests to the bucket.
+func (this *S3Driver) do(method string, objectName string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+
+	rawPath := this.basePath + "/" + UriEncode(objectName, true)
+	path, _ := url.PathUnescape(rawPath)
+	rawQuery := ""
+	if query != nil {
+		var pairs []string
+		for name, values := range query {
+			for _, value := range values {
+				pairs = append(pairs, UriEncode(name, false)+"="+UriEncode(value, false))
+			}
+		}
+		rawQuery = strings.Join(pairs, "&")
+	}
+	requestUrl := &url.URL{Scheme: this.scheme, Host: this.host, Path: path, RawPath: rawPath, RawQuery: rawQuery}
+
+	//the deadline is put off while the body goes either way, so that large files are not cut off but a stalled
+	//service is given up.
+	ctx, cancel := context.WithCancel(context.Background())
+	timer := time.AfterFunc(this.config.IdleTimeout, cancel)
+	if body != nil && size > 0 {
+		body = &s3IdleReader{Reader: body, timer: timer, timeout: this.config.IdleTimeout}
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, requestUrl.String(), body)
+	if err != nil {
+		timer.Stop()
+		cancel()
+		return nil, err
+	}
+	//keep the path exactly as signed.
+	request.URL = requestUrl
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	payloadHash := EMPTY_PAYLOAD
+	if body != nil {
+		request.ContentLength = size
+		payloadHash = UNSIGNED_PAYLOAD
+	}
+	SignV4(request, this.config.AccessKey, this.config.SecretKey, this.config.Region, payloadHash, time.Now())
+
+	response, err := this.client.Do(request)
+	if err != nil {
+		timer.Stop()
+		cancel()
+		return nil, err
+	}
+	response.Body = &s3IdleBody{
+		ReadCloser: response.Body,
+		reader:     s3IdleReader{Reader: response.Body, timer: timer, timeout: this.config.IdleTimeout},
+		cancel:     cancel,
+	}
+	return response, nil
+}
+
+// s3IdleReader puts off the deadline of the request whenever something is read.
+type s3IdleReader struct {
+	io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (this *s3IdleReader) Read(p []byte) (int, error) {
+	n, err := this.Reader.Read(p)
+	if n > 0 {
+		this.timer.Reset(this.timeout)
+	}
+	return n, err
+}
+
+// s3IdleBody is the body of a response, the request is done when it is closed.
+type s3IdleBody struct {
+	io.ReadCloser
+	reader s3IdleReader
+	cancel context.CancelFunc
+}
+
+func (this *s3IdleBody) Read(p []byte) (int, error) {
+	return this.reader.Read(p)
+}
+
+func (this *s3IdleBody) Close() error {
+	this.reader.timer.Stop()
+	err := this.ReadCloser.Close()
+	this.cancel()
+	return err
+}
+
+// the error of a failed response. the body is closed.
+func (this *S3Driver) responseError(op string, key string, response *http.Response) error {
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode == http.StatusNotFound {
+		return notExist(op, key)
+	}
+	s3Error := &struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}{}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 64*1024))
+	if xml.Unmarshal(body, s3Error) == nil && s3Error.Code != "" {
+		return fmt.Errorf("%s %s: %s %s", op, key, s3Error.Code, s3Error.Message)
+	}
+	return fmt.Errorf("%s %s: %s", op, key, response.Status)
+}
+
+// a request expecting no body in return.
+func (this *S3Driver) doEmpty(op string, method string, key string, objectName string, header http.Header, body io.Reader, size int64) error {
+	response, err := this.do(method, objectName, nil, header, body, size)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode/100 != 2 {
+		return this.responseError(op, key, response)
+	}
+	//copy answers 200 with an error in the body if it fails on the way.
+	content, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return err
+	}
+	if strings.Contains(string(content), "<Error>") {
+		return fmt.Errorf("%s %s: %s", op, key, string(content))
+	}
+	return nil
+}
+
+func (this *S3Driver) head(key string) (*FileInfo, error) {
+	response, err := this.do(http.MethodHead, this.objectName(key), nil, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, this.responseError("stat", key, response)
+	}
+	_ = response.Body.Close()
+
+	modTime, _ := http.ParseTime(response.Header.Get("Last-Modified"))
+	return NewFileInfo(baseName(key), response.ContentLength, modTime, false), nil
+}
+
+func (this *S3Driver) Stat(key string) (*FileInfo, error) {
+	fileInfo, err := this.head(key)
+	if err == nil || !os.IsNotExist(err) {
+		return fileInfo, err
+	}
+	//a directory, marked or with something under it.
+	objects, _, err := this.list(key+"/", "", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, notExist("stat", key)
+	}
+	return NewFileInfo(baseName(key), 0, objects[0].modTime, true), nil
+}
+
+type s3Object struct {
+	key     string
+	size    int64
+	modTime time.Time
+}
+
+// the objects under prefix, and the sub prefixes if delimited by slash. at most limit objects if limit > 0.
+func (this *S3Driver) list(prefix string, delimiter string, limit int) ([]*s3Object, []string, error) {
+
+	var objects []*s3Object
+	var prefixes []string
+	continuationToken := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", this.objectName(prefix))
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if limit > 0 {
+			query.Set("max-keys", strconv.Itoa(limit))
+		}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		response, err := this.do(http.MethodGet, "", query, nil, nil, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		if response.StatusCode != http.StatusOK {
+			return nil, nil, this.responseError("list", prefix, response)
+		}
+
+		listResult := &struct {
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+			Contents              []struct {
+				Key          string `xml:"Key"`
+				Size         int64  `xml:"Size"`
+				LastModified string `xml:"LastModified"`
+			} `xml:"Contents"`
+			CommonPrefixes []struct {
+				Prefix string `xml:"Prefix"`
+			} `xml:"CommonPrefixes"`
+		}{}
+		err = xml.NewDecoder(response.Body).Decode(listResult)
+		_ = response.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, content := range listResult.Contents {
+			modTime, _ := time.Parse(time.RFC3339, content.LastModified)
+			objects = append(objects, &s3Object{key: this.keyOf(content.Key), size: content.Size, modTime: modTime})
+		}
+		for _, commonPrefix := range listResult.CommonPrefixes {
+			prefixes = append(prefixes, this.keyOf(commonPrefix.Prefix))
+		}
+
+		if !listResult.IsTruncated || listResult.NextContinuationToken == "" || (limit > 0 && len(objects) >= limit) {
+			return objects, prefixes, nil
+		}
+		continuationToken = listResult.NextContinuationToken
+	}
+}
+
+func (this *S3Driver) List(key string) ([]*FileInfo, error) {
+	prefix := key + "/"
+	objects, prefixes, err := this.list(prefix, "/", 0)
+	if err != nil {
+		return nil, err
+	}
+	var fileInfos []*FileInfo
+	for _, subPrefix := range prefixes {
+		fileInfos = append(fileInfos, NewFileInfo(baseName(strings.TrimSuffix(subPrefix, "/")), 0, time.Time{}, true))
+	}
+	for _, object := range objects {
+		//the marker of the directory itself.
+		if object.key == prefix {
+			continue
+		}
+		fileInfos = append(fileInfos, NewFileInfo(baseName(object.key), object.size, object.modTime, false))
+	}
+	return fileInfos, nil
+}
+
+func (this *S3Driver) Open(key string) (File, error) {
+	fileInfo, err := this.head(key)
+	if err != nil {
+		return nil, err
+	}
+	return &s3File{driver: this, key: key, info: fileInfo}, nil
+}
+
+func (this *S3Driver) PutFile(key string, localPath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	if fileInfo.Size() > S3_PUT_MAX_SIZE {
+		_ = file.Close()
+		return fmt.Errorf("put %s: %d bytes exceed the limit of a single put", key, fileInfo.Size())
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	//a request without body would not send the empty file.
+	var body io.Reader = file
+	if fileInfo.Size() == 0 {
+		body = strings.NewReader("")
+	}
+	err = this.doEmpty("put", http.MethodPut, key, this.objectName(key), header, body, fileInfo.Size())
+	_ = file.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(localPath)
+}
+
+func (this *S3Driver) Copy(srcKey string, destKey string) error {
+	header := http.Header{}
+	header.Set("x-amz-copy-source", UriEncode("/"+this.config.Bucket+"/"+this.objectName(srcKey), true))
+	return this.doEmpty("copy", http.MethodPut, srcKey, this.objectName(destKey), header, nil, 0)
+}
+
+// objects cannot be renamed, they are copied and removed one by one.
+func (this *S3Driver) Rename(oldKey string, newKey string) error {
+	_, err := this.head(oldKey)
+	if err == nil {
+		if err := this.Copy(oldKey, newKey); err != nil {
+			return err
+		}
+		return this.Remove(oldKey)
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	objects, _, err := this.list(oldKey+"/", "", 0)
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		return notExist("rename", oldKey)
+	}
+	for _, object := range objects {
+		objectNewKey := newKey + strings.TrimPrefix(object.key, oldKey)
+		if err := this.Copy(object.key, objectNewKey); err != nil {
+			return err
+		}
+		if err := this.Remove(object.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *S3Driver) Remove(key string) error {
+	err := this.doEmpty("remove", http.MethodDelete, key, this.objectName(key), nil, nil, 0)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (this *S3Driver) RemoveDir(key string) error {
+	objects, _, err := this.list(key+"/", "", 2)
+	if err != nil {
+		return err
+	}
+	if len(objects) == 1 && objects[0].key == key+"/" {
+		return this.Remove(key + "/")
+	}
+	return nil
+}
+
+func (this *S3Driver) RemoveAll(key string) error {
+	if err := this.Remove(key); err != nil {
+		return err
+	}
+	objects, _, err := this.list(key+"/", "", 0)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := this.Remove(object.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mark the directory and its parents, so that they are listed even if empty.
+func (this *S3Driver) MakeDir(key string) error {
+	parts := strings.Split(key, "/")
+	for i := range parts {
+		marker := strings.Join(parts[:i+1], "/") + "/"
+		err := this.doEmpty("mkdir", http.MethodPut, key, this.objectName(marker), nil, strings.NewReader(""), 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// s3File reads the object by a ranged GET from the offset, which is sent again after a seek.
+type s3File struct {
+	driver *S3Driver
+	key    string
+	info   *FileInfo
+	offset int64
+	body   io.ReadCloser
+}
+
+func (this *s3File) Info() *FileInfo {
+	return this.info
+}
+
+func (this *s3File) Read(p []byte) (int, error) {
+	if this.offset >= this.info.Size() {
+		return 0, io.EOF
+	}
+	if this.body == nil {
+		header := http.Header{}
+		header.Set("Range", fmt.Sprintf("bytes=%d-", this.offset))
+		response, err := this.driver.do(http.MethodGet, this.driver.objectName(this.key), nil, header, nil, 0)
+		if err != nil {
+			return 0, err
+		}
+		if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusPartialContent {
+			return 0, this.driver.responseError("read", this.key, response)
+		}
+		this.body = response.Body
+	}
+	n, err := this.body.Read(p)
+	this.offset += int64(n)
+	return n, err
+}
+
+func (this *s3File) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += this.offset
+	case io.SeekEnd:
+		offset += this.info.Size()
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	if offset != this.offset && this.body != nil {
+		_ = this.body.Close()
+		this.body = nil
+	}
+	this.offset = offset
+	return offset, nil
+}
+
+func (this *s3File) Close() error {
+	if this.body != nil {
+		err := this.body.Close()
+		this.body = nil
+		return err
+	}
+	return nil
+}
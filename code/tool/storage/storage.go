@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Driver stores the files under keys like "space/root/dir/file.txt". keys are separated by slash, without the leading one.
+// a missing file is reported by an error satisfying os.IsNotExist.
+type Driver interface {
+	// info of the file or directory at key.
+	Stat(key string) (*FileInfo, error)
+	// the files and directories right under the directory at key. empty if the directory does not exist.
+	List(key string) ([]*FileInfo, error)
+	// open the file at key for reading.
+	Open(key string) (File, error)
+	// move the local file into the store at key, overwriting what is there. the local file is gone afterwards.
+	PutFile(key string, localPath string) error
+	// copy the file at srcKey to destKey.
+	Copy(srcKey string, destKey string) error
+	// move the file or directory at oldKey to newKey.
+	Rename(oldKey string, newKey string) error
+	// remove the file at key. a missing file is fine.
+	Remove(key string) error
+	// remove the directory at key if nothing is in it.
+	RemoveDir(key string) error
+	// remove the file or directory at key with everything under it.
+	RemoveAll(key string) error
+	// create the directory at key and its parents.
+	MakeDir(key string) error
+}
+
+// File is a file opened for reading. it seeks without reading what is skipped, so ranges are served cheaply.
+type File interface {
+	io.ReadSeekCloser
+	Info() *FileInfo
+}
+
+// FileInfo describes a file or directory of a driver. it is a fs.FileInfo, so it works with zip headers and webdav.
+type FileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func NewFileInfo(name string, size int64, modTime time.Time, dir bool) *FileInfo {
+	return &FileInfo{name: name, size: size, modTime: modTime, dir: dir}
+}
+
+func (this *FileInfo) Name() string       { return this.name }
+func (this *FileInfo) Size() int64        { return this.size }
+func (this *FileInfo) ModTime() time.Time { return this.modTime }
+func (this *FileInfo) IsDir() bool        { return this.dir }
+func (this *FileInfo) Sys() any           { return nil }
+
+func (this *FileInfo) Mode() fs.FileMode {
+	if this.dir {
+		return fs.ModeDir | 0777
+	}
+	return 0666
+}
+
+// the error of a missing key.
+func notExist(op string, key string) error {
+	return &fs.PathError{Op: op, Path: key, Err: os.ErrNotExist}
+}
+
+// the name of the key, ie. the part after the last slash.
+func baseName(key string) string {
+	for i := len(key) - 1; i >= 0; i-- {
+		if key[i] == '/' {
+			return key[i+1:]
+		}
+	}
+	return key
+}
@@ -0,0 +1,83 @@
+package util
+
+import (
+	"math"
+	"sort"
+)
+
+// arithmetic mean. 0 for empty values.
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// population standard deviation. 0 for empty values.
+func StdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	mean := Mean(values)
+	var sum float64
+	for _, value := range values {
+		sum += (value - mean) * (value - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+// standard score of the value. 0 when all the values are the same.
+func ZScore(value float64, mean float64, stdDev float64) float64 {
+	if stdDev == 0 {
+		return 0
+	}
+	return (value - mean) / stdDev
+}
+
+// mean after dropping the `trim` highest and `trim` lowest values, and the indexes of the dropped values.
+// nothing is dropped when there are not more than 2*trim values.
+func TrimmedMean(values []float64, trim int) (float64, []int) {
+	if trim <= 0 || len(values) <= 2*trim {
+		return Mean(values), []int{}
+	}
+
+	indexes := make([]int, len(values))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	//stable, so the earlier one of the same values is dropped as the lowest.
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return values[indexes[i]] < values[indexes[j]]
+	})
+
+	dropped := make([]int, 0, 2*trim)
+	dropped = append(dropped, indexes[:trim]...)
+	dropped = append(dropped, indexes[len(indexes)-trim:]...)
+	sort.Ints(dropped)
+
+	kept := make([]float64, 0, len(values)-2*trim)
+	for _, index := range indexes[trim : len(indexes)-trim] {
+		kept = append(kept, values[index])
+	}
+	return Mean(kept), dropped
+}
+
+// runs of at least minLength equal adjacent values, as [start, end) indexes.
+func EqualRuns(values []float64, minLength int) [][2]int {
+	runs := [][2]int{}
+	start := 0
+	for i := 1; i <= len(values); i++ {
+		if i < len(values) && values[i] == values[start] {
+			continue
+		}
+		if i-start >= minLength {
+			runs = append(runs, [2]int{start, i})
+		}
+		start = i
+	}
+	return runs
+}
@@ -1,8 +1,11 @@
 package util
 
 import (
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 )
 
 // get ip from request
@@ -25,6 +28,57 @@ func GetIpAddress(r *http.Request) string {
 	return ipAddress
 }
 
+// get the ip of the client from request. the forwarded headers are taken only if the request comes from one of the
+// trusted proxies, so that the ip cannot be made up by the client.
+func GetClientIp(r *http.Request, trustedProxies []string) string {
+	ipAddress := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ipAddress); err == nil {
+		ipAddress = host
+	}
+	if !IsTrustedProxy(ipAddress, trustedProxies) {
+		return ipAddress
+	}
+
+	//every proxy appends the address it got the request from. the first one from the right not a trusted proxy is the client.
+	forwarded := strings.TrimSpace(r.Header.Get("X-Forwarded-For"))
+	if forwarded == "" {
+		if realIp := strings.TrimSpace(r.Header.Get("X-Real-Ip")); realIp != "" {
+			return realIp
+		}
+		return ipAddress
+	}
+	hops := strings.Split(forwarded, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		ipAddress = hop
+		if !IsTrustedProxy(hop, trustedProxies) {
+			break
+		}
+	}
+	return ipAddress
+}
+
+// whether the ip is one of the proxies, which are ips or cidrs.
+func IsTrustedProxy(ipAddress string, trustedProxies []string) bool {
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range trustedProxies {
+		if strings.Contains(proxy, "/") {
+			if _, network, err := net.ParseCIDR(proxy); err == nil && network.Contains(ip) {
+				return true
+			}
+		} else if proxyIp := net.ParseIP(proxy); proxyIp != nil && proxyIp.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // get host from request
 func GetHostFromRequest(request *http.Request) string {
 
@@ -64,3 +118,48 @@ func DisableCache(writer http.ResponseWriter) {
 	writer.Header().Set("Cache-Control", "no-cache")
 	writer.Header().Set("Expires", "0")
 }
+
+// RateLimiter allows a key, eg. an ip, a fixed number of times in every window.
+type RateLimiter struct {
+	limit   int
+	window  time.Duration
+	mutex   sync.Mutex
+	windows map[string]*rateWindow
+	pruned  time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{limit: limit, window: window, windows: make(map[string]*rateWindow)}
+}
+
+// count a request of the key at the time, false if the key has used up its window.
+func (this *RateLimiter) Allow(key string, now time.Time) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	//forget the keys whose windows are over, so that the map does not grow with every ip ever seen.
+	if now.Sub(this.pruned) >= this.window {
+		for k, w := range this.windows {
+			if now.Sub(w.start) >= this.window {
+				delete(this.windows, k)
+			}
+		}
+		this.pruned = now
+	}
+
+	w := this.windows[key]
+	if w == nil || now.Sub(w.start) >= this.window {
+		w = &rateWindow{start: now}
+		this.windows[key] = w
+	}
+	if w.count >= this.limit {
+		return false
+	}
+	w.count++
+	return true
+}
@@ -90,6 +90,20 @@ func ExtractRequestOptionalInt(request *http.Request, key string, defaultValue i
 	}
 }
 
+// param is optional. when missing, return the default value.
+func ExtractRequestOptionalInt64(request *http.Request, key string, defaultValue int64) int64 {
+	str := request.FormValue(key)
+	if str == "" {
+		return defaultValue
+	} else {
+		intVal, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		return intVal
+	}
+}
+
 // param is required. when missing, panic error.
 func ExtractRequestOptionalString(request *http.Request, key string, defaultValue string) string {
 	str := request.FormValue(key)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -68,3 +69,12 @@ func RandomString4() string {
 
 	return string(b)
 }
+
+// replace the {key} placeholders in the text by the values. unknown placeholders are kept.
+func FillPlaceholders(text string, values map[string]string) string {
+	pairs := make([]string, 0, len(values)*2)
+	for key, value := range values {
+		pairs = append(pairs, "{"+key+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}